@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper"
+)
+
+type DynamoApiClient interface {
+	Scan(context.Context, *dynamodb.ScanInput, ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+type SchedulerApiClient interface {
+	CreateSchedule(context.Context, *scheduler.CreateScheduleInput, ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error)
+}
+
+// Export writes events of a user, or of all users when userID is empty, to w as JSON lines and
+// returns the number of exported events
+func Export(ctx context.Context, client DynamoApiClient, table, userID string, w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	exported := 0
+	var startKey map[string]dynamotypes.AttributeValue
+	for {
+		var items []map[string]dynamotypes.AttributeValue
+		var lastKey map[string]dynamotypes.AttributeValue
+		if userID == "" {
+			res, err := client.Scan(ctx, &dynamodb.ScanInput{
+				TableName:         aws.String(table),
+				ExclusiveStartKey: startKey,
+				ConsistentRead:    aws.Bool(true),
+			})
+			if err != nil {
+				return exported, err
+			}
+			items, lastKey = res.Items, res.LastEvaluatedKey
+		} else {
+			res, err := client.Query(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(table),
+				KeyConditionExpression: aws.String("UserID = :userID"),
+				ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+					":userID": &dynamotypes.AttributeValueMemberS{Value: userID},
+				},
+				ExclusiveStartKey: startKey,
+				ConsistentRead:    aws.Bool(true),
+			})
+			if err != nil {
+				return exported, err
+			}
+			items, lastKey = res.Items, res.LastEvaluatedKey
+		}
+
+		for _, item := range items {
+			encoded, err := encodeItem(item)
+			if err != nil {
+				return exported, err
+			}
+			if err := encoder.Encode(encoded); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+
+		if len(lastKey) == 0 {
+			return exported, nil
+		}
+		startKey = lastKey
+	}
+}
+
+// Restorer re-creates events and their schedules from a dump written by Export
+type Restorer struct {
+	DynamoClient    DynamoApiClient
+	SchedulerClient SchedulerApiClient
+	Table           string
+	// Target is where schedules are created, they're not created when its FunctionArn is empty
+	Target alarmschedule.Target
+	// Overwrite replaces events that already exist, by default they're left untouched
+	Overwrite bool
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
+}
+
+// RestoreReport is a result of restoring a dump
+type RestoreReport struct {
+	// Events is the number of events put into the table
+	Events int `json:"events"`
+	// Existing is the number of events skipped, because they already exist in the table
+	Existing int `json:"existing"`
+	// Schedules is the number of schedules created or already existing
+	Schedules int `json:"schedules"`
+	// Expired is the number of alarms that won't fire anymore, their schedules aren't created
+	Expired int `json:"expired"`
+}
+
+// Restore reads a dump from r and puts its events into the table. Schedules are created before
+// their event, the way creator does it, and existing ones are kept, so an interrupted restore
+// can be run again. Schedules of existing events are created as well, so a table restored from
+// a point in time backup can get its schedules back
+func (r *Restorer) Restore(ctx context.Context, dump io.Reader) (*RestoreReport, error) {
+	now := time.Now()
+	if r.Now != nil {
+		now = r.Now()
+	}
+
+	report := &RestoreReport{}
+	scanner := bufio.NewScanner(dump)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		item, err := decodeItem(scanner.Bytes())
+		if err != nil {
+			return report, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if r.Target.FunctionArn != "" {
+			for _, alarm := range alarmschedule.FromEvent(dynamomapper.SimplifyDynamoDBItem(item)) {
+				if !alarmschedule.WillFire(alarm, now) {
+					report.Expired++
+					continue
+				}
+				if err := r.createSchedule(ctx, alarm); err != nil {
+					return report, fmt.Errorf("line %d: schedule %s: %w", line, alarm.Name, err)
+				}
+				report.Schedules++
+			}
+		}
+
+		input := &dynamodb.PutItemInput{
+			TableName: aws.String(r.Table),
+			Item:      item,
+		}
+		if !r.Overwrite {
+			input.ConditionExpression = aws.String("attribute_not_exists(UserID)")
+		}
+		var errConditionFailed *dynamotypes.ConditionalCheckFailedException
+		if _, err := r.DynamoClient.PutItem(ctx, input); errors.As(err, &errConditionFailed) {
+			report.Existing++
+			continue
+		} else if err != nil {
+			return report, fmt.Errorf("line %d: %w", line, err)
+		}
+		report.Events++
+	}
+	return report, scanner.Err()
+}
+
+func (r *Restorer) createSchedule(ctx context.Context, alarm alarmschedule.Alarm) error {
+	input, err := alarmschedule.CreateScheduleInput(alarm, r.Target)
+	if err != nil {
+		return err
+	}
+	var errConflict *schedulertypes.ConflictException
+	if _, err := r.SchedulerClient.CreateSchedule(ctx, input); err != nil && !errors.As(err, &errConflict) {
+		return err
+	}
+	return nil
+}
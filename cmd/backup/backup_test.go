@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+)
+
+func event(userID, eventID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"UserID":   &types.AttributeValueMemberS{Value: userID},
+		"EventID":  &types.AttributeValueMemberS{Value: eventID},
+		"Title":    &types.AttributeValueMemberS{Value: "Stand-up"},
+		"Message":  &types.AttributeValueMemberS{Value: "{{title}} in 10 minutes"},
+		"Timezone": &types.AttributeValueMemberS{Value: "Europe/Warsaw"},
+		"Crons": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			userID + "_" + eventID + "_cron": &types.AttributeValueMemberS{Value: "0 10 ? * MON-FRI *"},
+		}},
+		"Dates": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			userID + "_" + eventID + "_date": &types.AttributeValueMemberS{Value: "2024-03-01T10:00:00"},
+		}},
+		"Phones":           &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "default"}}},
+		"IgnoreQuietHours": &types.AttributeValueMemberBOOL{Value: true},
+		"Segments":         &types.AttributeValueMemberN{Value: "1"},
+	}
+}
+
+func TestExportRestore(t *testing.T) {
+	ctx := context.Background()
+	source := fakes.NewDynamoDB()
+	source.CreateTable("GO_AlarmTable", "UserID", "EventID")
+	saved := []map[string]types.AttributeValue{event("1", "a"), event("1", "b"), event("2", "c")}
+	for _, item := range saved {
+		if _, err := source.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("GO_AlarmTable"), Item: item}); err != nil {
+			t.Fatalf("Error when saving event: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		desc     string
+		userID   string
+		expected int
+	}{
+		{desc: "whole table", expected: 3},
+		{desc: "single user", userID: "1", expected: 2},
+		{desc: "user without events", userID: "3", expected: 0},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var dump bytes.Buffer
+			exported, err := Export(ctx, source, "GO_AlarmTable", tC.userID, &dump)
+			if err != nil {
+				t.Fatalf("Error when exporting events: %v", err)
+			}
+			if lines := strings.Count(dump.String(), "\n"); exported != tC.expected || lines != tC.expected {
+				t.Errorf("Received result: %v (%v lines) is different than expected one: %v", exported, lines, tC.expected)
+			}
+		})
+	}
+
+	var dump bytes.Buffer
+	if _, err := Export(ctx, source, "GO_AlarmTable", "", &dump); err != nil {
+		t.Fatalf("Error when exporting events: %v", err)
+	}
+
+	target := fakes.NewDynamoDB()
+	target.CreateTable("GO_dev_AlarmTable", "UserID", "EventID")
+	sched := fakes.NewScheduler(fakes.NewVirtualClock(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)))
+	sched.CreateGroup("GO_dev_Alarms")
+
+	restorer := Restorer{
+		DynamoClient:    target,
+		SchedulerClient: sched,
+		Table:           "GO_dev_AlarmTable",
+		Target:          alarmschedule.Target{FunctionArn: "executor_arn", RoleArn: "role_arn", Group: "GO_dev_Alarms"},
+		Now:             func() time.Time { return time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC) },
+	}
+
+	report, err := restorer.Restore(ctx, bytes.NewReader(dump.Bytes()))
+	if err != nil {
+		t.Fatalf("Error when restoring events: %v", err)
+	}
+	if expected := (RestoreReport{Events: 3, Schedules: 3, Expired: 3}); *report != expected {
+		t.Errorf("Received result: %+v is different than expected one: %+v", *report, expected)
+	}
+
+	restored, _ := target.Items("GO_dev_AlarmTable")
+	if !reflect.DeepEqual(restored, saved) {
+		t.Errorf("Received items: %v are different than expected ones: %v", restored, saved)
+	}
+	if _, _, ok := sched.Schedule("GO_dev_Alarms", "1_a_cron"); !ok {
+		t.Errorf("Schedule of recurring alarm wasn't restored")
+	}
+	if _, _, ok := sched.Schedule("GO_dev_Alarms", "1_a_date"); ok {
+		t.Errorf("Schedule of expired alarm was restored")
+	}
+
+	// restore can be run again after it's interrupted
+	report, err = restorer.Restore(ctx, bytes.NewReader(dump.Bytes()))
+	if err != nil {
+		t.Fatalf("Error when restoring events again: %v", err)
+	}
+	if expected := (RestoreReport{Existing: 3, Schedules: 3, Expired: 3}); *report != expected {
+		t.Errorf("Received result: %+v is different than expected one: %+v", *report, expected)
+	}
+
+	if _, err := restorer.Restore(ctx, strings.NewReader(`{"UserID": {"X": "1"}}`)); err == nil {
+		t.Errorf("Invalid dump was restored")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Items are dumped in DynamoDB JSON format, the one used by table exports to S3, so that types of
+// attributes (e.g. numbers and sets) survive a round trip, e.g. {"UserID": {"S": "1"}}
+
+func encodeItem(item map[string]types.AttributeValue) (map[string]interface{}, error) {
+	encoded := make(map[string]interface{}, len(item))
+	for name, value := range item {
+		v, err := encodeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		encoded[name] = v
+	}
+	return encoded, nil
+}
+
+func encodeValue(value types.AttributeValue) (map[string]interface{}, error) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]interface{}{"S": v.Value}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]interface{}{"N": v.Value}, nil
+	case *types.AttributeValueMemberB:
+		return map[string]interface{}{"B": v.Value}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]interface{}{"BOOL": v.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]interface{}{"NULL": v.Value}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string]interface{}{"SS": v.Value}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string]interface{}{"NS": v.Value}, nil
+	case *types.AttributeValueMemberBS:
+		return map[string]interface{}{"BS": v.Value}, nil
+	case *types.AttributeValueMemberM:
+		m, err := encodeItem(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"M": m}, nil
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, 0, len(v.Value))
+		for _, element := range v.Value {
+			e, err := encodeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, e)
+		}
+		return map[string]interface{}{"L": list}, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute value %T", value)
+}
+
+// rawValue is an attribute value in DynamoDB JSON format, only one of its fields is set
+type rawValue struct {
+	S    *string              `json:"S"`
+	N    *string              `json:"N"`
+	B    []byte               `json:"B"`
+	BOOL *bool                `json:"BOOL"`
+	NULL *bool                `json:"NULL"`
+	SS   []string             `json:"SS"`
+	NS   []string             `json:"NS"`
+	BS   [][]byte             `json:"BS"`
+	M    map[string]*rawValue `json:"M"`
+	L    []*rawValue          `json:"L"`
+}
+
+func decodeItem(data []byte) (map[string]types.AttributeValue, error) {
+	var raw map[string]*rawValue
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return decodeMap(raw)
+}
+
+func decodeMap(raw map[string]*rawValue) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(raw))
+	for name, value := range raw {
+		v, err := decodeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		item[name] = v
+	}
+	return item, nil
+}
+
+func decodeValue(raw *rawValue) (types.AttributeValue, error) {
+	switch {
+	case raw == nil:
+		return nil, fmt.Errorf("missing attribute value")
+	case raw.S != nil:
+		return &types.AttributeValueMemberS{Value: *raw.S}, nil
+	case raw.N != nil:
+		return &types.AttributeValueMemberN{Value: *raw.N}, nil
+	case raw.B != nil:
+		return &types.AttributeValueMemberB{Value: raw.B}, nil
+	case raw.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *raw.BOOL}, nil
+	case raw.NULL != nil:
+		return &types.AttributeValueMemberNULL{Value: *raw.NULL}, nil
+	case raw.SS != nil:
+		return &types.AttributeValueMemberSS{Value: raw.SS}, nil
+	case raw.NS != nil:
+		return &types.AttributeValueMemberNS{Value: raw.NS}, nil
+	case raw.BS != nil:
+		return &types.AttributeValueMemberBS{Value: raw.BS}, nil
+	case raw.M != nil:
+		m, err := decodeMap(raw.M)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	case raw.L != nil:
+		list := make([]types.AttributeValue, 0, len(raw.L))
+		for _, element := range raw.L {
+			e, err := decodeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, e)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute value")
+}
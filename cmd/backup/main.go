@@ -0,0 +1,120 @@
+// Command backup exports events from the alarms table to JSON lines and restores them, together
+// with their schedules, from such a dump.
+//
+//	backup export -table GO_AlarmTable [-user <userID>] > events.jsonl
+//	backup restore -table GO_AlarmTable -group GO_Alarms -function-arn <executor> -role-arn <role> < events.jsonl
+//
+// Items are written in DynamoDB JSON format, so a dump can also be restored from a table export.
+// Schedules aren't created when -function-arn is empty, e.g. to restore a table to another stage.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "export":
+		export(os.Args[2:])
+	case "restore":
+		restore(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: backup export|restore [flags], run with -h to list flags")
+	os.Exit(2)
+}
+
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	table := flags.String("table", "GO_AlarmTable", "name of the alarms table")
+	userID := flags.String("user", "", "ID of a user whose events are exported, all events are exported when empty")
+	file := flags.String("file", "", "file the dump is written to, standard output is used when empty")
+	_ = flags.Parse(args)
+
+	var w io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	exported, err := Export(context.Background(), dynamodb.NewFromConfig(cfg), *table, *userID, w)
+	if err != nil {
+		log.Fatalf("export failed after %d events: %v", exported, err)
+	}
+	log.Printf("exported %d events", exported)
+}
+
+func restore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	table := flags.String("table", "GO_AlarmTable", "name of the alarms table")
+	file := flags.String("file", "", "file the dump is read from, standard input is used when empty")
+	group := flags.String("group", "GO_Alarms", "name of the schedule group")
+	functionArn := flags.String("function-arn", "", "ARN of alarm executor function, schedules aren't created when empty")
+	roleArn := flags.String("role-arn", "", "ARN of a role scheduler assumes to invoke alarm executor")
+	deadLetterArn := flags.String("dead-letter-arn", "", "ARN of a queue alarms that can't be delivered are sent to")
+	overwrite := flags.Bool("overwrite", false, "replace events that already exist")
+	_ = flags.Parse(args)
+
+	var r io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	restorer := Restorer{
+		DynamoClient:    dynamodb.NewFromConfig(cfg),
+		SchedulerClient: scheduler.NewFromConfig(cfg),
+		Table:           *table,
+		Target: alarmschedule.Target{
+			FunctionArn:   *functionArn,
+			RoleArn:       *roleArn,
+			Group:         *group,
+			DeadLetterArn: *deadLetterArn,
+		},
+		Overwrite: *overwrite,
+	}
+	report, err := restorer.Restore(context.Background(), r)
+	output, _ := json.Marshal(report)
+	if err != nil {
+		log.Fatalf("restore failed: %v, restored so far: %s", err, output)
+	}
+	log.Println(string(output))
+}
@@ -0,0 +1,51 @@
+// Command filterpolicy adds phone labels to filter policies of SNS subscriptions created before
+// labels were introduced. Such subscriptions only filter on user ID, so they receive alarms sent
+// to every phone of their user.
+//
+//	filterpolicy -topic-arn <alarms topic> -phones-table GO_PhonesTable [-dry-run]
+//
+// A subscription gets the label it's saved with in the phones table, or the default one when it's
+// only known to Cognito. Subscriptions already filtering on labels are left as they are, so it can
+// be run again after it's interrupted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+func main() {
+	topicArn := flag.String("topic-arn", "", "ARN of the topic alarms are sent through")
+	phonesTable := flag.String("phones-table", "GO_PhonesTable", "name of the phones table")
+	dryRun := flag.Bool("dry-run", false, "only report subscriptions that would be migrated")
+	flag.Parse()
+
+	if *topicArn == "" {
+		log.Fatal("-topic-arn is required")
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	migrator := Migrator{
+		SnsClient:    sns.NewFromConfig(cfg),
+		DynamoClient: dynamodb.NewFromConfig(cfg),
+		TopicArn:     *topicArn,
+		PhonesTable:  *phonesTable,
+		DryRun:       *dryRun,
+	}
+	report, err := migrator.Migrate(context.Background())
+	output, _ := json.Marshal(report)
+	if err != nil {
+		log.Fatalf("migration failed: %v, migrated so far: %s", err, output)
+	}
+	log.Println(string(output))
+}
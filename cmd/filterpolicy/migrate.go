@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook"
+)
+
+type SnsApiClient interface {
+	ListSubscriptionsByTopic(context.Context, *sns.ListSubscriptionsByTopicInput, ...func(*sns.Options)) (*sns.ListSubscriptionsByTopicOutput, error)
+	GetSubscriptionAttributes(context.Context, *sns.GetSubscriptionAttributesInput, ...func(*sns.Options)) (*sns.GetSubscriptionAttributesOutput, error)
+	SetSubscriptionAttributes(context.Context, *sns.SetSubscriptionAttributesInput, ...func(*sns.Options)) (*sns.SetSubscriptionAttributesOutput, error)
+}
+
+type DynamoApiClient interface {
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+// Migrator adds phone labels to filter policies of subscriptions of a topic
+type Migrator struct {
+	SnsClient    SnsApiClient
+	DynamoClient DynamoApiClient
+	TopicArn     string
+	PhonesTable  string
+	// DryRun only reports subscriptions that would be migrated
+	DryRun bool
+}
+
+// Migration is a subscription whose filter policy got a label
+type Migration struct {
+	SubscriptionArn string `json:"subscriptionArn"`
+	UserID          string `json:"userID"`
+	Label           string `json:"label"`
+}
+
+// Report is a result of migration
+type Report struct {
+	DryRun        bool        `json:"dryRun"`
+	Subscriptions int         `json:"subscriptions"`
+	Migrated      []Migration `json:"migrated"`
+	// Skipped are subscriptions whose filter policy doesn't name a single user, they're not ours
+	Skipped []string `json:"skipped"`
+}
+
+// Migrate sets filter policy matching user ID and phone label on every subscription of the topic
+// that only filters on user ID
+func (m *Migrator) Migrate(ctx context.Context) (*Report, error) {
+	report := &Report{DryRun: m.DryRun, Migrated: []Migration{}, Skipped: []string{}}
+	// labels of users' subscriptions by their ARNs, queried once per user
+	labels := make(map[string]map[string]string)
+
+	var nextToken *string
+	for {
+		res, err := m.SnsClient.ListSubscriptionsByTopic(ctx, &sns.ListSubscriptionsByTopicInput{
+			TopicArn:  aws.String(m.TopicArn),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, sub := range res.Subscriptions {
+			report.Subscriptions++
+			arn := aws.ToString(sub.SubscriptionArn)
+			// Subscriptions that aren't confirmed yet don't have attributes to migrate
+			if arn == "PendingConfirmation" {
+				continue
+			}
+
+			attributes, err := m.SnsClient.GetSubscriptionAttributes(ctx, &sns.GetSubscriptionAttributesInput{SubscriptionArn: aws.String(arn)})
+			if err != nil {
+				return report, err
+			}
+			var policy map[string][]string
+			if err := json.Unmarshal([]byte(attributes.Attributes["FilterPolicy"]), &policy); err != nil || len(policy["userID"]) != 1 {
+				report.Skipped = append(report.Skipped, arn)
+				continue
+			}
+			if _, ok := policy[phonebook.LabelAttribute]; ok {
+				continue
+			}
+
+			userID := policy["userID"][0]
+			if _, ok := labels[userID]; !ok {
+				if labels[userID], err = m.userLabels(ctx, userID); err != nil {
+					return report, err
+				}
+			}
+			label, ok := labels[userID][arn]
+			if !ok {
+				// Default phone numbers of users that signed up before labels were introduced
+				// are only stored in Cognito
+				label = phonebook.DefaultLabel
+			}
+
+			if !m.DryRun {
+				filterPolicy, err := phonebook.FilterPolicy(userID, label)
+				if err != nil {
+					return report, err
+				}
+				if _, err := m.SnsClient.SetSubscriptionAttributes(ctx, &sns.SetSubscriptionAttributesInput{
+					SubscriptionArn: aws.String(arn),
+					AttributeName:   aws.String("FilterPolicy"),
+					AttributeValue:  aws.String(filterPolicy),
+				}); err != nil {
+					return report, err
+				}
+			}
+			report.Migrated = append(report.Migrated, Migration{SubscriptionArn: arn, UserID: userID, Label: label})
+		}
+
+		if res.NextToken == nil {
+			return report, nil
+		}
+		nextToken = res.NextToken
+	}
+}
+
+// userLabels returns labels of user's phone numbers by ARNs of their subscriptions
+func (m *Migrator) userLabels(ctx context.Context, userID string) (map[string]string, error) {
+	labels := make(map[string]string)
+	var startKey map[string]dynamotypes.AttributeValue
+	for {
+		res, err := m.DynamoClient.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(m.PhonesTable),
+			KeyConditionExpression: aws.String("UserID = :userID"),
+			ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+				":userID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range res.Items {
+			phone := dynamomapper.SimplifyDynamoDBItem(item)
+			arn, _ := phone["SubscriptionArn"].(string)
+			label, _ := phone["Label"].(string)
+			labels[arn] = label
+		}
+
+		if len(res.LastEvaluatedKey) == 0 {
+			return labels, nil
+		}
+		startKey = res.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// mockSNS keeps filter policies of subscriptions of a single topic, listing them one per page
+type mockSNS struct {
+	arns     []string
+	policies map[string]string
+}
+
+func (m *mockSNS) ListSubscriptionsByTopic(ctx context.Context, input *sns.ListSubscriptionsByTopicInput, optFns ...func(*sns.Options)) (*sns.ListSubscriptionsByTopicOutput, error) {
+	start := 0
+	if input.NextToken != nil {
+		start, _ = strconv.Atoi(*input.NextToken)
+	}
+	output := &sns.ListSubscriptionsByTopicOutput{}
+	if start < len(m.arns) {
+		output.Subscriptions = []snstypes.Subscription{{SubscriptionArn: aws.String(m.arns[start])}}
+	}
+	if start+1 < len(m.arns) {
+		output.NextToken = aws.String(strconv.Itoa(start + 1))
+	}
+	return output, nil
+}
+
+func (m *mockSNS) GetSubscriptionAttributes(ctx context.Context, input *sns.GetSubscriptionAttributesInput, optFns ...func(*sns.Options)) (*sns.GetSubscriptionAttributesOutput, error) {
+	attributes := map[string]string{"SubscriptionArn": *input.SubscriptionArn}
+	if policy, ok := m.policies[*input.SubscriptionArn]; ok {
+		attributes["FilterPolicy"] = policy
+	}
+	return &sns.GetSubscriptionAttributesOutput{Attributes: attributes}, nil
+}
+
+func (m *mockSNS) SetSubscriptionAttributes(ctx context.Context, input *sns.SetSubscriptionAttributesInput, optFns ...func(*sns.Options)) (*sns.SetSubscriptionAttributesOutput, error) {
+	m.policies[*input.SubscriptionArn] = *input.AttributeValue
+	return &sns.SetSubscriptionAttributesOutput{}, nil
+}
+
+// mockDynamoDB returns phones saved in the phones table of a user
+type mockDynamoDB struct {
+	phones map[string][]map[string]types.AttributeValue
+}
+
+func (m *mockDynamoDB) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	userID := input.ExpressionAttributeValues[":userID"].(*types.AttributeValueMemberS).Value
+	return &dynamodb.QueryOutput{Items: m.phones[userID]}, nil
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	snsClient := &mockSNS{
+		arns: []string{"legacy", "work", "labeled", "foreign"},
+		policies: map[string]string{
+			// Signed up before labels were introduced, known to Cognito only
+			"legacy": `{"userID":["1"]}`,
+			// Saved in phones table, but subscribed without a label
+			"work":    `{"userID":["2"]}`,
+			"labeled": `{"userID":["2"],"phoneLabel":["default"]}`,
+		},
+	}
+	dynamoClient := &mockDynamoDB{phones: map[string][]map[string]types.AttributeValue{
+		"2": {{
+			"UserID":          &types.AttributeValueMemberS{Value: "2"},
+			"Label":           &types.AttributeValueMemberS{Value: "work"},
+			"PhoneNumber":     &types.AttributeValueMemberS{Value: "+48222222222"},
+			"SubscriptionArn": &types.AttributeValueMemberS{Value: "work"},
+		}},
+	}}
+
+	policies := func() map[string]map[string][]string {
+		policies := make(map[string]map[string][]string)
+		for arn, policy := range snsClient.policies {
+			var decoded map[string][]string
+			if err := json.Unmarshal([]byte(policy), &decoded); err != nil {
+				t.Fatalf("Error when decoding filter policy: %v", err)
+			}
+			policies[arn] = decoded
+		}
+		return policies
+	}
+	before := policies()
+
+	migrator := Migrator{SnsClient: snsClient, DynamoClient: dynamoClient, TopicArn: "topic", PhonesTable: "GO_PhonesTable", DryRun: true}
+	expected := []Migration{{SubscriptionArn: "legacy", UserID: "1", Label: "default"}, {SubscriptionArn: "work", UserID: "2", Label: "work"}}
+
+	report, err := migrator.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Error when running dry migration: %v", err)
+	}
+	if !reflect.DeepEqual(report.Migrated, expected) || !reflect.DeepEqual(report.Skipped, []string{"foreign"}) || report.Subscriptions != 4 {
+		t.Errorf("Received result: %+v is different than expected one: %+v", report, expected)
+	}
+	if after := policies(); !reflect.DeepEqual(after, before) {
+		t.Errorf("Dry run changed filter policies: %v", after)
+	}
+
+	migrator.DryRun = false
+	if report, err = migrator.Migrate(ctx); err != nil {
+		t.Fatalf("Error when migrating: %v", err)
+	}
+	if !reflect.DeepEqual(report.Migrated, expected) {
+		t.Errorf("Received result: %v is different than expected one: %v", report.Migrated, expected)
+	}
+	after := policies()
+	for arn, policy := range map[string]map[string][]string{
+		"legacy":  {"userID": {"1"}, "phoneLabel": {"default"}},
+		"work":    {"userID": {"2"}, "phoneLabel": {"work"}},
+		"labeled": {"userID": {"2"}, "phoneLabel": {"default"}},
+	} {
+		if !reflect.DeepEqual(after[arn], policy) {
+			t.Errorf("Received result: %v is different than expected one: %v", after[arn], policy)
+		}
+	}
+
+	if report, err = migrator.Migrate(ctx); err != nil || len(report.Migrated) != 0 {
+		t.Errorf("Received result: %v, %v is different than expected one: no migrations", report.Migrated, err)
+	}
+}
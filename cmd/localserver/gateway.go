@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// corsHeaders are returned to preflight requests, the same way API Gateway does with default
+// preflight options of the stack
+var corsHeaders = map[string]string{
+	"Access-Control-Allow-Origin":  "*",
+	"Access-Control-Allow-Headers": "Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,X-Amz-User-Agent,Idempotency-Key",
+	"Access-Control-Allow-Methods": "OPTIONS,GET,PUT,POST,DELETE,PATCH,HEAD",
+}
+
+// apiHandler handles API Gateway proxy requests
+type apiHandler func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// gateway emulates API Gateway with Cognito authorizer, converting HTTP requests into proxy
+// requests of handlers
+type gateway struct {
+	mux     *http.ServeMux
+	cognito *fakes.Cognito
+}
+
+func newGateway(cognito *fakes.Cognito) *gateway {
+	return &gateway{mux: http.NewServeMux(), cognito: cognito}
+}
+
+// route mounts handler under method and resource path, e.g. "/alarms/{id}", requiring authorization
+func (g *gateway) route(method, resource string, handler apiHandler) {
+	g.mux.HandleFunc(method+" "+resource, func(w http.ResponseWriter, r *http.Request) {
+		claims, err := g.claims(r.Header.Get("Authorization"))
+		if err != nil {
+			log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			return
+		}
+
+		request, err := proxyRequest(r, resource, claims)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+
+		response, err := handler(r.Context(), request)
+		if err != nil {
+			// API Gateway responds this way when Lambda proxy integration returns an error
+			log.Printf("%s %s: handler failed: %v", r.Method, r.URL.Path, err)
+			writeJSON(w, http.StatusBadGateway, map[string]string{"message": "Internal server error"})
+			return
+		}
+		writeResponse(w, response)
+	})
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for name, value := range corsHeaders {
+		w.Header().Set(name, value)
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+// claims returns Cognito claims of a dev token. Token is either a JWT, whose payload is used without
+// verifying its signature, or a name or sub of a user signed up to local server
+func (g *gateway) claims(authorization string) (map[string]interface{}, error) {
+	token := strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	if token == "" {
+		return nil, fmt.Errorf("missing Authorization header")
+	}
+
+	if parts := strings.Split(token, "."); len(parts) == 3 {
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token payload: %w", err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(payload, &decoded); err != nil {
+			return nil, fmt.Errorf("invalid token payload: %w", err)
+		}
+		// Cognito authorizer passes claims as strings
+		claims := make(map[string]interface{}, len(decoded))
+		for name, value := range decoded {
+			claims[name] = fmt.Sprint(value)
+		}
+		return claims, nil
+	}
+
+	userName, attributes, ok := g.cognito.User(token)
+	if !ok {
+		return nil, fmt.Errorf("user %s not found", token)
+	}
+	claims := map[string]interface{}{"cognito:username": userName}
+	for name, value := range attributes {
+		claims[name] = value
+	}
+	return claims, nil
+}
+
+// proxyRequest converts HTTP request into API Gateway proxy request of given resource
+func proxyRequest(r *http.Request, resource string, claims map[string]interface{}) (events.APIGatewayProxyRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	request := events.APIGatewayProxyRequest{
+		Resource:   resource,
+		Path:       r.URL.Path,
+		HTTPMethod: r.Method,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:    "000000000000",
+			Stage:        "local",
+			RequestID:    uuid.NewString(),
+			ResourcePath: resource,
+			HTTPMethod:   r.Method,
+			Path:         r.URL.Path,
+			Identity:     events.APIGatewayRequestIdentity{SourceIP: r.RemoteAddr, UserAgent: r.UserAgent()},
+			Authorizer:   map[string]interface{}{"claims": claims},
+		},
+	}
+
+	if len(r.Header) > 0 {
+		request.Headers = make(map[string]string, len(r.Header))
+		request.MultiValueHeaders = make(map[string][]string, len(r.Header))
+		for name, values := range r.Header {
+			request.Headers[name] = values[len(values)-1]
+			request.MultiValueHeaders[name] = values
+		}
+	}
+
+	if query := r.URL.Query(); len(query) > 0 {
+		request.QueryStringParameters = make(map[string]string, len(query))
+		request.MultiValueQueryStringParameters = make(map[string][]string, len(query))
+		for name, values := range query {
+			request.QueryStringParameters[name] = values[len(values)-1]
+			request.MultiValueQueryStringParameters[name] = values
+		}
+	}
+
+	for _, segment := range strings.Split(resource, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name := segment[1 : len(segment)-1]
+			if request.PathParameters == nil {
+				request.PathParameters = make(map[string]string)
+			}
+			request.PathParameters[name] = r.PathValue(name)
+		}
+	}
+
+	if utf8.Valid(body) {
+		request.Body = string(body)
+	} else {
+		request.Body = base64.StdEncoding.EncodeToString(body)
+		request.IsBase64Encoded = true
+	}
+	return request, nil
+}
+
+func writeResponse(w http.ResponseWriter, response events.APIGatewayProxyResponse) {
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range response.MultiValueHeaders {
+		w.Header().Del(name)
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	body := []byte(response.Body)
+	if response.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(response.Body)
+		if err != nil {
+			log.Printf("invalid base64 encoded response body: %v", err)
+			writeJSON(w, http.StatusBadGateway, map[string]string{"message": "Internal server error"})
+			return
+		}
+		body = decoded
+	}
+
+	status := response.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
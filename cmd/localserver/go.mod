@@ -0,0 +1,110 @@
+module github.com/Slimo300/Reminder-Serverless-Go/cmd/localserver
+
+go 1.22.0
+
+require (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/account-deleter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-creator v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-getter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/data-exporter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-getter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-modifier v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-verifier v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/post-confirmation-trigger v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-getter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-setter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/reconciler v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/usage-getter v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes v0.0.0-00010101000000-000000000000
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/google/uuid v1.6.0
+)
+
+require (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper v0.0.0-20240821145950-d2da7dbd1a33 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors v0.0.0-20240821145950-d2da7dbd1a33 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/ical v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/idempotency v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/msgtemplate v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing v0.0.0-00010101000000-000000000000 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/aws/aws-sdk-go v1.47.9 // indirect
+	github.com/aws/aws-sdk-go-v2 v1.30.4 // indirect
+	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.4 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.16 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.16 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/v4a v1.3.16 // indirect
+	github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider v1.43.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.34.5 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/checksum v1.3.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.17 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.18 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/s3shared v1.17.16 // indirect
+	github.com/aws/aws-sdk-go-v2/service/lambda v1.58.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.61.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/scheduler v1.10.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sns v1.31.4 // indirect
+	github.com/aws/aws-xray-sdk-go v1.8.5 // indirect
+	github.com/aws/smithy-go v1.20.4 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/klauspost/compress v1.17.6 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/thanhpk/randstr v1.0.6 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.52.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/grpc v1.64.1 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+replace (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule => ../../pkg/features/alarmschedule
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper => ../../pkg/features/dynamomapper
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig => ../../pkg/features/envconfig
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors => ../../pkg/features/errors
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx => ../../pkg/features/httpx
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/ical => ../../pkg/features/ical
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/idempotency => ../../pkg/features/idempotency
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging => ../../pkg/features/logging
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics => ../../pkg/features/metrics
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/msgtemplate => ../../pkg/features/msgtemplate
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook => ../../pkg/features/phonebook
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours => ../../pkg/features/quiethours
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule => ../../pkg/features/schedule
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms => ../../pkg/features/sms
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing => ../../pkg/features/tracing
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage => ../../pkg/features/usage
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool => ../../pkg/features/workerpool
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/account-deleter => ../../pkg/handlers/account-deleter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-creator => ../../pkg/handlers/alarm-creator
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter => ../../pkg/handlers/alarm-deleter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor => ../../pkg/handlers/alarm-executor
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-getter => ../../pkg/handlers/alarm-getter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/data-exporter => ../../pkg/handlers/data-exporter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-getter => ../../pkg/handlers/phone-getter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-modifier => ../../pkg/handlers/phone-modifier
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-verifier => ../../pkg/handlers/phone-verifier
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/post-confirmation-trigger => ../../pkg/handlers/post-confirmation-trigger
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-getter => ../../pkg/handlers/quiet-hours-getter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-setter => ../../pkg/handlers/quiet-hours-setter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/reconciler => ../../pkg/handlers/reconciler
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/usage-getter => ../../pkg/handlers/usage-getter
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes => ../../pkg/testing/fakes
+)
@@ -0,0 +1,310 @@
+// Command localserver runs all handlers of the stack behind a local HTTP server, with DynamoDB,
+// SNS, Cognito and EventBridge Scheduler replaced by in-memory implementations, so the frontend
+// can be developed without deploying the stack.
+//
+// Requests are authorized with a dev token passed in Authorization header, which is either
+// a name or sub of a user signed up with POST /_dev/signup, or a JWT whose claims are used
+// without verification. Sent SMS messages are logged and listed by GET /_dev/sms.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
+	accountdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/account-deleter"
+	alarmcreator "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-creator"
+	alarmdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter"
+	alarmexecutor "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor"
+	alarmgetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-getter"
+	dataexporter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/data-exporter"
+	phonegetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-getter"
+	phonemodifier "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-modifier"
+	phoneverifier "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-verifier"
+	postconfirmationtrigger "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/post-confirmation-trigger"
+	quiethoursgetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-getter"
+	quiethourssetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-setter"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/reconciler"
+	usagegetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/usage-getter"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+// Names of resources, matching the ones created by the stack
+const (
+	phonesTable      = "GO_PhonesTable"
+	alarmsTable      = "GO_AlarmTable"
+	codesTable       = "GO_CodesTable"
+	settingsTable    = "GO_SettingsTable"
+	deliveriesTable  = "GO_DeliveriesTable"
+	usageTable       = "GO_UsageTable"
+	idempotencyTable = "GO_IdempotencyTable"
+	auditTable       = "GO_AuditTable"
+	exportsTable     = "GO_ExportsTable"
+	scheduleGroup    = "GO_Alarms"
+
+	topicArn    = "arn:aws:sns:local:000000000000:GO_ReminderSnsTopic"
+	executorArn = "arn:aws:lambda:local:000000000000:function:GO_AlarmExecutor"
+	roleArn     = "arn:aws:iam::000000000000:role/GO_LambdaExecutorInvokeRole"
+	userPoolID  = "local_GO_ReminderUserPool"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address the server listens on")
+	tick := flag.Duration("tick", time.Second, "how often due schedules are fired")
+	flag.Parse()
+	logging.Setup()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := newServer()
+	go server.scheduler.Run(ctx, *tick, func(err error) { log.Println(err) })
+
+	httpServer := &http.Server{Addr: *addr, Handler: server.gateway}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+
+	log.Printf("local server listening on %s", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+// server wires handlers to in-memory services
+type server struct {
+	dynamo    *fakes.DynamoDB
+	sns       *fakes.SNS
+	cognito   *fakes.Cognito
+	scheduler *fakes.Scheduler
+	gateway   *gateway
+	trigger   *postconfirmationtrigger.Handler
+}
+
+func newServer() *server {
+	s := &server{
+		dynamo:    fakes.NewDynamoDB(),
+		sns:       fakes.NewSNS(fakes.SystemClock{}),
+		cognito:   fakes.NewCognito(),
+		scheduler: fakes.NewScheduler(fakes.SystemClock{}),
+	}
+	s.sns.OnSMS = func(sms fakes.SMS) {
+		log.Printf("SMS to %s: %s", sms.PhoneNumber, sms.Message)
+	}
+	s.gateway = newGateway(s.cognito)
+	s.trigger = &postconfirmationtrigger.Handler{CognitoClient: s.cognito, SnsClient: s.sns, DynamoClient: s.dynamo, Config: postconfirmationtrigger.Config{
+		TopicArn:        topicArn,
+		PhonesTableName: phonesTable,
+	}}
+
+	s.dynamo.CreateTable(phonesTable, "UserID", "Label")
+	s.dynamo.CreateTable(alarmsTable, "UserID", "EventID")
+	s.dynamo.CreateTable(codesTable, "UserID", "")
+	s.dynamo.CreateTable(settingsTable, "UserID", "")
+	s.dynamo.CreateTable(deliveriesTable, "UserID", "DeliveryID")
+	s.dynamo.CreateTable(usageTable, "UserID", "Month")
+	s.dynamo.CreateTable(idempotencyTable, "UserID", "Key")
+	s.dynamo.CreateTable(auditTable, "UserID", "Action")
+	s.dynamo.CreateTable(exportsTable, "UserID", "ExportID")
+	s.scheduler.CreateGroup(scheduleGroup)
+
+	s.routeAlarms()
+	s.routePhones()
+	s.routeSettings()
+	s.routeAccount()
+	s.routeDev()
+	return s
+}
+
+func (s *server) routeAlarms() {
+	executor := &alarmexecutor.Handler{SNSClient: s.sns, DynamoClient: s.dynamo, SchedulerClient: s.scheduler, Config: alarmexecutor.Config{
+		TopicArn:            topicArn,
+		TableName:           alarmsTable,
+		SettingsTableName:   settingsTable,
+		UsageTableName:      usageTable,
+		DeliveriesTableName: deliveriesTable,
+		RoleArn:             roleArn,
+		ScheduleGroup:       scheduleGroup,
+		MaxSegments:         sms.DefaultMaxSegments,
+		MonthlyQuota:        usage.DefaultMonthlyQuota,
+	}}
+	s.scheduler.Register(executorArn, fakes.LambdaTarget(executorArn, executor.Handle))
+
+	creator := &alarmcreator.Handler{DynamoClient: s.dynamo, SchedulerClient: s.scheduler, Config: alarmcreator.Config{
+		TableName:            alarmsTable,
+		IdempotencyTableName: idempotencyTable,
+		PhonesTableName:      phonesTable,
+		Target:               alarmschedule.Target{FunctionArn: executorArn, RoleArn: roleArn, Group: scheduleGroup},
+		MaxSegments:          sms.DefaultMaxSegments,
+		MonthlyQuota:         usage.DefaultMonthlyQuota,
+		SchedulerConcurrency: workerpool.DefaultSize,
+		Limits: alarmcreator.Limits{
+			SchedulesPerRequest: alarmcreator.DefaultSchedulesPerRequest,
+			CronsPerEvent:       alarmcreator.DefaultCronsPerEvent,
+			EventsPerUser:       alarmcreator.DefaultEventsPerUser,
+		},
+	}}
+	s.gateway.route(http.MethodPost, "/alarms", creator.Handle)
+
+	getter := &alarmgetter.AlarmGetterHandler{DynamoClient: s.dynamo, Config: alarmgetter.Config{TableName: alarmsTable}}
+	s.gateway.route(http.MethodGet, "/alarms", getter.Handle)
+
+	deleter := &alarmdeleter.Handler{DynamoClient: s.dynamo, SchedulerClient: s.scheduler, Config: alarmdeleter.Config{
+		TableName:            alarmsTable,
+		ScheduleGroup:        scheduleGroup,
+		SchedulerConcurrency: workerpool.DefaultSize,
+	}}
+	s.gateway.route(http.MethodDelete, "/alarms/{id}", deleter.Handle)
+}
+
+func (s *server) routePhones() {
+	modifier := &phonemodifier.Handler{SnsClient: s.sns, DynamoClient: s.dynamo, Config: phonemodifier.Config{CodesTableName: codesTable}}
+	s.gateway.route(http.MethodPost, "/update-phone-number", modifier.Handle)
+
+	verifier := &phoneverifier.Handler{SnsClient: s.sns, DynamoClient: s.dynamo, CognitoClient: s.cognito, Config: phoneverifier.Config{
+		CodesTableName:  codesTable,
+		PhonesTableName: phonesTable,
+		TopicArn:        topicArn,
+		UserPoolID:      userPoolID,
+	}}
+	s.gateway.route(http.MethodPost, "/verify-phone-number", verifier.Handle)
+
+	getter := &phonegetter.Handler{DynamoClient: s.dynamo, Config: phonegetter.Config{PhonesTableName: phonesTable}}
+	s.gateway.route(http.MethodGet, "/phones", getter.Handle)
+}
+
+func (s *server) routeSettings() {
+	setter := &quiethourssetter.Handler{DynamoClient: s.dynamo, Config: quiethourssetter.Config{SettingsTableName: settingsTable}}
+	s.gateway.route(http.MethodPut, "/quiet-hours", setter.Handle)
+
+	getter := &quiethoursgetter.Handler{DynamoClient: s.dynamo, Config: quiethoursgetter.Config{SettingsTableName: settingsTable}}
+	s.gateway.route(http.MethodGet, "/quiet-hours", getter.Handle)
+
+	usageGetter := &usagegetter.Handler{DynamoClient: s.dynamo, Config: usagegetter.Config{UsageTableName: usageTable, MonthlyQuota: usage.DefaultMonthlyQuota}}
+	s.gateway.route(http.MethodGet, "/me/usage", usageGetter.Handle)
+}
+
+func (s *server) routeAccount() {
+	deleter := &accountdeleter.Handler{DynamoClient: s.dynamo, SchedulerClient: s.scheduler, SnsClient: s.sns, CognitoClient: s.cognito, Config: accountdeleter.Config{
+		AlarmsTableName:      alarmsTable,
+		PhonesTableName:      phonesTable,
+		CodesTableName:       codesTable,
+		SettingsTableName:    settingsTable,
+		DeliveriesTableName:  deliveriesTable,
+		UsageTableName:       usageTable,
+		IdempotencyTableName: idempotencyTable,
+		ExportsTableName:     exportsTable,
+		AuditTableName:       auditTable,
+		UserPoolID:           userPoolID,
+		ScheduleGroup:        scheduleGroup,
+		SchedulerConcurrency: workerpool.DefaultSize,
+	}}
+	s.gateway.route(http.MethodDelete, "/me", deleter.Handle)
+
+	// There is no bucket to store exports in, so they're always returned in responses
+	exporter := &dataexporter.Handler{DynamoClient: s.dynamo, CognitoClient: s.cognito, SchedulerClient: s.scheduler, Config: dataexporter.Config{
+		AlarmsTableName:     alarmsTable,
+		PhonesTableName:     phonesTable,
+		CodesTableName:      codesTable,
+		SettingsTableName:   settingsTable,
+		DeliveriesTableName: deliveriesTable,
+		UsageTableName:      usageTable,
+		ExportsTableName:    exportsTable,
+		UserPoolID:          userPoolID,
+		ScheduleGroup:       scheduleGroup,
+		SyncLimit:           math.MaxInt32,
+	}}
+	s.gateway.route(http.MethodGet, "/me/export", exporter.Handle)
+}
+
+// routeDev mounts endpoints replacing parts of the stack that aren't exposed by API Gateway.
+// They don't require authorization
+func (s *server) routeDev() {
+	s.gateway.mux.HandleFunc("POST /_dev/signup", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			UserName    string `json:"username"`
+			PhoneNumber string `json:"phone_number"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.PhoneNumber == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "phone_number is required"})
+			return
+		}
+
+		sub := uuid.NewString()
+		if body.UserName == "" {
+			body.UserName = sub
+		}
+		if err := s.signUp(r.Context(), body.UserName, sub, body.PhoneNumber); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"message": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"username": body.UserName, "sub": sub, "token": sub})
+	})
+
+	s.gateway.mux.HandleFunc("GET /_dev/sms", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.sns.Messages())
+	})
+
+	rec := &reconciler.Handler{DynamoClient: s.dynamo, SchedulerClient: s.scheduler, Config: reconciler.Config{
+		TableName:            alarmsTable,
+		Target:               alarmschedule.Target{FunctionArn: executorArn, RoleArn: roleArn, Group: scheduleGroup},
+		SchedulerConcurrency: workerpool.DefaultSize,
+	}}
+	s.gateway.mux.HandleFunc("POST /_dev/reconcile", func(w http.ResponseWriter, r *http.Request) {
+		var request reconciler.Request
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "invalid request body"})
+			return
+		}
+
+		report, err := rec.Handle(r.Context(), request)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"message": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+}
+
+// signUp creates a confirmed user and runs post confirmation trigger, as Cognito does when user
+// confirms their phone number
+func (s *server) signUp(ctx context.Context, userName, sub, phoneNumber string) error {
+	s.cognito.AddUser(userName, map[string]string{
+		"sub":                   sub,
+		"phone_number":          phoneNumber,
+		"phone_number_verified": "true",
+	})
+
+	event := events.CognitoEventUserPoolsPostConfirmation{
+		CognitoEventUserPoolsHeader: events.CognitoEventUserPoolsHeader{
+			Version:       "1",
+			TriggerSource: "PostConfirmation_ConfirmSignUp",
+			Region:        "local",
+			UserPoolID:    userPoolID,
+			UserName:      userName,
+		},
+		Request: events.CognitoEventUserPoolsPostConfirmationRequest{
+			UserAttributes: map[string]string{"sub": sub, "phone_number": phoneNumber},
+		},
+	}
+	if _, err := s.trigger.Handle(ctx, event); err != nil {
+		return fmt.Errorf("post confirmation trigger failed: %w", err)
+	}
+	return nil
+}
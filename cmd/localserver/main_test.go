@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	server := httptest.NewServer(newServer().gateway)
+	defer server.Close()
+
+	do := func(method, path, token, body string) (*http.Response, map[string]interface{}) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Error when creating request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error when sending request: %v", err)
+		}
+		defer res.Body.Close()
+
+		var decoded map[string]interface{}
+		_ = json.NewDecoder(res.Body).Decode(&decoded)
+		return res, decoded
+	}
+
+	res, signup := do(http.MethodPost, "/_dev/signup", "", `{"username":"ann","phone_number":"+48111111111"}`)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("Received status: %v is different than expected one: %v", res.StatusCode, http.StatusCreated)
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		body           string
+		expectedStatus int
+	}{
+		{name: "preflight", method: http.MethodOptions, path: "/alarms", expectedStatus: http.StatusNoContent},
+		{name: "no token", method: http.MethodGet, path: "/alarms", expectedStatus: http.StatusUnauthorized},
+		{name: "unknown user", method: http.MethodGet, path: "/alarms", token: "bob", expectedStatus: http.StatusUnauthorized},
+		{name: "user name", method: http.MethodGet, path: "/phones", token: "ann", expectedStatus: http.StatusOK},
+		{name: "user sub", method: http.MethodGet, path: "/phones", token: signup["sub"].(string), expectedStatus: http.StatusOK},
+		{
+			name:   "jwt",
+			method: http.MethodGet,
+			path:   "/me/usage",
+			// {"sub":"7"} payload with empty header and signature
+			token:          "e30.eyJzdWIiOiI3In0.",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "create alarm",
+			method:         http.MethodPost,
+			path:           "/alarms",
+			token:          "ann",
+			body:           `{"message":"Stand-up","timezone":"Europe/Warsaw","crons":["0 10 ? * MON-FRI *"]}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{name: "delete unknown alarm", method: http.MethodDelete, path: "/alarms/1", token: "ann", expectedStatus: http.StatusNotFound},
+		{name: "unknown route", method: http.MethodPatch, path: "/alarms", token: "ann", expectedStatus: http.StatusMethodNotAllowed},
+		{name: "export data", method: http.MethodGet, path: "/me/export", token: "ann", expectedStatus: http.StatusOK},
+		{name: "export archive", method: http.MethodGet, path: "/me/export?format=zip", token: "ann", expectedStatus: http.StatusOK},
+		{name: "delete account", method: http.MethodDelete, path: "/me", token: "ann", expectedStatus: http.StatusOK},
+		{name: "deleted user", method: http.MethodGet, path: "/phones", token: "ann", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			res, _ := do(tC.method, tC.path, tC.token, tC.body)
+			if res.StatusCode != tC.expectedStatus {
+				t.Errorf("Received status: %v is different than expected one: %v", res.StatusCode, tC.expectedStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
+	accountdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/account-deleter"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := accountdeleter.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
+	}
+	tracing.Instrument(&cfg)
+
+	handler := accountdeleter.Handler{
+		DynamoClient:    dynamodb.NewFromConfig(cfg),
+		SchedulerClient: scheduler.NewFromConfig(cfg),
+		SnsClient:       sns.NewFromConfig(cfg),
+		S3Client:        s3.NewFromConfig(cfg),
+		CognitoClient:   cognitoidentityprovider.NewFromConfig(cfg),
+		Config:          handlerConfig,
+	}
+
+	lambda.Start(handler.Invoke)
+}
@@ -3,7 +3,9 @@ module github.com/Slimo300/Reminder-Serverless-Go/lambdas/alarm-deleter
 go 1.22.0
 
 require (
-	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter v0.0.0-20240824160752-4e7921ee5bb6
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter v0.0.0-00010101000000-000000000000
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go-v2/config v1.27.27
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.34.5
@@ -11,7 +13,13 @@ require (
 )
 
 require (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig v0.0.0-00010101000000-000000000000 // indirect
 	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors v0.0.0-20240821145950-d2da7dbd1a33 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool v0.0.0-00010101000000-000000000000 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/aws/aws-sdk-go v1.47.9 // indirect
 	github.com/aws/aws-sdk-go-v2 v1.30.4 // indirect
 	github.com/aws/aws-sdk-go-v2/credentials v1.17.27 // indirect
 	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.16.11 // indirect
@@ -24,7 +32,29 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/sso v1.22.4 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.26.4 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.30.3 // indirect
+	github.com/aws/aws-xray-sdk-go v1.8.5 // indirect
 	github.com/aws/smithy-go v1.20.4 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/klauspost/compress v1.17.6 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.52.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/grpc v1.64.1 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+replace (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig => ../../pkg/features/envconfig
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors => ../../pkg/features/errors
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx => ../../pkg/features/httpx
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging => ../../pkg/features/logging
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule => ../../pkg/features/schedule
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing => ../../pkg/features/tracing
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool => ../../pkg/features/workerpool
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter => ../../pkg/handlers/alarm-deleter
 )
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"os"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
 	alarmdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -11,14 +14,25 @@ import (
 )
 
 func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := alarmdeleter.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
+
 	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
-		return
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
 	}
+	tracing.Instrument(&cfg)
 
 	handler := alarmdeleter.Handler{
 		DynamoClient:    dynamodb.NewFromConfig(cfg),
 		SchedulerClient: scheduler.NewFromConfig(cfg),
+		Config:          handlerConfig,
 	}
 
 	lambda.Start(handler.Handle)
@@ -3,13 +3,27 @@ module github.com/Slimo300/Reminder-Serverless-Go/lambdas/alarm-executor
 go 1.22.0
 
 require (
-	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor v0.0.0-20240824160752-4e7921ee5bb6
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor v0.0.0-00010101000000-000000000000
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go-v2/config v1.27.30
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.34.5
+	github.com/aws/aws-sdk-go-v2/service/scheduler v1.10.4
 	github.com/aws/aws-sdk-go-v2/service/sns v1.31.5
 )
 
 require (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/msgtemplate v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms v0.0.0-00010101000000-000000000000 // indirect
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage v0.0.0-00010101000000-000000000000 // indirect
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/aws/aws-sdk-go v1.47.9 // indirect
 	github.com/aws/aws-sdk-go-v2 v1.30.4 // indirect
 	github.com/aws/aws-sdk-go-v2/credentials v1.17.29 // indirect
 	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.16.12 // indirect
@@ -17,9 +31,37 @@ require (
 	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.16 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.1 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.17 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.18 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sso v1.22.5 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.26.5 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.30.5 // indirect
+	github.com/aws/aws-xray-sdk-go v1.8.5 // indirect
 	github.com/aws/smithy-go v1.20.4 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/klauspost/compress v1.17.6 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	github.com/valyala/fasthttp v1.52.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/grpc v1.64.1 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+replace (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig => ../../pkg/features/envconfig
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging => ../../pkg/features/logging
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics => ../../pkg/features/metrics
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/msgtemplate => ../../pkg/features/msgtemplate
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook => ../../pkg/features/phonebook
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours => ../../pkg/features/quiethours
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule => ../../pkg/features/schedule
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms => ../../pkg/features/sms
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing => ../../pkg/features/tracing
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage => ../../pkg/features/usage
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor => ../../pkg/handlers/alarm-executor
 )
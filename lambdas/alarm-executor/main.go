@@ -2,22 +2,41 @@ package main
 
 import (
 	"context"
+	"os"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
 	alarmexecutor "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 )
 
 func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := alarmexecutor.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
-		return
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
 	}
+	tracing.Instrument(&cfg)
 
 	handler := alarmexecutor.Handler{
-		SNSClient: sns.NewFromConfig(cfg),
+		SNSClient:       sns.NewFromConfig(cfg),
+		DynamoClient:    dynamodb.NewFromConfig(cfg),
+		SchedulerClient: scheduler.NewFromConfig(cfg),
+		Config:          handlerConfig,
+		Metrics:         metrics.Emitter{Stage: handlerConfig.Stage},
 	}
 
 	lambda.Start(handler.Handle)
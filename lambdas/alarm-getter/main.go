@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"os"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
 	alarmgetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-getter"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -10,13 +13,24 @@ import (
 )
 
 func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := alarmgetter.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
+
 	cfg, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
-		return
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
 	}
+	tracing.Instrument(&cfg)
 
 	handler := alarmgetter.AlarmGetterHandler{
 		DynamoClient: dynamodb.NewFromConfig(cfg),
+		Config:       handlerConfig,
 	}
 
 	lambda.Start(handler.Handle)
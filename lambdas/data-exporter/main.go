@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
+	dataexporter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/data-exporter"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+)
+
+func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := dataexporter.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
+	}
+	tracing.Instrument(&cfg)
+
+	s3Client := s3.NewFromConfig(cfg)
+	handler := dataexporter.Handler{
+		DynamoClient:    dynamodb.NewFromConfig(cfg),
+		CognitoClient:   cognitoidentityprovider.NewFromConfig(cfg),
+		SchedulerClient: scheduler.NewFromConfig(cfg),
+		S3Client:        s3Client,
+		PresignClient:   s3.NewPresignClient(s3Client),
+		LambdaClient:    awslambda.NewFromConfig(cfg),
+		Config:          handlerConfig,
+	}
+
+	lambda.Start(handler.Invoke)
+}
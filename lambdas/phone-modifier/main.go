@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"os"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
 	phonemodifier "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-modifier"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -11,14 +15,26 @@ import (
 )
 
 func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := phonemodifier.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
+
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
-		return
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
 	}
+	tracing.Instrument(&cfg)
 
 	handler := phonemodifier.Handler{
 		SnsClient:    sns.NewFromConfig(cfg),
 		DynamoClient: dynamodb.NewFromConfig(cfg),
+		Config:       handlerConfig,
+		Metrics:      metrics.Emitter{Stage: handlerConfig.Stage},
 	}
 
 	lambda.Start(handler.Handle)
@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
+	"os"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
 	phoneverifier "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-verifier"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -12,15 +16,27 @@ import (
 )
 
 func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := phoneverifier.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
+
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
-		return
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
 	}
+	tracing.Instrument(&cfg)
 
 	handler := phoneverifier.Handler{
 		CognitoClient: cognitoidentityprovider.NewFromConfig(cfg),
 		DynamoClient:  dynamodb.NewFromConfig(cfg),
 		SnsClient:     sns.NewFromConfig(cfg),
+		Config:        handlerConfig,
+		Metrics:       metrics.Emitter{Stage: handlerConfig.Stage},
 	}
 
 	lambda.Start(handler.Handle)
@@ -2,26 +2,39 @@ package main
 
 import (
 	"context"
-	"log"
+	"os"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
 	postconfirmationtrigger "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/post-confirmation-trigger"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 )
 
 func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := postconfirmationtrigger.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
 
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
-		log.Println(err)
-		return
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
 	}
+	tracing.Instrument(&cfg)
 
 	handler := postconfirmationtrigger.Handler{
 		SnsClient:     sns.NewFromConfig(cfg),
 		CognitoClient: cognitoidentityprovider.NewFromConfig(cfg),
+		DynamoClient:  dynamodb.NewFromConfig(cfg),
+		Config:        handlerConfig,
 	}
 
 	lambda.Start(handler.Handle)
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
+	quiethourssetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-setter"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func main() {
+	logger := logging.Setup()
+
+	handlerConfig, err := quiethourssetter.ConfigFromEnv()
+	if err != nil {
+		logger.Error("function is misconfigured", "error", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		logger.Error("AWS config couldn't be loaded", "error", err)
+		os.Exit(1)
+	}
+	tracing.Instrument(&cfg)
+
+	handler := quiethourssetter.Handler{
+		DynamoClient: dynamodb.NewFromConfig(cfg),
+		Config:       handlerConfig,
+	}
+
+	lambda.Start(handler.Handle)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigateway"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatchactions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssnssubscriptions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// metricsNamespace is a namespace of business metrics functions emit, see metrics package
+const metricsNamespace = "Reminder"
+
+// MonitoredFunction is a function alarms are raised for, Name is used in names of its alarms
+type MonitoredFunction struct {
+	Name     string
+	Function awslambda.IFunction
+}
+
+type MonitoringProps struct {
+	// Stack is used for names of resources and dimensions of business metrics
+	Stack           *AlerterStackProps
+	Functions       []MonitoredFunction
+	Api             awsapigateway.RestApi
+	ScheduleGroup   *string
+	DeadLetterQueue awssqs.IQueue
+	// SmsTopic is a topic reminders are sent as SMS through
+	SmsTopic awssns.ITopic
+}
+
+// Monitoring raises alarms of the stack on its ops topic and shows its state on a dashboard
+type Monitoring struct {
+	constructs.Construct
+	Topic     awssns.Topic
+	Dashboard awscloudwatch.Dashboard
+}
+
+// NewMonitoring creates ops topic, alarms on failures of functions, scheduler, SMS delivery and
+// API, and a dashboard with them and with business metrics
+func NewMonitoring(scope constructs.Construct, id string, props *MonitoringProps) *Monitoring {
+	construct := constructs.NewConstruct(scope, &id)
+	stack := props.Stack
+	period := awscdk.Duration_Minutes(jsii.Number(5))
+
+	topic := awssns.NewTopic(construct, jsii.String("OpsTopic"), &awssns.TopicProps{
+		TopicName: stack.name("OpsTopic"),
+	})
+	for _, email := range stack.OpsEmails {
+		topic.AddSubscription(awssnssubscriptions.NewEmailSubscription(jsii.String(email), nil))
+	}
+	action := awscloudwatchactions.NewSnsAction(topic)
+
+	// alarm raises an alarm when metric reaches threshold, it's resolved once it goes below it
+	alarm := func(name, description string, metric awscloudwatch.IMetric, threshold float64) awscloudwatch.Alarm {
+		alarm := awscloudwatch.NewAlarm(construct, jsii.String(name), &awscloudwatch.AlarmProps{
+			AlarmName:          stack.name(name),
+			AlarmDescription:   jsii.String(description),
+			Metric:             metric,
+			Threshold:          jsii.Number(threshold),
+			EvaluationPeriods:  jsii.Number(1),
+			ComparisonOperator: awscloudwatch.ComparisonOperator_GREATER_THAN_OR_EQUAL_TO_THRESHOLD,
+			TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+		})
+		alarm.AddAlarmAction(action)
+		alarm.AddOkAction(action)
+		return alarm
+	}
+	sum := &awscloudwatch.MetricOptions{Statistic: jsii.String("Sum"), Period: period}
+
+	var alarms []awscloudwatch.IAlarm
+	var errorMetrics, throttleMetrics, durationMetrics []awscloudwatch.IMetric
+	for _, function := range props.Functions {
+		functionErrors := function.Function.MetricErrors(sum)
+		functionThrottles := function.Function.MetricThrottles(sum)
+		alarms = append(alarms,
+			alarm(function.Name+"Errors", function.Name+" function failed", functionErrors, 1),
+			alarm(function.Name+"Throttles", function.Name+" function was throttled", functionThrottles, 1),
+		)
+		// Graphs of all functions tell them apart by labels
+		label := &awscloudwatch.MetricOptions{Label: jsii.String(function.Name)}
+		errorMetrics = append(errorMetrics, functionErrors.With(label))
+		throttleMetrics = append(throttleMetrics, functionThrottles.With(label))
+		durationMetrics = append(durationMetrics, function.Function.MetricDuration(&awscloudwatch.MetricOptions{Statistic: jsii.String("p99"), Period: period, Label: jsii.String(function.Name)}))
+	}
+
+	// Scheduler couldn't invoke alarm executor, after retries such invocations go to the queue
+	schedulerMetric := func(name string) awscloudwatch.Metric {
+		return awscloudwatch.NewMetric(&awscloudwatch.MetricProps{
+			Namespace:     jsii.String("AWS/Scheduler"),
+			MetricName:    jsii.String(name),
+			DimensionsMap: &map[string]*string{"ScheduleGroup": props.ScheduleGroup},
+			Statistic:     jsii.String("Sum"),
+			Period:        period,
+		})
+	}
+	targetErrors := schedulerMetric("TargetErrorCount")
+	droppedInvocations := schedulerMetric("InvocationDroppedCount")
+	deadLetters := props.DeadLetterQueue.MetricApproximateNumberOfMessagesVisible(&awscloudwatch.MetricOptions{Statistic: jsii.String("Maximum"), Period: period})
+	alarms = append(alarms,
+		alarm("SchedulerTargetErrors", "Scheduler failed to invoke alarm executor", targetErrors, 1),
+		alarm("SchedulerDroppedInvocations", "Scheduler dropped invocations of alarm executor", droppedInvocations, 1),
+		alarm("DeadLetterQueueDepth", "Alarms that couldn't be delivered are waiting in dead letter queue", deadLetters, 1),
+	)
+
+	// Share of SMS SNS failed to deliver, in percents
+	delivered := props.SmsTopic.MetricNumberOfNotificationsDelivered(sum)
+	failed := props.SmsTopic.MetricNumberOfNotificationsFailed(sum)
+	smsFailureRate := awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+		Expression: jsii.String("IF(delivered + failed > 0, 100 * failed / (delivered + failed), 0)"),
+		UsingMetrics: &map[string]awscloudwatch.IMetric{
+			"delivered": delivered,
+			"failed":    failed,
+		},
+		Label:  jsii.String("SMS failure rate (%)"),
+		Period: period,
+	})
+	// Spend is reported for the whole account, the topic's own metric would add its name as a dimension no data is reported for
+	smsSpend := awscloudwatch.NewMetric(&awscloudwatch.MetricProps{
+		Namespace:  jsii.String("AWS/SNS"),
+		MetricName: jsii.String("SMSMonthToDateSpentUSD"),
+		Statistic:  jsii.String("Maximum"),
+		Period:     period,
+	})
+	alarms = append(alarms,
+		alarm("SmsFailureRate", "More than 10% of SMS weren't delivered", smsFailureRate, 10),
+		alarm("SmsSpend", "Month to date SMS spend reached its limit", smsSpend, stack.SmsSpendAlarm),
+	)
+
+	// Average of 5XXError metric is a share of requests that failed with server error
+	apiRequests := props.Api.MetricCount(sum)
+	apiServerErrors := props.Api.MetricServerError(&awscloudwatch.MetricOptions{Statistic: jsii.String("Average"), Period: period})
+	apiServerErrorRate := awscloudwatch.NewMathExpression(&awscloudwatch.MathExpressionProps{
+		Expression:   jsii.String("100 * errors"),
+		UsingMetrics: &map[string]awscloudwatch.IMetric{"errors": apiServerErrors},
+		Label:        jsii.String("API 5xx rate (%)"),
+		Period:       period,
+	})
+	alarms = append(alarms, alarm("ApiServerErrorRate", "More than 5% of API requests failed with server error", apiServerErrorRate, 5))
+
+	// business returns business metric of SMS channel emitted by functions of the stage
+	business := func(name, statistic string) awscloudwatch.IMetric {
+		return awscloudwatch.NewMetric(&awscloudwatch.MetricProps{
+			Namespace:  jsii.String(metricsNamespace),
+			MetricName: jsii.String(name),
+			DimensionsMap: &map[string]*string{
+				"Stage":   jsii.String(stack.metricsStage()),
+				"Channel": jsii.String("sms"),
+			},
+			Statistic: jsii.String(statistic),
+			Period:    period,
+		})
+	}
+
+	dashboard := awscloudwatch.NewDashboard(construct, jsii.String("Dashboard"), &awscloudwatch.DashboardProps{
+		DashboardName: stack.name("Dashboard"),
+	})
+	graph := func(title string, left []awscloudwatch.IMetric, right []awscloudwatch.IMetric) awscloudwatch.IWidget {
+		widgetProps := &awscloudwatch.GraphWidgetProps{Title: jsii.String(title), Width: jsii.Number(12), Left: &left}
+		if len(right) > 0 {
+			widgetProps.Right = &right
+		}
+		return awscloudwatch.NewGraphWidget(widgetProps)
+	}
+	dashboard.AddWidgets(awscloudwatch.NewAlarmStatusWidget(&awscloudwatch.AlarmStatusWidgetProps{
+		Title:  jsii.String("Alarms"),
+		Alarms: &alarms,
+		Width:  jsii.Number(24),
+	}))
+	dashboard.AddWidgets(
+		graph("Events", []awscloudwatch.IMetric{business("EventsCreated", "Sum")}, []awscloudwatch.IMetric{business("SchedulesPerEvent", "Average")}),
+		graph("Alarms", []awscloudwatch.IMetric{business("AlarmsSent", "Sum"), business("AlarmFailures", "Sum")}, []awscloudwatch.IMetric{business("FireDelay", "p99")}),
+	)
+	dashboard.AddWidgets(
+		graph("Phone verification", []awscloudwatch.IMetric{business("VerificationCodesSent", "Sum"), business("VerificationSuccesses", "Sum"), business("VerificationFailures", "Sum")}, nil),
+		graph("SMS", []awscloudwatch.IMetric{delivered, failed}, []awscloudwatch.IMetric{smsSpend}),
+	)
+	dashboard.AddWidgets(
+		graph("Scheduler", []awscloudwatch.IMetric{targetErrors, droppedInvocations}, []awscloudwatch.IMetric{deadLetters}),
+		graph("API", []awscloudwatch.IMetric{apiRequests}, []awscloudwatch.IMetric{apiServerErrorRate}),
+	)
+	dashboard.AddWidgets(
+		graph("Function errors", errorMetrics, nil),
+		graph("Function throttles", throttleMetrics, nil),
+	)
+	dashboard.AddWidgets(graph("Function duration (p99)", durationMetrics, nil))
+
+	return &Monitoring{Construct: construct, Topic: topic, Dashboard: dashboard}
+}
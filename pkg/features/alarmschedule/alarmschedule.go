@@ -0,0 +1,177 @@
+package alarmschedule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+)
+
+// Alarm describes a schedule that invokes alarm executor for an event
+type Alarm struct {
+	Name string
+	// Expression is a schedule expression, e.g. "cron(0 10 ? * MON-FRI *)" or "at(2024-03-05T10:00:00)"
+	Expression       string
+	Timezone         string
+	Message          string
+	Title            string
+	Variables        map[string]string
+	UserID           string
+	EventID          string
+	Phones           []string
+	IgnoreQuietHours bool
+	// TraceID is ID of X-Ray trace of a request that created alarm and RequestID is its ID
+	// returned in X-Request-Id header, executor links its traces and logs back to them
+	TraceID   string
+	RequestID string
+}
+
+// Target is where alarms are delivered to
+type Target struct {
+	// FunctionArn is ARN of alarm executor function
+	FunctionArn string
+	// RoleArn is ARN of a role scheduler assumes to invoke the function
+	RoleArn string
+	// Group is a name of schedule group, the default group is used when it's empty
+	Group string
+	// DeadLetterArn is ARN of a queue events scheduler fails to deliver to the function are sent
+	// to, they're dropped when it's empty
+	DeadLetterArn string
+}
+
+// CreateScheduleInput returns input creating a schedule of an alarm. Schedule invokes the executor
+// with alarm's event and deletes itself once it won't fire anymore
+func CreateScheduleInput(alarm Alarm, target Target) (*scheduler.CreateScheduleInput, error) {
+	// Scheduler substitutes placeholders in raw input, so they can't be escaped the way
+	// json.Marshal escapes HTML characters
+	var lambdaInput bytes.Buffer
+	encoder := json.NewEncoder(&lambdaInput)
+	encoder.SetEscapeHTML(false)
+	payload := map[string]interface{}{
+		"userID":           alarm.UserID,
+		"eventID":          alarm.EventID,
+		"message":          alarm.Message,
+		"title":            alarm.Title,
+		"timezone":         alarm.Timezone,
+		"variables":        alarm.Variables,
+		"phones":           alarm.Phones,
+		"ignoreQuietHours": alarm.IgnoreQuietHours,
+		// Scheduler replaces this placeholder with time alarm was set on
+		"scheduledTime": "<aws.scheduler.scheduled-time>",
+	}
+	// Alarms recreated from saved events don't know the request that created them
+	if alarm.TraceID != "" {
+		payload["originTraceId"] = alarm.TraceID
+	}
+	if alarm.RequestID != "" {
+		payload["originRequestId"] = alarm.RequestID
+	}
+	if err := encoder.Encode(payload); err != nil {
+		return nil, err
+	}
+
+	input := &scheduler.CreateScheduleInput{
+		ActionAfterCompletion:      types.ActionAfterCompletionDelete,
+		Description:                aws.String(alarm.Message),
+		Name:                       aws.String(alarm.Name),
+		ScheduleExpression:         aws.String(alarm.Expression),
+		ScheduleExpressionTimezone: aws.String(alarm.Timezone),
+		Target: &types.Target{
+			Arn:     aws.String(target.FunctionArn),
+			RoleArn: aws.String(target.RoleArn),
+			Input:   aws.String(strings.TrimSuffix(lambdaInput.String(), "\n")),
+		},
+		FlexibleTimeWindow: &types.FlexibleTimeWindow{
+			Mode: types.FlexibleTimeWindowModeOff,
+		},
+	}
+	if target.Group != "" {
+		input.GroupName = aws.String(target.Group)
+	}
+	if target.DeadLetterArn != "" {
+		input.Target.DeadLetterConfig = &types.DeadLetterConfig{Arn: aws.String(target.DeadLetterArn)}
+	}
+	return input, nil
+}
+
+// FromEvent returns alarms saved with an event, given as an item of alarms table simplified
+// with dynamomapper.SimplifyDynamoDBItem
+func FromEvent(item map[string]interface{}) []Alarm {
+	var base Alarm
+	base.UserID, _ = item["UserID"].(string)
+	base.EventID, _ = item["EventID"].(string)
+	base.Message, _ = item["Message"].(string)
+	base.Title, _ = item["Title"].(string)
+	base.Timezone, _ = item["Timezone"].(string)
+	base.IgnoreQuietHours, _ = item["IgnoreQuietHours"].(bool)
+	if variables, ok := item["Variables"].(map[string]interface{}); ok {
+		base.Variables = make(map[string]string, len(variables))
+		for name, value := range variables {
+			base.Variables[name] = fmt.Sprint(value)
+		}
+	}
+	if phones, ok := item["Phones"].([]interface{}); ok {
+		for _, label := range phones {
+			base.Phones = append(base.Phones, fmt.Sprint(label))
+		}
+	}
+
+	var result []Alarm
+	for field, scheduleType := range map[string]string{"Dates": "at", "Crons": "cron"} {
+		saved, _ := item[field].(map[string]interface{})
+		for name, expression := range saved {
+			alarm := base
+			alarm.Name = name
+			alarm.Expression = fmt.Sprintf("%s(%v)", scheduleType, expression)
+			result = append(result, alarm)
+		}
+	}
+	return result
+}
+
+// WillFire reports whether alarm would still fire after now if its schedule existed
+func WillFire(alarm Alarm, now time.Time) bool {
+	_, ok := Next(alarm, now)
+	return ok
+}
+
+// Next returns the first time alarm fires at after now, or false when it won't fire anymore or
+// its schedule is invalid
+func Next(alarm Alarm, now time.Time) (time.Time, bool) {
+	s, err := parse(alarm)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return s.Next(now)
+}
+
+// Fires returns times alarm fires at after now and before until, at most limit of them
+func Fires(alarm Alarm, now, until time.Time, limit int) []time.Time {
+	s, err := parse(alarm)
+	if err != nil {
+		return nil
+	}
+	var fires []time.Time
+	fire, ok := s.Next(now)
+	for ok && fire.Before(until) && len(fires) < limit {
+		fires = append(fires, fire)
+		fire, ok = s.Next(fire)
+	}
+	return fires
+}
+
+// parse returns schedule of alarm evaluated in its timezone
+func parse(alarm Alarm) (schedule.Schedule, error) {
+	loc, err := time.LoadLocation(alarm.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.Parse(alarm.Expression, loc)
+}
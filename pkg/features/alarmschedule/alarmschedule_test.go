@@ -0,0 +1,123 @@
+package alarmschedule_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+)
+
+func TestCreateScheduleInput(t *testing.T) {
+	alarm := alarmschedule.Alarm{
+		Name:       "schedule",
+		Expression: "cron(0 10 ? * MON-FRI *)",
+		Timezone:   "Europe/Warsaw",
+		Message:    "{{title}} in 10 minutes",
+		Title:      "Stand-up",
+		UserID:     "1",
+		EventID:    "2",
+		Phones:     []string{"default"},
+		TraceID:    "1-5759e988-bd862e3fe1be46a994272793",
+		RequestID:  "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
+	}
+
+	input, err := alarmschedule.CreateScheduleInput(alarm, alarmschedule.Target{FunctionArn: "executor_arn", RoleArn: "role_arn"})
+	if err != nil {
+		t.Fatalf("Error when creating input: %v", err)
+	}
+	if input.GroupName != nil {
+		t.Errorf("Unexpected schedule group: %v", *input.GroupName)
+	}
+	if input.Target.DeadLetterConfig != nil {
+		t.Errorf("Unexpected dead letter queue: %v", *input.Target.DeadLetterConfig.Arn)
+	}
+	if *input.Name != alarm.Name || *input.ScheduleExpression != alarm.Expression || *input.ScheduleExpressionTimezone != alarm.Timezone {
+		t.Errorf("Schedule %v: %v (%v) is different than expected", *input.Name, *input.ScheduleExpression, *input.ScheduleExpressionTimezone)
+	}
+	if *input.Target.Arn != "executor_arn" || *input.Target.RoleArn != "role_arn" {
+		t.Errorf("Schedule targets: %v (%v) instead of executor", *input.Target.Arn, *input.Target.RoleArn)
+	}
+
+	if !strings.Contains(*input.Target.Input, `"<aws.scheduler.scheduled-time>"`) {
+		t.Errorf("Scheduled time placeholder is escaped in payload: %v", *input.Target.Input)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(*input.Target.Input), &payload); err != nil {
+		t.Fatalf("Error when decoding payload: %v", err)
+	}
+	if payload["eventID"] != "2" || payload["title"] != "Stand-up" || payload["scheduledTime"] != "<aws.scheduler.scheduled-time>" {
+		t.Errorf("Invalid payload: %v", payload)
+	}
+	if payload["originTraceId"] != alarm.TraceID || payload["originRequestId"] != alarm.RequestID {
+		t.Errorf("Payload: %v isn't linked to request that created alarm", payload)
+	}
+
+	alarm.TraceID, alarm.RequestID = "", ""
+	input, _ = alarmschedule.CreateScheduleInput(alarm, alarmschedule.Target{Group: "alarms", DeadLetterArn: "queue_arn"})
+	if strings.Contains(*input.Target.Input, "origin") {
+		t.Errorf("Payload of alarm not created by a request is linked to one: %v", *input.Target.Input)
+	}
+	if input.GroupName == nil || *input.GroupName != "alarms" {
+		t.Errorf("Schedule not created in group")
+	}
+	if input.Target.DeadLetterConfig == nil || *input.Target.DeadLetterConfig.Arn != "queue_arn" {
+		t.Errorf("Undelivered events of schedule aren't sent to dead letter queue")
+	}
+}
+
+func TestFromEvent(t *testing.T) {
+	item := map[string]interface{}{
+		"UserID":    "1",
+		"EventID":   "2",
+		"Title":     "Stand-up",
+		"Message":   "{{title}} in 10 minutes",
+		"Timezone":  "Europe/Warsaw",
+		"Phones":    []interface{}{"default", "work"},
+		"Variables": map[string]interface{}{"room": "A1"},
+		"Crons":     map[string]interface{}{"1_2_a": "0 10 ? * MON-FRI *"},
+		"Dates":     map[string]interface{}{"1_2_b": "2024-03-05T10:00:00"},
+	}
+
+	received := make(map[string]alarmschedule.Alarm)
+	for _, alarm := range alarmschedule.FromEvent(item) {
+		received[alarm.Name] = alarm
+	}
+
+	cron, date := received["1_2_a"], received["1_2_b"]
+	if len(received) != 2 || cron.Expression != "cron(0 10 ? * MON-FRI *)" || date.Expression != "at(2024-03-05T10:00:00)" {
+		t.Fatalf("Received alarms: %v are different than expected", received)
+	}
+	if cron.UserID != "1" || cron.EventID != "2" || cron.Title != "Stand-up" || cron.Variables["room"] != "A1" || len(cron.Phones) != 2 {
+		t.Errorf("Received alarm: %+v is different than expected", cron)
+	}
+
+	now := time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)
+	if !alarmschedule.WillFire(cron, now) || alarmschedule.WillFire(date, now) {
+		t.Errorf("Only recurring alarm should fire after %v", now)
+	}
+}
+
+func TestFires(t *testing.T) {
+	alarm := alarmschedule.Alarm{Expression: "cron(0 10 ? * MON-FRI *)", Timezone: "Europe/Warsaw"}
+	// Wednesday, alarm fires on Thursday, Friday and Monday in the next 5 days
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+
+	fires := alarmschedule.Fires(alarm, now, now.Add(5*24*time.Hour), 10)
+	if len(fires) != 3 || !fires[0].Equal(time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Received fires: %v are different than expected", fires)
+	}
+	if fires := alarmschedule.Fires(alarm, now, now.Add(5*24*time.Hour), 2); len(fires) != 2 {
+		t.Errorf("Received %d fires instead of limit of 2", len(fires))
+	}
+
+	next, ok := alarmschedule.Next(alarm, now)
+	if !ok || !next.Equal(fires[0]) {
+		t.Errorf("Received next fire: %v is different than expected one: %v", next, fires[0])
+	}
+	if fires := alarmschedule.Fires(alarmschedule.Alarm{Expression: "rate(5 minutes)", Timezone: "UTC"}, now, now.Add(time.Hour), 10); len(fires) != 0 {
+		t.Errorf("Received fires of invalid schedule: %v", fires)
+	}
+}
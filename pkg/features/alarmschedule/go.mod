@@ -0,0 +1,17 @@
+module github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule
+
+go 1.22.0
+
+require (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule v0.0.0-00010101000000-000000000000
+	github.com/aws/aws-sdk-go-v2 v1.30.4
+	github.com/aws/aws-sdk-go-v2/service/scheduler v1.10.4
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.16 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.16 // indirect
+	github.com/aws/smithy-go v1.20.4 // indirect
+)
+
+replace github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule => ../schedule
@@ -0,0 +1,67 @@
+// Package envconfig reads configuration of functions from environment variables. Functions load
+// it once when they start, so that a misconfigured function fails on cold start with all missing
+// variables listed, instead of failing requests one by one.
+package envconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Env reads environment variables, remembering required ones that aren't set and ones that can't be parsed
+type Env struct {
+	lookup  func(string) (string, bool)
+	missing []string
+	invalid []string
+}
+
+// New returns Env reading variables of the process
+func New() *Env {
+	return &Env{lookup: os.LookupEnv}
+}
+
+// Required returns value of a variable. Variables that aren't set or are empty are reported by Err
+func (e *Env) Required(name string) string {
+	value, _ := e.lookup(name)
+	if value == "" {
+		e.missing = append(e.missing, name)
+	}
+	return value
+}
+
+// Optional returns value of a variable, which is empty when it's not set
+func (e *Env) Optional(name string) string {
+	value, _ := e.lookup(name)
+	return value
+}
+
+// Int returns value of an optional integer variable, which is fallback when it's not set. Values
+// that aren't integers or are lower than min are reported by Err
+func (e *Env) Int(name string, fallback, min int) int {
+	value, _ := e.lookup(name)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min {
+		e.invalid = append(e.invalid, fmt.Sprintf("%s (%q is not an integer of at least %d)", name, value, min))
+		return fallback
+	}
+	return number
+}
+
+// Err returns an error listing all required variables that were read but aren't set and all
+// variables that were read but can't be parsed
+func (e *Env) Err() error {
+	var errs []error
+	if len(e.missing) > 0 {
+		errs = append(errs, fmt.Errorf("missing environment variables: %s", strings.Join(e.missing, ", ")))
+	}
+	if len(e.invalid) > 0 {
+		errs = append(errs, fmt.Errorf("invalid environment variables: %s", strings.Join(e.invalid, ", ")))
+	}
+	return errors.Join(errs...)
+}
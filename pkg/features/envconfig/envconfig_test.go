@@ -0,0 +1,111 @@
+package envconfig_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+)
+
+func TestEnv(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		variables map[string]string
+		expected  string
+	}{
+		{
+			desc:      "all set",
+			variables: map[string]string{"TABLE_NAME": "table", "TOPIC_ARN": "topic"},
+			expected:  "<nil>",
+		},
+		{
+			desc:      "optional not set",
+			variables: map[string]string{"TABLE_NAME": "table", "TOPIC_ARN": "topic", "GROUP_NAME": ""},
+			expected:  "<nil>",
+		},
+		{
+			desc:      "required empty",
+			variables: map[string]string{"TABLE_NAME": "", "TOPIC_ARN": "topic"},
+			expected:  "missing environment variables: TABLE_NAME",
+		},
+		{
+			desc:      "all missing",
+			variables: map[string]string{},
+			expected:  "missing environment variables: TABLE_NAME, TOPIC_ARN",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			for name, value := range tC.variables {
+				t.Setenv(name, value)
+			}
+			env := envconfig.New()
+			env.Required("TABLE_NAME")
+			env.Optional("GROUP_NAME")
+			env.Required("TOPIC_ARN")
+			if err := fmt.Sprint(env.Err()); err != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", err, tC.expected)
+			}
+		})
+	}
+}
+
+func TestEnvInt(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		value         string
+		expected      int
+		expectedError string
+	}{
+		{
+			desc:          "not set",
+			expected:      10,
+			expectedError: "<nil>",
+		},
+		{
+			desc:          "set",
+			value:         "5",
+			expected:      5,
+			expectedError: "<nil>",
+		},
+		{
+			desc:          "not an integer",
+			value:         "abc",
+			expected:      10,
+			expectedError: `invalid environment variables: MAX_SEGMENTS ("abc" is not an integer of at least 1)`,
+		},
+		{
+			desc:          "too low",
+			value:         "0",
+			expected:      10,
+			expectedError: `invalid environment variables: MAX_SEGMENTS ("0" is not an integer of at least 1)`,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			t.Setenv("MAX_SEGMENTS", tC.value)
+			env := envconfig.New()
+			if res := env.Int("MAX_SEGMENTS", 10, 1); res != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", res, tC.expected)
+			}
+			if err := fmt.Sprint(env.Err()); err != tC.expectedError {
+				t.Errorf("Received result: %v is different than expected one: %v", err, tC.expectedError)
+			}
+		})
+	}
+}
+
+func TestEnvMissingAndInvalid(t *testing.T) {
+	t.Setenv("TABLE_NAME", "")
+	t.Setenv("MAX_SEGMENTS", "abc")
+	env := envconfig.New()
+	env.Required("TABLE_NAME")
+	env.Int("MAX_SEGMENTS", 10, 1)
+
+	expected := "missing environment variables: TABLE_NAME\ninvalid environment variables: MAX_SEGMENTS (\"abc\" is not an integer of at least 1)"
+	if err := fmt.Sprint(env.Err()); err != expected {
+		t.Errorf("Received result: %v is different than expected one: %v", err, expected)
+	}
+}
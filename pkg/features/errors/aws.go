@@ -0,0 +1,38 @@
+package errors
+
+import (
+	"context"
+	goerrors "errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/smithy-go"
+)
+
+// throttlingCodes are error codes AWS services fail with when requests exceed their rate limits
+var throttlingCodes = map[string]bool{
+	"ThrottlingException":                    true,
+	"Throttling":                             true,
+	"TooManyRequestsException":               true,
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+}
+
+// FromAWS returns response to a failed call of AWS SDK. Failed conditions and conflicts become
+// conflict, missing resources become not found and throttling becomes too many requests, so that
+// clients know they can retry. Any other error is internal
+func FromAWS(ctx context.Context, err error, message string) (events.APIGatewayProxyResponse, error) {
+	var apiErr smithy.APIError
+	if !goerrors.As(err, &apiErr) {
+		return Internal(ctx, err)
+	}
+
+	switch code := apiErr.ErrorCode(); {
+	case code == "ConditionalCheckFailedException" || code == "TransactionConflictException" || code == "ConflictException":
+		return Conflict(message)
+	case code == "ResourceNotFoundException":
+		return NotFound(message)
+	case throttlingCodes[code]:
+		return TooManyRequests("service is busy, try again later")
+	}
+	return Internal(ctx, err)
+}
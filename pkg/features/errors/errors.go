@@ -1,44 +1,142 @@
 package errors
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 )
 
-func ErrorResponse(message string, code int) (events.APIGatewayProxyResponse, error) {
+// ContentType is a media type of error responses
+const ContentType = "application/problem+json"
 
-	responseJSON, _ := json.Marshal(map[string]string{
-		"message": message,
-	})
+// Code is a machine-readable type of an error, clients should match on it rather than on detail
+type Code string
 
+const (
+	CodeBadRequest          Code = "bad_request"
+	CodeValidationFailed    Code = "validation_failed"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeForbidden           Code = "forbidden"
+	CodeNotFound            Code = "not_found"
+	CodeConflict            Code = "conflict"
+	CodePayloadTooLarge     Code = "payload_too_large"
+	CodeUnprocessableEntity Code = "unprocessable_entity"
+	CodeTooManyRequests     Code = "too_many_requests"
+	CodeInternal            Code = "internal_error"
+)
+
+// codes are default codes of statuses, used when a response is created with just a status
+var codes = map[int]Code{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnprocessableEntity:   CodeUnprocessableEntity,
+	http.StatusTooManyRequests:       CodeTooManyRequests,
+	http.StatusInternalServerError:   CodeInternal,
+}
+
+// FieldError describes why a single field of request body is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is a body of error responses in RFC 7807 format
+type Problem struct {
+	// Type is a URI identifying the problem, "about:blank" means that title is enough to explain it
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   Code   `json:"code"`
+	// RequestID identifies request in logs, it's filled in by httpx middleware
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// New returns problem of given status and code
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	return string(p.Code) + ": " + p.Detail
+}
+
+// Response returns API Gateway response with the problem
+func (p *Problem) Response() (events.APIGatewayProxyResponse, error) {
+	body, _ := json.Marshal(p)
 	return events.APIGatewayProxyResponse{
-		StatusCode: code,
-		Headers: map[string]string{
-			"Content-Type":                     "application/json",
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Headers":     "Content-Type",
-			"Access-Control-Allow-Methods":     "OPTIONS, GET, POST, DELETE",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		Body: string(responseJSON),
+		StatusCode: p.Status,
+		Headers:    map[string]string{"Content-Type": ContentType},
+		Body:       string(body),
 	}, nil
 }
 
-// It returns internal server error
-func Internal(err error) (events.APIGatewayProxyResponse, error) {
-	log.Println(err.Error())
+// ErrorResponse returns response with given message and status, its code is the default one
+// of the status
+func ErrorResponse(message string, status int) (events.APIGatewayProxyResponse, error) {
+	code, ok := codes[status]
+	if !ok {
+		code = CodeInternal
+	}
+	return New(status, code, message).Response()
+}
+
+// It returns internal server error, err is only logged with attributes of ctx, so that details
+// of failure don't leak
+func Internal(ctx context.Context, err error) (events.APIGatewayProxyResponse, error) {
+	slog.ErrorContext(ctx, "internal server error", "error", err)
 	return ErrorResponse("internal server error", http.StatusInternalServerError)
 }
 
 // It returns bad request response with given message
+func BadRequest(message string) (events.APIGatewayProxyResponse, error) {
+	return ErrorResponse(message, http.StatusBadRequest)
+}
+
+// It returns bad request response listing invalid fields of request body
+func Validation(message string, fields ...FieldError) (events.APIGatewayProxyResponse, error) {
+	problem := New(http.StatusBadRequest, CodeValidationFailed, message)
+	problem.Errors = fields
+	return problem.Response()
+}
+
+// It returns unauthorized response with given message
 func Unauthorized(message string) (events.APIGatewayProxyResponse, error) {
 	return ErrorResponse(message, http.StatusUnauthorized)
 }
 
-// It returns bad request response with given message
-func BadRequest(message string) (events.APIGatewayProxyResponse, error) {
-	return ErrorResponse(message, http.StatusBadRequest)
+// It returns forbidden response with given message
+func Forbidden(message string) (events.APIGatewayProxyResponse, error) {
+	return ErrorResponse(message, http.StatusForbidden)
+}
+
+// It returns not found response with given message
+func NotFound(message string) (events.APIGatewayProxyResponse, error) {
+	return ErrorResponse(message, http.StatusNotFound)
+}
+
+// It returns conflict response with given message
+func Conflict(message string) (events.APIGatewayProxyResponse, error) {
+	return ErrorResponse(message, http.StatusConflict)
+}
+
+// It returns too many requests response with given message, clients may retry after a second
+func TooManyRequests(message string) (events.APIGatewayProxyResponse, error) {
+	response, err := ErrorResponse(message, http.StatusTooManyRequests)
+	response.Headers["Retry-After"] = "1"
+	return response, err
 }
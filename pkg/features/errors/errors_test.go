@@ -0,0 +1,184 @@
+package errors_test
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/aws/smithy-go"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+)
+
+func TestProblem(t *testing.T) {
+	response, _ := errors.Validation("invalid request body", errors.FieldError{Field: "timezone", Message: "unknown timezone"})
+	if response.StatusCode != http.StatusBadRequest || response.Headers["Content-Type"] != "application/problem+json" {
+		t.Errorf("Received response: %v, %v is different than expected one", response.StatusCode, response.Headers)
+	}
+
+	var received map[string]interface{}
+	if err := json.Unmarshal([]byte(response.Body), &received); err != nil {
+		t.Fatalf("Error when decoding body: %v", err)
+	}
+	expected := map[string]interface{}{
+		"type":   "about:blank",
+		"title":  "Bad Request",
+		"status": float64(400),
+		"detail": "invalid request body",
+		"code":   "validation_failed",
+		"errors": []interface{}{map[string]interface{}{"field": "timezone", "message": "unknown timezone"}},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Received result: %v is different than expected one: %v", received, expected)
+	}
+}
+
+func TestFromAWS(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		err            error
+		expectedStatus int
+		expectedCode   errors.Code
+	}{
+		{
+			desc:           "condition failed",
+			err:            fmt.Errorf("operation PutItem: %w", &smithy.GenericAPIError{Code: "ConditionalCheckFailedException"}),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   errors.CodeConflict,
+		},
+		{
+			desc:           "resource not found",
+			err:            &smithy.GenericAPIError{Code: "ResourceNotFoundException"},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   errors.CodeNotFound,
+		},
+		{
+			desc:           "throttling",
+			err:            &smithy.GenericAPIError{Code: "ProvisionedThroughputExceededException"},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedCode:   errors.CodeTooManyRequests,
+		},
+		{
+			desc:           "other API error",
+			err:            &smithy.GenericAPIError{Code: "AccessDeniedException"},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   errors.CodeInternal,
+		},
+		{
+			desc:           "not an API error",
+			err:            fmt.Errorf("connection reset"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   errors.CodeInternal,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			response, _ := errors.FromAWS(context.Background(), tC.err, "event not found")
+
+			var problem errors.Problem
+			if err := json.Unmarshal([]byte(response.Body), &problem); err != nil {
+				t.Fatalf("Error when decoding body: %v", err)
+			}
+			if response.StatusCode != tC.expectedStatus || problem.Status != tC.expectedStatus || problem.Code != tC.expectedCode {
+				t.Errorf("Received result: %v (%v) is different than expected one: %v (%v)", response.StatusCode, problem.Code, tC.expectedStatus, tC.expectedCode)
+			}
+		})
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		err      error
+		expected []errors.FieldError
+	}{
+		{
+			desc:     "plain error",
+			err:      fmt.Errorf("invalid request body"),
+			expected: nil,
+		},
+		{
+			desc:     "field error",
+			err:      errors.Field("timezone", fmt.Errorf("invalid timezone: %q", "Mars")),
+			expected: []errors.FieldError{{Field: "timezone", Message: `invalid timezone: "Mars"`}},
+		},
+		{
+			desc:     "wrapped field error",
+			err:      fmt.Errorf("dates: %w", errors.Field("dates", fmt.Errorf("invalid date"))),
+			expected: []errors.FieldError{{Field: "dates", Message: "invalid date"}},
+		},
+		{
+			desc: "joined field errors",
+			err: goerrors.Join(
+				errors.Field("message", fmt.Errorf("empty message")),
+				fmt.Errorf("other error"),
+				errors.Field("title", fmt.Errorf("empty title")),
+			),
+			expected: []errors.FieldError{{Field: "message", Message: "empty message"}, {Field: "title", Message: "empty title"}},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			response, _ := errors.Invalid(tC.err)
+			var received struct {
+				Code   errors.Code
+				Detail string
+				Errors []errors.FieldError
+			}
+			if err := json.Unmarshal([]byte(response.Body), &received); err != nil {
+				t.Fatalf("Error when decoding body: %v", err)
+			}
+			if received.Code != errors.CodeValidationFailed || received.Detail != tC.err.Error() {
+				t.Errorf("Received result: %v, %v is different than expected one: %v, %v", received.Code, received.Detail, errors.CodeValidationFailed, tC.err.Error())
+			}
+			if !reflect.DeepEqual(received.Errors, tC.expected) {
+				t.Errorf("Received result: %v is different than expected one: %v", received.Errors, tC.expected)
+			}
+		})
+	}
+}
+
+func TestUnprocessable(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		err      error
+		expected []errors.FieldError
+	}{
+		{
+			desc:     "field error",
+			err:      errors.Field("phones", fmt.Errorf("unknown phone label: %q", "work")),
+			expected: []errors.FieldError{{Field: "phones", Message: `unknown phone label: "work"`}},
+		},
+		{
+			desc: "message only",
+			err:  goerrors.New("limits exceeded: 50 events per user"),
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			response, _ := errors.Unprocessable(tC.err)
+			if response.StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("Received result: %v is different than expected one: %v", response.StatusCode, http.StatusUnprocessableEntity)
+			}
+
+			var received struct {
+				Code   errors.Code
+				Detail string
+				Errors []errors.FieldError
+			}
+			if err := json.Unmarshal([]byte(response.Body), &received); err != nil {
+				t.Fatalf("Error when decoding body: %v", err)
+			}
+			if received.Code != errors.CodeUnprocessableEntity || received.Detail != tC.err.Error() || !reflect.DeepEqual(received.Errors, tC.expected) {
+				t.Errorf("Received result: %v, %v, %v is different than expected one: %v, %v, %v", received.Code, received.Detail, received.Errors, errors.CodeUnprocessableEntity, tC.err.Error(), tC.expected)
+			}
+		})
+	}
+}
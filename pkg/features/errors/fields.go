@@ -0,0 +1,59 @@
+package errors
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// fieldError is an error of a single field of request body
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+// Field marks err as caused by given field of request body, so that Invalid can list it
+func Field(field string, err error) error {
+	return &fieldError{field: field, err: err}
+}
+
+// Invalid returns validation response for an error of request body validation. Errors marked
+// with Field, also joined or wrapped ones, are listed in its errors
+func Invalid(err error) (events.APIGatewayProxyResponse, error) {
+	return Validation(err.Error(), fieldErrors(err)...)
+}
+
+// Unprocessable returns unprocessable entity response for a request body that is well-formed but
+// cannot be acted upon. Message of err is its detail and errors marked with Field are listed
+// in its errors just like in Invalid, so other errors give the detail only
+func Unprocessable(err error) (events.APIGatewayProxyResponse, error) {
+	problem := New(http.StatusUnprocessableEntity, CodeUnprocessableEntity, err.Error())
+	problem.Errors = fieldErrors(err)
+	return problem.Response()
+}
+
+func fieldErrors(err error) []FieldError {
+	switch wrapped := err.(type) {
+	case *fieldError:
+		return []FieldError{{Field: wrapped.field, Message: wrapped.err.Error()}}
+	case interface{ Unwrap() []error }:
+		var fields []FieldError
+		for _, err := range wrapped.Unwrap() {
+			fields = append(fields, fieldErrors(err)...)
+		}
+		return fields
+	case interface{ Unwrap() error }:
+		if inner := wrapped.Unwrap(); inner != nil {
+			return fieldErrors(inner)
+		}
+	}
+	return nil
+}
@@ -2,4 +2,7 @@ module github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors
 
 go 1.22.0
 
-require github.com/aws/aws-lambda-go v1.47.0
+require (
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/aws/smithy-go v1.20.4
+)
@@ -0,0 +1,80 @@
+package httpx
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	allowedHeaders = "Content-Type, Authorization, Idempotency-Key"
+	allowedMethods = "OPTIONS, GET, POST, PUT, DELETE"
+	exposedHeaders = "X-Request-Id"
+)
+
+// AllowedOrigins is a set of origins CORS allows, nil allows all of them
+type AllowedOrigins map[string]bool
+
+// ParseOrigins parses comma separated list of allowed origins, e.g. CORS_ALLOWED_ORIGINS variable.
+// All origins are allowed when it's empty or one of them is "*"
+func ParseOrigins(value string) AllowedOrigins {
+	allowed := make(AllowedOrigins)
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "*" {
+			return nil
+		}
+		if origin != "" {
+			allowed[origin] = true
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return allowed
+}
+
+// CORS adds CORS headers to responses of next handler. Origin of a request is allowed only when
+// it's one of allowed origins, unless all of them are allowed
+func CORS(allowed AllowedOrigins, next Handler) Handler {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		response, err := next(ctx, request)
+		if err != nil {
+			return response, err
+		}
+
+		if response.Headers == nil {
+			response.Headers = make(map[string]string)
+		}
+		response.Headers["Access-Control-Allow-Headers"] = allowedHeaders
+		response.Headers["Access-Control-Allow-Methods"] = allowedMethods
+		response.Headers["Access-Control-Expose-Headers"] = exposedHeaders
+
+		origin := header(request, "Origin")
+		switch {
+		case allowed == nil:
+			response.Headers["Access-Control-Allow-Origin"] = "*"
+			delete(response.Headers, "Access-Control-Allow-Credentials")
+		case origin != "" && allowed[origin]:
+			response.Headers["Access-Control-Allow-Origin"] = origin
+			response.Headers["Access-Control-Allow-Credentials"] = "true"
+			response.Headers["Vary"] = "Origin"
+		default:
+			delete(response.Headers, "Access-Control-Allow-Origin")
+			delete(response.Headers, "Access-Control-Allow-Credentials")
+			response.Headers["Vary"] = "Origin"
+		}
+		return response, nil
+	}
+}
+
+// header returns value of request header regardless of its case
+func header(request events.APIGatewayProxyRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+module github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx
+
+go 1.22.0
+
+require (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors v0.0.0-20240821145950-d2da7dbd1a33
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging v0.0.0-00010101000000-000000000000
+	github.com/aws/aws-lambda-go v1.47.0
+)
+
+require github.com/aws/smithy-go v1.20.4 // indirect
+
+replace (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors => ../errors
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging => ../logging
+)
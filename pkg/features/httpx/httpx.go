@@ -0,0 +1,58 @@
+// Package httpx contains middleware and helpers shared by handlers of API Gateway proxy requests.
+package httpx
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+)
+
+// Handler handles API Gateway proxy request
+type Handler func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// AuthenticatedHandler handles API Gateway proxy request of an authenticated user
+type AuthenticatedHandler func(context.Context, Principal, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Principal is a user request was authorized for by Cognito authorizer
+type Principal struct {
+	// UserID is user's sub, it's the only claim that's always present
+	UserID          string
+	Username        string
+	PhoneNumber     string
+	SubscriptionArn string
+}
+
+// PrincipalFrom returns principal from claims of Cognito authorizer, it fails when request
+// wasn't authorized
+func PrincipalFrom(request events.APIGatewayProxyRequest) (Principal, bool) {
+	claims, ok := request.RequestContext.Authorizer["claims"].(map[string]interface{})
+	if !ok {
+		return Principal{}, false
+	}
+	var principal Principal
+	principal.UserID, _ = claims["sub"].(string)
+	principal.Username, _ = claims["cognito:username"].(string)
+	principal.PhoneNumber, _ = claims["phone_number"].(string)
+	principal.SubscriptionArn, _ = claims["custom:subscription_arn"].(string)
+	return principal, principal.UserID != ""
+}
+
+// Authenticate passes principal of a request to next handler, requests without one are rejected
+// with unauthorized response. Lines next handler logs carry ID of the user
+func Authenticate(next AuthenticatedHandler) Handler {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		principal, ok := PrincipalFrom(request)
+		if !ok {
+			return errors.Unauthorized("authorization data not found")
+		}
+		return next(logging.With(ctx, logging.KeyUserID, principal.UserID), principal, request)
+	}
+}
+
+// API wraps handler of an authenticated endpoint with middleware shared by all of them
+func API(allowed AllowedOrigins, next AuthenticatedHandler) Handler {
+	return CORS(allowed, Correlate(Authenticate(next)))
+}
@@ -0,0 +1,312 @@
+package httpx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+)
+
+func request(claims map[string]interface{}) events.APIGatewayProxyRequest {
+	request := events.APIGatewayProxyRequest{Headers: map[string]string{}}
+	if claims != nil {
+		request.RequestContext.Authorizer = map[string]interface{}{"claims": claims}
+	}
+	return request
+}
+
+func TestAuthenticate(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		request  events.APIGatewayProxyRequest
+		expected *httpx.Principal
+	}{
+		{
+			desc: "all claims",
+			request: request(map[string]interface{}{
+				"sub":                     "1",
+				"cognito:username":        "john",
+				"phone_number":            "+48123456789",
+				"custom:subscription_arn": "arn",
+			}),
+			expected: &httpx.Principal{UserID: "1", Username: "john", PhoneNumber: "+48123456789", SubscriptionArn: "arn"},
+		},
+		{
+			desc:     "only sub",
+			request:  request(map[string]interface{}{"sub": "1"}),
+			expected: &httpx.Principal{UserID: "1"},
+		},
+		{
+			desc:    "no sub",
+			request: request(map[string]interface{}{"cognito:username": "john"}),
+		},
+		{
+			desc:    "sub of invalid type",
+			request: request(map[string]interface{}{"sub": 1}),
+		},
+		{
+			desc:    "no claims",
+			request: request(nil),
+		},
+		{
+			desc: "claims of invalid type",
+			request: events.APIGatewayProxyRequest{RequestContext: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]interface{}{"claims": "sub=1"},
+			}},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var received *httpx.Principal
+			handler := httpx.Authenticate(func(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				received = &principal
+				return httpx.NoContent()
+			})
+
+			response, err := handler(context.Background(), tC.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(received, tC.expected) {
+				t.Errorf("Received result: %v is different than expected one: %v", received, tC.expected)
+			}
+			if tC.expected == nil && response.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Received status code: %v is different than expected one: %v", response.StatusCode, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		allowed  string
+		origin   string
+		expected map[string]string
+	}{
+		{
+			desc:     "all origins by default",
+			origin:   "https://example.com",
+			expected: map[string]string{"Access-Control-Allow-Origin": "*"},
+		},
+		{
+			desc:     "wildcard",
+			allowed:  "https://app.example.com, *",
+			origin:   "https://example.com",
+			expected: map[string]string{"Access-Control-Allow-Origin": "*"},
+		},
+		{
+			desc:     "allowed origin",
+			allowed:  "https://app.example.com, https://example.com",
+			origin:   "https://example.com",
+			expected: map[string]string{"Access-Control-Allow-Origin": "https://example.com", "Access-Control-Allow-Credentials": "true", "Vary": "Origin"},
+		},
+		{
+			desc:     "other origin",
+			allowed:  "https://app.example.com",
+			origin:   "https://example.com",
+			expected: map[string]string{"Vary": "Origin"},
+		},
+		{
+			desc:     "no origin",
+			allowed:  "https://app.example.com",
+			expected: map[string]string{"Vary": "Origin"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			handler := httpx.CORS(httpx.ParseOrigins(tC.allowed), func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				return httpx.JSON(ctx, http.StatusOK, map[string]string{"status": "ok"})
+			})
+
+			req := request(nil)
+			if tC.origin != "" {
+				req.Headers["origin"] = tC.origin
+			}
+			response, err := handler(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			tC.expected["Content-Type"] = "application/json"
+			tC.expected["Access-Control-Allow-Headers"] = "Content-Type, Authorization, Idempotency-Key"
+			tC.expected["Access-Control-Allow-Methods"] = "OPTIONS, GET, POST, PUT, DELETE"
+			tC.expected["Access-Control-Expose-Headers"] = "X-Request-Id"
+			if !reflect.DeepEqual(response.Headers, tC.expected) {
+				t.Errorf("Received result: %v is different than expected one: %v", response.Headers, tC.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	type body struct {
+		Title string `json:"title"`
+	}
+
+	testCases := []struct {
+		desc           string
+		request        events.APIGatewayProxyRequest
+		expected       body
+		expectedStatus int
+	}{
+		{
+			desc:     "valid body",
+			request:  events.APIGatewayProxyRequest{Body: `{"title": "Stand-up"}` + "\n"},
+			expected: body{Title: "Stand-up"},
+		},
+		{
+			desc:     "base64 encoded body",
+			request:  events.APIGatewayProxyRequest{Body: base64.StdEncoding.EncodeToString([]byte(`{"title": "Stand-up"}`)), IsBase64Encoded: true},
+			expected: body{Title: "Stand-up"},
+		},
+		{
+			desc:           "empty body",
+			request:        events.APIGatewayProxyRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "trailing data",
+			request:        events.APIGatewayProxyRequest{Body: `{"title": "Stand-up"} {}`},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "invalid type",
+			request:        events.APIGatewayProxyRequest{Body: `{"title": 1}`},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "too large body",
+			request:        events.APIGatewayProxyRequest{Body: `{"title": "` + strings.Repeat("a", httpx.MaxBodySize) + `"}`},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var received body
+			err := httpx.DecodeJSON(tC.request, &received)
+			if tC.expectedStatus == 0 {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if received != tC.expected {
+					t.Errorf("Received result: %v is different than expected one: %v", received, tC.expected)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Invalid body was decoded: %v", received)
+			}
+			response, _ := httpx.InvalidBody(err)
+			if response.StatusCode != tC.expectedStatus {
+				t.Errorf("Received status code: %v is different than expected one: %v", response.StatusCode, tC.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	lambdaCtx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-id"})
+	apiRequest := events.APIGatewayProxyRequest{RequestContext: events.APIGatewayProxyRequestContext{RequestID: "api-id"}}
+
+	testCases := []struct {
+		desc              string
+		ctx               context.Context
+		handler           httpx.Handler
+		expectedRequestID string
+		expectedBody      string
+	}{
+		{
+			desc: "error response",
+			ctx:  lambdaCtx,
+			handler: func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				return errors.NotFound("event not found")
+			},
+			expectedRequestID: "lambda-id",
+		},
+		{
+			desc: "error response outside of Lambda",
+			ctx:  context.Background(),
+			handler: func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				return errors.NotFound("event not found")
+			},
+			expectedRequestID: "api-id",
+		},
+		{
+			desc: "successful response",
+			ctx:  lambdaCtx,
+			handler: func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+				return httpx.JSON(ctx, http.StatusOK, map[string]string{"requestId": "other"})
+			},
+			expectedRequestID: "lambda-id",
+			expectedBody:      `{"requestId":"other"}`,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			response, err := httpx.Correlate(tC.handler)(tC.ctx, apiRequest)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if response.Headers["X-Request-Id"] != tC.expectedRequestID {
+				t.Errorf("Received result: %v is different than expected one: %v", response.Headers["X-Request-Id"], tC.expectedRequestID)
+			}
+
+			if tC.expectedBody != "" {
+				if response.Body != tC.expectedBody {
+					t.Errorf("Received body: %v is different than expected one: %v", response.Body, tC.expectedBody)
+				}
+				return
+			}
+			var problem errors.Problem
+			if err := json.Unmarshal([]byte(response.Body), &problem); err != nil {
+				t.Fatalf("Error when decoding body: %v", err)
+			}
+			if problem.RequestID != tC.expectedRequestID || problem.Code != errors.CodeNotFound {
+				t.Errorf("Received problem: %+v is different than expected", problem)
+			}
+		})
+	}
+}
+
+func TestJSONFailure(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(logging.New(&buf, slog.LevelInfo))
+	defer slog.SetDefault(defaultLogger)
+
+	// channels can't be encoded as JSON
+	ctx := logging.With(context.Background(), logging.KeyUserID, "1")
+	response, err := httpx.JSON(ctx, http.StatusOK, map[string]interface{}{"updates": make(chan int)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Received result: %v is different than expected one: %v", response.StatusCode, http.StatusInternalServerError)
+	}
+	// failure is logged with attributes of the request
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Error when decoding log line %q: %v", buf.String(), err)
+	}
+	if line[logging.KeyUserID] != "1" {
+		t.Errorf("Logged line: %v doesn't carry user ID of the request", line)
+	}
+}
@@ -0,0 +1,75 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	goerrors "errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+)
+
+// MaxBodySize is the largest request body DecodeJSON accepts, in bytes
+const MaxBodySize = 64 << 10
+
+var (
+	ErrInvalidBody  = goerrors.New("invalid request body")
+	ErrBodyTooLarge = goerrors.New("request body too large")
+)
+
+// DecodeJSON decodes JSON body of a request into v. It fails with ErrBodyTooLarge when body
+// exceeds MaxBodySize and with ErrInvalidBody when it isn't a single JSON value
+func DecodeJSON(request events.APIGatewayProxyRequest, v interface{}) error {
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			return ErrInvalidBody
+		}
+		body = decoded
+	}
+	if len(body) > MaxBodySize {
+		return ErrBodyTooLarge
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if err := decoder.Decode(v); err != nil {
+		return ErrInvalidBody
+	}
+	// trailing data after the value means body isn't a valid JSON document
+	if decoder.More() {
+		return ErrInvalidBody
+	}
+	return nil
+}
+
+// InvalidBody returns response to a request whose body DecodeJSON failed to decode
+func InvalidBody(err error) (events.APIGatewayProxyResponse, error) {
+	if goerrors.Is(err, ErrBodyTooLarge) {
+		return errors.ErrorResponse(err.Error(), http.StatusRequestEntityTooLarge)
+	}
+	return errors.BadRequest(ErrInvalidBody.Error())
+}
+
+// JSON returns response with status code and body encoded as JSON. Failure to encode body is
+// logged with attributes of ctx
+func JSON(ctx context.Context, statusCode int, body interface{}) (events.APIGatewayProxyResponse, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return errors.Internal(ctx, err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(encoded),
+	}, nil
+}
+
+// NoContent returns response without body
+func NoContent() (events.APIGatewayProxyResponse, error) {
+	return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent, Headers: map[string]string{}}, nil
+}
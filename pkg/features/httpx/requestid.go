@@ -0,0 +1,51 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+)
+
+// RequestID returns ID of a request, which is ID of Lambda invocation, so that it can be found in
+// logs of the function. ID given by API Gateway is used when handler isn't invoked by Lambda
+func RequestID(ctx context.Context, request events.APIGatewayProxyRequest) string {
+	if lc, ok := lambdacontext.FromContext(ctx); ok && lc.AwsRequestID != "" {
+		return lc.AwsRequestID
+	}
+	return request.RequestContext.RequestID
+}
+
+// Correlate adds ID of a request to X-Request-Id header of responses of next handler and to
+// bodies of its error responses. Lines it logs carry ID given by API Gateway as well
+func Correlate(next Handler) Handler {
+	return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		ctx = logging.With(ctx, logging.KeyAPIRequestID, request.RequestContext.RequestID)
+		response, err := next(ctx, request)
+		requestID := RequestID(ctx, request)
+		if err != nil || requestID == "" {
+			return response, err
+		}
+
+		if response.Headers == nil {
+			response.Headers = make(map[string]string)
+		}
+		response.Headers["X-Request-Id"] = requestID
+
+		if response.Headers["Content-Type"] != errors.ContentType {
+			return response, nil
+		}
+		var problem errors.Problem
+		if err := json.Unmarshal([]byte(response.Body), &problem); err != nil {
+			return response, nil
+		}
+		problem.RequestID = requestID
+		body, _ := json.Marshal(problem)
+		response.Body = string(body)
+		return response, nil
+	}
+}
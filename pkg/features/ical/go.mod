@@ -0,0 +1,3 @@
+module github.com/Slimo300/Reminder-Serverless-Go/pkg/features/ical
+
+go 1.22.0
@@ -0,0 +1,81 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLineLength is the length in octets lines are folded at, see RFC 5545 section 3.1
+const maxLineLength = 75
+
+const dateTimeLayout = "20060102T150405Z"
+
+// Event is a single occurrence of an event in a calendar
+type Event struct {
+	// UID identifies the occurrence globally, it must be stable between exports of the same calendar
+	UID         string
+	Start       time.Time
+	Summary     string
+	Description string
+}
+
+// Write writes events as iCalendar object in RFC 5545 format, times are written in UTC
+func Write(w io.Writer, productID string, events []Event, now time.Time) error {
+	writer := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(writer, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", escape(productID))
+	line("CALSCALE", "GREGORIAN")
+	for _, event := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("DTSTAMP", now.UTC().Format(dateTimeLayout))
+		line("DTSTART", event.Start.UTC().Format(dateTimeLayout))
+		if event.Summary != "" {
+			line("SUMMARY", escape(event.Summary))
+		}
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return writer.Flush()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape escapes text value, see RFC 5545 section 3.3.11
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// writeFolded writes content line ended with CRLF, splitting it into lines of at most 75 octets,
+// continuation lines start with a space. Lines are never split inside of a UTF-8 character
+func writeFolded(w *bufio.Writer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isCharStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines are preceded by a space, which counts towards the limit
+		limit = maxLineLength - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// isCharStart reports whether b starts a UTF-8 character rather than continues one
+func isCharStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,55 @@
+package ical_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/ical"
+)
+
+func TestWrite(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+
+	var b strings.Builder
+	err := ical.Write(&b, "-//Reminder//Export//EN", []ical.Event{{
+		UID:         "1.2.a-1709802000@reminder",
+		Start:       time.Date(2024, 3, 7, 10, 0, 0, 0, warsaw),
+		Summary:     "Stand-up; room A1, floor 2",
+		Description: strings.Repeat("Zażółć gęślą jaźń\n", 10),
+	}}, now)
+	if err != nil {
+		t.Fatalf("Error when writing calendar: %v", err)
+	}
+	calendar := b.String()
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"DTSTAMP:20240306T120000Z\r\n",
+		"DTSTART:20240307T090000Z\r\n",
+		`SUMMARY:Stand-up\; room A1\, floor 2` + "\r\n",
+		"END:VEVENT\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(calendar, expected) {
+			t.Errorf("Calendar doesn't contain %q: %q", expected, calendar)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(calendar, "\r\n"), "\r\n")
+	var description string
+	for _, line := range lines {
+		if len(line) > 75 || !utf8.ValidString(line) {
+			t.Errorf("Line isn't folded properly: %q", line)
+		}
+		if strings.HasPrefix(line, "DESCRIPTION:") {
+			description = strings.TrimPrefix(line, "DESCRIPTION:")
+		} else if description != "" && strings.HasPrefix(line, " ") {
+			description += line[1:]
+		}
+	}
+	if expected := strings.Repeat(`Zażółć gęślą jaźń\n`, 10); description != expected {
+		t.Errorf("Received description: %v is different than expected one: %v", description, expected)
+	}
+}
@@ -0,0 +1,62 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Header is a name of request header clients use to mark retries of the same request
+const Header = "Idempotency-Key"
+
+// Statuses of a request stored under idempotency key
+const (
+	StatusInProgress = "in-progress"
+	StatusCompleted  = "completed"
+)
+
+const (
+	// TTL is how long responses are kept for retries
+	TTL = 24 * time.Hour
+	// Lease is how long a key stays reserved by a request being processed. It should be longer than
+	// a function timeout, so that key of a request that crashed can be reused after it passes
+	Lease = time.Minute
+)
+
+const maxKeyLength = 255
+
+// Key returns idempotency key sent in request headers or an empty string when there is none.
+// Header names are matched case-insensitively as clients and proxies may change their case
+func Key(headers map[string]string) (string, error) {
+	for name, value := range headers {
+		if !strings.EqualFold(name, Header) {
+			continue
+		}
+		if value == "" || len(value) > maxKeyLength {
+			return "", errors.New("idempotency key must be 1-255 characters long")
+		}
+		for _, r := range value {
+			if r < ' ' || r > '~' {
+				return "", errors.New("idempotency key must contain only printable ASCII characters")
+			}
+		}
+		return value, nil
+	}
+	return "", nil
+}
+
+// Fingerprint returns a hash identifying request body. JSON bodies are normalized first, so
+// that differences in formatting or order of fields don't make the same request a different one
+func Fingerprint(body string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err == nil {
+		if normalized, err := json.Marshal(value); err == nil {
+			body = string(normalized)
+		}
+	}
+	hash := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,50 @@
+package idempotency_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/idempotency"
+)
+
+func TestKey(t *testing.T) {
+	testCases := []struct {
+		name      string
+		headers   map[string]string
+		expected  string
+		returnErr bool
+	}{
+		{name: "no header", headers: map[string]string{"Content-Type": "application/json"}},
+		{name: "header", headers: map[string]string{"Idempotency-Key": "8e03978e-40d5"}, expected: "8e03978e-40d5"},
+		{name: "lowercase header", headers: map[string]string{"idempotency-key": "8e03978e-40d5"}, expected: "8e03978e-40d5"},
+		{name: "empty key", headers: map[string]string{"Idempotency-Key": ""}, returnErr: true},
+		{name: "too long key", headers: map[string]string{"Idempotency-Key": strings.Repeat("a", 256)}, returnErr: true},
+		{name: "non ascii key", headers: map[string]string{"Idempotency-Key": "klucz-żółty"}, returnErr: true},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			key, err := idempotency.Key(tC.headers)
+			if tC.returnErr != (err != nil) {
+				t.Fatalf("Unexpected result: %v", err)
+			}
+			if key != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", key, tC.expected)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	body := `{"message":"Stand-up","crons":["0 10 ? * MON-FRI *"]}`
+
+	if idempotency.Fingerprint(body) != idempotency.Fingerprint("{\n  \"crons\": [\"0 10 ? * MON-FRI *\"],\n  \"message\": \"Stand-up\"\n}") {
+		t.Error("Differently formatted bodies have different fingerprints")
+	}
+	if idempotency.Fingerprint(body) == idempotency.Fingerprint(`{"message":"Stand-up","crons":["0 11 ? * MON-FRI *"]}`) {
+		t.Error("Different bodies have the same fingerprint")
+	}
+	if idempotency.Fingerprint("not json") == idempotency.Fingerprint("not json either") {
+		t.Error("Different non JSON bodies have the same fingerprint")
+	}
+}
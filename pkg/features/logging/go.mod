@@ -0,0 +1,5 @@
+module github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging
+
+go 1.22.0
+
+require github.com/aws/aws-lambda-go v1.47.0
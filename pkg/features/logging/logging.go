@@ -0,0 +1,99 @@
+// Package logging sets up structured JSON logs of functions. Every line carries IDs of a request,
+// a user and an event it concerns, and phone numbers and verification codes are redacted from it.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// Keys of attributes added to every line logged with a context
+const (
+	KeyLambdaRequestID = "lambdaRequestId"
+	KeyAPIRequestID    = "apiRequestId"
+	KeyUserID          = "userId"
+	KeyEventID         = "eventId"
+	// IDs of X-Ray trace and of a request that created an alarm, logged by alarm executor
+	KeyOriginTraceID   = "originTraceId"
+	KeyOriginRequestID = "originRequestId"
+)
+
+type contextKey struct{}
+
+// Setup makes JSON logger writing to stdout at level from LOG_LEVEL variable the default one.
+// Lines logged with log package go through it as well. Every main should call it first
+func Setup() *slog.Logger {
+	logger := New(os.Stdout, Level(os.Getenv("LOG_LEVEL")))
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New returns JSON logger writing lines of at least given level to w
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(&handler{
+		Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: redactAttr,
+		}),
+	})
+}
+
+// Level returns level of given name ("debug", "info", "warn" or "error"), it defaults to info
+func Level(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// With returns context whose lines carry given attributes, e.g. With(ctx, KeyUserID, userID).
+// Attributes with empty values are skipped
+func With(ctx context.Context, key, value string) context.Context {
+	if value == "" {
+		return ctx
+	}
+	parent, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	attrs := make([]slog.Attr, 0, len(parent)+1)
+	for _, attr := range parent {
+		if attr.Key != key {
+			attrs = append(attrs, attr)
+		}
+	}
+	return context.WithValue(ctx, contextKey{}, append(attrs, slog.String(key, value)))
+}
+
+// handler adds attributes of context and ID of Lambda invocation to records
+type handler struct {
+	slog.Handler
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	if ctx != nil {
+		if lc, ok := lambdacontext.FromContext(ctx); ok && lc.AwsRequestID != "" {
+			redacted.AddAttrs(slog.String(KeyLambdaRequestID, lc.AwsRequestID))
+		}
+		if attrs, ok := ctx.Value(contextKey{}).([]slog.Attr); ok {
+			redacted.AddAttrs(attrs...)
+		}
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(attr)
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,94 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+)
+
+func TestLogger(t *testing.T) {
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"})
+	ctx = logging.With(ctx, logging.KeyAPIRequestID, "api-1")
+	ctx = logging.With(ctx, logging.KeyUserID, "user-1")
+	ctx = logging.With(ctx, logging.KeyEventID, "")
+
+	testCases := []struct {
+		desc     string
+		ctx      context.Context
+		message  string
+		args     []any
+		expected map[string]interface{}
+	}{
+		{
+			desc:    "context attributes",
+			ctx:     logging.With(ctx, logging.KeyEventID, "event-1"),
+			message: "alarm sent",
+			expected: map[string]interface{}{
+				"level":           "INFO",
+				"msg":             "alarm sent",
+				"lambdaRequestId": "lambda-1",
+				"apiRequestId":    "api-1",
+				"userId":          "user-1",
+				"eventId":         "event-1",
+			},
+		},
+		{
+			desc:    "sensitive attributes",
+			ctx:     context.Background(),
+			message: "code sent to +48123456789",
+			args:    []any{"phone_number", "+48123456789", "VerificationCode", "123456", "error", errors.New("invalid parameter: +48123456789")},
+			expected: map[string]interface{}{
+				"level":            "INFO",
+				"msg":              "code sent to +*********89",
+				"phone_number":     "[REDACTED]",
+				"VerificationCode": "[REDACTED]",
+				"error":            "invalid parameter: +*********89",
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			logging.New(&buf, slog.LevelInfo).InfoContext(tC.ctx, tC.message, tC.args...)
+
+			var received map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &received); err != nil {
+				t.Fatalf("Error when decoding line %q: %v", buf.String(), err)
+			}
+			delete(received, "time")
+			if !reflect.DeepEqual(received, tC.expected) {
+				t.Errorf("Received result: %v is different than expected one: %v", received, tC.expected)
+			}
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected slog.Level
+	}{
+		{name: "debug", expected: slog.LevelDebug},
+		{name: "WARN", expected: slog.LevelWarn},
+		{name: "error", expected: slog.LevelError},
+		{name: "", expected: slog.LevelInfo},
+		{name: "verbose", expected: slog.LevelInfo},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			if received := logging.Level(tC.name); received != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", received, tC.expected)
+			}
+		})
+	}
+}
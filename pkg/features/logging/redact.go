@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces values of sensitive attributes
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are keys of attributes whose values are never logged, compared case-insensitively
+// and without separators, so that "phone_number" and "PhoneNumber" are the same key
+var sensitiveKeys = map[string]bool{
+	"phonenumber":      true,
+	"phone":            true,
+	"newphonenumber":   true,
+	"verificationcode": true,
+}
+
+// phoneNumber matches phone numbers in E.164 format
+var phoneNumber = regexp.MustCompile(`\+[1-9]\d{6,14}`)
+
+// Redact masks phone numbers in s leaving their last two digits, so that lines can still be told apart
+func Redact(s string) string {
+	return phoneNumber.ReplaceAllStringFunc(s, func(number string) string {
+		return "+" + strings.Repeat("*", len(number)-3) + number[len(number)-2:]
+	})
+}
+
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) == 0 && (attr.Key == slog.TimeKey || attr.Key == slog.LevelKey || attr.Key == slog.SourceKey) {
+		return attr
+	}
+	key := strings.NewReplacer("_", "", "-", "", ":", "").Replace(strings.ToLower(attr.Key))
+	if sensitiveKeys[key] {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch value := attr.Value.Resolve(); value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(value.String()))
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+	}
+	return attr
+}
@@ -0,0 +1,124 @@
+// Package metrics writes business metrics to stdout in CloudWatch Embedded Metric Format, which
+// CloudWatch Logs turns into metrics without any calls to CloudWatch API.
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+)
+
+// DefaultNamespace is a CloudWatch namespace of all metrics of the application
+const DefaultNamespace = "Reminder"
+
+// Names of dimensions
+const (
+	DimensionStage   = "Stage"
+	DimensionChannel = "Channel"
+)
+
+// ChannelSMS is a channel of alarms and verification codes sent as SMS
+const ChannelSMS = "sms"
+
+// Unit of a metric
+type Unit string
+
+const (
+	Count        Unit = "Count"
+	Milliseconds Unit = "Milliseconds"
+)
+
+// Metric is a single value of a metric
+type Metric struct {
+	Name  string
+	Value float64
+	Unit  Unit
+}
+
+// Emitter writes metrics as EMF lines, zero value writes them to stdout
+type Emitter struct {
+	// W is where lines are written, os.Stdout is used when it's not set
+	W io.Writer
+	// Namespace of metrics, DefaultNamespace is used when it's empty
+	Namespace string
+	// Stage is added to dimensions of all metrics, so that metrics of stages sharing an account
+	// don't mix. "default" is used when it's empty
+	Stage string
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
+}
+
+type directive struct {
+	Namespace  string       `json:"Namespace"`
+	Dimensions [][]string   `json:"Dimensions"`
+	Metrics    []definition `json:"Metrics"`
+}
+
+type definition struct {
+	Name string `json:"Name"`
+	Unit Unit   `json:"Unit"`
+}
+
+// Emit writes metrics with given dimensions and stage in a single line
+func (e *Emitter) Emit(dimensions map[string]string, metrics ...Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	now := time.Now()
+	if e.Now != nil {
+		now = e.Now()
+	}
+	namespace := e.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	line := make(map[string]interface{}, len(dimensions)+len(metrics)+2)
+	stage := e.Stage
+	if stage == "" {
+		stage = "default"
+	}
+	line[DimensionStage] = stage
+	for name, value := range dimensions {
+		line[name] = value
+	}
+	keys := make([]string, 0, len(line))
+	for name := range line {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+
+	definitions := make([]definition, 0, len(metrics))
+	for _, metric := range metrics {
+		definitions = append(definitions, definition{Name: metric.Name, Unit: metric.Unit})
+		line[metric.Name] = metric.Value
+	}
+	line["_aws"] = map[string]interface{}{
+		"Timestamp":         now.UnixMilli(),
+		"CloudWatchMetrics": []directive{{Namespace: namespace, Dimensions: [][]string{keys}, Metrics: definitions}},
+	}
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	w := e.W
+	if w == nil {
+		w = os.Stdout
+	}
+	// Line is written at once, so that lines of concurrent emits don't interleave
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
+
+// Record emits metrics of given channel. Failures are only logged, since metrics must never fail
+// handling of requests
+func (e *Emitter) Record(ctx context.Context, channel string, metrics ...Metric) {
+	if err := e.Emit(map[string]string{DimensionChannel: channel}, metrics...); err != nil {
+		slog.WarnContext(ctx, "metrics couldn't be emitted", "error", err)
+	}
+}
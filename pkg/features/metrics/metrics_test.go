@@ -0,0 +1,67 @@
+package metrics_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+)
+
+func TestEmit(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		stage      string
+		dimensions map[string]string
+		metrics    []metrics.Metric
+		expected   string
+	}{
+		{
+			desc:       "stage and channel",
+			stage:      "dev",
+			dimensions: map[string]string{metrics.DimensionChannel: metrics.ChannelSMS},
+			metrics: []metrics.Metric{
+				{Name: "AlarmsSent", Value: 2, Unit: metrics.Count},
+				{Name: "FireDelay", Value: 1500, Unit: metrics.Milliseconds},
+			},
+			expected: `{"Channel":"sms","Stage":"dev","AlarmsSent":2,"FireDelay":1500,"_aws":{"Timestamp":1718445600000,"CloudWatchMetrics":[{"Namespace":"Reminder","Dimensions":[["Channel","Stage"]],"Metrics":[{"Name":"AlarmsSent","Unit":"Count"},{"Name":"FireDelay","Unit":"Milliseconds"}]}]}}`,
+		},
+		{
+			desc:     "no stage",
+			metrics:  []metrics.Metric{{Name: "EventsCreated", Value: 1, Unit: metrics.Count}},
+			expected: `{"Stage":"default","EventsCreated":1,"_aws":{"Timestamp":1718445600000,"CloudWatchMetrics":[{"Namespace":"Reminder","Dimensions":[["Stage"]],"Metrics":[{"Name":"EventsCreated","Unit":"Count"}]}]}}`,
+		},
+		{
+			desc:     "no metrics",
+			stage:    "dev",
+			expected: "",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			emitter := &metrics.Emitter{W: &buf, Stage: tC.stage, Now: func() time.Time { return time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC) }}
+			if err := emitter.Emit(tC.dimensions, tC.metrics...); err != nil {
+				t.Fatalf("Error when emitting metrics: %v", err)
+			}
+
+			if tC.expected == "" {
+				if buf.Len() != 0 {
+					t.Errorf("Expected nothing to be written, but got %q", buf.String())
+				}
+				return
+			}
+			var received, expected map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &received); err != nil {
+				t.Fatalf("Error when decoding line %q: %v", buf.String(), err)
+			}
+			json.Unmarshal([]byte(tC.expected), &expected)
+			if !reflect.DeepEqual(received, expected) {
+				t.Errorf("Received result: %v is different than expected one: %v", received, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package msgtemplate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Built-in variables available in every template
+const (
+	VarTime       = "time"     // fire time, e.g. 09:30
+	VarDate       = "date"     // fire date, e.g. 2024-03-05
+	VarWeekday    = "weekday"  // weekday of fire time, e.g. Tuesday
+	VarTimezone   = "timezone" // timezone of an event, e.g. Europe/Warsaw
+	VarTitle      = "title"    // title of an event
+	VarOccurrence = "n"        // number of times alarms of an event fired, starting with 1
+)
+
+// FuncDaysUntil takes a date (either literal or a custom variable holding it) and
+// renders number of days left until it, counted from fire date
+const FuncDaysUntil = "days_until"
+
+const dateLayout = "2006-01-02"
+
+// Values used to estimate the longest possible rendering of dynamic values
+const (
+	longestTime       = "00:00"
+	longestDate       = "2006-01-02"
+	longestWeekday    = "Wednesday"
+	longestOccurrence = "999999"
+	longestDaysUntil  = "-99999"
+)
+
+// maxVariables limits the number of custom variables an event can define
+const maxVariables = 10
+
+var (
+	nameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,31}$`)
+	builtins   = map[string]bool{
+		VarTime: true, VarDate: true, VarWeekday: true, VarTimezone: true, VarTitle: true, VarOccurrence: true,
+	}
+)
+
+// Data holds values that change between firings of an alarm
+type Data struct {
+	// Time is a fire time in event's timezone
+	Time       time.Time
+	Timezone   string
+	Title      string
+	Occurrence int
+}
+
+type node struct {
+	text string // literal text, used when name is empty
+	name string // variable or function name
+	date string // argument of FuncDaysUntil
+}
+
+// Template is a parsed message. Text between "{{" and "}}" is either a variable
+// name ("{{weekday}}") or a function call ("{{days_until deadline}}")
+type Template struct {
+	nodes []node
+	vars  map[string]string
+}
+
+// Parse parses text into a template. vars are custom variables defined along with an
+// event, they can be referenced in a template by name just like built-in ones
+func Parse(text string, vars map[string]string) (*Template, error) {
+	if len(vars) > maxVariables {
+		return nil, fmt.Errorf("there can be at most %d variables", maxVariables)
+	}
+	for name := range vars {
+		if !nameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable name: %q", name)
+		}
+		if builtins[name] || name == FuncDaysUntil {
+			return nil, fmt.Errorf("variable %q is reserved", name)
+		}
+	}
+
+	t := &Template{vars: vars}
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start:], "}}")
+		if end < 0 {
+			return nil, errors.New(`unclosed "{{" in template`)
+		}
+
+		action, err := t.parseAction(strings.Fields(text[start+2 : start+end]))
+		if err != nil {
+			return nil, err
+		}
+		if start > 0 {
+			t.nodes = append(t.nodes, node{text: text[:start]})
+		}
+		t.nodes = append(t.nodes, action)
+		text = text[start+end+2:]
+	}
+	if text != "" {
+		t.nodes = append(t.nodes, node{text: text})
+	}
+
+	return t, nil
+}
+
+func (t *Template) parseAction(fields []string) (node, error) {
+	switch {
+	case len(fields) == 1:
+		if _, ok := t.vars[fields[0]]; !ok && !builtins[fields[0]] {
+			return node{}, fmt.Errorf("unknown variable: %q", fields[0])
+		}
+		return node{name: fields[0]}, nil
+	case len(fields) == 2 && fields[0] == FuncDaysUntil:
+		date := fields[1]
+		if value, ok := t.vars[date]; ok {
+			date = value
+		}
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return node{}, fmt.Errorf("%s expects a date in YYYY-MM-DD format, got: %q", FuncDaysUntil, fields[1])
+		}
+		return node{name: FuncDaysUntil, date: date}, nil
+	case len(fields) == 0:
+		return node{}, errors.New("empty action in template")
+	default:
+		return node{}, fmt.Errorf("invalid action in template: %q", strings.Join(fields, " "))
+	}
+}
+
+// Uses reports whether template references given variable
+func (t *Template) Uses(name string) bool {
+	for _, n := range t.nodes {
+		if n.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute renders template with given data
+func (t *Template) Execute(data Data) string {
+	var b strings.Builder
+	for _, n := range t.nodes {
+		switch n.name {
+		case "":
+			b.WriteString(n.text)
+		case VarTime:
+			b.WriteString(data.Time.Format("15:04"))
+		case VarDate:
+			b.WriteString(data.Time.Format(dateLayout))
+		case VarWeekday:
+			b.WriteString(data.Time.Weekday().String())
+		case VarTimezone:
+			b.WriteString(data.Timezone)
+		case VarTitle:
+			b.WriteString(data.Title)
+		case VarOccurrence:
+			b.WriteString(strconv.Itoa(data.Occurrence))
+		case FuncDaysUntil:
+			b.WriteString(strconv.Itoa(daysUntil(data.Time, n.date)))
+		default:
+			b.WriteString(t.vars[n.name])
+		}
+	}
+	return b.String()
+}
+
+// Longest renders template with the longest values dynamic variables can take, so it can
+// be used to estimate size of messages sent
+func (t *Template) Longest(title, timezone string) string {
+	var b strings.Builder
+	for _, n := range t.nodes {
+		switch n.name {
+		case "":
+			b.WriteString(n.text)
+		case VarTime:
+			b.WriteString(longestTime)
+		case VarDate:
+			b.WriteString(longestDate)
+		case VarWeekday:
+			b.WriteString(longestWeekday)
+		case VarTimezone:
+			b.WriteString(timezone)
+		case VarTitle:
+			b.WriteString(title)
+		case VarOccurrence:
+			b.WriteString(longestOccurrence)
+		case FuncDaysUntil:
+			b.WriteString(longestDaysUntil)
+		default:
+			b.WriteString(t.vars[n.name])
+		}
+	}
+	return b.String()
+}
+
+// daysUntil counts calendar days between date of t and given date
+func daysUntil(t time.Time, date string) int {
+	deadline, _ := time.Parse(dateLayout, date)
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int(deadline.Sub(today).Hours() / 24)
+}
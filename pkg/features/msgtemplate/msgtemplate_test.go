@@ -0,0 +1,154 @@
+package msgtemplate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/msgtemplate"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name      string
+		text      string
+		vars      map[string]string
+		returnErr bool
+	}{
+		{
+			name: "plain text",
+			text: "Stand-up",
+		},
+		{
+			name: "builtins",
+			text: "{{title}} at {{time}} on {{weekday}} {{date}} ({{timezone}}), occurrence {{ n }}",
+		},
+		{
+			name: "custom variables",
+			text: "{{room}}: {{days_until deadline}} days left, {{days_until 2024-12-24}} until Christmas Eve",
+			vars: map[string]string{"room": "B-12", "deadline": "2024-06-30"},
+		},
+		{
+			name:      "unknown variable",
+			text:      "{{room}}",
+			returnErr: true,
+		},
+		{
+			name:      "unclosed action",
+			text:      "{{weekday",
+			returnErr: true,
+		},
+		{
+			name:      "empty action",
+			text:      "{{ }}",
+			returnErr: true,
+		},
+		{
+			name:      "unknown function",
+			text:      "{{upper title}}",
+			returnErr: true,
+		},
+		{
+			name:      "days_until without date",
+			text:      "{{days_until room}}",
+			vars:      map[string]string{"room": "B-12"},
+			returnErr: true,
+		},
+		{
+			name:      "reserved variable",
+			text:      "{{title}}",
+			vars:      map[string]string{"title": "other"},
+			returnErr: true,
+		},
+		{
+			name:      "invalid variable name",
+			text:      "text",
+			vars:      map[string]string{"Room 1": "B-12"},
+			returnErr: true,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			_, err := msgtemplate.Parse(tC.text, tC.vars)
+			if tC.returnErr != (err != nil) {
+				t.Errorf("Unexpected parsing result: %v", err)
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+
+	testCases := []struct {
+		name     string
+		text     string
+		vars     map[string]string
+		data     msgtemplate.Data
+		expected string
+	}{
+		{
+			name:     "plain text",
+			text:     "Stand-up in 10 min",
+			expected: "Stand-up in 10 min",
+		},
+		{
+			name:     "builtins",
+			text:     "Stand-up in 10 min ({{weekday}}, occurrence {{n}})",
+			data:     msgtemplate.Data{Time: time.Date(2024, 3, 5, 9, 50, 0, 0, warsaw), Occurrence: 7},
+			expected: "Stand-up in 10 min (Tuesday, occurrence 7)",
+		},
+		{
+			name:     "time and title",
+			text:     "{{title}}: {{date}} {{time}} {{timezone}}",
+			data:     msgtemplate.Data{Time: time.Date(2024, 3, 5, 9, 50, 0, 0, warsaw), Title: "Stand-up", Timezone: "Europe/Warsaw"},
+			expected: "Stand-up: 2024-03-05 09:50 Europe/Warsaw",
+		},
+		{
+			name:     "countdown",
+			text:     "{{days_until deadline}} days left for {{project}}",
+			vars:     map[string]string{"deadline": "2024-04-01", "project": "thesis"},
+			data:     msgtemplate.Data{Time: time.Date(2024, 3, 30, 23, 30, 0, 0, warsaw)},
+			expected: "2 days left for thesis",
+		},
+		{
+			name:     "countdown over DST change",
+			text:     "{{days_until 2024-04-01}}",
+			data:     msgtemplate.Data{Time: time.Date(2024, 3, 30, 0, 30, 0, 0, warsaw)},
+			expected: "2",
+		},
+		{
+			name:     "deadline passed",
+			text:     "{{days_until 2024-03-01}}",
+			data:     msgtemplate.Data{Time: time.Date(2024, 3, 5, 9, 0, 0, 0, warsaw)},
+			expected: "-4",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			tmpl, err := msgtemplate.Parse(tC.text, tC.vars)
+			if err != nil {
+				t.Fatalf("Error when parsing template: %v", err)
+			}
+			if res := tmpl.Execute(tC.data); res != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", res, tC.expected)
+			}
+		})
+	}
+}
+
+func TestLongest(t *testing.T) {
+	tmpl, err := msgtemplate.Parse("{{title}} on {{weekday}} #{{n}} in {{room}}", map[string]string{"room": "B-12"})
+	if err != nil {
+		t.Fatalf("Error when parsing template: %v", err)
+	}
+
+	expected := "Stand-up on Wednesday #999999 in B-12"
+	if res := tmpl.Longest("Stand-up", "UTC"); res != expected {
+		t.Errorf("Received result: %v is different than expected one: %v", res, expected)
+	}
+	if !tmpl.Uses(msgtemplate.VarOccurrence) || tmpl.Uses(msgtemplate.VarDate) {
+		t.Error("Template reports invalid variable usage")
+	}
+}
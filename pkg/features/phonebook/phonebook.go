@@ -0,0 +1,46 @@
+package phonebook
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+)
+
+// DefaultLabel is a label of phone number user signed up with
+const DefaultLabel = "default"
+
+// LabelAttribute is a name of SNS message attribute that holds labels of phone numbers
+// message should be delivered to
+const LabelAttribute = "phoneLabel"
+
+var labelRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ValidateLabel checks if label can be used to name a phone number
+func ValidateLabel(label string) error {
+	if !labelRegexp.MatchString(label) {
+		return errors.New(`phone label must be 1-32 characters long and contain only lowercase letters, digits, "_" or "-"`)
+	}
+	return nil
+}
+
+// Labels returns labels alarm should be delivered to, falling back to DefaultLabel
+// when none were specified
+func Labels(labels []string) []string {
+	if len(labels) == 0 {
+		return []string{DefaultLabel}
+	}
+	return labels
+}
+
+// FilterPolicy returns SNS subscription filter policy that matches messages
+// sent to user's phone number with given label
+func FilterPolicy(userID, label string) (string, error) {
+	filterPolicy, err := json.Marshal(map[string]interface{}{
+		"userID":       []string{userID},
+		LabelAttribute: []string{label},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(filterPolicy), nil
+}
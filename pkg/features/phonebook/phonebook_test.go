@@ -0,0 +1,50 @@
+package phonebook_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook"
+)
+
+func TestValidateLabel(t *testing.T) {
+	testCases := []struct {
+		label     string
+		returnErr bool
+	}{
+		{label: "default"},
+		{label: "work-phone_2"},
+		{label: "", returnErr: true},
+		{label: "Work", returnErr: true},
+		{label: "my phone", returnErr: true},
+		{label: "abcdefghijklmnopqrstuvwxyz1234567", returnErr: true},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.label, func(t *testing.T) {
+			err := phonebook.ValidateLabel(tC.label)
+			if tC.returnErr != (err != nil) {
+				t.Errorf("Unexpected validation result for label %q: %v", tC.label, err)
+			}
+		})
+	}
+}
+
+func TestLabels(t *testing.T) {
+	if labels := phonebook.Labels(nil); !reflect.DeepEqual(labels, []string{phonebook.DefaultLabel}) {
+		t.Errorf("Expected default label, got: %v", labels)
+	}
+	if labels := phonebook.Labels([]string{"work", "home"}); !reflect.DeepEqual(labels, []string{"work", "home"}) {
+		t.Errorf("Expected labels to be returned unchanged, got: %v", labels)
+	}
+}
+
+func TestFilterPolicy(t *testing.T) {
+	policy, err := phonebook.FilterPolicy("1", "work")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if policy != `{"phoneLabel":["work"],"userID":["1"]}` {
+		t.Errorf("Unexpected filter policy: %v", policy)
+	}
+}
@@ -0,0 +1,60 @@
+package quiethours
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ToAttributeValue converts settings to a form they are stored in DynamoDB
+func (s *Settings) ToAttributeValue() types.AttributeValue {
+	windows := make([]types.AttributeValue, 0, len(s.Windows))
+	for _, window := range s.Windows {
+		windows = append(windows, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"Start": &types.AttributeValueMemberS{Value: window.Start},
+			"End":   &types.AttributeValueMemberS{Value: window.End},
+		}})
+	}
+
+	return &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"Timezone": &types.AttributeValueMemberS{Value: s.Timezone},
+		"Policy":   &types.AttributeValueMemberS{Value: string(s.Policy)},
+		"Windows":  &types.AttributeValueMemberL{Value: windows},
+	}}
+}
+
+// FromAttributeValue reads settings stored in DynamoDB
+func FromAttributeValue(value types.AttributeValue) (*Settings, error) {
+	settingsMap, ok := value.(*types.AttributeValueMemberM)
+	if !ok {
+		return nil, errors.New("quiet hours settings are not a map")
+	}
+
+	var settings Settings
+	settings.Timezone = stringValue(settingsMap.Value["Timezone"])
+	settings.Policy = Policy(stringValue(settingsMap.Value["Policy"]))
+
+	windows, ok := settingsMap.Value["Windows"].(*types.AttributeValueMemberL)
+	if !ok {
+		return &settings, nil
+	}
+	for _, value := range windows.Value {
+		windowMap, ok := value.(*types.AttributeValueMemberM)
+		if !ok {
+			return nil, errors.New("quiet hours window is not a map")
+		}
+		settings.Windows = append(settings.Windows, Window{
+			Start: stringValue(windowMap.Value["Start"]),
+			End:   stringValue(windowMap.Value["End"]),
+		})
+	}
+
+	return &settings, nil
+}
+
+func stringValue(value types.AttributeValue) string {
+	if s, ok := value.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
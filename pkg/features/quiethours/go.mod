@@ -0,0 +1,7 @@
+module github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours
+
+go 1.22.0
+
+require github.com/aws/aws-sdk-go-v2/service/dynamodb v1.34.5
+
+require github.com/aws/smithy-go v1.20.4 // indirect
@@ -0,0 +1,151 @@
+package quiethours
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Policy string
+
+const (
+	// PolicyDefer postpones reminders falling into quiet hours until the end of the window
+	PolicyDefer Policy = "defer"
+	// PolicyDrop skips reminders falling into quiet hours
+	PolicyDrop Policy = "drop"
+)
+
+const clockLayout = "15:04"
+
+// ErrWholeDay is returned by Validate for windows that together cover whole day, as reminders
+// could then never be sent
+var ErrWholeDay = errors.New("quiet hours cannot cover whole day")
+
+// Window is a daily period of time given in "HH:MM" format. Windows ending before
+// they start span over midnight, e.g. 22:00 - 07:00
+type Window struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type Settings struct {
+	Timezone string   `json:"timezone"`
+	Policy   Policy   `json:"policy"`
+	Windows  []Window `json:"windows"`
+}
+
+func (s *Settings) Validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil || s.Timezone == "" {
+		return fmt.Errorf("invalid timezone: %q", s.Timezone)
+	}
+	if s.Policy != PolicyDefer && s.Policy != PolicyDrop {
+		return fmt.Errorf(`"policy" must be either %q or %q`, PolicyDefer, PolicyDrop)
+	}
+	for _, window := range s.Windows {
+		start, err := time.Parse(clockLayout, window.Start)
+		if err != nil {
+			return fmt.Errorf("invalid window start: %q", window.Start)
+		}
+		end, err := time.Parse(clockLayout, window.End)
+		if err != nil {
+			return fmt.Errorf("invalid window end: %q", window.End)
+		}
+		if start.Equal(end) {
+			return errors.New("window cannot start and end at the same time")
+		}
+	}
+	if s.coverWholeDay() {
+		return ErrWholeDay
+	}
+	return nil
+}
+
+// coverWholeDay reports whether every minute of a day falls into some of windows. Windows
+// have to be valid
+func (s *Settings) coverWholeDay() bool {
+	const minutesPerDay = 24 * 60
+	var covered [minutesPerDay]bool
+	for _, window := range s.Windows {
+		start, _ := time.Parse(clockLayout, window.Start)
+		end, _ := time.Parse(clockLayout, window.End)
+		for minute := start.Hour()*60 + start.Minute(); minute != end.Hour()*60+end.Minute(); minute = (minute + 1) % minutesPerDay {
+			covered[minute] = true
+		}
+	}
+	for _, quiet := range covered {
+		if !quiet {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports whether t falls into any of quiet-hour windows. If so it also
+// returns the moment quiet hours end, taking adjacent and overlapping windows into account
+func (s *Settings) Check(t time.Time) (bool, time.Time, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	end, quiet, err := s.windowEnd(t.In(loc))
+	if err != nil || !quiet {
+		return false, time.Time{}, err
+	}
+
+	// windows can follow each other so we look for the first moment outside of all of them,
+	// there can't be more iterations than windows unless they cover whole day
+	for i := 0; i < len(s.Windows); i++ {
+		next, stillQuiet, err := s.windowEnd(end)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		if !stillQuiet {
+			return true, end, nil
+		}
+		end = next
+	}
+
+	return false, time.Time{}, errors.New("quiet hours cover whole day")
+}
+
+// windowEnd returns the latest end of windows containing t
+func (s *Settings) windowEnd(t time.Time) (time.Time, bool, error) {
+	var (
+		latest time.Time
+		quiet  bool
+	)
+
+	for _, window := range s.Windows {
+		start, err := time.Parse(clockLayout, window.Start)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		end, err := time.Parse(clockLayout, window.End)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		// window containing t could have started either today or yesterday
+		for _, day := range []time.Time{t.AddDate(0, 0, -1), t} {
+			windowStart := atClock(day, start)
+			windowEnd := atClock(day, end)
+			if !windowEnd.After(windowStart) {
+				windowEnd = atClock(day.AddDate(0, 0, 1), end)
+			}
+
+			if !t.Before(windowStart) && t.Before(windowEnd) {
+				quiet = true
+				if windowEnd.After(latest) {
+					latest = windowEnd
+				}
+			}
+		}
+	}
+
+	return latest, quiet, nil
+}
+
+func atClock(day, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+}
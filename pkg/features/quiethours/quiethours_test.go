@@ -0,0 +1,163 @@
+package quiethours_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		settings  quiethours.Settings
+		returnErr bool
+	}{
+		{
+			name:     "valid",
+			settings: quiethours.Settings{Timezone: "Europe/Warsaw", Policy: quiethours.PolicyDefer, Windows: []quiethours.Window{{Start: "22:00", End: "07:00"}}},
+		},
+		{
+			name:      "invalid timezone",
+			settings:  quiethours.Settings{Timezone: "Mars/Olympus", Policy: quiethours.PolicyDefer},
+			returnErr: true,
+		},
+		{
+			name:      "invalid policy",
+			settings:  quiethours.Settings{Timezone: "Europe/Warsaw", Policy: "snooze"},
+			returnErr: true,
+		},
+		{
+			name:      "invalid clock",
+			settings:  quiethours.Settings{Timezone: "Europe/Warsaw", Policy: quiethours.PolicyDrop, Windows: []quiethours.Window{{Start: "25:00", End: "07:00"}}},
+			returnErr: true,
+		},
+		{
+			name:      "empty window",
+			settings:  quiethours.Settings{Timezone: "Europe/Warsaw", Policy: quiethours.PolicyDrop, Windows: []quiethours.Window{{Start: "07:00", End: "07:00"}}},
+			returnErr: true,
+		},
+		{
+			name:      "whole day",
+			settings:  quiethours.Settings{Timezone: "Europe/Warsaw", Policy: quiethours.PolicyDefer, Windows: []quiethours.Window{{Start: "22:00", End: "12:00"}, {Start: "11:00", End: "22:30"}}},
+			returnErr: true,
+		},
+		{
+			name:     "whole day but a minute",
+			settings: quiethours.Settings{Timezone: "Europe/Warsaw", Policy: quiethours.PolicyDefer, Windows: []quiethours.Window{{Start: "00:00", End: "12:00"}, {Start: "12:00", End: "23:59"}}},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			err := tC.settings.Validate()
+			if tC.returnErr != (err != nil) {
+				t.Errorf("Unexpected validation result: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+
+	testCases := []struct {
+		name        string
+		windows     []quiethours.Window
+		time        time.Time
+		expectQuiet bool
+		expectedEnd time.Time
+	}{
+		{
+			name:        "before midnight",
+			windows:     []quiethours.Window{{Start: "22:00", End: "07:00"}},
+			time:        time.Date(2024, 3, 5, 23, 30, 0, 0, warsaw),
+			expectQuiet: true,
+			expectedEnd: time.Date(2024, 3, 6, 7, 0, 0, 0, warsaw),
+		},
+		{
+			name:        "after midnight",
+			windows:     []quiethours.Window{{Start: "22:00", End: "07:00"}},
+			time:        time.Date(2024, 3, 6, 3, 0, 0, 0, warsaw),
+			expectQuiet: true,
+			expectedEnd: time.Date(2024, 3, 6, 7, 0, 0, 0, warsaw),
+		},
+		{
+			name:    "window end is not quiet",
+			windows: []quiethours.Window{{Start: "22:00", End: "07:00"}},
+			time:    time.Date(2024, 3, 6, 7, 0, 0, 0, warsaw),
+		},
+		{
+			name:    "outside of window",
+			windows: []quiethours.Window{{Start: "13:00", End: "14:00"}},
+			time:    time.Date(2024, 3, 6, 12, 59, 0, 0, warsaw),
+		},
+		{
+			name:        "adjacent windows",
+			windows:     []quiethours.Window{{Start: "22:00", End: "02:00"}, {Start: "02:00", End: "06:30"}},
+			time:        time.Date(2024, 3, 6, 23, 0, 0, 0, warsaw),
+			expectQuiet: true,
+			expectedEnd: time.Date(2024, 3, 7, 6, 30, 0, 0, warsaw),
+		},
+		{
+			name:        "time given in UTC",
+			windows:     []quiethours.Window{{Start: "22:00", End: "07:00"}},
+			time:        time.Date(2024, 3, 6, 5, 0, 0, 0, time.UTC),
+			expectQuiet: true,
+			expectedEnd: time.Date(2024, 3, 6, 7, 0, 0, 0, warsaw),
+		},
+		{
+			name:        "daylight saving time change",
+			windows:     []quiethours.Window{{Start: "01:00", End: "04:00"}},
+			time:        time.Date(2024, 3, 31, 1, 30, 0, 0, warsaw),
+			expectQuiet: true,
+			expectedEnd: time.Date(2024, 3, 31, 4, 0, 0, 0, warsaw),
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			settings := quiethours.Settings{Timezone: "Europe/Warsaw", Policy: quiethours.PolicyDefer, Windows: tC.windows}
+
+			quiet, end, err := settings.Check(tC.time)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if quiet != tC.expectQuiet {
+				t.Errorf("Expected quiet: %v, got: %v", tC.expectQuiet, quiet)
+			}
+			if !end.Equal(tC.expectedEnd) {
+				t.Errorf("Expected quiet hours to end at: %v, got: %v", tC.expectedEnd, end)
+			}
+		})
+	}
+}
+
+func TestCheckWholeDay(t *testing.T) {
+	settings := quiethours.Settings{
+		Timezone: "UTC",
+		Policy:   quiethours.PolicyDefer,
+		Windows:  []quiethours.Window{{Start: "00:00", End: "12:00"}, {Start: "12:00", End: "00:00"}},
+	}
+
+	if _, _, err := settings.Check(time.Date(2024, 3, 6, 5, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("Expected error when quiet hours cover whole day")
+	}
+}
+
+func TestAttributeValue(t *testing.T) {
+	settings := quiethours.Settings{
+		Timezone: "Europe/Warsaw",
+		Policy:   quiethours.PolicyDrop,
+		Windows:  []quiethours.Window{{Start: "22:00", End: "07:00"}, {Start: "13:00", End: "14:00"}},
+	}
+
+	result, err := quiethours.FromAttributeValue(settings.ToAttributeValue())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*result, settings) {
+		t.Errorf("Settings read: %v are different than stored: %v", *result, settings)
+	}
+}
@@ -0,0 +1,331 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minYear = 1970
+	maxYear = 2199
+)
+
+var (
+	monthNames   = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	weekdayNames = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// cron is a schedule of EventBridge Scheduler cron expression with fields:
+// minutes, hours, day-of-month, month, day-of-week (1 is Sunday) and year.
+//
+// Day-of-month supports L (last day of month), LW (last weekday of month) and nW (weekday
+// nearest to n-th day), day-of-week supports nL (last n-th weekday of month) and n#k
+// (k-th n-th weekday of month). Exactly one of day fields has to be ?, so days are restricted
+// by the other one only.
+//
+// Wall clock times skipped by DST transition don't fire and times repeated by it fire once.
+type cron struct {
+	minutes, hours, days, months, weekdays, years []bool
+	anyWeekday                                    bool
+
+	lastDay        bool // L
+	lastWorkday    bool // LW
+	nearestWorkday int  // nW
+	lastWeekday    int  // nL, 1-7
+	nthWeekday     int  // n#k, 1-7
+	nth            int
+
+	lastYear int
+	loc      *time.Location
+}
+
+// ParseCron parses six fields of cron expression, e.g. "0 10 ? * MON-FRI *". Seven field
+// expressions starting with seconds (Quartz format) are accepted as long as seconds are 0
+func ParseCron(expression string, loc *time.Location) (Schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) == 7 {
+		if fields[0] != "0" {
+			return nil, fmt.Errorf("invalid cron expression %q: seconds must be 0", expression)
+		}
+		fields = fields[1:]
+	}
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 6 fields, got %d", expression, len(fields))
+	}
+	if (fields[2] == "?") == (fields[4] == "?") {
+		return nil, fmt.Errorf("invalid cron expression %q: exactly one of day-of-month and day-of-week must be ?", expression)
+	}
+
+	c := &cron{loc: loc}
+	var err error
+	if c.minutes, _, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: minutes: %w", expression, err)
+	}
+	if c.hours, _, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: hours: %w", expression, err)
+	}
+	if err = c.parseDays(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: day-of-month: %w", expression, err)
+	}
+	if c.months, _, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: month: %w", expression, err)
+	}
+	if err = c.parseWeekdays(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: day-of-week: %w", expression, err)
+	}
+	if c.years, _, err = parseField(fields[5], minYear, maxYear, nil); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: year: %w", expression, err)
+	}
+	for year := maxYear; year >= minYear; year-- {
+		if c.years[year] {
+			c.lastYear = year
+			break
+		}
+	}
+
+	return c, nil
+}
+
+func (c *cron) parseDays(field string) error {
+	switch {
+	case field == "L":
+		c.lastDay = true
+		return nil
+	case field == "LW":
+		c.lastWorkday = true
+		return nil
+	case strings.HasSuffix(field, "W"):
+		day, err := strconv.Atoi(strings.TrimSuffix(field, "W"))
+		if err != nil || day < 1 || day > 31 {
+			return fmt.Errorf("invalid value: %q", field)
+		}
+		c.nearestWorkday = day
+		return nil
+	}
+
+	var err error
+	c.days, _, err = parseField(field, 1, 31, nil)
+	return err
+}
+
+func (c *cron) parseWeekdays(field string) error {
+	switch {
+	case strings.HasSuffix(field, "L") && len(field) > 1:
+		weekday, err := parseValue(strings.TrimSuffix(field, "L"), 1, 7, weekdayNames)
+		if err != nil {
+			return err
+		}
+		c.lastWeekday = weekday
+		return nil
+	case strings.Contains(field, "#"):
+		weekday, nth, _ := strings.Cut(field, "#")
+		var err error
+		if c.nthWeekday, err = parseValue(weekday, 1, 7, weekdayNames); err != nil {
+			return err
+		}
+		if c.nth, err = strconv.Atoi(nth); err != nil || c.nth < 1 || c.nth > 5 {
+			return fmt.Errorf("invalid value: %q", field)
+		}
+		return nil
+	}
+
+	var err error
+	c.weekdays, c.anyWeekday, err = parseField(field, 1, 7, weekdayNames)
+	return err
+}
+
+// parseField parses comma separated list of values, ranges and increments into a set indexed
+// by value. It also reports whether field matches any value
+func parseField(field string, min, max int, names []string) ([]bool, bool, error) {
+	set := make([]bool, max+1)
+	if field == "*" || field == "?" {
+		for i := min; i <= max; i++ {
+			set[i] = true
+		}
+		return set, true, nil
+	}
+
+	for _, item := range strings.Split(field, ",") {
+		rng, step, hasStep := strings.Cut(item, "/")
+
+		var start, end int
+		var err error
+		switch {
+		case rng == "*":
+			start, end = min, max
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			if start, err = parseValue(from, min, max, names); err != nil {
+				return nil, false, err
+			}
+			if end, err = parseValue(to, min, max, names); err != nil {
+				return nil, false, err
+			}
+			if end < start {
+				return nil, false, fmt.Errorf("invalid range: %q", rng)
+			}
+		default:
+			if start, err = parseValue(rng, min, max, names); err != nil {
+				return nil, false, err
+			}
+			end = start
+			if hasStep {
+				end = max
+			}
+		}
+
+		increment := 1
+		if hasStep {
+			if increment, err = strconv.Atoi(step); err != nil || increment < 1 {
+				return nil, false, fmt.Errorf("invalid increment: %q", step)
+			}
+		}
+		for i := start; i <= end; i += increment {
+			set[i] = true
+		}
+	}
+	return set, false, nil
+}
+
+func parseValue(value string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(value, name) {
+			return min + i, nil
+		}
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		return 0, fmt.Errorf("invalid value: %q", value)
+	}
+	return number, nil
+}
+
+func (c *cron) Next(t time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	c.walk(t, func(fire time.Time) bool {
+		if fire.After(t) {
+			next, found = fire, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// walk calls fn with fire times in ascending order, starting with the day from belongs to,
+// until fn returns false or schedule won't fire anymore
+func (c *cron) walk(from time.Time, fn func(fire time.Time) bool) {
+	local := from.In(c.loc)
+	// days are iterated in UTC so that DST transitions don't affect date arithmetic
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	for day.Year() <= c.lastYear {
+		year, month, dayOfMonth := day.Date()
+		if year < minYear || !c.years[year] {
+			day = time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.months[month] {
+			day = time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if c.matchDay(day) {
+			for hour := range c.hours {
+				if !c.hours[hour] {
+					continue
+				}
+				for minute := range c.minutes {
+					if !c.minutes[minute] {
+						continue
+					}
+					if fire, ok := c.fireTime(year, month, dayOfMonth, hour, minute); ok && !fn(fire) {
+						return
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+}
+
+// fireTime converts wall clock time into an instant, reporting false for times skipped by DST
+// transition. Wall clock times occurring twice resolve to the earlier instant
+func (c *cron) fireTime(year int, month time.Month, day, hour, minute int) (time.Time, bool) {
+	fire := time.Date(year, month, day, hour, minute, 0, 0, c.loc)
+	if fire.Day() != day || fire.Hour() != hour || fire.Minute() != minute {
+		return time.Time{}, false
+	}
+	if earlier := fire.Add(-time.Hour); earlier.Day() == day && earlier.Hour() == hour && earlier.Minute() == minute {
+		return earlier, true
+	}
+	return fire, true
+}
+
+func (c *cron) matchDay(day time.Time) bool {
+	// One of day fields is always ?, so when day-of-week matches any day it's day-of-month that restricts days
+	if c.anyWeekday {
+		return c.matchDayOfMonth(day)
+	}
+	return c.matchDayOfWeek(day)
+}
+
+func (c *cron) matchDayOfMonth(day time.Time) bool {
+	last := daysIn(day)
+	switch {
+	case c.lastDay:
+		return day.Day() == last
+	case c.lastWorkday:
+		lastWorkday := time.Date(day.Year(), day.Month(), last, 0, 0, 0, 0, time.UTC)
+		for isWeekend(lastWorkday) {
+			lastWorkday = lastWorkday.AddDate(0, 0, -1)
+		}
+		return day.Day() == lastWorkday.Day()
+	case c.nearestWorkday > 0:
+		if c.nearestWorkday > last {
+			return false
+		}
+		target := time.Date(day.Year(), day.Month(), c.nearestWorkday, 0, 0, 0, 0, time.UTC)
+		switch {
+		case target.Weekday() == time.Saturday && target.Day() == 1:
+			target = target.AddDate(0, 0, 2)
+		case target.Weekday() == time.Saturday:
+			target = target.AddDate(0, 0, -1)
+		case target.Weekday() == time.Sunday && target.Day() == last:
+			target = target.AddDate(0, 0, -2)
+		case target.Weekday() == time.Sunday:
+			target = target.AddDate(0, 0, 1)
+		}
+		return day.Day() == target.Day()
+	case c.days != nil:
+		return c.days[day.Day()]
+	default:
+		return false
+	}
+}
+
+func (c *cron) matchDayOfWeek(day time.Time) bool {
+	// cron weekdays start with Sunday as 1
+	weekday := int(day.Weekday()) + 1
+	switch {
+	case c.lastWeekday > 0:
+		return weekday == c.lastWeekday && day.Day()+7 > daysIn(day)
+	case c.nthWeekday > 0:
+		return weekday == c.nthWeekday && (day.Day()-1)/7+1 == c.nth
+	case c.weekdays != nil:
+		return c.weekdays[weekday]
+	default:
+		return false
+	}
+}
+
+func daysIn(day time.Time) int {
+	return time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func isWeekend(day time.Time) bool {
+	return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+}
@@ -0,0 +1,84 @@
+package schedule
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventBridge Scheduler doesn't support tags on schedules, so IDs of a user and an event a
+// schedule belongs to are carried in its name instead: "<userID>.<eventID>.<suffix>". This
+// lets all schedules of a user or of an event be listed in a schedule group by name prefix.
+// UUIDs are shortened to 22 characters so that names fit in the limit of 64 characters
+const nameSeparator = "."
+
+// deferredPrefix starts suffixes of schedules of alarms deferred due to quiet hours
+const deferredPrefix = "q"
+
+// Name returns a name of a schedule of given event, suffix distinguishes schedules of the same event
+func Name(userID, eventID, suffix string) string {
+	return EventPrefix(userID, eventID) + suffix
+}
+
+// DeferredName returns a name of a schedule of an alarm that was set on fireTime and deferred
+// due to quiet hours. Alarm that was already deferred to deferredTo and falls into quiet hours
+// again is named after both times, deferredTo is zero for alarms deferred for the first time.
+// Name is the same for retries of deferring
+func DeferredName(userID, eventID string, fireTime, deferredTo time.Time) string {
+	suffix := deferredPrefix + strconv.FormatInt(fireTime.Unix(), 36)
+	if !deferredTo.IsZero() {
+		suffix += "-" + strconv.FormatInt(deferredTo.Unix(), 36)
+	}
+	return Name(userID, eventID, suffix)
+}
+
+// IsDeferred reports whether a schedule name was returned by DeferredName. Such schedules aren't
+// saved with events
+func IsDeferred(name string) bool {
+	parts := strings.SplitN(name, nameSeparator, 3)
+	return len(parts) == 3 && strings.HasPrefix(parts[2], deferredPrefix)
+}
+
+// UserPrefix returns a prefix names of all schedules of a user start with
+func UserPrefix(userID string) string {
+	return shorten(userID) + nameSeparator
+}
+
+// EventPrefix returns a prefix names of all schedules of an event start with
+func EventPrefix(userID, eventID string) string {
+	return UserPrefix(userID) + shorten(eventID) + nameSeparator
+}
+
+// ParseName returns IDs of a user and an event given schedule belongs to. It reports false for
+// names not created with Name, e.g. of schedules created before names carried IDs
+func ParseName(name string) (string, string, bool) {
+	parts := strings.SplitN(name, nameSeparator, 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return expand(parts[0]), expand(parts[1]), true
+}
+
+// shorten encodes UUID in URL-safe base64, other IDs are returned unchanged
+func shorten(id string) string {
+	if len(id) != 36 || strings.Count(id, "-") != 4 {
+		return id
+	}
+	raw, err := hex.DecodeString(strings.ReplaceAll(id, "-", ""))
+	if err != nil {
+		return id
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// expand reverses shorten
+func expand(id string) string {
+	raw, err := base64.RawURLEncoding.DecodeString(id)
+	if len(id) != 22 || err != nil {
+		return id
+	}
+	encoded := hex.EncodeToString(raw)
+	return encoded[:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:]
+}
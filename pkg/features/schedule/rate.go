@@ -0,0 +1,55 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var rateUnits = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// rate is a schedule of EventBridge Scheduler rate expression. It fires at fixed intervals
+// measured in elapsed time, so it isn't affected by timezones or DST transitions
+type rate struct {
+	start    time.Time
+	interval time.Duration
+}
+
+// ParseRate parses rate expression in "value unit" format, e.g. "5 minutes", where unit is
+// minute, hour or day, singular for value of 1 and plural otherwise. Schedule fires at start
+// and every interval after it
+func ParseRate(expression string, start time.Time) (Schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid rate expression %q: expected value and unit", expression)
+	}
+	value, err := strconv.Atoi(fields[0])
+	if err != nil || value < 1 {
+		return nil, fmt.Errorf("invalid rate expression %q: value must be a positive integer", expression)
+	}
+
+	unit := fields[1]
+	if value > 1 {
+		if !strings.HasSuffix(unit, "s") {
+			return nil, fmt.Errorf("invalid rate expression %q: unit must be plural", expression)
+		}
+		unit = strings.TrimSuffix(unit, "s")
+	}
+	duration, ok := rateUnits[unit]
+	if !ok {
+		return nil, fmt.Errorf("invalid rate expression %q: unknown unit %q", expression, fields[1])
+	}
+	return rate{start: start, interval: time.Duration(value) * duration}, nil
+}
+
+func (r rate) Next(t time.Time) (time.Time, bool) {
+	if t.Before(r.start) {
+		return r.start, true
+	}
+	return r.start.Add((t.Sub(r.start)/r.interval + 1) * r.interval), true
+}
@@ -0,0 +1,80 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule computes fire times of EventBridge Scheduler expressions
+type Schedule interface {
+	// Next returns the first fire time after t, or false when schedule won't fire anymore
+	Next(t time.Time) (time.Time, bool)
+}
+
+// walker is implemented by schedules that can list their fire times faster than by calling Next repeatedly
+type walker interface {
+	walk(from time.Time, fn func(fire time.Time) bool)
+}
+
+// Layouts accepted in at() expressions, seconds are optional
+var atLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// Parse parses schedule expression in "at(...)" or "cron(...)" format, evaluated in given location
+func Parse(expression string, loc *time.Location) (Schedule, error) {
+	switch {
+	case strings.HasPrefix(expression, "at(") && strings.HasSuffix(expression, ")"):
+		return ParseAt(expression[3:len(expression)-1], loc)
+	case strings.HasPrefix(expression, "cron(") && strings.HasSuffix(expression, ")"):
+		return ParseCron(expression[5:len(expression)-1], loc)
+	default:
+		return nil, fmt.Errorf("invalid schedule expression: %q", expression)
+	}
+}
+
+type at struct {
+	fire time.Time
+}
+
+// ParseAt parses a date of one-time schedule given in "yyyy-mm-ddThh:mm:ss" format
+func ParseAt(date string, loc *time.Location) (Schedule, error) {
+	for _, layout := range atLayouts {
+		if fire, err := time.ParseInLocation(layout, date, loc); err == nil {
+			return at{fire: fire}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date: %q", date)
+}
+
+func (a at) Next(t time.Time) (time.Time, bool) {
+	if a.fire.After(t) {
+		return a.fire, true
+	}
+	return time.Time{}, false
+}
+
+// Count returns the number of times schedule fires in [from, to) time range, counting
+// stops at limit
+func Count(s Schedule, from, to time.Time, limit int) int {
+	count := 0
+	if w, ok := s.(walker); ok {
+		w.walk(from, func(fire time.Time) bool {
+			if fire.Before(from) {
+				return true
+			}
+			if !fire.Before(to) {
+				return false
+			}
+			count++
+			return count < limit
+		})
+		return count
+	}
+
+	fire, ok := s.Next(from.Add(-time.Nanosecond))
+	for ok && fire.Before(to) && count < limit {
+		count++
+		fire, ok = s.Next(fire)
+	}
+	return count
+}
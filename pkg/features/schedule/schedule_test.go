@@ -0,0 +1,313 @@
+package schedule_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		returnErr  bool
+	}{
+		{name: "at", expression: "at(2024-03-05T10:00:00)"},
+		{name: "at without seconds", expression: "at(2024-03-05T10:00)"},
+		{name: "cron", expression: "cron(0 10 ? * MON-FRI *)"},
+		{name: "cron with names and steps", expression: "cron(*/15 8-16 ? JAN,MAR-MAY 2-6 2024-2026)"},
+		{name: "quartz cron", expression: "cron(0 0 10 1/1 * ? *)"},
+		{name: "quartz cron with seconds", expression: "cron(30 0 10 1/1 * ? *)", returnErr: true},
+		{name: "cron last day", expression: "cron(0 10 L * ? *)"},
+		{name: "cron last workday", expression: "cron(0 10 LW * ? *)"},
+		{name: "cron nearest workday", expression: "cron(0 10 15W * ? *)"},
+		{name: "cron last friday", expression: "cron(0 10 ? * 6L *)"},
+		{name: "cron nth weekday", expression: "cron(0 10 ? * MON#2 *)"},
+		{name: "unknown type", expression: "every(5 minutes)", returnErr: true},
+		{name: "invalid date", expression: "at(2024-13-05T10:00:00)", returnErr: true},
+		{name: "missing cron field", expression: "cron(0 10 * * ?)", returnErr: true},
+		{name: "invalid minute", expression: "cron(60 10 * * ? *)", returnErr: true},
+		{name: "invalid range", expression: "cron(0 10-8 * * ? *)", returnErr: true},
+		{name: "invalid step", expression: "cron(*/0 10 * * ? *)", returnErr: true},
+		{name: "invalid weekday", expression: "cron(0 10 ? * FUN *)", returnErr: true},
+		{name: "invalid nth weekday", expression: "cron(0 10 ? * MON#6 *)", returnErr: true},
+		{name: "both day fields any", expression: "cron(0 10 ? * ? *)", returnErr: true},
+		{name: "both day fields restricted", expression: "cron(0 10 15 * MON *)", returnErr: true},
+		{name: "both day fields all", expression: "cron(0 10 * * * *)", returnErr: true},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			_, err := schedule.Parse(tC.expression, time.UTC)
+			if tC.returnErr != (err != nil) {
+				t.Errorf("Unexpected parsing result: %v", err)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+
+	testCases := []struct {
+		name       string
+		expression string
+		from       time.Time
+		expected   []time.Time
+	}{
+		{
+			name:       "at",
+			expression: "at(2024-03-05T10:00:00)",
+			from:       time.Date(2024, 3, 1, 0, 0, 0, 0, warsaw),
+			expected:   []time.Time{time.Date(2024, 3, 5, 10, 0, 0, 0, warsaw)},
+		},
+		{
+			name:       "at in the past",
+			expression: "at(2024-03-05T10:00:00)",
+			from:       time.Date(2024, 3, 6, 0, 0, 0, 0, warsaw),
+		},
+		{
+			name:       "workdays",
+			expression: "cron(30 9 ? * MON-FRI *)",
+			from:       time.Date(2024, 3, 8, 10, 0, 0, 0, warsaw),
+			expected: []time.Time{
+				time.Date(2024, 3, 11, 9, 30, 0, 0, warsaw),
+				time.Date(2024, 3, 12, 9, 30, 0, 0, warsaw),
+			},
+		},
+		{
+			name:       "last day of month",
+			expression: "cron(0 12 L * ? *)",
+			from:       time.Date(2024, 1, 31, 13, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "last workday of month",
+			expression: "cron(0 12 LW * ? *)",
+			from:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 30, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "nearest workday",
+			expression: "cron(0 12 1W * ? *)",
+			from:       time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				// June 1st 2024 is Saturday, so the nearest workday in June is Monday 3rd
+				time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC),
+				time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "last friday of month",
+			expression: "cron(0 12 ? * 6L *)",
+			from:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 26, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "second monday of month",
+			expression: "cron(0 12 ? * MON#2 *)",
+			from:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 8, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "limited years",
+			expression: "cron(0 12 1 1 ? 2024-2025)",
+			from:       time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "nonexistent date",
+			expression: "cron(0 12 30 2 ? *)",
+			from:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "skipped by DST",
+			expression: "cron(30 2 * * ? *)",
+			from:       time.Date(2024, 3, 30, 12, 0, 0, 0, warsaw),
+			expected: []time.Time{
+				time.Date(2024, 4, 1, 2, 30, 0, 0, warsaw),
+				time.Date(2024, 4, 2, 2, 30, 0, 0, warsaw),
+			},
+		},
+		{
+			name:       "repeated by DST",
+			expression: "cron(30 2 * * ? *)",
+			from:       time.Date(2024, 10, 26, 12, 0, 0, 0, warsaw),
+			expected: []time.Time{
+				// 02:30 CEST, the first of two 02:30s
+				time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC),
+				time.Date(2024, 10, 28, 2, 30, 0, 0, warsaw),
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			s, err := schedule.Parse(tC.expression, tC.from.Location())
+			if err != nil {
+				t.Fatalf("Error when parsing expression: %v", err)
+			}
+
+			from := tC.from
+			for _, expected := range tC.expected {
+				next, ok := s.Next(from)
+				if !ok || !next.Equal(expected) {
+					t.Fatalf("Received result: %v (%v) is different than expected one: %v", next, ok, expected)
+				}
+				from = next
+			}
+			if len(tC.expected) < 2 {
+				if next, ok := s.Next(from); ok {
+					t.Errorf("Unexpected fire time: %v", next)
+				}
+			}
+		})
+	}
+}
+
+func TestRate(t *testing.T) {
+	start := time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		expression string
+		from       time.Time
+		returnErr  bool
+		expected   []time.Time
+	}{
+		{
+			name:       "before start",
+			expression: "5 minutes",
+			from:       start.Add(-time.Hour),
+			expected:   []time.Time{start, start.Add(5 * time.Minute)},
+		},
+		{
+			name:       "between fires",
+			expression: "1 day",
+			from:       start.Add(36 * time.Hour),
+			expected:   []time.Time{start.Add(48 * time.Hour), start.Add(72 * time.Hour)},
+		},
+		{
+			// DST starts in Europe at 01:00 UTC, rates keep firing every 60 minutes of elapsed time
+			name:       "across DST",
+			expression: "1 hour",
+			from:       start,
+			expected: []time.Time{
+				time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC),
+			},
+		},
+		{name: "plural for one", expression: "1 minutes", returnErr: true},
+		{name: "singular for many", expression: "5 minute", returnErr: true},
+		{name: "zero", expression: "0 hours", returnErr: true},
+		{name: "unknown unit", expression: "2 weeks", returnErr: true},
+		{name: "missing unit", expression: "5", returnErr: true},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			s, err := schedule.ParseRate(tC.expression, start)
+			if tC.returnErr != (err != nil) {
+				t.Fatalf("Unexpected parsing result: %v", err)
+			}
+			if err != nil {
+				return
+			}
+
+			from := tC.from
+			for _, expected := range tC.expected {
+				next, ok := s.Next(from)
+				if !ok || !next.Equal(expected) {
+					t.Fatalf("Received result: %v (%v) is different than expected one: %v", next, ok, expected)
+				}
+				from = next
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, warsaw)
+	to := from.AddDate(0, 1, 0)
+
+	testCases := []struct {
+		name       string
+		expression string
+		limit      int
+		expected   int
+	}{
+		{name: "every minute", expression: "cron(* * * * ? *)", limit: 100000, expected: 31*24*60 - 60},
+		{name: "limited", expression: "cron(* * * * ? *)", limit: 500, expected: 500},
+		{name: "workdays", expression: "cron(0 9 ? * MON-FRI *)", limit: 100, expected: 21},
+		{name: "at", expression: "at(2024-03-05T10:00:00)", limit: 100, expected: 1},
+		{name: "at outside of range", expression: "at(2024-04-05T10:00:00)", limit: 100, expected: 0},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			s, err := schedule.Parse(tC.expression, warsaw)
+			if err != nil {
+				t.Fatalf("Error when parsing expression: %v", err)
+			}
+			if count := schedule.Count(s, from, to, tC.limit); count != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", count, tC.expected)
+			}
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	userID, eventID := "4f1c3a52-0a8d-4e4b-9b1e-2d6c1f0e7a31", "b7e2d0c4-5f3a-4c8e-a1d9-6e0f2b3c4d5e"
+
+	name := schedule.Name(userID, eventID, "12")
+	if len(name) > 64 {
+		t.Errorf("Name %v is longer than 64 characters", name)
+	}
+	if !strings.HasPrefix(name, schedule.EventPrefix(userID, eventID)) || !strings.HasPrefix(name, schedule.UserPrefix(userID)) {
+		t.Errorf("Name %v doesn't start with user and event prefixes", name)
+	}
+	if strings.HasPrefix(schedule.Name("other", eventID, "12"), schedule.UserPrefix(userID)) {
+		t.Error("Name of other user's schedule starts with user prefix")
+	}
+
+	parsedUserID, parsedEventID, ok := schedule.ParseName(name)
+	if !ok || parsedUserID != userID || parsedEventID != eventID {
+		t.Errorf("Received result: %v, %v (%v) is different than expected one: %v, %v", parsedUserID, parsedEventID, ok, userID, eventID)
+	}
+	if _, _, ok := schedule.ParseName(eventID); ok {
+		t.Error("Legacy name was parsed")
+	}
+
+	fireTime := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	deferred := schedule.DeferredName(userID, eventID, fireTime, time.Time{})
+	deferredAgain := schedule.DeferredName(userID, eventID, fireTime, fireTime.Add(12*time.Hour))
+	for _, name := range []string{deferred, deferredAgain} {
+		if len(name) > 64 || !strings.HasPrefix(name, schedule.EventPrefix(userID, eventID)) {
+			t.Errorf("Invalid name of deferred alarm: %v", name)
+		}
+	}
+	if deferred == deferredAgain {
+		t.Errorf("Alarm deferred again takes the same name: %v", deferred)
+	}
+	if !schedule.IsDeferred(deferred) || !schedule.IsDeferred(deferredAgain) || schedule.IsDeferred(name) {
+		t.Error("Deferred alarms aren't recognized by name")
+	}
+}
@@ -0,0 +1,123 @@
+package sms
+
+import "strings"
+
+type Encoding string
+
+const (
+	// GSM7 packs characters of GSM 03.38 alphabet into 7 bits
+	GSM7 Encoding = "GSM-7"
+	// UCS2 is used whenever message contains a character outside of GSM 03.38 alphabet
+	UCS2 Encoding = "UCS-2"
+)
+
+// DefaultMaxSegments is a limit of segments a single message can take when it isn't configured
+const DefaultMaxSegments = 3
+
+// Number of units (septets for GSM-7, 16-bit code units for UCS-2) that fit into a single
+// message and into a segment of concatenated one, which needs room for its header
+const (
+	gsm7SingleUnits  = 160
+	gsm7SegmentUnits = 153
+	ucs2SingleUnits  = 70
+	ucs2SegmentUnits = 67
+)
+
+const (
+	gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	// characters of extension table take two septets as they're preceded by escape character
+	gsm7Extension = "\f^{}\\[~]|€"
+)
+
+// Info describes how a message is sent over SMS
+type Info struct {
+	Encoding Encoding
+	// Units is a length of a message in septets (GSM-7) or 16-bit code units (UCS-2)
+	Units    int
+	Segments int
+}
+
+// Analyze computes encoding and number of segments of a message
+func Analyze(message string) Info {
+	widths, encoding := units(message)
+
+	single, segment := gsm7SingleUnits, gsm7SegmentUnits
+	if encoding == UCS2 {
+		single, segment = ucs2SingleUnits, ucs2SegmentUnits
+	}
+
+	info := Info{Encoding: encoding}
+	for _, w := range widths {
+		info.Units += w
+	}
+	if info.Units == 0 {
+		return info
+	}
+	if info.Units <= single {
+		info.Segments = 1
+		return info
+	}
+
+	// Characters taking two units (escaped GSM-7 characters and UCS-2 surrogate pairs)
+	// cannot be split between segments
+	info.Segments = 1
+	used := 0
+	for _, w := range widths {
+		if used+w > segment {
+			info.Segments++
+			used = 0
+		}
+		used += w
+	}
+	return info
+}
+
+// Truncate cuts message so it fits into maxSegments segments, marking the cut with "..."
+func Truncate(message string, maxSegments int) string {
+	if Analyze(message).Segments <= maxSegments {
+		return message
+	}
+
+	// Segments of a prefix never decrease with its length, so we can search for the longest one that fits
+	runes := []rune(message)
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if Analyze(string(runes[:mid])+"...").Segments <= maxSegments {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return string(runes[:low]) + "..."
+}
+
+// units returns number of units every character of a message takes in its encoding
+func units(message string) ([]int, Encoding) {
+	widths := make([]int, 0, len(message))
+	for _, r := range message {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			widths = append(widths, 1)
+		case strings.ContainsRune(gsm7Extension, r):
+			widths = append(widths, 2)
+		default:
+			return ucs2Units(message), UCS2
+		}
+	}
+	return widths, GSM7
+}
+
+func ucs2Units(message string) []int {
+	widths := make([]int, 0, len(message))
+	for _, r := range message {
+		// characters outside of Basic Multilingual Plane (e.g. emoji) are encoded with surrogate pairs
+		if r > 0xFFFF {
+			widths = append(widths, 2)
+		} else {
+			widths = append(widths, 1)
+		}
+	}
+	return widths
+}
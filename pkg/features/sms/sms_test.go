@@ -0,0 +1,122 @@
+package sms_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms"
+)
+
+func TestAnalyze(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		expected sms.Info
+	}{
+		{
+			name:     "empty",
+			message:  "",
+			expected: sms.Info{Encoding: sms.GSM7},
+		},
+		{
+			name:     "short GSM-7",
+			message:  "Stand-up in 10 min @ room B",
+			expected: sms.Info{Encoding: sms.GSM7, Units: 27, Segments: 1},
+		},
+		{
+			name:     "full GSM-7 segment",
+			message:  strings.Repeat("a", 160),
+			expected: sms.Info{Encoding: sms.GSM7, Units: 160, Segments: 1},
+		},
+		{
+			name:     "concatenated GSM-7",
+			message:  strings.Repeat("a", 161),
+			expected: sms.Info{Encoding: sms.GSM7, Units: 161, Segments: 2},
+		},
+		{
+			name:     "extension characters",
+			message:  strings.Repeat("€", 80),
+			expected: sms.Info{Encoding: sms.GSM7, Units: 160, Segments: 1},
+		},
+		{
+			name:     "escape not split between segments",
+			message:  strings.Repeat("a", 152) + "{" + strings.Repeat("a", 10),
+			expected: sms.Info{Encoding: sms.GSM7, Units: 164, Segments: 2},
+		},
+		{
+			name:     "escape moved to next segment",
+			message:  strings.Repeat("a", 152) + "{" + strings.Repeat("a", 152),
+			expected: sms.Info{Encoding: sms.GSM7, Units: 306, Segments: 3},
+		},
+		{
+			name:     "polish characters",
+			message:  "Spotkanie o 10:00 w sali żółtej",
+			expected: sms.Info{Encoding: sms.UCS2, Units: 31, Segments: 1},
+		},
+		{
+			name:     "concatenated UCS-2",
+			message:  strings.Repeat("ż", 71),
+			expected: sms.Info{Encoding: sms.UCS2, Units: 71, Segments: 2},
+		},
+		{
+			name:     "emoji",
+			message:  strings.Repeat("a", 68) + "⏰🎉",
+			expected: sms.Info{Encoding: sms.UCS2, Units: 71, Segments: 2},
+		},
+		{
+			name:     "surrogate pair not split between segments",
+			message:  strings.Repeat("a", 66) + "🎉" + strings.Repeat("a", 10),
+			expected: sms.Info{Encoding: sms.UCS2, Units: 78, Segments: 2},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			if res := sms.Analyze(tC.message); res != tC.expected {
+				t.Errorf("Received result: %+v is different than expected one: %+v", res, tC.expected)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		message     string
+		maxSegments int
+		expected    string
+	}{
+		{
+			name:        "fits",
+			message:     strings.Repeat("a", 161),
+			maxSegments: 2,
+			expected:    strings.Repeat("a", 161),
+		},
+		{
+			name:        "GSM-7",
+			message:     strings.Repeat("a", 161),
+			maxSegments: 1,
+			expected:    strings.Repeat("a", 157) + "...",
+		},
+		{
+			name:        "UCS-2",
+			message:     strings.Repeat("ż", 150),
+			maxSegments: 2,
+			expected:    strings.Repeat("ż", 131) + "...",
+		},
+		{
+			name:        "UCS-2 cut down to GSM-7",
+			message:     strings.Repeat("a", 160) + "🎉",
+			maxSegments: 1,
+			expected:    strings.Repeat("a", 157) + "...",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			if res := sms.Truncate(tC.message, tC.maxSegments); res != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", res, tC.expected)
+			}
+		})
+	}
+}
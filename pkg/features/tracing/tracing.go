@@ -0,0 +1,61 @@
+// Package tracing records work of functions in AWS X-Ray. Calls of AWS SDK clients show up as
+// subsegments of function's trace, and alarms carry ID of the trace of a request that created
+// them, so that traces of alarm executor can be linked back to it.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-xray-sdk-go/header"
+	"github.com/aws/aws-xray-sdk-go/instrumentation/awsv2"
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+// Header is a header API Gateway passes trace of a request in
+const Header = "X-Amzn-Trace-Id"
+
+// Keys of annotations traces of alarm executor can be searched by in X-Ray, e.g.
+// annotation.originTraceId = "1-5759e988-bd862e3fe1be46a994272793"
+const (
+	AnnotationOriginTraceID   = "originTraceId"
+	AnnotationOriginRequestID = "originRequestId"
+)
+
+// Instrument records calls of AWS SDK clients created from cfg in X-Ray
+func Instrument(cfg *aws.Config) {
+	awsv2.AWSV2Instrumentor(&cfg.APIOptions)
+}
+
+// TraceID returns ID of X-Ray trace of current Lambda invocation. When handler isn't invoked by
+// Lambda, ID is taken from trace header of a request, it's empty when request isn't traced either
+func TraceID(ctx context.Context, headers map[string]string) string {
+	if value, ok := ctx.Value(xray.LambdaTraceHeaderKey).(string); ok {
+		if id := header.FromString(value).TraceID; id != "" {
+			return id
+		}
+	}
+	for name, value := range headers {
+		if strings.EqualFold(name, Header) {
+			return header.FromString(value).TraceID
+		}
+	}
+	return ""
+}
+
+// Capture runs fn in a subsegment of current trace with given annotations, empty ones are
+// skipped. When there's no trace, e.g. in tests or local server, fn is just called
+func Capture(ctx context.Context, name string, annotations map[string]string, fn func(context.Context) error) error {
+	if ctx.Value(xray.LambdaTraceHeaderKey) == nil && xray.GetSegment(ctx) == nil {
+		return fn(ctx)
+	}
+	return xray.Capture(ctx, name, func(ctx context.Context) error {
+		for key, value := range annotations {
+			if value != "" {
+				_ = xray.AddAnnotation(ctx, key, value)
+			}
+		}
+		return fn(ctx)
+	})
+}
@@ -0,0 +1,55 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
+)
+
+func TestTraceID(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		ctx      context.Context
+		headers  map[string]string
+		expected string
+	}{
+		{
+			desc:     "lambda invocation",
+			ctx:      context.WithValue(context.Background(), "x-amzn-trace-id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"),
+			headers:  map[string]string{"X-Amzn-Trace-Id": "Root=1-00000000-000000000000000000000000"},
+			expected: "1-5759e988-bd862e3fe1be46a994272793",
+		},
+		{
+			desc:     "request header",
+			ctx:      context.Background(),
+			headers:  map[string]string{"x-amzn-trace-id": "Root=1-5759e988-bd862e3fe1be46a994272793"},
+			expected: "1-5759e988-bd862e3fe1be46a994272793",
+		},
+		{
+			desc:     "not traced",
+			ctx:      context.Background(),
+			headers:  map[string]string{"Content-Type": "application/json"},
+			expected: "",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			if traceID := tracing.TraceID(tC.ctx, tC.headers); traceID != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", traceID, tC.expected)
+			}
+		})
+	}
+}
+
+func TestCaptureWithoutTrace(t *testing.T) {
+	expected := errors.New("failed")
+	err := tracing.Capture(context.Background(), "Alarm", map[string]string{tracing.AnnotationOriginTraceID: "1-5759e988-bd862e3fe1be46a994272793"}, func(context.Context) error {
+		return expected
+	})
+	if err != expected {
+		t.Errorf("Received result: %v is different than expected one: %v", err, expected)
+	}
+}
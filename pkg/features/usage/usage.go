@@ -0,0 +1,13 @@
+package usage
+
+import "time"
+
+// DefaultMonthlyQuota is a number of SMS segments a user can be sent in a month when quota isn't configured
+const DefaultMonthlyQuota = 300
+
+const monthLayout = "2006-01"
+
+// Month returns a month t belongs to in "yyyy-mm" format. Months are counted in UTC
+func Month(t time.Time) string {
+	return t.UTC().Format(monthLayout)
+}
@@ -0,0 +1,20 @@
+package usage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+)
+
+func TestMonth(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+
+	// It's still February in UTC
+	if month := usage.Month(time.Date(2024, 3, 1, 0, 30, 0, 0, warsaw)); month != "2024-02" {
+		t.Errorf("Received result: %v is different than expected one: %v", month, "2024-02")
+	}
+	if month := usage.Month(time.Date(2024, 3, 1, 12, 0, 0, 0, warsaw)); month != "2024-03" {
+		t.Errorf("Received result: %v is different than expected one: %v", month, "2024-03")
+	}
+}
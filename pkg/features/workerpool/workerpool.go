@@ -0,0 +1,61 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultSize is a number of workers used when pool size isn't configured
+const DefaultSize = 10
+
+// Run calls fn for every item using at most size goroutines at once. When fn returns an error
+// context passed to other calls is cancelled, items that weren't started yet are skipped and
+// the first error is returned
+func Run[T any](ctx context.Context, size int, items []T, fn func(context.Context, T) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if size < 1 {
+		size = 1
+	}
+
+	jobs := make(chan T)
+	errChan := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(size, len(items)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := fn(ctx, item); err != nil {
+					// Only the first error is reported, the rest are results of cancellation
+					select {
+					case errChan <- err:
+						cancel()
+					default:
+					}
+				}
+			}
+		}()
+	}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- item
+	}
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errChan:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
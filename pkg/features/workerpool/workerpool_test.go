@@ -0,0 +1,89 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
+)
+
+func TestRun(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	var running, maxRunning atomic.Int32
+	var mu sync.Mutex
+	processed := make(map[int]bool)
+
+	err := workerpool.Run(context.Background(), 5, items, func(ctx context.Context, item int) error {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			peak := maxRunning.Load()
+			if current <= peak || maxRunning.CompareAndSwap(peak, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		processed[item] = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(processed) != len(items) {
+		t.Errorf("Processed %v items instead of %v", len(processed), len(items))
+	}
+	if maxRunning.Load() > 5 {
+		t.Errorf("%v workers were running at once, expected at most 5", maxRunning.Load())
+	}
+}
+
+func TestRunError(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	someErr := errors.New("some error")
+
+	var calls atomic.Int32
+	err := workerpool.Run(context.Background(), 2, items, func(ctx context.Context, item int) error {
+		calls.Add(1)
+		if item == 3 {
+			return someErr
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return nil
+	})
+	if !errors.Is(err, someErr) {
+		t.Errorf("Received error: %v is different than expected one: %v", err, someErr)
+	}
+	if calls.Load() == int32(len(items)) {
+		t.Error("Items were processed after an error occured")
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := workerpool.Run(ctx, 2, []int{1, 2, 3}, func(ctx context.Context, item int) error {
+		t.Errorf("Item %v processed with cancelled context", item)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Received error: %v is different than expected one: %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,444 @@
+package accountdeleter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	cognitotypes "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	pkgerrors "github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
+	alarmdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter"
+)
+
+// Action is the action account deletions are recorded under in the audit table
+const Action = "AccountDeletion"
+
+// Statuses of account deletion recorded in the audit table
+const (
+	StatusStarted   = "started"
+	StatusCompleted = "completed"
+)
+
+type DynamoApiClient interface {
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+type SnsApiClient interface {
+	Unsubscribe(context.Context, *sns.UnsubscribeInput, ...func(*sns.Options)) (*sns.UnsubscribeOutput, error)
+}
+
+type S3ApiClient interface {
+	ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObject(context.Context, *s3.DeleteObjectInput, ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+type CognitoApiClient interface {
+	AdminGetUser(context.Context, *cognito.AdminGetUserInput, ...func(*cognito.Options)) (*cognito.AdminGetUserOutput, error)
+	AdminDeleteUser(context.Context, *cognito.AdminDeleteUserInput, ...func(*cognito.Options)) (*cognito.AdminDeleteUserOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	AlarmsTableName      string
+	PhonesTableName      string
+	CodesTableName       string
+	SettingsTableName    string
+	DeliveriesTableName  string
+	UsageTableName       string
+	IdempotencyTableName string
+	ExportsTableName     string
+	// ExportsBucketName is a bucket exports of personal data are stored in, there are no objects
+	// to delete when it's empty
+	ExportsBucketName string
+	// AuditTableName is a table deletions are recorded in, its entries are kept after the account is gone
+	AuditTableName string
+	UserPoolID     string
+	// ScheduleGroup is a group alarms are created in, the default group is used when it's empty
+	ScheduleGroup string
+	// SchedulerConcurrency is a number of schedules deleted at once
+	SchedulerConcurrency int
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		AlarmsTableName:      env.Required("DYNAMO_TABLE_NAME"),
+		PhonesTableName:      env.Required("PHONES_TABLE_NAME"),
+		CodesTableName:       env.Required("CODES_TABLE_NAME"),
+		SettingsTableName:    env.Required("SETTINGS_TABLE_NAME"),
+		DeliveriesTableName:  env.Required("DELIVERIES_TABLE_NAME"),
+		UsageTableName:       env.Required("USAGE_TABLE_NAME"),
+		IdempotencyTableName: env.Required("IDEMPOTENCY_TABLE_NAME"),
+		ExportsTableName:     env.Required("EXPORTS_TABLE_NAME"),
+		ExportsBucketName:    env.Required("EXPORTS_BUCKET_NAME"),
+		AuditTableName:       env.Required("AUDIT_TABLE_NAME"),
+		UserPoolID:           env.Required("USER_POOL_ID"),
+		ScheduleGroup:        env.Optional("SCHEDULE_GROUP_NAME"),
+		SchedulerConcurrency: env.Int("SCHEDULER_CONCURRENCY", workerpool.DefaultSize, 1),
+		AllowedOrigins:       httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+	}
+	return config, env.Err()
+}
+
+// userTable is a table keeping data of users, partitioned by their IDs
+type userTable struct {
+	name string
+	// sortKey is empty for tables keeping a single item of a user
+	sortKey string
+}
+
+// userTables returns all tables keeping data of users
+func (c Config) userTables() []userTable {
+	return []userTable{
+		{name: c.AlarmsTableName, sortKey: "EventID"},
+		{name: c.PhonesTableName, sortKey: "Label"},
+		{name: c.CodesTableName},
+		{name: c.SettingsTableName},
+		{name: c.DeliveriesTableName, sortKey: "DeliveryID"},
+		{name: c.UsageTableName, sortKey: "Month"},
+		{name: c.IdempotencyTableName, sortKey: "Key"},
+		{name: c.ExportsTableName, sortKey: "ExportID"},
+	}
+}
+
+type Handler struct {
+	DynamoClient    DynamoApiClient
+	SchedulerClient alarmdeleter.SchedulerApiClient
+	SnsClient       SnsApiClient
+	S3Client        S3ApiClient
+	CognitoClient   CognitoApiClient
+	Config          Config
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
+}
+
+// Account is an account that is deleted
+type Account struct {
+	UserID   string
+	Username string
+}
+
+// AdminRequest is an input of deletion invoked by an admin, it identifies a user by name
+type AdminRequest struct {
+	Username string `json:"username"`
+}
+
+// Invoke handles both DELETE /me requests of API Gateway and direct invocations of admins with
+// AdminRequest. Requests coming through API Gateway always have HTTP method, so users can't
+// reach the admin flow
+func (h *Handler) Invoke(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var request events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, err
+	}
+	if request.HTTPMethod != "" {
+		return h.Handle(ctx, request)
+	}
+
+	var adminRequest AdminRequest
+	if err := json.Unmarshal(payload, &adminRequest); err != nil {
+		return nil, err
+	}
+	return nil, h.HandleAdmin(ctx, adminRequest)
+}
+
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
+
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if principal.Username == "" {
+		return pkgerrors.Unauthorized("authorization data not found")
+	}
+
+	if err := h.Delete(ctx, Account{UserID: principal.UserID, Username: principal.Username}, "user", httpx.RequestID(ctx, request)); err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+
+	return httpx.JSON(ctx, http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+// HandleAdmin deletes account of a user given by an admin
+func (h *Handler) HandleAdmin(ctx context.Context, request AdminRequest) error {
+	if request.Username == "" {
+		return errors.New("username not specified")
+	}
+
+	user, err := h.CognitoClient.AdminGetUser(ctx, &cognito.AdminGetUserInput{
+		UserPoolId: aws.String(h.Config.UserPoolID),
+		Username:   aws.String(request.Username),
+	})
+	if err != nil {
+		return fmt.Errorf("user %s couldn't be found: %w", request.Username, err)
+	}
+	account := Account{Username: request.Username, UserID: attribute(user.UserAttributes, "sub")}
+	if account.UserID == "" {
+		return fmt.Errorf("user %s has no sub", request.Username)
+	}
+
+	var requestID string
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		requestID = lc.AwsRequestID
+	}
+	return h.Delete(logging.With(ctx, logging.KeyUserID, account.UserID), account, "admin", requestID)
+}
+
+// Delete erases an account: schedules of its events, all its items in tables, subscriptions of its
+// phone numbers, its exports and finally the user in Cognito. Every step can be repeated, so a deletion that
+// failed part way is completed by running it again, for as long as the user exists. Deletion is
+// recorded in the audit table by ID of the user only, along with who requested it
+func (h *Handler) Delete(ctx context.Context, account Account, requestedBy, requestID string) error {
+	if err := h.audit(ctx, account.UserID, "SET #status = :status, RequestedBy = :requestedBy, RequestID = :requestID, StartedAt = if_not_exists(StartedAt, :now) ADD Attempts :one", map[string]dynamotypes.AttributeValue{
+		":status":      &dynamotypes.AttributeValueMemberS{Value: StatusStarted},
+		":requestedBy": &dynamotypes.AttributeValueMemberS{Value: requestedBy},
+		":requestID":   &dynamotypes.AttributeValueMemberS{Value: requestID},
+		":one":         &dynamotypes.AttributeValueMemberN{Value: "1"},
+	}); err != nil {
+		return fmt.Errorf("deletion couldn't be recorded: %w", err)
+	}
+
+	if err := h.deleteSchedules(ctx, account.UserID); err != nil {
+		return fmt.Errorf("schedules couldn't be deleted: %w", err)
+	}
+	if err := h.unsubscribe(ctx, account); err != nil {
+		return fmt.Errorf("phone numbers couldn't be unsubscribed: %w", err)
+	}
+	if err := h.deleteExports(ctx, account.UserID); err != nil {
+		return fmt.Errorf("exports couldn't be deleted: %w", err)
+	}
+	for _, table := range h.Config.userTables() {
+		if err := h.deleteItems(ctx, table, account.UserID); err != nil {
+			return fmt.Errorf("items of %s couldn't be deleted: %w", table.name, err)
+		}
+	}
+
+	var errNotFound *cognitotypes.UserNotFoundException
+	if _, err := h.CognitoClient.AdminDeleteUser(ctx, &cognito.AdminDeleteUserInput{
+		UserPoolId: aws.String(h.Config.UserPoolID),
+		Username:   aws.String(account.Username),
+	}); err != nil && !errors.As(err, &errNotFound) {
+		return fmt.Errorf("user couldn't be deleted: %w", err)
+	}
+
+	if err := h.audit(ctx, account.UserID, "SET #status = :status, CompletedAt = :now", map[string]dynamotypes.AttributeValue{
+		":status": &dynamotypes.AttributeValueMemberS{Value: StatusCompleted},
+	}); err != nil {
+		return fmt.Errorf("deletion couldn't be recorded: %w", err)
+	}
+	slog.InfoContext(ctx, "account deleted", "requestedBy", requestedBy)
+	return nil
+}
+
+// audit updates entry of account deletion in the audit table with an update expression, :now
+// value is set to current time
+func (h *Handler) audit(ctx context.Context, userID, expression string, values map[string]dynamotypes.AttributeValue) error {
+	now := time.Now()
+	if h.Now != nil {
+		now = h.Now()
+	}
+	values[":now"] = &dynamotypes.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)}
+
+	_, err := h.DynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(h.Config.AuditTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			"Action": &dynamotypes.AttributeValueMemberS{Value: Action},
+		},
+		UpdateExpression:          aws.String(expression),
+		ExpressionAttributeNames:  map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
+
+// deleteSchedules deletes all schedules of a user, both the ones in the schedule group and the ones
+// saved with events, which are also in the default group for events created before groups existed
+func (h *Handler) deleteSchedules(ctx context.Context, userID string) error {
+	deleter := &alarmdeleter.Handler{
+		SchedulerClient: h.SchedulerClient,
+		Config: alarmdeleter.Config{
+			ScheduleGroup:        h.Config.ScheduleGroup,
+			SchedulerConcurrency: h.Config.SchedulerConcurrency,
+		},
+	}
+	if err := deleter.DeleteUserSchedules(ctx, userID); err != nil {
+		return err
+	}
+
+	events, err := h.query(ctx, h.Config.AlarmsTableName, userID, "")
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, event := range events {
+		for _, alarm := range alarmschedule.FromEvent(dynamomapper.SimplifyDynamoDBItem(event)) {
+			names = append(names, alarm.Name)
+		}
+	}
+	return deleter.DeleteSchedules(ctx, names)
+}
+
+// unsubscribe removes subscriptions of all phone numbers of an account from the topic, the ones
+// that no longer exist are skipped
+func (h *Handler) unsubscribe(ctx context.Context, account Account) error {
+	phones, err := h.query(ctx, h.Config.PhonesTableName, account.UserID, "")
+	if err != nil {
+		return err
+	}
+	subscriptions := make(map[string]bool)
+
+	// Default phone numbers of users that signed up before phone labels were introduced are only
+	// stored in Cognito. It's read from the pool, as claims of a token may be outdated
+	var errNotFound *cognitotypes.UserNotFoundException
+	user, err := h.CognitoClient.AdminGetUser(ctx, &cognito.AdminGetUserInput{
+		UserPoolId: aws.String(h.Config.UserPoolID),
+		Username:   aws.String(account.Username),
+	})
+	if err != nil && !errors.As(err, &errNotFound) {
+		return err
+	}
+	if user != nil {
+		if subscriptionArn := attribute(user.UserAttributes, "custom:subscription_arn"); subscriptionArn != "" {
+			subscriptions[subscriptionArn] = true
+		}
+	}
+	for _, phone := range phones {
+		if subscription, ok := phone["SubscriptionArn"].(*dynamotypes.AttributeValueMemberS); ok && subscription.Value != "" {
+			subscriptions[subscription.Value] = true
+		}
+	}
+
+	for subscriptionArn := range subscriptions {
+		var errNotFound *snstypes.NotFoundException
+		if _, err := h.SnsClient.Unsubscribe(ctx, &sns.UnsubscribeInput{
+			SubscriptionArn: aws.String(subscriptionArn),
+		}); err != nil && !errors.As(err, &errNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExports deletes all objects under the exports prefix of a user in the exports bucket
+func (h *Handler) deleteExports(ctx context.Context, userID string) error {
+	if h.Config.ExportsBucketName == "" {
+		return nil
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(h.Config.ExportsBucketName),
+		Prefix: aws.String("exports/" + userID + "/"),
+	}
+	for {
+		res, err := h.S3Client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return err
+		}
+		if err := workerpool.Run(ctx, workerpool.DefaultSize, res.Contents, func(ctx context.Context, object s3types.Object) error {
+			_, err := h.S3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket: input.Bucket,
+				Key:    object.Key,
+			})
+			return err
+		}); err != nil {
+			return err
+		}
+
+		if !aws.ToBool(res.IsTruncated) {
+			return nil
+		}
+		input.ContinuationToken = res.NextContinuationToken
+	}
+}
+
+// deleteItems deletes all items of a user in a table
+func (h *Handler) deleteItems(ctx context.Context, table userTable, userID string) error {
+	if table.sortKey == "" {
+		_, err := h.DynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(table.name),
+			Key: map[string]dynamotypes.AttributeValue{
+				"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			},
+		})
+		return err
+	}
+
+	keys, err := h.query(ctx, table.name, userID, table.sortKey)
+	if err != nil {
+		return err
+	}
+	return workerpool.Run(ctx, workerpool.DefaultSize, keys, func(ctx context.Context, key map[string]dynamotypes.AttributeValue) error {
+		_, err := h.DynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(table.name),
+			Key:       key,
+		})
+		return err
+	})
+}
+
+// query returns all items of a user in a table. When sort key is given, only keys of items are returned
+func (h *Handler) query(ctx context.Context, tableName, userID, sortKey string) ([]map[string]dynamotypes.AttributeValue, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("UserID = :userID"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":userID": &dynamotypes.AttributeValueMemberS{Value: userID},
+		},
+	}
+	if sortKey != "" {
+		input.ProjectionExpression = aws.String("UserID, #sortKey")
+		input.ExpressionAttributeNames = map[string]string{"#sortKey": sortKey}
+	}
+
+	var items []map[string]dynamotypes.AttributeValue
+	for {
+		res, err := h.DynamoClient.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, res.Items...)
+
+		if len(res.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
+}
+
+// attribute returns value of an attribute of a Cognito user, which is empty when user doesn't have it
+func attribute(attributes []cognitotypes.AttributeType, name string) string {
+	for _, attribute := range attributes {
+		if aws.ToString(attribute.Name) == name {
+			return aws.ToString(attribute.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,426 @@
+package accountdeleter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	accountdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/account-deleter"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+)
+
+const (
+	topicArn   = "arn:aws:sns:eu-central-1:000000000000:GO_ReminderSnsTopic"
+	userPoolID = "eu-central-1_GOReminder"
+)
+
+var config = accountdeleter.Config{
+	AlarmsTableName:      "GO_AlarmTable",
+	PhonesTableName:      "GO_PhonesTable",
+	CodesTableName:       "GO_CodesTable",
+	SettingsTableName:    "GO_SettingsTable",
+	DeliveriesTableName:  "GO_DeliveriesTable",
+	UsageTableName:       "GO_UsageTable",
+	IdempotencyTableName: "GO_IdempotencyTable",
+	ExportsTableName:     "GO_ExportsTable",
+	ExportsBucketName:    "go-exports-bucket",
+	AuditTableName:       "GO_AuditTable",
+	UserPoolID:           userPoolID,
+	ScheduleGroup:        "GO_Alarms",
+	SchedulerConcurrency: 2,
+}
+
+// mockS3 keeps keys of objects in a single bucket, listing them one per page. Deletions fail when
+// fail returns an error
+type mockS3 struct {
+	keys []string
+	fail func(interface{}) error
+}
+
+func (m *mockS3) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	for _, key := range m.keys {
+		if strings.HasPrefix(key, *input.Prefix) && key > aws.ToString(input.ContinuationToken) {
+			return &s3.ListObjectsV2Output{
+				Contents:              []s3types.Object{{Key: aws.String(key)}},
+				IsTruncated:           aws.Bool(true),
+				NextContinuationToken: aws.String(key),
+			}, nil
+		}
+	}
+	return &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}, nil
+}
+
+func (m *mockS3) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	if m.fail != nil {
+		if err := m.fail(input); err != nil {
+			return nil, err
+		}
+	}
+	for i, key := range m.keys {
+		if key == *input.Key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+type backends struct {
+	dynamo    *fakes.DynamoDB
+	scheduler *fakes.Scheduler
+	sns       *fakes.SNS
+	s3        *mockS3
+	cognito   *fakes.Cognito
+}
+
+// newBackends returns backends with accounts of two users, "john" whose account is deleted and
+// "jane" whose data has to stay intact
+func newBackends(t *testing.T) *backends {
+	clock := fakes.NewVirtualClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	b := &backends{
+		dynamo:    fakes.NewDynamoDB(),
+		scheduler: fakes.NewScheduler(clock),
+		sns:       fakes.NewSNS(clock),
+		s3:        &mockS3{},
+		cognito:   fakes.NewCognito(),
+	}
+	b.dynamo.CreateTable(config.AlarmsTableName, "UserID", "EventID")
+	b.dynamo.CreateTable(config.PhonesTableName, "UserID", "Label")
+	b.dynamo.CreateTable(config.CodesTableName, "UserID", "")
+	b.dynamo.CreateTable(config.SettingsTableName, "UserID", "")
+	b.dynamo.CreateTable(config.DeliveriesTableName, "UserID", "DeliveryID")
+	b.dynamo.CreateTable(config.UsageTableName, "UserID", "Month")
+	b.dynamo.CreateTable(config.IdempotencyTableName, "UserID", "Key")
+	b.dynamo.CreateTable(config.ExportsTableName, "UserID", "ExportID")
+	b.dynamo.CreateTable(config.AuditTableName, "UserID", "Action")
+	b.scheduler.CreateGroup(config.ScheduleGroup)
+
+	for _, user := range []struct{ name, sub string }{{"john", "1"}, {"jane", "2"}} {
+		defaultSubscription := b.subscribe(t, "+4812345678"+user.sub)
+		b.cognito.AddUser(user.name, map[string]string{
+			"sub":                     user.sub,
+			"phone_number":            "+4812345678" + user.sub,
+			"custom:subscription_arn": defaultSubscription,
+		})
+
+		b.put(t, config.AlarmsTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":  &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"EventID": &dynamotypes.AttributeValueMemberS{Value: "1"},
+			"Dates": &dynamotypes.AttributeValueMemberM{Value: map[string]dynamotypes.AttributeValue{
+				schedule.Name(user.sub, "1", "0"): &dynamotypes.AttributeValueMemberS{Value: "2030-01-01T00:00:00"},
+				// created before schedule groups were introduced
+				"legacy-" + user.sub: &dynamotypes.AttributeValueMemberS{Value: "2030-01-01T00:00:00"},
+			}},
+		})
+		b.createSchedule(t, config.ScheduleGroup, schedule.Name(user.sub, "1", "0"))
+		b.createSchedule(t, "", "legacy-"+user.sub)
+		// deferred alarm, it isn't saved with the event
+		b.createSchedule(t, config.ScheduleGroup, schedule.Name(user.sub, "1", "q1"))
+
+		b.put(t, config.PhonesTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":          &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"Label":           &dynamotypes.AttributeValueMemberS{Value: "work"},
+			"PhoneNumber":     &dynamotypes.AttributeValueMemberS{Value: "+4898765432" + user.sub},
+			"SubscriptionArn": &dynamotypes.AttributeValueMemberS{Value: b.subscribe(t, "+4898765432"+user.sub)},
+		})
+		b.put(t, config.CodesTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":           &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"VerificationCode": &dynamotypes.AttributeValueMemberS{Value: "123456"},
+		})
+		b.put(t, config.SettingsTableName, map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: user.sub},
+		})
+		b.put(t, config.DeliveriesTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":     &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"DeliveryID": &dynamotypes.AttributeValueMemberS{Value: "1"},
+		})
+		b.put(t, config.UsageTableName, map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"Month":  &dynamotypes.AttributeValueMemberS{Value: "2024-05"},
+		})
+		b.put(t, config.IdempotencyTableName, map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"Key":    &dynamotypes.AttributeValueMemberS{Value: "key"},
+		})
+		for _, exportID := range []string{"1", "2"} {
+			b.put(t, config.ExportsTableName, map[string]dynamotypes.AttributeValue{
+				"UserID":    &dynamotypes.AttributeValueMemberS{Value: user.sub},
+				"ExportID":  &dynamotypes.AttributeValueMemberS{Value: exportID},
+				"ObjectKey": &dynamotypes.AttributeValueMemberS{Value: "exports/" + user.sub + "/" + exportID + ".json"},
+			})
+			b.s3.keys = append(b.s3.keys, "exports/"+user.sub+"/"+exportID+".json")
+		}
+	}
+	sort.Strings(b.s3.keys)
+	return b
+}
+
+func (b *backends) put(t *testing.T, tableName string, item map[string]dynamotypes.AttributeValue) {
+	if _, err := b.dynamo.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String(tableName), Item: item}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (b *backends) subscribe(t *testing.T, phoneNumber string) string {
+	res, err := b.sns.Subscribe(context.Background(), &sns.SubscribeInput{
+		TopicArn: aws.String(topicArn),
+		Protocol: aws.String("sms"),
+		Endpoint: aws.String(phoneNumber),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *res.SubscriptionArn
+}
+
+func (b *backends) createSchedule(t *testing.T, group, name string) {
+	input := &scheduler.CreateScheduleInput{
+		Name:               aws.String(name),
+		ScheduleExpression: aws.String("at(2030-01-01T00:00:00)"),
+		Target:             &schedulertypes.Target{Arn: aws.String("arn:aws:lambda:eu-central-1:000000000000:function:GO_AlarmExecutor")},
+	}
+	if group != "" {
+		input.GroupName = aws.String(group)
+	}
+	if _, err := b.scheduler.CreateSchedule(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (b *backends) handler() *accountdeleter.Handler {
+	return &accountdeleter.Handler{
+		DynamoClient:    b.dynamo,
+		SchedulerClient: b.scheduler,
+		SnsClient:       b.sns,
+		S3Client:        b.s3,
+		CognitoClient:   b.cognito,
+		Config:          config,
+		Now:             func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) },
+	}
+}
+
+// users returns IDs of users that have items in any table but the audit one
+func (b *backends) users(t *testing.T) map[string][]string {
+	users := make(map[string][]string)
+	for _, tableName := range []string{config.AlarmsTableName, config.PhonesTableName, config.CodesTableName, config.SettingsTableName, config.DeliveriesTableName, config.UsageTableName, config.IdempotencyTableName, config.ExportsTableName} {
+		items, err := b.dynamo.Items(tableName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			userID := item["UserID"].(*dynamotypes.AttributeValueMemberS).Value
+			users[userID] = append(users[userID], tableName)
+		}
+	}
+	return users
+}
+
+// schedules returns names of existing schedules, both in the schedule group and the default one
+func (b *backends) schedules() []string {
+	var names []string
+	for _, s := range []struct{ group, name string }{
+		{config.ScheduleGroup, schedule.Name("1", "1", "0")},
+		{config.ScheduleGroup, schedule.Name("1", "1", "q1")},
+		{"", "legacy-1"},
+		{config.ScheduleGroup, schedule.Name("2", "1", "0")},
+		{config.ScheduleGroup, schedule.Name("2", "1", "q1")},
+		{"", "legacy-2"},
+	} {
+		if _, _, ok := b.scheduler.Schedule(s.group, s.name); ok {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+func (b *backends) audit(t *testing.T, userID string) map[string]dynamotypes.AttributeValue {
+	res, err := b.dynamo.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String(config.AuditTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			"Action": &dynamotypes.AttributeValueMemberS{Value: accountdeleter.Action},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Item
+}
+
+func deleteRequest(sub, userName string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: "DELETE",
+		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: "request-1",
+			Authorizer: map[string]interface{}{
+				"claims": map[string]interface{}{
+					"sub":              sub,
+					"cognito:username": userName,
+				},
+			},
+		},
+	}
+}
+
+func TestHandle(t *testing.T) {
+	b := newBackends(t)
+	response, err := b.handler().Handle(context.Background(), deleteRequest("1", "john"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != 200 {
+		t.Fatalf("Received status code: %v is different than expected one: %v, body: %s", response.StatusCode, 200, response.Body)
+	}
+
+	users := b.users(t)
+	if _, ok := users["1"]; ok {
+		t.Errorf("Items of deleted user remained in tables: %v", users["1"])
+	}
+	if len(users["2"]) != 9 {
+		t.Errorf("Items of other user were deleted, remaining ones are in tables: %v", users["2"])
+	}
+
+	expectedSchedules := []string{schedule.Name("2", "1", "0"), schedule.Name("2", "1", "q1"), "legacy-2"}
+	if schedules := b.schedules(); !reflect.DeepEqual(schedules, expectedSchedules) {
+		t.Errorf("Received result: %v is different than expected one: %v", schedules, expectedSchedules)
+	}
+
+	expectedKeys := []string{"exports/2/1.json", "exports/2/2.json"}
+	if !reflect.DeepEqual(b.s3.keys, expectedKeys) {
+		t.Errorf("Received result: %v is different than expected one: %v", b.s3.keys, expectedKeys)
+	}
+
+	var endpoints []string
+	for _, subscription := range b.sns.Subscriptions() {
+		endpoints = append(endpoints, subscription.Endpoint)
+	}
+	sort.Strings(endpoints)
+	expectedEndpoints := []string{"+48123456782", "+48987654322"}
+	if !reflect.DeepEqual(endpoints, expectedEndpoints) {
+		t.Errorf("Received result: %v is different than expected one: %v", endpoints, expectedEndpoints)
+	}
+
+	if _, _, ok := b.cognito.User("john"); ok {
+		t.Errorf("Deleted user still exists in Cognito")
+	}
+	if _, _, ok := b.cognito.User("jane"); !ok {
+		t.Errorf("Other user was deleted from Cognito")
+	}
+
+	entry := b.audit(t, "1")
+	expectedEntry := map[string]dynamotypes.AttributeValue{
+		"UserID":      &dynamotypes.AttributeValueMemberS{Value: "1"},
+		"Action":      &dynamotypes.AttributeValueMemberS{Value: accountdeleter.Action},
+		"Status":      &dynamotypes.AttributeValueMemberS{Value: accountdeleter.StatusCompleted},
+		"RequestedBy": &dynamotypes.AttributeValueMemberS{Value: "user"},
+		"RequestID":   &dynamotypes.AttributeValueMemberS{Value: "request-1"},
+		"StartedAt":   &dynamotypes.AttributeValueMemberS{Value: "2024-05-01T12:00:00Z"},
+		"CompletedAt": &dynamotypes.AttributeValueMemberS{Value: "2024-05-01T12:00:00Z"},
+		"Attempts":    &dynamotypes.AttributeValueMemberN{Value: "1"},
+	}
+	if !reflect.DeepEqual(entry, expectedEntry) {
+		t.Errorf("Received result: %v is different than expected one: %v", entry, expectedEntry)
+	}
+}
+
+func TestHandleResumes(t *testing.T) {
+	for _, operation := range []string{"UpdateItem", "DeleteSchedule", "Unsubscribe", "DeleteObject", "Query", "DeleteItem", "AdminDeleteUser"} {
+		t.Run(operation, func(t *testing.T) {
+			b := newBackends(t)
+			request := deleteRequest("1", "john")
+
+			// operation fails the second time it's called, after some data was deleted
+			var calls int
+			fail := func(interface{}) error {
+				calls++
+				if calls == 2 {
+					return errors.New("some error")
+				}
+				return nil
+			}
+			switch operation {
+			case "DeleteSchedule":
+				b.scheduler.InjectFunc(operation, fail)
+			case "Unsubscribe":
+				b.sns.InjectFunc(operation, fail)
+			case "DeleteObject":
+				b.s3.fail = fail
+			case "AdminDeleteUser":
+				b.cognito.InjectN(operation, 1, errors.New("some error"))
+			default:
+				b.dynamo.InjectFunc(operation, fail)
+			}
+
+			response, _ := b.handler().Handle(context.Background(), request)
+			if response.StatusCode != 500 {
+				t.Fatalf("Received status code: %v is different than expected one: %v, body: %s", response.StatusCode, 500, response.Body)
+			}
+
+			response, _ = b.handler().Handle(context.Background(), request)
+			if response.StatusCode != 200 {
+				t.Fatalf("Received status code: %v is different than expected one: %v, body: %s", response.StatusCode, 200, response.Body)
+			}
+			if users := b.users(t); len(users["1"]) != 0 || len(users["2"]) != 9 {
+				t.Errorf("Received result: %v is different than expected one: %v", users, "all items of user 2 only")
+			}
+			if expectedKeys := []string{"exports/2/1.json", "exports/2/2.json"}; !reflect.DeepEqual(b.s3.keys, expectedKeys) {
+				t.Errorf("Received result: %v is different than expected one: %v", b.s3.keys, expectedKeys)
+			}
+			if _, _, ok := b.cognito.User("john"); ok {
+				t.Errorf("Deleted user still exists in Cognito")
+			}
+			if status := b.audit(t, "1")["Status"]; !reflect.DeepEqual(status, &dynamotypes.AttributeValueMemberS{Value: accountdeleter.StatusCompleted}) {
+				t.Errorf("Received result: %v is different than expected one: %v", status, accountdeleter.StatusCompleted)
+			}
+		})
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	b := newBackends(t)
+
+	if _, err := b.handler().Invoke(context.Background(), json.RawMessage(`{"username": "nobody"}`)); err == nil {
+		t.Errorf("Deletion of user that doesn't exist succeeded")
+	}
+
+	payload, _ := json.Marshal(deleteRequest("1", "john"))
+	response, err := b.handler().Invoke(context.Background(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.(events.APIGatewayProxyResponse).StatusCode != 200 {
+		t.Fatalf("Received result: %v is different than expected one: %v", response, 200)
+	}
+
+	if _, err := b.handler().Invoke(context.Background(), json.RawMessage(`{"username": "jane"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if users := b.users(t); len(users) != 0 {
+		t.Errorf("Items of deleted users remained in tables: %v", users)
+	}
+	if len(b.s3.keys) != 0 {
+		t.Errorf("Exports of deleted users remained: %v", b.s3.keys)
+	}
+	if subscriptions := b.sns.Subscriptions(); len(subscriptions) != 0 {
+		t.Errorf("Subscriptions of deleted users remained: %v", subscriptions)
+	}
+	if requestedBy := b.audit(t, "2")["RequestedBy"]; !reflect.DeepEqual(requestedBy, &dynamotypes.AttributeValueMemberS{Value: "admin"}) {
+		t.Errorf("Received result: %v is different than expected one: %v", requestedBy, "admin")
+	}
+}
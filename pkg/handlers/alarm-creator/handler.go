@@ -2,23 +2,38 @@ package alarmcreator
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/scheduler"
-	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
 	"github.com/google/uuid"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
 	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
 	pkgerrors "github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/idempotency"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/msgtemplate"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
 )
 
 type scheduleType int
@@ -41,14 +56,70 @@ func (t scheduleType) string() string {
 
 type DynamoApiClient interface {
 	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 }
 type SchedulerApiClient interface {
 	CreateSchedule(context.Context, *scheduler.CreateScheduleInput, ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error)
 }
 
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	TableName            string
+	IdempotencyTableName string
+	// PhonesTableName is a table labels of user's phone numbers are checked in
+	PhonesTableName string
+	// Target is where schedules of created events deliver alarms to
+	Target alarmschedule.Target
+	// MaxSegments is a number of SMS segments a single message can take
+	MaxSegments int
+	// MonthlyQuota is a number of SMS segments user can use in a month
+	MonthlyQuota int
+	// SchedulerConcurrency is a number of schedules created at once
+	SchedulerConcurrency int
+	Limits               Limits
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+	// Stage is a dimension of metrics handler emits
+	Stage string
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		TableName:            env.Required("DYNAMO_TABLE_NAME"),
+		IdempotencyTableName: env.Required("IDEMPOTENCY_TABLE_NAME"),
+		PhonesTableName:      env.Required("PHONES_TABLE_NAME"),
+		Target: alarmschedule.Target{
+			FunctionArn:   env.Required("LAMBDA_FUNCTION_ARN"),
+			RoleArn:       env.Required("ROLE_ARN"),
+			Group:         env.Optional("SCHEDULE_GROUP_NAME"),
+			DeadLetterArn: env.Optional("DEAD_LETTER_QUEUE_ARN"),
+		},
+		MaxSegments:          env.Int("MAX_SMS_SEGMENTS", sms.DefaultMaxSegments, 1),
+		MonthlyQuota:         env.Int("MONTHLY_SMS_QUOTA", usage.DefaultMonthlyQuota, 0),
+		SchedulerConcurrency: env.Int("SCHEDULER_CONCURRENCY", workerpool.DefaultSize, 1),
+		Limits: Limits{
+			SchedulesPerRequest: env.Int("MAX_SCHEDULES_PER_REQUEST", DefaultSchedulesPerRequest, 1),
+			CronsPerEvent:       env.Int("MAX_CRONS_PER_EVENT", DefaultCronsPerEvent, 1),
+			EventsPerUser:       env.Int("MAX_EVENTS_PER_USER", DefaultEventsPerUser, 1),
+		},
+		AllowedOrigins: httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+		Stage:          env.Optional("STAGE"),
+	}
+	return config, env.Err()
+}
+
 type Handler struct {
 	DynamoClient    DynamoApiClient
 	SchedulerClient SchedulerApiClient
+	Config          Config
+	// Metrics are written to stdout unless writer of the emitter is set
+	Metrics metrics.Emitter
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
 }
 
 type createScheduleInput struct {
@@ -56,198 +127,508 @@ type createScheduleInput struct {
 	ScheduleExpression string
 	Timezone           string
 	Message            string
+	Title              string
+	Variables          map[string]string
 	UserID             string
+	EventID            string
+	Phones             []string
+	IgnoreQuietHours   bool
 	ScheduleType       scheduleType
+	TraceID            string
+	RequestID          string
 }
 
 func (h *Handler) createSchedule(ctx context.Context, input createScheduleInput) error {
-	ruleID := uuid.NewString()
-
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	lambdaInput, err := json.Marshal(map[string]string{
-		"userID":  input.UserID,
-		"message": input.Message,
-	})
+	scheduleInput, err := alarmschedule.CreateScheduleInput(alarmschedule.Alarm{
+		Name:             input.RuleID,
+		Expression:       fmt.Sprintf("%s(%s)", input.ScheduleType.string(), input.ScheduleExpression),
+		Timezone:         input.Timezone,
+		Message:          input.Message,
+		Title:            input.Title,
+		Variables:        input.Variables,
+		UserID:           input.UserID,
+		EventID:          input.EventID,
+		Phones:           input.Phones,
+		IgnoreQuietHours: input.IgnoreQuietHours,
+		TraceID:          input.TraceID,
+		RequestID:        input.RequestID,
+	}, h.Config.Target)
 	if err != nil {
 		return err
 	}
 
-	if _, err := h.SchedulerClient.CreateSchedule(ctx, &scheduler.CreateScheduleInput{
-		ActionAfterCompletion:      schedulertypes.ActionAfterCompletionDelete,
-		Description:                &input.Message,
-		Name:                       &ruleID,
-		ScheduleExpression:         aws.String(fmt.Sprintf("%s(%s)", input.ScheduleType.string(), input.ScheduleExpression)),
-		ScheduleExpressionTimezone: &input.Timezone,
-		Target: &schedulertypes.Target{
-			Arn:     aws.String(os.Getenv("LAMBDA_FUNCTION_ARN")),
-			RoleArn: aws.String(os.Getenv("ROLE_ARN")),
-			Input:   aws.String(string(lambdaInput)),
-		},
-		FlexibleTimeWindow: &schedulertypes.FlexibleTimeWindow{
-			Mode: schedulertypes.FlexibleTimeWindowModeOff,
-		},
-	}); err != nil {
-		return err
-	}
-
-	return nil
+	_, err = h.SchedulerClient.CreateSchedule(ctx, scheduleInput)
+	return err
 }
 
+// ErrEmptyMessage is returned by Validate for a message that renders to nothing but whitespace.
+// It's syntactically valid, so it's reported as unprocessable rather than bad request
+var ErrEmptyMessage = errors.New("message renders to an empty string")
+
 type RequestBody struct {
-	Message  string   `json:"message"`
-	Timezone string   `json:"timezone"`
-	Dates    []string `json:"dates"`
-	Crons    []string `json:"crons"`
+	// Message is a template rendered every time an alarm fires, see msgtemplate package
+	Message string `json:"message"`
+	// Title is available in a message as {{title}}, it defaults to the message itself
+	Title     string            `json:"title"`
+	Variables map[string]string `json:"variables"`
+	Timezone  string            `json:"timezone"`
+	Dates     []string          `json:"dates"`
+	Crons     []string          `json:"crons"`
+	Phones    []string          `json:"phones"`
+	// IgnoreQuietHours makes alarms of an event fire even during user's quiet hours
+	IgnoreQuietHours bool `json:"ignoreQuietHours"`
 }
 
+// Validate returns the first problem of request body, marked with a field it concerns
 func (b *RequestBody) Validate() error {
 	if len(b.Crons) == 0 && len(b.Dates) == 0 {
-		return errors.New("there are no crons or dates specified")
+		return pkgerrors.Field("dates", errors.New("there are no crons or dates specified"))
 	}
 	if b.Message == "" {
-		return errors.New(`"message" cannot be an empty string`)
+		return pkgerrors.Field("message", errors.New(`"message" cannot be an empty string`))
 	}
 	if b.Timezone == "" {
-		return errors.New(`"timezone" cannot be an empty string`)
+		return pkgerrors.Field("timezone", errors.New(`"timezone" cannot be an empty string`))
+	}
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return pkgerrors.Field("timezone", fmt.Errorf("invalid timezone: %q", b.Timezone))
+	}
+	for _, date := range b.Dates {
+		if _, err := schedule.ParseAt(date, loc); err != nil {
+			return pkgerrors.Field("dates", err)
+		}
+	}
+	for _, cron := range b.Crons {
+		if _, err := schedule.ParseCron(cron, loc); err != nil {
+			return pkgerrors.Field("crons", err)
+		}
+	}
+	tmpl, err := msgtemplate.Parse(b.Message, b.Variables)
+	if err != nil {
+		return pkgerrors.Field("message", err)
+	}
+	if tmpl.Uses(msgtemplate.VarTitle) && b.Title == "" {
+		return pkgerrors.Field("title", errors.New(`"title" must be set when message uses it`))
+	}
+	// Built-in variables never render empty, so the longest rendering is empty only when every one is
+	if strings.TrimSpace(tmpl.Longest(b.Title, b.Timezone)) == "" {
+		return pkgerrors.Field("message", ErrEmptyMessage)
+	}
+	for _, label := range b.Phones {
+		if err := phonebook.ValidateLabel(label); err != nil {
+			return pkgerrors.Field("phones", err)
+		}
 	}
 	return nil
 }
 
-func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-
-	claims, ok := request.RequestContext.Authorizer["claims"].(map[string]interface{})
-	if !ok {
-		return pkgerrors.Unauthorized("authorization data not found")
+// unknownLabels returns labels user has no phone numbers saved under. Default label is always known,
+// as phone numbers of users that signed up before labels were introduced are only stored in Cognito
+func (h *Handler) unknownLabels(ctx context.Context, userID string, labels []string) ([]string, error) {
+	var unknown []string
+	for _, label := range labels {
+		if label != phonebook.DefaultLabel && !slices.Contains(unknown, label) {
+			unknown = append(unknown, label)
+		}
 	}
-	userID, ok := claims["sub"].(string)
-	if !ok {
-		return pkgerrors.Unauthorized("authorization data not found")
+	if len(unknown) == 0 {
+		return nil, nil
 	}
 
-	var reqBody RequestBody
-	if err := json.Unmarshal([]byte(request.Body), &reqBody); err != nil {
-		return pkgerrors.BadRequest("invalid request body")
-	}
-	if err := reqBody.Validate(); err != nil {
-		return pkgerrors.BadRequest(err.Error())
+	var startKey map[string]dynamotypes.AttributeValue
+	for {
+		res, err := h.DynamoClient.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(h.Config.PhonesTableName),
+			KeyConditionExpression: aws.String("#userID = :userID"),
+			ProjectionExpression:   aws.String("#label"),
+			ExpressionAttributeNames: map[string]string{
+				"#userID": "UserID",
+				"#label":  "Label",
+			},
+			ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+				":userID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range res.Items {
+			if label, ok := item["Label"].(*dynamotypes.AttributeValueMemberS); ok {
+				unknown = slices.DeleteFunc(unknown, func(l string) bool { return l == label.Value })
+			}
+		}
+
+		if len(unknown) == 0 || len(res.LastEvaluatedKey) == 0 {
+			return unknown, nil
+		}
+		startKey = res.LastEvaluatedKey
 	}
+}
 
-	cronMap := make(map[string]dynamotypes.AttributeValue)
-	dateMap := make(map[string]dynamotypes.AttributeValue)
+// EstimateSMS returns encoding and number of segments of the longest message alarms of an event
+// can send. It should be called on validated request body only
+func (b *RequestBody) EstimateSMS() sms.Info {
+	tmpl, err := msgtemplate.Parse(b.Message, b.Variables)
+	if err != nil {
+		return sms.Analyze(b.Message)
+	}
+	return sms.Analyze(tmpl.Longest(b.Title, b.Timezone))
+}
 
-	cronMutex := &sync.Mutex{}
-	dateMutex := &sync.Mutex{}
+// EstimateMonthlyFires returns the number of times alarms of an event fire within a month
+// from given time, both for crons only and for all alarms. Counting stops at limit.
+// It should be called on validated request body only
+func (b *RequestBody) EstimateMonthlyFires(from time.Time, limit int) (int, int) {
+	loc, _ := time.LoadLocation(b.Timezone)
+	to := from.AddDate(0, 1, 0)
+
+	var cronFires, dateFires int
+	for _, cron := range b.Crons {
+		s, _ := schedule.ParseCron(cron, loc)
+		cronFires += schedule.Count(s, from, to, limit)
+	}
+	for _, date := range b.Dates {
+		s, _ := schedule.ParseAt(date, loc)
+		dateFires += schedule.Count(s, from, to, limit)
+	}
+	return cronFires, cronFires + dateFires
+}
 
-	errChan := make(chan error, 1)
-	defer close(errChan)
+// Limits restrict the number of schedules and events so that a single request cannot exhaust
+// scheduler quotas or time out halfway through creating schedules
+type Limits struct {
+	// SchedulesPerRequest limits dates and crons given in a single request
+	SchedulesPerRequest int
+	// CronsPerEvent limits recurring schedules of an event, one-time ones are deleted after they fire
+	CronsPerEvent int
+	// EventsPerUser limits events a single user can have
+	EventsPerUser int
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// Default limits used when they're not configured
+const (
+	DefaultSchedulesPerRequest = 25
+	DefaultCronsPerEvent       = 10
+	DefaultEventsPerUser       = 50
+)
 
-	var wg sync.WaitGroup
+// Check returns descriptions of limits request exceeds, given the number of events user already has
+func (l Limits) Check(b *RequestBody, events int) []string {
+	var exceeded []string
+	if schedules := len(b.Dates) + len(b.Crons); schedules > l.SchedulesPerRequest {
+		exceeded = append(exceeded, fmt.Sprintf("%d schedules per request (got %d)", l.SchedulesPerRequest, schedules))
+	}
+	if len(b.Crons) > l.CronsPerEvent {
+		exceeded = append(exceeded, fmt.Sprintf("%d crons per event (got %d)", l.CronsPerEvent, len(b.Crons)))
+	}
+	if events >= l.EventsPerUser {
+		exceeded = append(exceeded, fmt.Sprintf("%d events per user (already has %d)", l.EventsPerUser, events))
+	}
+	return exceeded
+}
 
-	for _, date := range reqBody.Dates {
-		wg.Add(1)
-		go func(expr string) {
-			defer wg.Done()
-
-			ruleID := uuid.NewString()
-
-			if err := h.createSchedule(ctx, createScheduleInput{
-				RuleID:             ruleID,
-				UserID:             userID,
-				ScheduleExpression: expr,
-				ScheduleType:       AT,
-				Message:            reqBody.Message,
-				Timezone:           reqBody.Timezone,
-			}); err != nil {
-				select {
-				case errChan <- err:
-					cancel()
-				default:
+// userEvents returns the number of user's events and sum of their estimated monthly SMS segments
+func (h *Handler) userEvents(ctx context.Context, userID string) (int, int, error) {
+	var count, committed int
+	var startKey map[string]dynamotypes.AttributeValue
+	for {
+		res, err := h.DynamoClient.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(h.Config.TableName),
+			KeyConditionExpression: aws.String("#userID = :userID"),
+			ProjectionExpression:   aws.String("MonthlySegments"),
+			ExpressionAttributeNames: map[string]string{
+				"#userID": "UserID",
+			},
+			ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+				":userID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+
+		count += len(res.Items)
+		for _, item := range res.Items {
+			// Events created before quotas were introduced don't have an estimate
+			if segments, ok := item["MonthlySegments"].(*dynamotypes.AttributeValueMemberN); ok {
+				value, err := strconv.Atoi(segments.Value)
+				if err != nil {
+					return 0, 0, err
 				}
-				return
+				committed += value
 			}
-			dateMutex.Lock()
-			dateMap[ruleID] = &dynamotypes.AttributeValueMemberS{Value: expr}
-			dateMutex.Unlock()
+		}
 
-		}(date)
+		if len(res.LastEvaluatedKey) == 0 {
+			return count, committed, nil
+		}
+		startKey = res.LastEvaluatedKey
 	}
+}
 
-	for _, cron := range reqBody.Crons {
-		wg.Add(1)
-		go func(expr string) {
-			defer wg.Done()
-
-			ruleID := uuid.NewString()
-
-			if err := h.createSchedule(ctx, createScheduleInput{
-				RuleID:             ruleID,
-				UserID:             userID,
-				ScheduleExpression: expr,
-				ScheduleType:       CRON,
-				Message:            reqBody.Message,
-				Timezone:           reqBody.Timezone,
-			}); err != nil {
-				select {
-				case errChan <- err:
-					cancel()
-				default:
-				}
-				return
-			}
+func (h *Handler) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
 
-			// sending entry to dateChan for further handling
-			cronMutex.Lock()
-			cronMap[ruleID] = &dynamotypes.AttributeValueMemberS{Value: expr}
-			cronMutex.Unlock()
-		}(cron)
+// reserveKey saves idempotency key of a request so that its retries aren't processed again.
+// When key was already used it returns a response the request should get instead
+func (h *Handler) reserveKey(ctx context.Context, userID, key, fingerprint string) (*events.APIGatewayProxyResponse, error) {
+	now := h.now()
+	_, err := h.DynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(h.Config.IdempotencyTableName),
+		Item: map[string]dynamotypes.AttributeValue{
+			"UserID":      &dynamotypes.AttributeValueMemberS{Value: userID},
+			"Key":         &dynamotypes.AttributeValueMemberS{Value: key},
+			"Fingerprint": &dynamotypes.AttributeValueMemberS{Value: fingerprint},
+			"Status":      &dynamotypes.AttributeValueMemberS{Value: idempotency.StatusInProgress},
+			"ExpireOn":    &dynamotypes.AttributeValueMemberN{Value: fmt.Sprint(now.Add(idempotency.Lease).Unix())},
+		},
+		// Items are removed by TTL with a delay, so expired ones are treated as if they didn't exist
+		ConditionExpression: aws.String("attribute_not_exists(UserID) OR ExpireOn < :now"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":now": &dynamotypes.AttributeValueMemberN{Value: fmt.Sprint(now.Unix())},
+		},
+		ReturnValuesOnConditionCheckFailure: dynamotypes.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var conditionErr *dynamotypes.ConditionalCheckFailedException
+	if !errors.As(err, &conditionErr) {
+		return nil, err
 	}
 
-	// We wait for all goroutines to finish and cancel a context to
-	// end last goroutine if it wasn't cancelled before
-	wg.Wait()
+	stored := dynamomapper.SimplifyDynamoDBItem(conditionErr.Item)
+	if stored["Fingerprint"] != fingerprint {
+		response, _ := pkgerrors.Conflict("idempotency key was already used with a different request")
+		return &response, nil
+	}
+	if stored["Status"] != idempotency.StatusCompleted {
+		response, _ := pkgerrors.Conflict("request with this idempotency key is still being processed")
+		return &response, nil
+	}
 
-	select {
-	case err := <-errChan:
-		return pkgerrors.Internal(err)
-	default:
+	statusCode, err := strconv.Atoi(fmt.Sprint(stored["StatusCode"]))
+	if err != nil {
+		return nil, err
 	}
+	body, _ := stored["Body"].(string)
+	return &events.APIGatewayProxyResponse{
+		Body:       body,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		StatusCode: statusCode,
+	}, nil
+}
 
-	item := map[string]dynamotypes.AttributeValue{
-		"EventID":  &dynamotypes.AttributeValueMemberS{Value: uuid.NewString()},
-		"UserID":   &dynamotypes.AttributeValueMemberS{Value: userID},
-		"Title":    &dynamotypes.AttributeValueMemberS{Value: reqBody.Message},
-		"Crons":    &dynamotypes.AttributeValueMemberM{Value: cronMap},
-		"Dates":    &dynamotypes.AttributeValueMemberM{Value: dateMap},
-		"Timezone": &dynamotypes.AttributeValueMemberS{Value: reqBody.Timezone},
+// saveResponse stores response under idempotency key so that it's returned to retries. Keys of
+// requests that failed on server side are released instead, so that they can be retried
+func (h *Handler) saveResponse(ctx context.Context, userID, key string, response events.APIGatewayProxyResponse) error {
+	itemKey := map[string]dynamotypes.AttributeValue{
+		"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+		"Key":    &dynamotypes.AttributeValueMemberS{Value: key},
+	}
+	if response.StatusCode >= http.StatusInternalServerError {
+		_, err := h.DynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(h.Config.IdempotencyTableName),
+			Key:       itemKey,
+		})
+		return err
 	}
 
-	if _, err := h.DynamoClient.PutItem(context.Background(), &dynamodb.PutItemInput{
-		TableName: aws.String(os.Getenv("DYNAMO_TABLE_NAME")),
-		Item:      item,
-	}); err != nil {
-		return pkgerrors.Internal(err)
+	_, err := h.DynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(h.Config.IdempotencyTableName),
+		Key:              itemKey,
+		UpdateExpression: aws.String("SET #status = :status, StatusCode = :statusCode, #body = :body, ExpireOn = :expireOn"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "Status",
+			"#body":   "Body",
+		},
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":status":     &dynamotypes.AttributeValueMemberS{Value: idempotency.StatusCompleted},
+			":statusCode": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(response.StatusCode)},
+			":body":       &dynamotypes.AttributeValueMemberS{Value: response.Body},
+			":expireOn":   &dynamotypes.AttributeValueMemberN{Value: fmt.Sprint(h.now().Add(idempotency.TTL).Unix())},
+		},
+	})
+	return err
+}
+
+// Handle creates an event. Requests with Idempotency-Key header are processed once, their
+// retries get the original response, or conflict when they come with a different body
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
+
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	key, err := idempotency.Key(request.Headers)
+	if err != nil {
+		return pkgerrors.BadRequest(err.Error())
+	}
+	if key == "" {
+		return h.create(ctx, principal.UserID, request)
 	}
 
-	responseJSON, err := json.Marshal(dynamomapper.SimplifyDynamoDBItem(item))
+	fingerprint := idempotency.Fingerprint(request.Body)
+	previous, err := h.reserveKey(ctx, principal.UserID, key, fingerprint)
 	if err != nil {
-		return pkgerrors.Internal(err)
+		return pkgerrors.Internal(ctx, err)
+	}
+	if previous != nil {
+		return *previous, nil
 	}
 
-	return events.APIGatewayProxyResponse{
-		Body: string(responseJSON),
-		Headers: map[string]string{
-			"Content-Type":                     "application/json",
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Headers":     "Content-Type",
-			"Access-Control-Allow-Methods":     "OPTIONS, GET, POST, DELETE",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		StatusCode: http.StatusCreated,
-	}, nil
+	response, err := h.create(ctx, principal.UserID, request)
+	if err != nil {
+		return response, err
+	}
+	if err := h.saveResponse(ctx, principal.UserID, key, response); err != nil {
+		// Event is already created, so the response is returned anyway. Retries will get
+		// conflict until the key's lease expires
+		slog.WarnContext(ctx, "response of idempotent request couldn't be saved", "error", err)
+	}
+	return response, nil
+}
+
+// create validates request body and creates an event with all its schedules
+func (h *Handler) create(ctx context.Context, userID string, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var reqBody RequestBody
+	if err := httpx.DecodeJSON(request, &reqBody); err != nil {
+		return httpx.InvalidBody(err)
+	}
+	if err := reqBody.Validate(); errors.Is(err, ErrEmptyMessage) {
+		return pkgerrors.Unprocessable(err)
+	} else if err != nil {
+		return pkgerrors.Invalid(err)
+	}
+	estimate := reqBody.EstimateSMS()
+	if estimate.Segments > h.Config.MaxSegments {
+		return pkgerrors.Invalid(pkgerrors.Field("message", fmt.Errorf("message can take at most %d SMS segments, but it may take %d (%s)", h.Config.MaxSegments, estimate.Segments, estimate.Encoding)))
+	}
+	phones := phonebook.Labels(reqBody.Phones)
+	unknown, err := h.unknownLabels(ctx, userID, phones)
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	if len(unknown) > 0 {
+		return pkgerrors.Unprocessable(pkgerrors.Field("phones", fmt.Errorf("user has no phone numbers labeled: %s", strings.Join(unknown, ", "))))
+	}
+
+	now := h.now()
+	eventCount, committed, err := h.userEvents(ctx, userID)
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	// Limits are checked before estimating usage as it takes time proportional to number of schedules
+	if exceeded := h.Config.Limits.Check(&reqBody, eventCount); len(exceeded) > 0 {
+		return pkgerrors.Unprocessable(errors.New("limits exceeded: " + strings.Join(exceeded, ", ")))
+	}
+
+	quota := h.Config.MonthlyQuota
+	segmentsPerFire := estimate.Segments * len(phones)
+	cronFires, allFires := reqBody.EstimateMonthlyFires(now, quota/max(segmentsPerFire, 1)+1)
+	if committed+allFires*segmentsPerFire > quota {
+		return pkgerrors.Unprocessable(fmt.Errorf("event exceeds monthly quota of %d SMS segments: it may take %d of them and other events already take %d", quota, allFires*segmentsPerFire, committed))
+	}
+
+	eventID := uuid.NewString()
+	ctx = logging.With(ctx, logging.KeyEventID, eventID)
+	title := reqBody.Title
+	if title == "" {
+		title = reqBody.Message
+	}
+
+	schedules := make([]createScheduleInput, 0, len(reqBody.Dates)+len(reqBody.Crons))
+	for _, date := range reqBody.Dates {
+		schedules = append(schedules, createScheduleInput{ScheduleExpression: date, ScheduleType: AT})
+	}
+	for _, cron := range reqBody.Crons {
+		schedules = append(schedules, createScheduleInput{ScheduleExpression: cron, ScheduleType: CRON})
+	}
+	for i := range schedules {
+		schedules[i].RuleID = schedule.Name(userID, eventID, strconv.Itoa(i))
+	}
+
+	cronMap := make(map[string]dynamotypes.AttributeValue)
+	dateMap := make(map[string]dynamotypes.AttributeValue)
+	var mapMutex sync.Mutex
+	// Alarms carry trace and ID of this request so that executor can be followed back to it
+	traceID, requestID := tracing.TraceID(ctx, request.Headers), httpx.RequestID(ctx, request)
+
+	if err := workerpool.Run(ctx, h.Config.SchedulerConcurrency, schedules, func(ctx context.Context, input createScheduleInput) error {
+		input.UserID = userID
+		input.Message = reqBody.Message
+		input.Title = title
+		input.Variables = reqBody.Variables
+		input.Timezone = reqBody.Timezone
+		input.EventID = eventID
+		input.Phones = phones
+		input.IgnoreQuietHours = reqBody.IgnoreQuietHours
+		input.TraceID = traceID
+		input.RequestID = requestID
+
+		if err := h.createSchedule(ctx, input); err != nil {
+			return err
+		}
+
+		mapMutex.Lock()
+		defer mapMutex.Unlock()
+		if input.ScheduleType == CRON {
+			cronMap[input.RuleID] = &dynamotypes.AttributeValueMemberS{Value: input.ScheduleExpression}
+		} else {
+			dateMap[input.RuleID] = &dynamotypes.AttributeValueMemberS{Value: input.ScheduleExpression}
+		}
+		return nil
+	}); err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+
+	phoneList := make([]dynamotypes.AttributeValue, 0, len(phones))
+	for _, label := range phones {
+		phoneList = append(phoneList, &dynamotypes.AttributeValueMemberS{Value: label})
+	}
+
+	item := map[string]dynamotypes.AttributeValue{
+		"EventID":          &dynamotypes.AttributeValueMemberS{Value: eventID},
+		"UserID":           &dynamotypes.AttributeValueMemberS{Value: userID},
+		"Title":            &dynamotypes.AttributeValueMemberS{Value: title},
+		"Message":          &dynamotypes.AttributeValueMemberS{Value: reqBody.Message},
+		"Crons":            &dynamotypes.AttributeValueMemberM{Value: cronMap},
+		"Dates":            &dynamotypes.AttributeValueMemberM{Value: dateMap},
+		"Timezone":         &dynamotypes.AttributeValueMemberS{Value: reqBody.Timezone},
+		"Phones":           &dynamotypes.AttributeValueMemberL{Value: phoneList},
+		"IgnoreQuietHours": &dynamotypes.AttributeValueMemberBOOL{Value: reqBody.IgnoreQuietHours},
+		// Estimated number of SMS segments billed per firing of an alarm
+		"Segments": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(estimate.Segments)},
+		"Encoding": &dynamotypes.AttributeValueMemberS{Value: string(estimate.Encoding)},
+		// Estimated number of SMS segments recurring alarms of an event take within a month
+		"MonthlySegments": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(cronFires * segmentsPerFire)},
+	}
+	if len(reqBody.Variables) > 0 {
+		variables := make(map[string]dynamotypes.AttributeValue, len(reqBody.Variables))
+		for name, value := range reqBody.Variables {
+			variables[name] = &dynamotypes.AttributeValueMemberS{Value: value}
+		}
+		item["Variables"] = &dynamotypes.AttributeValueMemberM{Value: variables}
+	}
+
+	if _, err := h.DynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(h.Config.TableName),
+		Item:      item,
+	}); err != nil {
+		return pkgerrors.FromAWS(ctx, err, "event already exists")
+	}
+	h.Metrics.Record(ctx, metrics.ChannelSMS,
+		metrics.Metric{Name: "EventsCreated", Value: 1, Unit: metrics.Count},
+		metrics.Metric{Name: "SchedulesPerEvent", Value: float64(len(schedules)), Unit: metrics.Count},
+	)
+
+	return httpx.JSON(ctx, http.StatusCreated, dynamomapper.SimplifyDynamoDBItem(item))
 }
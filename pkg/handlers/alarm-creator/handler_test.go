@@ -4,43 +4,109 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/idempotency"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
 	alarmcreator "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-creator"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/scheduler"
 )
 
 type mockDynamoDB struct {
+	committedSegments []string
+	// keys holds items of idempotency table by their key
+	keys map[string]map[string]dynamotypes.AttributeValue
 }
 
 func (m *mockDynamoDB) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	key, ok := input.Item["Key"].(*dynamotypes.AttributeValueMemberS)
+	if !ok {
+		return nil, nil
+	}
+	if item, ok := m.keys[key.Value]; ok {
+		return nil, &dynamotypes.ConditionalCheckFailedException{Item: item}
+	}
+	m.keys[key.Value] = input.Item
+	return nil, nil
+}
+
+func (m *mockDynamoDB) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	item := m.keys[input.Key["Key"].(*dynamotypes.AttributeValueMemberS).Value]
+	item["Status"] = input.ExpressionAttributeValues[":status"]
+	item["StatusCode"] = input.ExpressionAttributeValues[":statusCode"]
+	item["Body"] = input.ExpressionAttributeValues[":body"]
 	return nil, nil
 }
 
+func (m *mockDynamoDB) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	delete(m.keys, input.Key["Key"].(*dynamotypes.AttributeValueMemberS).Value)
+	return nil, nil
+}
+
+func (m *mockDynamoDB) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	// phones of a user are queried for their labels
+	if _, ok := input.ExpressionAttributeNames["#label"]; ok {
+		return &dynamodb.QueryOutput{Items: []map[string]dynamotypes.AttributeValue{
+			{"Label": &dynamotypes.AttributeValueMemberS{Value: "work"}},
+			{"Label": &dynamotypes.AttributeValueMemberS{Value: "home"}},
+		}}, nil
+	}
+	items := []map[string]dynamotypes.AttributeValue{{}}
+	for _, segments := range m.committedSegments {
+		items = append(items, map[string]dynamotypes.AttributeValue{
+			"MonthlySegments": &dynamotypes.AttributeValueMemberN{Value: segments},
+		})
+	}
+	return &dynamodb.QueryOutput{Items: items}, nil
+}
+
 type mockScheduler struct {
 	*sync.Mutex
 	counter   int
 	failureAt int
+	names     []string
+	payloads  []string
 }
 
 func (m *mockScheduler) CreateSchedule(ctx context.Context, input *scheduler.CreateScheduleInput, opts ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error) {
 	m.Lock()
 	m.counter++
+	counter := m.counter
+	m.names = append(m.names, *input.Name)
+	m.payloads = append(m.payloads, *input.Target.Input)
 	m.Unlock()
 
 	if ctx.Err() != nil {
 		return nil, context.Canceled
 	}
-	if m.counter == m.failureAt {
+	if counter == m.failureAt {
 		return nil, errors.New("some error")
 	}
 	return nil, nil
 }
 
+// config is configuration of the handler when no optional variables are set
+var config = alarmcreator.Config{
+	MaxSegments:          sms.DefaultMaxSegments,
+	MonthlyQuota:         usage.DefaultMonthlyQuota,
+	SchedulerConcurrency: workerpool.DefaultSize,
+	Limits: alarmcreator.Limits{
+		SchedulesPerRequest: alarmcreator.DefaultSchedulesPerRequest,
+		CronsPerEvent:       alarmcreator.DefaultCronsPerEvent,
+		EventsPerUser:       alarmcreator.DefaultEventsPerUser,
+	},
+}
+
 func TestHandler(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -50,13 +116,15 @@ func TestHandler(t *testing.T) {
 		expectedStatusCode int
 		returnResult       bool
 		failureAt          int
+		committedSegments  []string
+		limits             *alarmcreator.Limits
 	}{
 		{
 			name: "no authorizer",
 			request: events.APIGatewayProxyRequest{
 				RequestContext: events.APIGatewayProxyRequestContext{},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -68,7 +136,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -88,7 +156,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"\"message\" cannot be an empty string"}`,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"\"message\" cannot be an empty string","code":"validation_failed","errors":[{"field":"message","message":"\"message\" cannot be an empty string"}]}`,
 			expectedStatusCode: 400,
 		},
 		{
@@ -108,7 +176,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"\"timezone\" cannot be an empty string"}`,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"\"timezone\" cannot be an empty string","code":"validation_failed","errors":[{"field":"timezone","message":"\"timezone\" cannot be an empty string"}]}`,
 			expectedStatusCode: 400,
 		},
 		{
@@ -128,7 +196,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"there are no crons or dates specified"}`,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"there are no crons or dates specified","code":"validation_failed","errors":[{"field":"dates","message":"there are no crons or dates specified"}]}`,
 			expectedStatusCode: 400,
 		},
 		{
@@ -148,7 +216,263 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"there are no crons or dates specified"}`,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"there are no crons or dates specified","code":"validation_failed","errors":[{"field":"dates","message":"there are no crons or dates specified"}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "invalid phone label",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Europe/Warsaw",
+				Dates:    []string{"2012-12-04T12:12"},
+				Phones:   []string{"Work Phone"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"phone label must be 1-32 characters long and contain only lowercase letters, digits, \"_\" or \"-\"","code":"validation_failed","errors":[{"field":"phones","message":"phone label must be 1-32 characters long and contain only lowercase letters, digits, \"_\" or \"-\""}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "unknown phone label",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Europe/Warsaw",
+				Dates:    []string{"2012-12-04T12:12"},
+				Phones:   []string{"default", "work", "car"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"user has no phone numbers labeled: car","code":"unprocessable_entity","errors":[{"field":"phones","message":"user has no phone numbers labeled: car"}]}`,
+			expectedStatusCode: 422,
+		},
+		{
+			name: "invalid cron",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Europe/Warsaw",
+				Crons:    []string{"0 25 * * ? *"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid cron expression \"0 25 * * ? *\": hours: invalid value: \"25\"","code":"validation_failed","errors":[{"field":"crons","message":"invalid cron expression \"0 25 * * ? *\": hours: invalid value: \"25\""}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "invalid timezone",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Mars/Olympus",
+				Dates:    []string{"2012-12-04T12:12"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid timezone: \"Mars/Olympus\"","code":"validation_failed","errors":[{"field":"timezone","message":"invalid timezone: \"Mars/Olympus\""}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "monthly quota exceeded",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Europe/Warsaw",
+				Crons:    []string{"* * * * ? *"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"event exceeds monthly quota of 300 SMS segments: it may take 301 of them and other events already take 0","code":"unprocessable_entity"}`,
+			expectedStatusCode: 422,
+		},
+		{
+			name: "monthly quota exceeded with other events",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Europe/Warsaw",
+				Phones:   []string{"work", "home"},
+				Crons:    []string{"0 10 ? * MON-FRI *"},
+			},
+			committedSegments: []string{"200", "70"},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"event exceeds monthly quota of 300 SMS segments: it may take 42 of them and other events already take 270","code":"unprocessable_entity"}`,
+			expectedStatusCode: 422,
+		},
+		{
+			name: "too many schedules in request",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Europe/Warsaw",
+				Dates:    repeat("2012-12-04T12:12", 20),
+				Crons:    repeat("0 10 ? * MON-FRI *", 6),
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"limits exceeded: 25 schedules per request (got 26)","code":"unprocessable_entity"}`,
+			expectedStatusCode: 422,
+		},
+		{
+			name: "too many crons and events",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "some message",
+				Timezone: "Europe/Warsaw",
+				Crons:    repeat("0 10 ? * MON-FRI *", 4),
+			},
+			committedSegments: []string{"0", "0"},
+			limits:            &alarmcreator.Limits{SchedulesPerRequest: alarmcreator.DefaultSchedulesPerRequest, CronsPerEvent: 3, EventsPerUser: 3},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"limits exceeded: 3 crons per event (got 4), 3 events per user (already has 3)","code":"unprocessable_entity"}`,
+			expectedStatusCode: 422,
+		},
+		{
+			name: "invalid template",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "{{room}} meeting",
+				Timezone: "Europe/Warsaw",
+				Dates:    []string{"2012-12-04T12:12"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"unknown variable: \"room\"","code":"validation_failed","errors":[{"field":"message","message":"unknown variable: \"room\""}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "title not set",
+			requestBody: alarmcreator.RequestBody{
+				Message:  "{{title}} in 10 minutes",
+				Timezone: "Europe/Warsaw",
+				Dates:    []string{"2012-12-04T12:12"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"\"title\" must be set when message uses it","code":"validation_failed","errors":[{"field":"title","message":"\"title\" must be set when message uses it"}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "message rendering empty",
+			requestBody: alarmcreator.RequestBody{
+				Message:   "{{room}} ",
+				Variables: map[string]string{"room": ""},
+				Timezone:  "Europe/Warsaw",
+				Dates:     []string{"2012-12-04T12:12"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"message renders to an empty string","code":"unprocessable_entity","errors":[{"field":"message","message":"message renders to an empty string"}]}`,
+			expectedStatusCode: 422,
+		},
+		{
+			name: "message too long",
+			requestBody: alarmcreator.RequestBody{
+				Message:   strings.Repeat("a", 450) + "{{weekday}}{{room}}",
+				Variables: map[string]string{"room": "B-12"},
+				Timezone:  "Europe/Warsaw",
+				Dates:     []string{"2012-12-04T12:12"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"message can take at most 3 SMS segments, but it may take 4 (GSM-7)","code":"validation_failed","errors":[{"field":"message","message":"message can take at most 3 SMS segments, but it may take 4 (GSM-7)"}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "UCS-2 message too long",
+			requestBody: alarmcreator.RequestBody{
+				Message:  strings.Repeat("⏰", 202),
+				Timezone: "Europe/Warsaw",
+				Dates:    []string{"2012-12-04T12:12"},
+			},
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"message can take at most 3 SMS segments, but it may take 4 (UCS-2)","code":"validation_failed","errors":[{"field":"message","message":"message can take at most 3 SMS segments, but it may take 4 (UCS-2)"}]}`,
 			expectedStatusCode: 400,
 		},
 		{
@@ -168,7 +492,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			failureAt:          1,
 			returnResult:       true,
 			expectedStatusCode: 500,
@@ -189,7 +513,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			failureAt:          3,
 			returnResult:       true,
 			expectedStatusCode: 500,
@@ -211,7 +535,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			failureAt:          6,
 			returnResult:       true,
 			expectedStatusCode: 500,
@@ -220,15 +544,21 @@ func TestHandler(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
+			config := config
+			if testCase.limits != nil {
+				config.Limits = *testCase.limits
+			}
 			handler := alarmcreator.Handler{
-				DynamoClient:    &mockDynamoDB{},
+				DynamoClient:    &mockDynamoDB{committedSegments: testCase.committedSegments},
 				SchedulerClient: &mockScheduler{failureAt: testCase.failureAt, Mutex: &sync.Mutex{}},
+				Config:          config,
+				Now:             func() time.Time { return time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC) },
 			}
 
 			jsonBody, _ := json.Marshal(testCase.requestBody)
 			testCase.request.Body = string(jsonBody)
 
-			response, _ := handler.Handle(testCase.request)
+			response, _ := handler.Handle(context.Background(), testCase.request)
 			if response.Body != testCase.expectedBody {
 				t.Errorf("Expected response %v, but got %v", testCase.expectedBody, response.Body)
 			}
@@ -240,38 +570,65 @@ func TestHandler(t *testing.T) {
 	}
 }
 
+func repeat(value string, count int) []string {
+	values := make([]string, count)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
+
 func TestHandleSuccess(t *testing.T) {
+	schedulerClient := &mockScheduler{Mutex: &sync.Mutex{}}
 	handler := alarmcreator.Handler{
-		DynamoClient: &mockDynamoDB{},
-		SchedulerClient: &mockScheduler{
-			Mutex: &sync.Mutex{}},
+		DynamoClient:    &mockDynamoDB{},
+		SchedulerClient: schedulerClient,
+		Config:          config,
 	}
 
 	requestBody := alarmcreator.RequestBody{
-		Message:  "some message",
-		Timezone: "Europe/Warsaw",
-		Dates:    []string{"2012-12-04T12:12", "2013-12-04T12:12"},
-		Crons:    []string{"0 10 4 10 * ? 2024", "0 10 4 11 * ? 2024"},
+		Message:   "{{title}} in {{room}} ({{weekday}}, occurrence {{n}})",
+		Title:     "Stand-up",
+		Variables: map[string]string{"room": "B-12"},
+		Timezone:  "Europe/Warsaw",
+		Dates:     []string{"2012-12-04T12:12", "2013-12-04T12:12"},
+		Crons:     []string{"0 10 4 10 * ? 2024", "0 10 4 11 * ? 2024"},
+		Phones:    []string{"work", "home"},
+
+		IgnoreQuietHours: true,
 	}
 
 	jsonRequestBody, _ := json.Marshal(requestBody)
 
 	request := events.APIGatewayProxyRequest{
 		RequestContext: events.APIGatewayProxyRequestContext{
+			RequestID: "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
 			Authorizer: map[string]interface{}{
 				"claims": map[string]interface{}{
 					"sub": "1",
 				},
 			},
 		},
-		Body: string(jsonRequestBody),
+		Headers: map[string]string{"X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"},
+		Body:    string(jsonRequestBody),
 	}
 
-	res, err := handler.Handle(request)
+	res, err := handler.Handle(context.Background(), request)
 	if err != nil {
 		t.Errorf("Error occured during HandleSuccess test: %v", err)
 	}
 
+	// Executor traces are linked back to this request through payloads of schedules
+	for _, payload := range schedulerClient.payloads {
+		var decodedPayload map[string]interface{}
+		if err := json.Unmarshal([]byte(payload), &decodedPayload); err != nil {
+			t.Fatalf("Error decoding schedule payload: %v", err)
+		}
+		if decodedPayload["originTraceId"] != "1-5759e988-bd862e3fe1be46a994272793" || decodedPayload["originRequestId"] != "c6af9ac6-7b61-11e6-9a41-93e8deadbeef" {
+			t.Errorf("Schedule payload: %v isn't linked to request that created it", payload)
+		}
+	}
+
 	var decodedResult map[string]interface{}
 	if err := json.NewDecoder(strings.NewReader(res.Body)).Decode(&decodedResult); err != nil {
 		t.Errorf("Error decoding response in HandleSuccess test: %v", err)
@@ -280,13 +637,44 @@ func TestHandleSuccess(t *testing.T) {
 	if decodedResult["Timezone"] != requestBody.Timezone {
 		t.Errorf("Returned timezone: %v different than expected: %v", decodedResult["Timezone"], requestBody.Timezone)
 	}
-	if decodedResult["Title"] != requestBody.Message {
+	if decodedResult["Title"] != requestBody.Title {
+		t.Errorf("Returned title: %v different than expected: %v", decodedResult["Title"], requestBody.Title)
+	}
+	if decodedResult["Message"] != requestBody.Message {
 		t.Errorf("Returned message: %v different than expected: %v", decodedResult["Message"], requestBody.Message)
 	}
+	if decodedResult["Segments"] != "1" || decodedResult["Encoding"] != "GSM-7" {
+		t.Errorf("Returned segments estimate: %v (%v) different than expected: 1 (GSM-7)", decodedResult["Segments"], decodedResult["Encoding"])
+	}
+	if !reflect.DeepEqual(decodedResult["Variables"], map[string]interface{}{"room": "B-12"}) {
+		t.Errorf("Returned variables: %v different than expected: %v", decodedResult["Variables"], requestBody.Variables)
+	}
+
+	if decodedResult["IgnoreQuietHours"] != true {
+		t.Errorf("Returned IgnoreQuietHours: %v different than expected: %v", decodedResult["IgnoreQuietHours"], requestBody.IgnoreQuietHours)
+	}
+	if !reflect.DeepEqual(decodedResult["Phones"], []interface{}{"work", "home"}) {
+		t.Errorf("Returned phones: %v different than expected: %v", decodedResult["Phones"], requestBody.Phones)
+	}
 
 	crons := decodedResult["Crons"].(map[string]interface{})
 	dates := decodedResult["Dates"].(map[string]interface{})
 
+	// Schedules have to be saved under their names so that they can be deleted along with an event
+	for _, name := range schedulerClient.names {
+		_, isCron := crons[name]
+		_, isDate := dates[name]
+		if !isCron && !isDate {
+			t.Errorf("Schedule %v not saved in event", name)
+		}
+		if userID, eventID, ok := schedule.ParseName(name); !ok || userID != "1" || eventID != decodedResult["EventID"] {
+			t.Errorf("Schedule %v doesn't carry IDs of user and event", name)
+		}
+	}
+	if len(schedulerClient.names) != len(crons)+len(dates) {
+		t.Errorf("Created %v schedules, but %v were saved in event", len(schedulerClient.names), len(crons)+len(dates))
+	}
+
 	for key, resCron := range crons {
 		for _, reqCron := range requestBody.Crons {
 			if resCron == reqCron {
@@ -309,3 +697,76 @@ func TestHandleSuccess(t *testing.T) {
 		t.Errorf("Unexpected dates expressions: %v", crons)
 	}
 }
+
+func TestHandlerIdempotency(t *testing.T) {
+	dynamoClient := &mockDynamoDB{keys: map[string]map[string]dynamotypes.AttributeValue{
+		"in-progress": {
+			"Fingerprint": &dynamotypes.AttributeValueMemberS{Value: idempotency.Fingerprint(`{"message":"some message"}`)},
+			"Status":      &dynamotypes.AttributeValueMemberS{Value: idempotency.StatusInProgress},
+		},
+	}}
+	schedulerClient := &mockScheduler{Mutex: &sync.Mutex{}}
+	handler := alarmcreator.Handler{
+		DynamoClient:    dynamoClient,
+		SchedulerClient: schedulerClient,
+		Config:          config,
+		Now:             func() time.Time { return time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC) },
+	}
+
+	body := `{"message":"some message","timezone":"Europe/Warsaw","crons":["0 10 ? * MON-FRI *"]}`
+	request := func(key, body string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			Headers: map[string]string{"idempotency-key": key},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]interface{}{
+					"claims": map[string]interface{}{
+						"sub": "1",
+					},
+				},
+			},
+			Body: body,
+		}
+	}
+
+	first, _ := handler.Handle(context.Background(), request("key", body))
+	if first.StatusCode != 201 {
+		t.Fatalf("Expected status code 201, but got %v: %v", first.StatusCode, first.Body)
+	}
+
+	// Retry with reformatted body gets the original response without creating another event
+	retry, _ := handler.Handle(context.Background(), request("key", `{"timezone":"Europe/Warsaw", "crons":["0 10 ? * MON-FRI *"], "message":"some message"}`))
+	if retry.StatusCode != first.StatusCode || retry.Body != first.Body {
+		t.Errorf("Expected original response %v, but got %v (%v)", first.Body, retry.Body, retry.StatusCode)
+	}
+	if schedulerClient.counter != 1 {
+		t.Errorf("Expected 1 schedule to be created, but got %v", schedulerClient.counter)
+	}
+
+	conflict, _ := handler.Handle(context.Background(), request("key", `{"message":"other message","timezone":"Europe/Warsaw","crons":["0 10 ? * MON-FRI *"]}`))
+	if conflict.StatusCode != 409 || conflict.Body != `{"type":"about:blank","title":"Conflict","status":409,"detail":"idempotency key was already used with a different request","code":"conflict"}` {
+		t.Errorf("Expected conflict, but got %v (%v)", conflict.Body, conflict.StatusCode)
+	}
+
+	inProgress, _ := handler.Handle(context.Background(), request("in-progress", `{"message":"some message"}`))
+	if inProgress.StatusCode != 409 || inProgress.Body != `{"type":"about:blank","title":"Conflict","status":409,"detail":"request with this idempotency key is still being processed","code":"conflict"}` {
+		t.Errorf("Expected conflict, but got %v (%v)", inProgress.Body, inProgress.StatusCode)
+	}
+
+	invalid, _ := handler.Handle(context.Background(), request("", body))
+	if invalid.StatusCode != 400 {
+		t.Errorf("Expected status code 400, but got %v", invalid.StatusCode)
+	}
+
+	// Keys of requests that failed are released, so they can be retried
+	schedulerClient.failureAt = 2
+	failed, _ := handler.Handle(context.Background(), request("failing", body))
+	if failed.StatusCode != 500 {
+		t.Fatalf("Expected status code 500, but got %v", failed.StatusCode)
+	}
+	if _, ok := dynamoClient.keys["failing"]; ok {
+		t.Error("Key of failed request wasn't released")
+	}
+	if retried, _ := handler.Handle(context.Background(), request("failing", body)); retried.StatusCode != 201 {
+		t.Errorf("Expected status code 201, but got %v: %v", retried.StatusCode, retried.Body)
+	}
+}
@@ -3,7 +3,12 @@ module github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter
 go 1.22.0
 
 require (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig v0.0.0-00010101000000-000000000000
 	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors v0.0.0-20240821145950-d2da7dbd1a33
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule v0.0.0-00010101000000-000000000000
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool v0.0.0-00010101000000-000000000000
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go-v2 v1.30.4
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.34.5
@@ -19,3 +24,12 @@ require (
 	github.com/aws/smithy-go v1.20.4 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 )
+
+replace (
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig => ../../features/envconfig
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors => ../../features/errors
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx => ../../features/httpx
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging => ../../features/logging
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule => ../../features/schedule
+	github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool => ../../features/workerpool
+)
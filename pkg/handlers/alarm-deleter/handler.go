@@ -2,10 +2,8 @@ package alarmdeleter
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"os"
-	"sync"
+	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,7 +13,12 @@ import (
 	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
 	"github.com/google/uuid"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
 	pkgerrors "github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
 )
 
 type DynamoApiClient interface {
@@ -25,126 +28,185 @@ type DynamoApiClient interface {
 
 type SchedulerApiClient interface {
 	DeleteSchedule(context.Context, *scheduler.DeleteScheduleInput, ...func(*scheduler.Options)) (*scheduler.DeleteScheduleOutput, error)
+	ListSchedules(context.Context, *scheduler.ListSchedulesInput, ...func(*scheduler.Options)) (*scheduler.ListSchedulesOutput, error)
+}
+
+// scheduleRef identifies a schedule within a schedule group
+type scheduleRef struct {
+	Name      *string
+	GroupName *string
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	TableName string
+	// ScheduleGroup is a group alarms are created in, the default group is used when it's empty
+	ScheduleGroup string
+	// SchedulerConcurrency is a number of schedules deleted at once
+	SchedulerConcurrency int
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		TableName:            env.Required("DYNAMO_TABLE_NAME"),
+		ScheduleGroup:        env.Optional("SCHEDULE_GROUP_NAME"),
+		SchedulerConcurrency: env.Int("SCHEDULER_CONCURRENCY", workerpool.DefaultSize, 1),
+		AllowedOrigins:       httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+	}
+	return config, env.Err()
+}
+
+// scheduleGroup returns a name of schedule group alarms are created in, nil stands for the default group
+func (c Config) scheduleGroup() *string {
+	if c.ScheduleGroup == "" {
+		return nil
+	}
+	return aws.String(c.ScheduleGroup)
 }
 
 type Handler struct {
 	DynamoClient    DynamoApiClient
 	SchedulerClient SchedulerApiClient
+	Config          Config
 }
 
-func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	claims, ok := request.RequestContext.Authorizer["claims"].(map[string]interface{})
-	if !ok {
-		return pkgerrors.Unauthorized("authorization data not found")
+// DeleteUserSchedules deletes all schedules of a user, including deferred alarms, in one pass
+// through the schedule group
+func (h *Handler) DeleteUserSchedules(ctx context.Context, userID string) error {
+	schedules, err := h.listSchedules(ctx, schedule.UserPrefix(userID))
+	if err != nil {
+		return err
+	}
+	return h.deleteSchedules(ctx, schedules)
+}
+
+// DeleteSchedules deletes schedules saved with events under given names, including the ones
+// created in the default group before schedule groups were introduced
+func (h *Handler) DeleteSchedules(ctx context.Context, names []string) error {
+	schedules := make([]scheduleRef, 0, len(names))
+	for _, name := range names {
+		schedules = append(schedules, h.savedSchedule(name))
+	}
+	return h.deleteSchedules(ctx, schedules)
+}
+
+// savedSchedule returns reference to a schedule saved with an event. Schedules created before
+// schedule groups were introduced are in the default group
+func (h *Handler) savedSchedule(name string) scheduleRef {
+	s := scheduleRef{Name: aws.String(name)}
+	if _, _, ok := schedule.ParseName(name); ok {
+		s.GroupName = h.Config.scheduleGroup()
 	}
-	userID, ok := claims["sub"].(string)
-	if !ok {
-		return pkgerrors.Unauthorized("authorization data not found")
+	return s
+}
+
+// listSchedules returns schedules in the schedule group whose names start with prefix
+func (h *Handler) listSchedules(ctx context.Context, prefix string) ([]scheduleRef, error) {
+	var schedules []scheduleRef
+	var nextToken *string
+	for {
+		res, err := h.SchedulerClient.ListSchedules(ctx, &scheduler.ListSchedulesInput{
+			GroupName:  h.Config.scheduleGroup(),
+			NamePrefix: aws.String(prefix),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range res.Schedules {
+			schedules = append(schedules, scheduleRef{Name: s.Name, GroupName: s.GroupName})
+		}
+
+		if res.NextToken == nil {
+			return schedules, nil
+		}
+		nextToken = res.NextToken
 	}
+}
 
+// deleteSchedules deletes schedules concurrently, schedules that no longer exist are skipped
+func (h *Handler) deleteSchedules(ctx context.Context, schedules []scheduleRef) error {
+	return workerpool.Run(ctx, h.Config.SchedulerConcurrency, schedules, func(ctx context.Context, s scheduleRef) error {
+		var errNotFound *schedulertypes.ResourceNotFoundException
+		if _, err := h.SchedulerClient.DeleteSchedule(ctx, &scheduler.DeleteScheduleInput{
+			Name:        s.Name,
+			GroupName:   s.GroupName,
+			ClientToken: aws.String(uuid.NewString()),
+		}); err != nil && !errors.As(err, &errNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
+
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	eventID := request.PathParameters["id"]
 	if eventID == "" {
 		return pkgerrors.BadRequest("no eventID specified")
 	}
+	ctx = logging.With(ctx, logging.KeyEventID, eventID)
 
-	res, err := h.DynamoClient.GetItem(context.Background(), &dynamodb.GetItemInput{
+	res, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
 		Key: map[string]dynamotypes.AttributeValue{
-			"UserID":  &dynamotypes.AttributeValueMemberS{Value: userID},
+			"UserID":  &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
 			"EventID": &dynamotypes.AttributeValueMemberS{Value: eventID},
 		},
-		TableName: aws.String(os.Getenv("DYNAMO_TABLE_NAME")),
+		TableName: aws.String(h.Config.TableName),
 	})
 	if err != nil {
-		return pkgerrors.Internal(err)
+		return pkgerrors.Internal(ctx, err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	errChan := make(chan error, 1)
-	defer close(errChan)
-	defer cancel()
-	var wg sync.WaitGroup
-
-	for key := range res.Item["Dates"].(*dynamotypes.AttributeValueMemberM).Value {
-		wg.Add(1)
-		go func(key string) {
-			defer wg.Done()
-
-			if ctx.Err() != nil {
-				return
-			}
-			var errNotFound *schedulertypes.ResourceNotFoundException
-			if _, err := h.SchedulerClient.DeleteSchedule(ctx, &scheduler.DeleteScheduleInput{
-				Name:        &key,
-				ClientToken: aws.String(uuid.NewString()),
-			}); err != nil && !errors.As(err, &errNotFound) {
-				// Here we try to send error to errChan but if it is not answering we return as it means
-				// that other goroutine already published an error
-				select {
-				case errChan <- err:
-					cancel()
-				default:
-				}
-			}
-		}(key)
+	schedules, err := h.listSchedules(ctx, schedule.EventPrefix(principal.UserID, eventID))
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
 	}
-	for key := range res.Item["Crons"].(*dynamotypes.AttributeValueMemberM).Value {
-		wg.Add(1)
-		go func(key string) {
-			defer wg.Done()
-
-			if ctx.Err() != nil {
-				return
-			}
-			var errNotFound *schedulertypes.ResourceNotFoundException
-			if _, err := h.SchedulerClient.DeleteSchedule(ctx, &scheduler.DeleteScheduleInput{
-				Name:        &key,
-				ClientToken: aws.String(uuid.NewString()),
-			}); err != nil && !errors.As(err, &errNotFound) {
-				// Here we try to send error to errChan but if it is not answering we return as it means
-				// that other goroutine already published an error
-				select {
-				case errChan <- err:
-					cancel()
-				default:
-				}
+	listed := make(map[string]bool, len(schedules))
+	for _, s := range schedules {
+		listed[*s.Name] = true
+	}
+	// Listing is eventually consistent, so schedules saved with the event are deleted as well
+	for _, field := range []string{"Dates", "Crons"} {
+		saved, ok := res.Item[field].(*dynamotypes.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+		for name := range saved.Value {
+			if listed[name] {
+				continue
 			}
-		}(key)
+			schedules = append(schedules, h.savedSchedule(name))
+		}
 	}
 
-	wg.Wait()
-
-	select {
-	case err := <-errChan:
-		return pkgerrors.Internal(err)
-	default:
+	if err := h.deleteSchedules(ctx, schedules); err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	// Schedules left behind by an event that's already deleted are cleaned up, but the event isn't there
+	if res.Item == nil {
+		return pkgerrors.NotFound("event not found")
 	}
 
-	if _, err := h.DynamoClient.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
-		TableName: aws.String(os.Getenv("DYNAMO_TABLE_NAME")),
+	if _, err := h.DynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(h.Config.TableName),
 		Key: map[string]dynamotypes.AttributeValue{
-			"UserID":  &dynamotypes.AttributeValueMemberS{Value: userID},
+			"UserID":  &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
 			"EventID": &dynamotypes.AttributeValueMemberS{Value: eventID},
 		},
 	}); err != nil {
-		return pkgerrors.Internal(err)
+		return pkgerrors.FromAWS(ctx, err, "event not found")
 	}
 
-	responseJSON, err := json.Marshal(map[string]string{
+	return httpx.JSON(ctx, http.StatusOK, map[string]string{
 		"message": "ok",
 	})
-	if err != nil {
-		return pkgerrors.Internal(err)
-	}
-
-	return events.APIGatewayProxyResponse{
-		Body: string(responseJSON),
-		Headers: map[string]string{
-			"Content-Type":                     "application/json",
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Headers":     "Content-Type",
-			"Access-Control-Allow-Methods":     "OPTIONS, GET, POST, DELETE",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		StatusCode: 200,
-	}, nil
 }
@@ -3,20 +3,33 @@ package alarmdeleter_test
 import (
 	"context"
 	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
 	alarmdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter"
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
 )
 
 type mockDynamoDB struct {
+	// missing makes the event not found, deleted tells whether it was deleted
+	missing bool
+	deleted bool
 }
 
 func (m *mockDynamoDB) GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if m.missing {
+		return &dynamodb.GetItemOutput{}, nil
+	}
 	return &dynamodb.GetItemOutput{
 		Item: map[string]dynamotypes.AttributeValue{
 			"Crons": &dynamotypes.AttributeValueMemberM{
@@ -27,14 +40,15 @@ func (m *mockDynamoDB) GetItem(context.Context, *dynamodb.GetItemInput, ...func(
 			},
 			"Dates": &dynamotypes.AttributeValueMemberM{
 				Value: map[string]dynamotypes.AttributeValue{
-					"1": &dynamotypes.AttributeValueMemberS{Value: "1"},
-					"2": &dynamotypes.AttributeValueMemberS{Value: "2"},
+					"3": &dynamotypes.AttributeValueMemberS{Value: "3"},
+					"4": &dynamotypes.AttributeValueMemberS{Value: "4"},
 				},
 			},
 		},
 	}, nil
 }
 func (m *mockDynamoDB) DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	m.deleted = true
 	return nil, nil
 }
 
@@ -42,22 +56,52 @@ type mockScheduler struct {
 	*sync.Mutex
 	counter   int
 	failureAt int
+	// schedules holds names of schedules in the group, deleted ones are removed
+	schedules []string
+	deleted   []string
 }
 
 func (m *mockScheduler) DeleteSchedule(ctx context.Context, input *scheduler.DeleteScheduleInput, opts ...func(*scheduler.Options)) (*scheduler.DeleteScheduleOutput, error) {
 	m.Lock()
 	m.counter++
+	counter := m.counter
+	m.deleted = append(m.deleted, *input.Name)
 	m.Unlock()
 
 	if ctx.Err() != nil {
 		return nil, context.Canceled
 	}
-	if m.counter == m.failureAt {
+	if counter == m.failureAt {
 		return nil, errors.New("some error")
 	}
 	return nil, nil
 }
 
+// ListSchedules returns one schedule per page to check pagination
+func (m *mockScheduler) ListSchedules(ctx context.Context, input *scheduler.ListSchedulesInput, opts ...func(*scheduler.Options)) (*scheduler.ListSchedulesOutput, error) {
+	var matching []string
+	for _, name := range m.schedules {
+		if strings.HasPrefix(name, *input.NamePrefix) {
+			matching = append(matching, name)
+		}
+	}
+
+	page := 0
+	if input.NextToken != nil {
+		page, _ = strconv.Atoi(*input.NextToken)
+	}
+	if page >= len(matching) {
+		return &scheduler.ListSchedulesOutput{}, nil
+	}
+	output := &scheduler.ListSchedulesOutput{
+		Schedules: []schedulertypes.ScheduleSummary{{Name: aws.String(matching[page]), GroupName: input.GroupName}},
+	}
+	if page+1 < len(matching) {
+		output.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return output, nil
+}
+
 func TestHandler(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -72,7 +116,7 @@ func TestHandler(t *testing.T) {
 			request: events.APIGatewayProxyRequest{
 				RequestContext: events.APIGatewayProxyRequestContext{},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -84,7 +128,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -98,7 +142,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"no eventID specified"}`,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"no eventID specified","code":"bad_request"}`,
 			expectedStatusCode: 400,
 		},
 		{
@@ -115,7 +159,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			failureAt:          1,
 			returnResult:       true,
 			expectedStatusCode: 500,
@@ -133,7 +177,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			failureAt:          2,
 			returnResult:       true,
 			expectedStatusCode: 500,
@@ -152,7 +196,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			failureAt:          4,
 			returnResult:       true,
 			expectedStatusCode: 500,
@@ -184,7 +228,7 @@ func TestHandler(t *testing.T) {
 				DynamoClient:    &mockDynamoDB{},
 				SchedulerClient: &mockScheduler{failureAt: testCase.failureAt, Mutex: &sync.Mutex{}},
 			}
-			response, _ := handler.Handle(testCase.request)
+			response, _ := handler.Handle(context.Background(), testCase.request)
 			if response.Body != testCase.expectedBody {
 				t.Errorf("Expected response %v, but got %v", testCase.expectedBody, response.Body)
 			}
@@ -194,3 +238,93 @@ func TestHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerEventNotFound(t *testing.T) {
+	schedulerClient := &mockScheduler{Mutex: &sync.Mutex{}, schedules: []string{schedule.Name("1", "1", "q1")}}
+	dynamoClient := &mockDynamoDB{missing: true}
+	handler := alarmdeleter.Handler{DynamoClient: dynamoClient, SchedulerClient: schedulerClient}
+
+	response, _ := handler.Handle(context.Background(), events.APIGatewayProxyRequest{
+		PathParameters: map[string]string{
+			"id": "1",
+		},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{
+				"claims": map[string]interface{}{
+					"sub": "1",
+				},
+			},
+		},
+	})
+	expectedBody := `{"type":"about:blank","title":"Not Found","status":404,"detail":"event not found","code":"not_found"}`
+	if response.StatusCode != 404 || response.Body != expectedBody {
+		t.Errorf("Received result: %v %v is different than expected one: %v %v", response.StatusCode, response.Body, 404, expectedBody)
+	}
+	if dynamoClient.deleted {
+		t.Error("Event that wasn't found was deleted")
+	}
+	// stray schedules of the event are deleted anyway
+	expected := []string{schedule.Name("1", "1", "q1")}
+	if !reflect.DeepEqual(schedulerClient.deleted, expected) {
+		t.Errorf("Deleted schedules: %v are different than expected: %v", schedulerClient.deleted, expected)
+	}
+}
+
+func TestHandlerScheduleGroup(t *testing.T) {
+	schedulerClient := &mockScheduler{Mutex: &sync.Mutex{}, schedules: []string{
+		schedule.Name("1", "1", "0"),
+		// not saved with an event, e.g. deferred alarm
+		schedule.Name("1", "1", "q1"),
+		schedule.Name("1", "2", "0"),
+		schedule.Name("2", "1", "0"),
+	}}
+	handler := alarmdeleter.Handler{
+		DynamoClient:    &mockDynamoDB{},
+		SchedulerClient: schedulerClient,
+		Config:          alarmdeleter.Config{ScheduleGroup: "alarms"},
+	}
+
+	response, _ := handler.Handle(context.Background(), events.APIGatewayProxyRequest{
+		PathParameters: map[string]string{
+			"id": "1",
+		},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{
+				"claims": map[string]interface{}{
+					"sub": "1",
+				},
+			},
+		},
+	})
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status code 200, but got %v: %v", response.StatusCode, response.Body)
+	}
+	sort.Strings(schedulerClient.deleted)
+	expected := []string{"1", "2", "3", "4", schedule.Name("1", "1", "0"), schedule.Name("1", "1", "q1")}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(schedulerClient.deleted, expected) {
+		t.Errorf("Deleted schedules: %v are different than expected: %v", schedulerClient.deleted, expected)
+	}
+
+	schedulerClient.deleted = nil
+	if err := handler.DeleteUserSchedules(context.Background(), "1"); err != nil {
+		t.Fatalf("Error when deleting user's schedules: %v", err)
+	}
+	sort.Strings(schedulerClient.deleted)
+	expected = []string{schedule.Name("1", "1", "0"), schedule.Name("1", "1", "q1"), schedule.Name("1", "2", "0")}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(schedulerClient.deleted, expected) {
+		t.Errorf("Deleted schedules: %v are different than expected: %v", schedulerClient.deleted, expected)
+	}
+
+	schedulerClient.deleted = nil
+	if err := handler.DeleteSchedules(context.Background(), []string{"5", schedule.Name("1", "3", "0")}); err != nil {
+		t.Fatalf("Error when deleting saved schedules: %v", err)
+	}
+	sort.Strings(schedulerClient.deleted)
+	expected = []string{"5", schedule.Name("1", "3", "0")}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(schedulerClient.deleted, expected) {
+		t.Errorf("Deleted schedules: %v are different than expected: %v", schedulerClient.deleted, expected)
+	}
+}
@@ -2,40 +2,512 @@ package alarmexecutor
 
 import (
 	"context"
-	"os"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
 
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/google/uuid"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/msgtemplate"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/tracing"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+)
+
+// Statuses of deliveries saved in delivery log
+const (
+	DeliverySent     = "sent"
+	DeliveryDeferred = "deferred"
+	DeliveryDropped  = "dropped"
+	// DeliveryOverQuota marks alarms not sent because user exceeded monthly SMS quota
+	DeliveryOverQuota = "over-quota"
 )
 
+const overQuotaMessage = "You have reached your monthly limit of SMS reminders. Reminders won't be sent until the end of the month."
+
+// deliveryLogRetention is how long entries of delivery log are kept
+const deliveryLogRetention = 90 * 24 * time.Hour
+
 type AlarmEvent struct {
-	UserID  string `json:"userID"`
-	Message string `json:"message"`
+	UserID           string            `json:"userID"`
+	EventID          string            `json:"eventID"`
+	Message          string            `json:"message"`
+	Title            string            `json:"title"`
+	Timezone         string            `json:"timezone"`
+	Variables        map[string]string `json:"variables"`
+	Phones           []string          `json:"phones"`
+	IgnoreQuietHours bool              `json:"ignoreQuietHours"`
+	// ScheduledTime is a time alarm was set on in RFC3339 format, filled in by EventBridge Scheduler
+	ScheduledTime string `json:"scheduledTime"`
+	// DeferredTo is a time alarm deferred due to quiet hours was set on in RFC3339 format, it's
+	// empty for alarms that weren't deferred. ScheduledTime of deferred alarms stays unchanged
+	DeferredTo string `json:"deferredTo,omitempty"`
+	// OriginTraceID and OriginRequestID identify a request that created alarm, they're empty
+	// for alarms recreated from saved events
+	OriginTraceID   string `json:"originTraceId,omitempty"`
+	OriginRequestID string `json:"originRequestId,omitempty"`
 }
 
+// errEventDeleted is returned when alarm fires for an event that no longer exists
+var errEventDeleted = errors.New("event deleted")
+
 type SnsApiClient interface {
 	Publish(context.Context, *sns.PublishInput, ...func(*sns.Options)) (*sns.PublishOutput, error)
 }
+type DynamoApiClient interface {
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+type SchedulerApiClient interface {
+	CreateSchedule(context.Context, *scheduler.CreateScheduleInput, ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	// TopicArn is a topic alarms are sent through
+	TopicArn            string
+	TableName           string
+	SettingsTableName   string
+	UsageTableName      string
+	DeliveriesTableName string
+	// RoleArn is a role scheduler assumes to invoke the function with deferred alarms
+	RoleArn string
+	// ScheduleGroup is a group deferred alarms are created in, the default group is used when it's empty
+	ScheduleGroup string
+	// DeadLetterArn is a queue deferred alarms scheduler fails to deliver are sent to
+	DeadLetterArn string
+	// MaxSegments is a number of SMS segments a single message can take
+	MaxSegments int
+	// MonthlyQuota is a number of SMS segments user can use in a month
+	MonthlyQuota int
+	// Stage is a dimension of metrics handler emits
+	Stage string
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		TopicArn:            env.Required("SNS_TOPIC_ARN"),
+		TableName:           env.Required("DYNAMO_TABLE_NAME"),
+		SettingsTableName:   env.Required("SETTINGS_TABLE_NAME"),
+		UsageTableName:      env.Required("USAGE_TABLE_NAME"),
+		DeliveriesTableName: env.Required("DELIVERIES_TABLE_NAME"),
+		RoleArn:             env.Required("ROLE_ARN"),
+		ScheduleGroup:       env.Optional("SCHEDULE_GROUP_NAME"),
+		DeadLetterArn:       env.Optional("DEAD_LETTER_QUEUE_ARN"),
+		MaxSegments:         env.Int("MAX_SMS_SEGMENTS", sms.DefaultMaxSegments, 1),
+		MonthlyQuota:        env.Int("MONTHLY_SMS_QUOTA", usage.DefaultMonthlyQuota, 0),
+		Stage:               env.Optional("STAGE"),
+	}
+	return config, env.Err()
+}
 
 type Handler struct {
-	SNSClient SnsApiClient
+	SNSClient       SnsApiClient
+	DynamoClient    DynamoApiClient
+	SchedulerClient SchedulerApiClient
+	Config          Config
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
+	// Metrics are written to stdout unless writer of the emitter is set
+	Metrics metrics.Emitter
+}
+
+// Handle delivers an alarm. Its trace is annotated with trace and ID of a request that created
+// the alarm, so that it can be found from the request and the other way round
+func (h *Handler) Handle(ctx context.Context, event AlarmEvent) (err error) {
+	ctx = logging.With(ctx, logging.KeyUserID, event.UserID)
+	ctx = logging.With(ctx, logging.KeyEventID, event.EventID)
+	ctx = logging.With(ctx, logging.KeyOriginTraceID, event.OriginTraceID)
+	ctx = logging.With(ctx, logging.KeyOriginRequestID, event.OriginRequestID)
+	defer func() {
+		if err != nil {
+			h.Metrics.Record(ctx, metrics.ChannelSMS, metrics.Metric{Name: "AlarmFailures", Value: 1, Unit: metrics.Count})
+		}
+	}()
+
+	return tracing.Capture(ctx, "Alarm", map[string]string{
+		tracing.AnnotationOriginTraceID:   event.OriginTraceID,
+		tracing.AnnotationOriginRequestID: event.OriginRequestID,
+	}, func(ctx context.Context) error {
+		return h.deliver(ctx, event)
+	})
+}
+
+// deliver sends an alarm unless quiet hours or quota prevent it, and saves the outcome in delivery log
+func (h *Handler) deliver(ctx context.Context, event AlarmEvent) error {
+	now := time.Now()
+	if h.Now != nil {
+		now = h.Now()
+	}
+
+	if !event.IgnoreQuietHours {
+		settings, err := h.getQuietHours(ctx, event.UserID)
+		if err != nil {
+			return err
+		}
+
+		if settings != nil {
+			quiet, end, err := settings.Check(now)
+			if err != nil {
+				return err
+			}
+			if quiet && settings.Policy == quiethours.PolicyDefer {
+				if err := h.deferAlarm(ctx, event, now, end, settings.Timezone); err != nil {
+					return err
+				}
+				slog.InfoContext(ctx, "alarm deferred due to quiet hours", "until", end.Format(time.RFC3339))
+				return h.logDelivery(ctx, event, DeliveryDeferred, now)
+			}
+			if quiet {
+				slog.InfoContext(ctx, "alarm dropped due to quiet hours")
+				return h.logDelivery(ctx, event, DeliveryDropped, now)
+			}
+		}
+	}
+
+	message, err := h.renderMessage(ctx, event, now)
+	if errors.Is(err, errEventDeleted) {
+		slog.InfoContext(ctx, "alarm skipped as event no longer exists")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Alarms created before phone labels were introduced don't specify any phones
+	// and are delivered to default phone number
+	phones := phonebook.Labels(event.Phones)
+
+	allowed, err := h.chargeUsage(ctx, event, len(phones), sms.Analyze(message).Segments*len(phones), now)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		slog.InfoContext(ctx, "alarm not sent as user exceeded monthly quota")
+		if err := h.notifyOverQuota(ctx, event.UserID, now); err != nil {
+			return err
+		}
+		return h.logDelivery(ctx, event, DeliveryOverQuota, now)
+	}
+
+	if err := h.publish(ctx, event.UserID, message, phones); err != nil {
+		return err
+	}
+	sent := []metrics.Metric{{Name: "AlarmsSent", Value: float64(len(phones)), Unit: metrics.Count}}
+	// Delay between time alarm was set on and time it was sent, deferred alarms are measured
+	// from the end of quiet hours they were deferred to rather than from their scheduled time
+	setOn := event.ScheduledTime
+	if event.DeferredTo != "" {
+		setOn = event.DeferredTo
+	}
+	if scheduled, err := time.Parse(time.RFC3339, setOn); err == nil {
+		sent = append(sent, metrics.Metric{Name: "FireDelay", Value: float64(now.Sub(scheduled).Milliseconds()), Unit: metrics.Milliseconds})
+	}
+	h.Metrics.Record(ctx, metrics.ChannelSMS, sent...)
+
+	return h.logDelivery(ctx, event, DeliverySent, now)
 }
 
-func (h *Handler) Handle(event AlarmEvent) error {
+func (h *Handler) publish(ctx context.Context, userID, message string, phones []string) error {
+	labels, err := json.Marshal(phones)
+	if err != nil {
+		return err
+	}
 
-	if _, err := h.SNSClient.Publish(context.Background(), &sns.PublishInput{
-		TopicArn: aws.String(os.Getenv("SNS_TOPIC_ARN")),
-		Message:  &event.Message,
+	_, err = h.SNSClient.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(h.Config.TopicArn),
+		Message:  &message,
 		MessageAttributes: map[string]types.MessageAttributeValue{
 			"userID": {
 				DataType:    aws.String("String"),
-				StringValue: &event.UserID,
+				StringValue: &userID,
+			},
+			phonebook.LabelAttribute: {
+				DataType:    aws.String("String.Array"),
+				StringValue: aws.String(string(labels)),
 			},
 		},
-	}); err != nil {
+	})
+	return err
+}
+
+// chargeUsage adds sent messages and segments to user's monthly usage, reporting false
+// without changing it when that would exceed monthly quota. Fires are remembered in usage,
+// so a retried invocation of an alarm that was already charged is allowed without charging again
+func (h *Handler) chargeUsage(ctx context.Context, event AlarmEvent, messages, segments int, now time.Time) (bool, error) {
+	remaining := h.Config.MonthlyQuota - segments
+	if remaining < 0 {
+		return false, nil
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(h.Config.UsageTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: event.UserID},
+			"Month":  &dynamotypes.AttributeValueMemberS{Value: usage.Month(now)},
+		},
+		// Segments is a reserved word of DynamoDB, so all attributes are referred to by names
+		UpdateExpression:    aws.String("ADD #segments :segments, #messages :messages"),
+		ConditionExpression: aws.String("attribute_not_exists(#segments) OR #segments <= :remaining"),
+		ExpressionAttributeNames: map[string]string{
+			"#segments": "Segments",
+			"#messages": "Messages",
+		},
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":segments":  &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(segments)},
+			":messages":  &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(messages)},
+			":remaining": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(remaining)},
+		},
+	}
+	// Alarms invoked without scheduled time can't be told apart from their retries
+	fire := event.EventID + "@" + event.ScheduledTime
+	if event.ScheduledTime != "" {
+		input.UpdateExpression = aws.String("ADD #segments :segments, #messages :messages, #fires :fires")
+		input.ConditionExpression = aws.String("NOT contains(#fires, :fire) AND (attribute_not_exists(#segments) OR #segments <= :remaining)")
+		input.ExpressionAttributeNames["#fires"] = "Fires"
+		input.ExpressionAttributeValues[":fire"] = &dynamotypes.AttributeValueMemberS{Value: fire}
+		input.ExpressionAttributeValues[":fires"] = &dynamotypes.AttributeValueMemberSS{Value: []string{fire}}
+		input.ReturnValuesOnConditionCheckFailure = dynamotypes.ReturnValuesOnConditionCheckFailureAllOld
+	}
+
+	_, err := h.DynamoClient.UpdateItem(ctx, input)
+	var conditionErr *dynamotypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		fires, _ := conditionErr.Item["Fires"].(*dynamotypes.AttributeValueMemberSS)
+		return fires != nil && slices.Contains(fires.Value, fire), nil
+	}
+	return err == nil, err
+}
+
+// notifyOverQuota lets user know on default phone number that alarms won't be sent until
+// the end of the month. It's done once a month
+func (h *Handler) notifyOverQuota(ctx context.Context, userID string, now time.Time) error {
+	_, err := h.DynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(h.Config.UsageTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			"Month":  &dynamotypes.AttributeValueMemberS{Value: usage.Month(now)},
+		},
+		UpdateExpression:    aws.String("SET Notified = :notified"),
+		ConditionExpression: aws.String("attribute_not_exists(Notified)"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":notified": &dynamotypes.AttributeValueMemberBOOL{Value: true},
+		},
+	})
+	var conditionErr *dynamotypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.publish(ctx, userID, overQuotaMessage, []string{phonebook.DefaultLabel})
+}
+
+// renderMessage evaluates message template of an event and cuts the result down to configured number of SMS segments
+func (h *Handler) renderMessage(ctx context.Context, event AlarmEvent, now time.Time) (string, error) {
+	maxSegments := h.Config.MaxSegments
+
+	tmpl, err := msgtemplate.Parse(event.Message, event.Variables)
+	if err != nil {
+		// Messages of events created before templating was introduced weren't validated
+		// so they are sent as they are
+		return sms.Truncate(event.Message, maxSegments), nil
+	}
+
+	fireTime := now
+	if event.ScheduledTime != "" {
+		if fireTime, err = time.Parse(time.RFC3339, event.ScheduledTime); err != nil {
+			return "", err
+		}
+	}
+	loc := time.UTC
+	if event.Timezone != "" {
+		if loc, err = time.LoadLocation(event.Timezone); err != nil {
+			return "", err
+		}
+	}
+
+	var occurrence int
+	if tmpl.Uses(msgtemplate.VarOccurrence) {
+		if occurrence, err = h.countOccurrence(ctx, event); err != nil {
+			return "", err
+		}
+	}
+
+	return sms.Truncate(tmpl.Execute(msgtemplate.Data{
+		Time:       fireTime.In(loc),
+		Timezone:   event.Timezone,
+		Title:      event.Title,
+		Occurrence: occurrence,
+	}), maxSegments), nil
+}
+
+// countOccurrence increments the number of times alarms of an event fired and returns it. The last
+// fire counted is saved along with the counter, so that a retried invocation gets the same number
+func (h *Handler) countOccurrence(ctx context.Context, event AlarmEvent) (int, error) {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(h.Config.TableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID":  &dynamotypes.AttributeValueMemberS{Value: event.UserID},
+			"EventID": &dynamotypes.AttributeValueMemberS{Value: event.EventID},
+		},
+		UpdateExpression:    aws.String("ADD Occurrences :one"),
+		ConditionExpression: aws.String("attribute_exists(EventID)"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":one": &dynamotypes.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: dynamotypes.ReturnValueUpdatedNew,
+	}
+	if event.ScheduledTime != "" {
+		input.UpdateExpression = aws.String("ADD Occurrences :one SET LastFire = :fire")
+		input.ConditionExpression = aws.String("attribute_exists(EventID) AND (attribute_not_exists(LastFire) OR LastFire <> :fire)")
+		input.ExpressionAttributeValues[":fire"] = &dynamotypes.AttributeValueMemberS{Value: event.ScheduledTime}
+		input.ReturnValuesOnConditionCheckFailure = dynamotypes.ReturnValuesOnConditionCheckFailureAllOld
+	}
+
+	res, err := h.DynamoClient.UpdateItem(ctx, input)
+	var conditionErr *dynamotypes.ConditionalCheckFailedException
+	if errors.As(err, &conditionErr) {
+		if len(conditionErr.Item) == 0 {
+			return 0, errEventDeleted
+		}
+		// The fire was already counted by an invocation that failed later on
+		return occurrences(conditionErr.Item)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return occurrences(res.Attributes)
+}
+
+// occurrences returns occurrence counter of an event item
+func occurrences(item map[string]dynamotypes.AttributeValue) (int, error) {
+	value, ok := item["Occurrences"].(*dynamotypes.AttributeValueMemberN)
+	if !ok {
+		return 0, errors.New("occurrence counter not returned")
+	}
+	return strconv.Atoi(value.Value)
+}
+
+// getQuietHours returns quiet hours settings of a user or nil if user didn't set them
+func (h *Handler) getQuietHours(ctx context.Context, userID string) (*quiethours.Settings, error) {
+	res, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(h.Config.SettingsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := res.Item["QuietHours"]
+	if !ok {
+		return nil, nil
+	}
+	return quiethours.FromAttributeValue(value)
+}
+
+// deferAlarm creates one-time schedule that invokes this function again with the same event when quiet hours end.
+// Schedule is named after the time alarm was set on and the time it was already deferred to, so retries
+// of an invocation don't defer it twice, while alarm deferred again, e.g. after quiet hours changed, gets a new schedule
+func (h *Handler) deferAlarm(ctx context.Context, event AlarmEvent, now, end time.Time, timezone string) error {
+	lc, ok := lambdacontext.FromContext(ctx)
+	if !ok {
+		return errors.New("lambda context not found")
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	fireTime := now
+	if scheduledTime, err := time.Parse(time.RFC3339, event.ScheduledTime); err == nil {
+		fireTime = scheduledTime
+	}
+	var deferredTo time.Time
+	if event.DeferredTo != "" {
+		if deferredTo, err = time.Parse(time.RFC3339, event.DeferredTo); err != nil {
+			return err
+		}
+	}
+
+	event.DeferredTo = end.Format(time.RFC3339)
+	lambdaInput, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	input := &scheduler.CreateScheduleInput{
+		ActionAfterCompletion:      schedulertypes.ActionAfterCompletionDelete,
+		Description:                aws.String(fmt.Sprintf("Alarm of event %s deferred due to quiet hours", event.EventID)),
+		Name:                       aws.String(schedule.DeferredName(event.UserID, event.EventID, fireTime, deferredTo)),
+		GroupName:                  h.Config.scheduleGroup(),
+		ScheduleExpression:         aws.String(fmt.Sprintf("at(%s)", end.In(loc).Format("2006-01-02T15:04:05"))),
+		ScheduleExpressionTimezone: &timezone,
+		Target: &schedulertypes.Target{
+			Arn:     &lc.InvokedFunctionArn,
+			RoleArn: aws.String(h.Config.RoleArn),
+			Input:   aws.String(string(lambdaInput)),
+		},
+		FlexibleTimeWindow: &schedulertypes.FlexibleTimeWindow{
+			Mode: schedulertypes.FlexibleTimeWindowModeOff,
+		},
+	}
+	if h.Config.DeadLetterArn != "" {
+		input.Target.DeadLetterConfig = &schedulertypes.DeadLetterConfig{Arn: aws.String(h.Config.DeadLetterArn)}
+	}
+
+	_, err = h.SchedulerClient.CreateSchedule(ctx, input)
+	var conflictErr *schedulertypes.ConflictException
+	if errors.As(err, &conflictErr) {
+		return nil
+	}
+	return err
+}
+
+// scheduleGroup returns a name of schedule group alarms are created in, nil stands for the default group
+func (c Config) scheduleGroup() *string {
+	if c.ScheduleGroup == "" {
+		return nil
+	}
+	return aws.String(c.ScheduleGroup)
+}
+
+// logDelivery saves the outcome of an alarm in user's delivery log
+func (h *Handler) logDelivery(ctx context.Context, event AlarmEvent, status string, now time.Time) error {
+	_, err := h.DynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(h.Config.DeliveriesTableName),
+		Item: map[string]dynamotypes.AttributeValue{
+			"UserID":     &dynamotypes.AttributeValueMemberS{Value: event.UserID},
+			"DeliveryID": &dynamotypes.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339Nano) + "#" + uuid.NewString()},
+			"EventID":    &dynamotypes.AttributeValueMemberS{Value: event.EventID},
+			"Status":     &dynamotypes.AttributeValueMemberS{Value: status},
+			"ExpireOn":   &dynamotypes.AttributeValueMemberN{Value: fmt.Sprint(now.Add(deliveryLogRetention).Unix())},
+		},
+	})
+	return err
 }
@@ -0,0 +1,588 @@
+package alarmexecutor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+	alarmexecutor "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+type mockSns struct {
+	input    *sns.PublishInput
+	messages []string
+	// failures is a number of publishes that fail before the first one succeeds
+	failures int
+}
+
+func (m *mockSns) Publish(ctx context.Context, input *sns.PublishInput, opts ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	if m.failures > 0 {
+		m.failures--
+		return nil, errors.New("throttled")
+	}
+	m.input = input
+	m.messages = append(m.messages, *input.Message)
+	return &sns.PublishOutput{}, nil
+}
+
+type mockDynamo struct {
+	quietHours   *quiethours.Settings
+	deliveries   []string
+	occurrences  int
+	eventDeleted bool
+	segments     int
+	notified     bool
+	// fires charged in usage and the last fire counted in occurrences
+	fires    []string
+	lastFire string
+	// usageInput is the last update charging usage
+	usageInput *dynamodb.UpdateItemInput
+}
+
+func (m *mockDynamo) GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if m.quietHours == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{
+		Item: map[string]dynamotypes.AttributeValue{
+			"QuietHours": m.quietHours.ToAttributeValue(),
+		},
+	}, nil
+}
+func (m *mockDynamo) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	m.deliveries = append(m.deliveries, input.Item["Status"].(*dynamotypes.AttributeValueMemberS).Value)
+	return nil, nil
+}
+
+func (m *mockDynamo) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if _, ok := input.Key["Month"]; ok {
+		return m.updateUsage(input)
+	}
+	if m.eventDeleted {
+		return nil, &dynamotypes.ConditionalCheckFailedException{}
+	}
+	if fire, ok := input.ExpressionAttributeValues[":fire"].(*dynamotypes.AttributeValueMemberS); ok {
+		if fire.Value == m.lastFire {
+			return nil, &dynamotypes.ConditionalCheckFailedException{Item: map[string]dynamotypes.AttributeValue{
+				"Occurrences": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(m.occurrences)},
+			}}
+		}
+		m.lastFire = fire.Value
+	}
+	m.occurrences++
+	return &dynamodb.UpdateItemOutput{
+		Attributes: map[string]dynamotypes.AttributeValue{
+			"Occurrences": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(m.occurrences)},
+		},
+	}, nil
+}
+
+func (m *mockDynamo) updateUsage(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	if strings.HasPrefix(*input.UpdateExpression, "SET Notified") {
+		if m.notified {
+			return nil, &dynamotypes.ConditionalCheckFailedException{}
+		}
+		m.notified = true
+		return &dynamodb.UpdateItemOutput{}, nil
+	}
+
+	m.usageInput = input
+	segments, _ := strconv.Atoi(input.ExpressionAttributeValues[":segments"].(*dynamotypes.AttributeValueMemberN).Value)
+	remaining, _ := strconv.Atoi(input.ExpressionAttributeValues[":remaining"].(*dynamotypes.AttributeValueMemberN).Value)
+	fire, ok := input.ExpressionAttributeValues[":fire"].(*dynamotypes.AttributeValueMemberS)
+	if ok && slices.Contains(m.fires, fire.Value) {
+		return nil, &dynamotypes.ConditionalCheckFailedException{Item: map[string]dynamotypes.AttributeValue{
+			"Fires": &dynamotypes.AttributeValueMemberSS{Value: m.fires},
+		}}
+	}
+	if m.segments > remaining {
+		return nil, &dynamotypes.ConditionalCheckFailedException{}
+	}
+	m.segments += segments
+	if ok {
+		m.fires = append(m.fires, fire.Value)
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+type mockScheduler struct {
+	input *scheduler.CreateScheduleInput
+}
+
+func (m *mockScheduler) CreateSchedule(ctx context.Context, input *scheduler.CreateScheduleInput, opts ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error) {
+	m.input = input
+	return &scheduler.CreateScheduleOutput{}, nil
+}
+
+// config is configuration of the handler when no optional variables are set
+var config = alarmexecutor.Config{MaxSegments: sms.DefaultMaxSegments, MonthlyQuota: usage.DefaultMonthlyQuota}
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		event         alarmexecutor.AlarmEvent
+		expectedLabel string
+	}{
+		{
+			name:          "no phones",
+			event:         alarmexecutor.AlarmEvent{UserID: "1", Message: "some message"},
+			expectedLabel: `["default"]`,
+		},
+		{
+			name:          "labeled phones",
+			event:         alarmexecutor.AlarmEvent{UserID: "1", Message: "some message", Phones: []string{"work", "home"}},
+			expectedLabel: `["work","home"]`,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			snsClient := &mockSns{}
+			handler := alarmexecutor.Handler{SNSClient: snsClient, DynamoClient: &mockDynamo{}, SchedulerClient: &mockScheduler{}, Config: config}
+
+			if err := handler.Handle(context.Background(), tC.event); err != nil {
+				t.Fatalf("Error occured when handling event: %v", err)
+			}
+
+			if *snsClient.input.Message != tC.event.Message {
+				t.Errorf("Published message: %v is different than expected: %v", *snsClient.input.Message, tC.event.Message)
+			}
+			if userID := *snsClient.input.MessageAttributes["userID"].StringValue; userID != tC.event.UserID {
+				t.Errorf("Published userID: %v is different than expected: %v", userID, tC.event.UserID)
+			}
+			label := snsClient.input.MessageAttributes["phoneLabel"]
+			if *label.DataType != "String.Array" || *label.StringValue != tC.expectedLabel {
+				t.Errorf("Published phone labels: %v (%v) are different than expected: %v", *label.StringValue, *label.DataType, tC.expectedLabel)
+			}
+		})
+	}
+}
+
+func TestHandlerQuietHours(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	night := time.Date(2024, 3, 5, 23, 30, 0, 0, warsaw)
+	day := time.Date(2024, 3, 5, 12, 0, 0, 0, warsaw)
+
+	testCases := []struct {
+		name               string
+		event              alarmexecutor.AlarmEvent
+		policy             quiethours.Policy
+		now                time.Time
+		expectPublish      bool
+		expectedSchedule   string
+		expectedDeliveries []string
+	}{
+		{
+			name:               "outside of quiet hours",
+			event:              alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message"},
+			policy:             quiethours.PolicyDefer,
+			now:                day,
+			expectPublish:      true,
+			expectedDeliveries: []string{alarmexecutor.DeliverySent},
+		},
+		{
+			name:               "deferred",
+			event:              alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message", OriginTraceID: "1-5759e988-bd862e3fe1be46a994272793"},
+			policy:             quiethours.PolicyDefer,
+			now:                night,
+			expectedSchedule:   "at(2024-03-06T07:00:00)",
+			expectedDeliveries: []string{alarmexecutor.DeliveryDeferred},
+		},
+		{
+			name:               "dropped",
+			event:              alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message"},
+			policy:             quiethours.PolicyDrop,
+			now:                night,
+			expectedDeliveries: []string{alarmexecutor.DeliveryDropped},
+		},
+		{
+			name:               "event ignoring quiet hours",
+			event:              alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "take your pills", IgnoreQuietHours: true},
+			policy:             quiethours.PolicyDrop,
+			now:                night,
+			expectPublish:      true,
+			expectedDeliveries: []string{alarmexecutor.DeliverySent},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			snsClient := &mockSns{}
+			dynamoClient := &mockDynamo{quietHours: &quiethours.Settings{
+				Timezone: "Europe/Warsaw",
+				Policy:   tC.policy,
+				Windows:  []quiethours.Window{{Start: "22:00", End: "07:00"}},
+			}}
+			schedulerClient := &mockScheduler{}
+
+			handler := alarmexecutor.Handler{
+				SNSClient:       snsClient,
+				DynamoClient:    dynamoClient,
+				SchedulerClient: schedulerClient,
+				Config:          config,
+				Now:             func() time.Time { return tC.now },
+			}
+
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{InvokedFunctionArn: "executor_arn"})
+			if err := handler.Handle(ctx, tC.event); err != nil {
+				t.Fatalf("Error occured when handling event: %v", err)
+			}
+
+			if published := snsClient.input != nil; published != tC.expectPublish {
+				t.Errorf("Expected message to be published: %v, but got: %v", tC.expectPublish, published)
+			}
+
+			if tC.expectedSchedule == "" && schedulerClient.input != nil {
+				t.Errorf("Unexpected schedule created: %v", *schedulerClient.input.ScheduleExpression)
+			}
+			if tC.expectedSchedule != "" {
+				if schedulerClient.input == nil {
+					t.Fatalf("Expected schedule %v to be created", tC.expectedSchedule)
+				}
+				if *schedulerClient.input.ScheduleExpression != tC.expectedSchedule || *schedulerClient.input.ScheduleExpressionTimezone != "Europe/Warsaw" {
+					t.Errorf("Created schedule: %v (%v) is different than expected: %v", *schedulerClient.input.ScheduleExpression, *schedulerClient.input.ScheduleExpressionTimezone, tC.expectedSchedule)
+				}
+				if *schedulerClient.input.Target.Arn != "executor_arn" {
+					t.Errorf("Deferred alarm targets: %v instead of executor itself", *schedulerClient.input.Target.Arn)
+				}
+				if !strings.HasPrefix(*schedulerClient.input.Name, schedule.EventPrefix(tC.event.UserID, tC.event.EventID)) || !schedule.IsDeferred(*schedulerClient.input.Name) {
+					t.Errorf("Deferred alarm schedule: %v isn't named after its event", *schedulerClient.input.Name)
+				}
+				if !strings.Contains(*schedulerClient.input.Target.Input, tC.event.OriginTraceID) {
+					t.Errorf("Deferred alarm: %v isn't linked to request that created it", *schedulerClient.input.Target.Input)
+				}
+			}
+
+			if len(dynamoClient.deliveries) != len(tC.expectedDeliveries) || (len(tC.expectedDeliveries) > 0 && dynamoClient.deliveries[0] != tC.expectedDeliveries[0]) {
+				t.Errorf("Logged deliveries: %v are different than expected: %v", dynamoClient.deliveries, tC.expectedDeliveries)
+			}
+		})
+	}
+}
+
+func TestHandlerDeferredAgain(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	dynamoClient := &mockDynamo{quietHours: &quiethours.Settings{
+		Timezone: "Europe/Warsaw",
+		Policy:   quiethours.PolicyDefer,
+		Windows:  []quiethours.Window{{Start: "22:00", End: "07:00"}},
+	}}
+	schedulerClient := &mockScheduler{}
+	now := time.Date(2024, 3, 5, 23, 30, 0, 0, warsaw)
+	handler := alarmexecutor.Handler{
+		SNSClient:       &mockSns{},
+		DynamoClient:    dynamoClient,
+		SchedulerClient: schedulerClient,
+		Config:          config,
+		Now:             func() time.Time { return now },
+	}
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{InvokedFunctionArn: "executor_arn"})
+
+	event := alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message", ScheduledTime: "2024-03-05T22:30:00Z"}
+	if err := handler.Handle(ctx, event); err != nil {
+		t.Fatalf("Error occured when handling event: %v", err)
+	}
+	firstName := *schedulerClient.input.Name
+	var deferred alarmexecutor.AlarmEvent
+	if err := json.Unmarshal([]byte(*schedulerClient.input.Target.Input), &deferred); err != nil {
+		t.Fatalf("Error when decoding deferred alarm: %v", err)
+	}
+	if deferred.ScheduledTime != event.ScheduledTime || deferred.DeferredTo != "2024-03-06T07:00:00+01:00" {
+		t.Errorf("Received result: %v, %v is different than expected one: %v, %v", deferred.ScheduledTime, deferred.DeferredTo, event.ScheduledTime, "2024-03-06T07:00:00+01:00")
+	}
+
+	// Quiet hours were extended before deferred alarm fired
+	dynamoClient.quietHours.Windows = []quiethours.Window{{Start: "22:00", End: "08:00"}}
+	now = time.Date(2024, 3, 6, 7, 0, 0, 0, warsaw)
+	if err := handler.Handle(ctx, deferred); err != nil {
+		t.Fatalf("Error occured when handling deferred alarm: %v", err)
+	}
+	if *schedulerClient.input.Name == firstName || !schedule.IsDeferred(*schedulerClient.input.Name) {
+		t.Errorf("Alarm deferred again: %v isn't named differently than: %v", *schedulerClient.input.Name, firstName)
+	}
+	if *schedulerClient.input.ScheduleExpression != "at(2024-03-06T08:00:00)" {
+		t.Errorf("Received result: %v is different than expected one: %v", *schedulerClient.input.ScheduleExpression, "at(2024-03-06T08:00:00)")
+	}
+}
+
+func TestHandlerTemplate(t *testing.T) {
+	testCases := []struct {
+		name            string
+		event           alarmexecutor.AlarmEvent
+		occurrences     int
+		eventDeleted    bool
+		expectedMessage string
+	}{
+		{
+			name: "fire time and occurrence",
+			event: alarmexecutor.AlarmEvent{
+				UserID: "1", EventID: "1", Timezone: "Europe/Warsaw", ScheduledTime: "2024-03-05T08:50:00Z",
+				Message: "Stand-up in 10 min ({{weekday}} {{time}}, occurrence {{n}})",
+			},
+			occurrences:     6,
+			expectedMessage: "Stand-up in 10 min (Tuesday 09:50, occurrence 7)",
+		},
+		{
+			name: "countdown",
+			event: alarmexecutor.AlarmEvent{
+				UserID: "1", EventID: "1", Timezone: "America/New_York", ScheduledTime: "2024-03-05T03:00:00Z",
+				Title: "Thesis", Variables: map[string]string{"deadline": "2024-03-10"},
+				Message: "{{title}}: {{days_until deadline}} days left",
+			},
+			expectedMessage: "Thesis: 6 days left",
+		},
+		{
+			name: "legacy message",
+			event: alarmexecutor.AlarmEvent{
+				UserID: "1", EventID: "1", Message: "see {{ you",
+			},
+			expectedMessage: "see {{ you",
+		},
+		{
+			name: "too long message",
+			event: alarmexecutor.AlarmEvent{
+				UserID: "1", EventID: "1", Message: strings.Repeat("a", 500),
+			},
+			expectedMessage: strings.Repeat("a", 456) + "...",
+		},
+		{
+			name: "event deleted",
+			event: alarmexecutor.AlarmEvent{
+				UserID: "1", EventID: "1", Message: "occurrence {{n}}",
+			},
+			eventDeleted: true,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			snsClient := &mockSns{}
+			handler := alarmexecutor.Handler{
+				SNSClient:       snsClient,
+				DynamoClient:    &mockDynamo{occurrences: tC.occurrences, eventDeleted: tC.eventDeleted},
+				SchedulerClient: &mockScheduler{},
+				Config:          config,
+			}
+
+			if err := handler.Handle(context.Background(), tC.event); err != nil {
+				t.Fatalf("Error occured when handling event: %v", err)
+			}
+
+			if tC.eventDeleted {
+				if snsClient.input != nil {
+					t.Errorf("Unexpected message published: %v", *snsClient.input.Message)
+				}
+				return
+			}
+			if *snsClient.input.Message != tC.expectedMessage {
+				t.Errorf("Published message: %v is different than expected: %v", *snsClient.input.Message, tC.expectedMessage)
+			}
+		})
+	}
+}
+
+func TestHandlerQuota(t *testing.T) {
+	testCases := []struct {
+		name               string
+		event              alarmexecutor.AlarmEvent
+		segments           int
+		notified           bool
+		expectedMessages   []string
+		expectedSegments   int
+		expectedNotified   bool
+		expectedDeliveries []string
+	}{
+		{
+			name:               "within quota",
+			event:              alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message", Phones: []string{"work", "home"}},
+			segments:           298,
+			expectedMessages:   []string{"some message"},
+			expectedSegments:   300,
+			expectedDeliveries: []string{alarmexecutor.DeliverySent},
+		},
+		{
+			name:               "over quota",
+			event:              alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message", Phones: []string{"work", "home"}},
+			segments:           299,
+			expectedMessages:   []string{"You have reached your monthly limit of SMS reminders. Reminders won't be sent until the end of the month."},
+			expectedSegments:   299,
+			expectedNotified:   true,
+			expectedDeliveries: []string{alarmexecutor.DeliveryOverQuota},
+		},
+		{
+			name:               "over quota and already notified",
+			event:              alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message"},
+			segments:           300,
+			notified:           true,
+			expectedSegments:   300,
+			expectedNotified:   true,
+			expectedDeliveries: []string{alarmexecutor.DeliveryOverQuota},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			snsClient := &mockSns{}
+			dynamoClient := &mockDynamo{segments: tC.segments, notified: tC.notified}
+			handler := alarmexecutor.Handler{
+				SNSClient:       snsClient,
+				DynamoClient:    dynamoClient,
+				SchedulerClient: &mockScheduler{},
+				Config:          config,
+			}
+
+			if err := handler.Handle(context.Background(), tC.event); err != nil {
+				t.Fatalf("Error occured when handling event: %v", err)
+			}
+
+			if !reflect.DeepEqual(snsClient.messages, tC.expectedMessages) {
+				t.Errorf("Published messages: %v are different than expected: %v", snsClient.messages, tC.expectedMessages)
+			}
+			if dynamoClient.segments != tC.expectedSegments {
+				t.Errorf("Used segments: %v are different than expected: %v", dynamoClient.segments, tC.expectedSegments)
+			}
+			if dynamoClient.notified != tC.expectedNotified {
+				t.Errorf("User marked as notified: %v, expected: %v", dynamoClient.notified, tC.expectedNotified)
+			}
+			if !reflect.DeepEqual(dynamoClient.deliveries, tC.expectedDeliveries) {
+				t.Errorf("Logged deliveries: %v are different than expected: %v", dynamoClient.deliveries, tC.expectedDeliveries)
+			}
+		})
+	}
+}
+
+func TestHandlerRetry(t *testing.T) {
+	snsClient := &mockSns{failures: 1}
+	dynamoClient := &mockDynamo{}
+	handler := alarmexecutor.Handler{SNSClient: snsClient, DynamoClient: dynamoClient, SchedulerClient: &mockScheduler{}, Config: config}
+	event := alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "occurrence {{n}}", ScheduledTime: "2024-03-05T09:30:00Z"}
+
+	// Publishing fails after usage and occurrence are already saved, so the invocation is retried
+	if err := handler.Handle(context.Background(), event); err == nil {
+		t.Fatalf("Expected error when publishing fails")
+	}
+	if err := handler.Handle(context.Background(), event); err != nil {
+		t.Fatalf("Error occured when retrying event: %v", err)
+	}
+	next := event
+	next.ScheduledTime = "2024-03-06T09:30:00Z"
+	if err := handler.Handle(context.Background(), next); err != nil {
+		t.Fatalf("Error occured when handling next fire: %v", err)
+	}
+
+	expectedMessages := []string{"occurrence 1", "occurrence 2"}
+	if !reflect.DeepEqual(snsClient.messages, expectedMessages) {
+		t.Errorf("Received result: %v is different than expected one: %v", snsClient.messages, expectedMessages)
+	}
+	if dynamoClient.segments != 2 {
+		t.Errorf("Received result: %v is different than expected one: %v", dynamoClient.segments, 2)
+	}
+}
+
+func TestHandlerUsageAttributeNames(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		scheduledTime string
+		expectedNames map[string]string
+	}{
+		{
+			desc:          "without scheduled time",
+			expectedNames: map[string]string{"#segments": "Segments", "#messages": "Messages"},
+		},
+		{
+			desc:          "with scheduled time",
+			scheduledTime: "2024-03-05T09:30:00Z",
+			expectedNames: map[string]string{"#segments": "Segments", "#messages": "Messages", "#fires": "Fires"},
+		},
+	}
+
+	// Attributes are only allowed in expressions as names, as some of them are DynamoDB reserved words
+	placeholders := regexp.MustCompile(`[#:]\w+`)
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			dynamoClient := &mockDynamo{}
+			handler := alarmexecutor.Handler{SNSClient: &mockSns{}, DynamoClient: dynamoClient, SchedulerClient: &mockScheduler{}, Config: config}
+			event := alarmexecutor.AlarmEvent{UserID: "1", EventID: "1", Message: "some message", ScheduledTime: tC.scheduledTime}
+			if err := handler.Handle(context.Background(), event); err != nil {
+				t.Fatalf("Error occured when handling event: %v", err)
+			}
+
+			input := dynamoClient.usageInput
+			if !reflect.DeepEqual(input.ExpressionAttributeNames, tC.expectedNames) {
+				t.Errorf("Received result: %v is different than expected one: %v", input.ExpressionAttributeNames, tC.expectedNames)
+			}
+			for _, expression := range []string{*input.UpdateExpression, *input.ConditionExpression} {
+				for _, name := range tC.expectedNames {
+					if strings.Contains(placeholders.ReplaceAllString(expression, ""), name) {
+						t.Errorf("Expression %q refers to %s without a name", expression, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerMetrics(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    alarmexecutor.AlarmEvent
+		expected map[string]interface{}
+	}{
+		{
+			name:     "sent on time",
+			event:    alarmexecutor.AlarmEvent{UserID: "1", Message: "some message", Phones: []string{"work", "home"}, ScheduledTime: "2024-06-15T10:00:00Z"},
+			expected: map[string]interface{}{"Channel": "sms", "AlarmsSent": float64(2), "FireDelay": float64(2000)},
+		},
+		{
+			name:     "deferred",
+			event:    alarmexecutor.AlarmEvent{UserID: "1", Message: "some message", ScheduledTime: "2024-06-14T23:00:00Z", DeferredTo: "2024-06-15T10:00:00Z"},
+			expected: map[string]interface{}{"Channel": "sms", "AlarmsSent": float64(1), "FireDelay": float64(2000)},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := alarmexecutor.Handler{
+				SNSClient:       &mockSns{},
+				DynamoClient:    &mockDynamo{},
+				SchedulerClient: &mockScheduler{},
+				Config:          config,
+				Now:             func() time.Time { return time.Date(2024, 6, 15, 10, 0, 2, 0, time.UTC) },
+				Metrics:         metrics.Emitter{W: &buf},
+			}
+
+			if err := handler.Handle(context.Background(), tC.event); err != nil {
+				t.Fatalf("Error occured when handling event: %v", err)
+			}
+
+			var received map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &received); err != nil {
+				t.Fatalf("Error when decoding metrics %q: %v", buf.String(), err)
+			}
+			for name, value := range tC.expected {
+				if received[name] != value {
+					t.Errorf("Received %s: %v is different than expected one: %v", name, received[name], value)
+				}
+			}
+		})
+	}
+}
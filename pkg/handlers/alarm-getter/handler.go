@@ -2,8 +2,7 @@ package alarmgetter
 
 import (
 	"context"
-	"encoding/json"
-	"os"
+	"net/http"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -11,36 +10,50 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
 	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
 )
 
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	TableName string
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		TableName:      env.Required("DYNAMO_TABLE_NAME"),
+		AllowedOrigins: httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+	}
+	return config, env.Err()
+}
+
 type AlarmGetterHandler struct {
 	DynamoClient dynamodb.QueryAPIClient
+	Config       Config
 }
 
-func (h *AlarmGetterHandler) Handle(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-
-	claims, ok := request.RequestContext.Authorizer["claims"]
-	if !ok {
-		return errors.Unauthorized("authorization data not found")
-	}
-	userID, ok := claims.(map[string]interface{})["sub"].(string)
-	if !ok {
-		return errors.Unauthorized("authorization data not found")
-	}
+func (h *AlarmGetterHandler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
 
-	response, err := h.DynamoClient.Query(context.Background(), &dynamodb.QueryInput{
+func (h *AlarmGetterHandler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	response, err := h.DynamoClient.Query(ctx, &dynamodb.QueryInput{
 		ExpressionAttributeNames: map[string]string{
 			"#userID": "UserID",
 		},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":userID": &types.AttributeValueMemberS{Value: userID},
+			":userID": &types.AttributeValueMemberS{Value: principal.UserID},
 		},
 		KeyConditionExpression: aws.String("#userID = :userID"),
-		TableName:              aws.String(os.Getenv("DYNAMO_TABLE_NAME")),
+		TableName:              aws.String(h.Config.TableName),
 	})
 	if err != nil {
-		return errors.Internal(err)
+		return errors.Internal(ctx, err)
 	}
 
 	result := []map[string]interface{}{}
@@ -48,20 +61,5 @@ func (h *AlarmGetterHandler) Handle(request events.APIGatewayProxyRequest) (even
 		result = append(result, dynamomapper.SimplifyDynamoDBItem(item))
 	}
 
-	responseJSON, err := json.Marshal(result)
-	if err != nil {
-		return errors.Internal(err)
-	}
-
-	return events.APIGatewayProxyResponse{
-		Body: string(responseJSON),
-		Headers: map[string]string{
-			"Content-Type":                     "application/json",
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Headers":     "Content-Type",
-			"Access-Control-Allow-Methods":     "OPTIONS, GET, POST, DELETE",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		StatusCode: 200,
-	}, nil
+	return httpx.JSON(ctx, http.StatusOK, result)
 }
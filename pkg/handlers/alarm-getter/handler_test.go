@@ -54,7 +54,7 @@ func TestHandler(t *testing.T) {
 			request: events.APIGatewayProxyRequest{
 				RequestContext: events.APIGatewayProxyRequestContext{},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -66,7 +66,19 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
+			expectedStatusCode: 401,
+		},
+		{
+			name: "claims of invalid type",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": "sub=1",
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -101,7 +113,7 @@ func TestHandler(t *testing.T) {
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			response, _ := handler.Handle(testCase.request)
+			response, _ := handler.Handle(context.Background(), testCase.request)
 			if response.Body != testCase.expectedBody {
 				t.Errorf("Expected response %v, but got %v", testCase.expectedBody, response.Body)
 			}
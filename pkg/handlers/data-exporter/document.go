@@ -0,0 +1,307 @@
+package dataexporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/ical"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+)
+
+// ZipMediaType is a media type of zip exports, API Gateway returns it as binary
+const ZipMediaType = "application/zip"
+
+// Names of files in zip exports
+const (
+	DocumentFile = "export.json"
+	CalendarFile = "events.ics"
+)
+
+// Calendars list alarms firing within calendarHorizon, at most calendarLimit of each schedule
+const (
+	calendarHorizon = 365 * 24 * time.Hour
+	calendarLimit   = 100
+)
+
+// Document is everything kept about a user. Items of tables are exported as they're stored,
+// without ID of the user and secrets such as verification codes
+type Document struct {
+	ExportedAt time.Time `json:"exportedAt"`
+	UserID     string    `json:"userId"`
+	Username   string    `json:"username"`
+	// Attributes are attributes of the user in Cognito
+	Attributes map[string]string        `json:"attributes"`
+	Phones     []map[string]interface{} `json:"phones"`
+	// PendingPhoneChange is a phone number waiting for verification
+	PendingPhoneChange map[string]interface{}   `json:"pendingPhoneChange,omitempty"`
+	QuietHours         map[string]interface{}   `json:"quietHours,omitempty"`
+	Usage              []map[string]interface{} `json:"usage"`
+	Events             []Event                  `json:"events"`
+	Deliveries         []map[string]interface{} `json:"deliveries"`
+
+	alarms []alarmschedule.Alarm
+}
+
+// Event is an event along with its schedules
+type Event struct {
+	Details   map[string]interface{} `json:"details"`
+	Schedules []Schedule             `json:"schedules"`
+}
+
+// Schedule describes a schedule of an event
+type Schedule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression,omitempty"`
+	Timezone   string `json:"timezone,omitempty"`
+	// State is a state of the schedule in Scheduler, it's empty when it isn't there, e.g. after
+	// one-time alarm fired
+	State        string     `json:"state,omitempty"`
+	NextFireTime *time.Time `json:"nextFireTime,omitempty"`
+	// Deferred schedules deliver alarms deferred due to quiet hours, they aren't saved with events
+	Deferred bool `json:"deferred,omitempty"`
+}
+
+// Assemble gathers data of an account from Cognito, all tables and Scheduler into a document
+func (h *Handler) Assemble(ctx context.Context, account Account) (*Document, error) {
+	now := h.now()
+	document := &Document{
+		ExportedAt: now.UTC(),
+		UserID:     account.UserID,
+		Username:   account.Username,
+		Attributes: make(map[string]string),
+	}
+
+	user, err := h.CognitoClient.AdminGetUser(ctx, &cognito.AdminGetUserInput{
+		UserPoolId: aws.String(h.Config.UserPoolID),
+		Username:   aws.String(account.Username),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("user couldn't be read: %w", err)
+	}
+	for _, attribute := range user.UserAttributes {
+		document.Attributes[aws.ToString(attribute.Name)] = aws.ToString(attribute.Value)
+	}
+
+	for _, list := range []struct {
+		tableName string
+		items     *[]map[string]interface{}
+	}{
+		{tableName: h.Config.PhonesTableName, items: &document.Phones},
+		{tableName: h.Config.UsageTableName, items: &document.Usage},
+		{tableName: h.Config.DeliveriesTableName, items: &document.Deliveries},
+	} {
+		items, err := h.query(ctx, list.tableName, account.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("items of %s couldn't be read: %w", list.tableName, err)
+		}
+		*list.items = make([]map[string]interface{}, 0, len(items))
+		for _, item := range items {
+			*list.items = append(*list.items, exported(item))
+		}
+	}
+
+	if document.PendingPhoneChange, err = h.getItem(ctx, h.Config.CodesTableName, account.UserID); err != nil {
+		return nil, fmt.Errorf("pending phone change couldn't be read: %w", err)
+	}
+	delete(document.PendingPhoneChange, "VerificationCode")
+	if document.QuietHours, err = h.getItem(ctx, h.Config.SettingsTableName, account.UserID); err != nil {
+		return nil, fmt.Errorf("quiet hours couldn't be read: %w", err)
+	}
+
+	if err := h.events(ctx, document, now); err != nil {
+		return nil, fmt.Errorf("events couldn't be read: %w", err)
+	}
+	return document, nil
+}
+
+// events fills in events of a document, their schedules are matched with the ones in Scheduler
+func (h *Handler) events(ctx context.Context, document *Document, now time.Time) error {
+	items, err := h.query(ctx, h.Config.AlarmsTableName, document.UserID)
+	if err != nil {
+		return err
+	}
+	states, err := h.scheduleStates(ctx, document.UserID)
+	if err != nil {
+		return err
+	}
+
+	deferred := make(map[string][]string)
+	for _, name := range sortedKeys(states) {
+		if _, eventID, ok := schedule.ParseName(name); ok && schedule.IsDeferred(name) {
+			deferred[eventID] = append(deferred[eventID], name)
+		}
+	}
+
+	document.Events = make([]Event, 0, len(items))
+	for _, item := range items {
+		simplified := dynamomapper.SimplifyDynamoDBItem(item)
+		event := Event{Details: exported(item), Schedules: []Schedule{}}
+
+		alarms := alarmschedule.FromEvent(simplified)
+		sort.Slice(alarms, func(i, j int) bool { return alarms[i].Name < alarms[j].Name })
+		for _, alarm := range alarms {
+			s := Schedule{Name: alarm.Name, Expression: alarm.Expression, Timezone: alarm.Timezone, State: states[alarm.Name]}
+			if next, ok := alarmschedule.Next(alarm, now); ok {
+				next = next.UTC()
+				s.NextFireTime = &next
+			}
+			event.Schedules = append(event.Schedules, s)
+		}
+		document.alarms = append(document.alarms, alarms...)
+
+		eventID, _ := simplified["EventID"].(string)
+		for _, name := range deferred[eventID] {
+			event.Schedules = append(event.Schedules, Schedule{Name: name, State: states[name], Deferred: true})
+		}
+		document.Events = append(document.Events, event)
+	}
+	return nil
+}
+
+// scheduleStates returns states of schedules of a user in the schedule group by their names
+func (h *Handler) scheduleStates(ctx context.Context, userID string) (map[string]string, error) {
+	input := &scheduler.ListSchedulesInput{
+		NamePrefix: aws.String(schedule.UserPrefix(userID)),
+	}
+	if h.Config.ScheduleGroup != "" {
+		input.GroupName = aws.String(h.Config.ScheduleGroup)
+	}
+
+	states := make(map[string]string)
+	for {
+		res, err := h.SchedulerClient.ListSchedules(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range res.Schedules {
+			states[aws.ToString(summary.Name)] = string(summary.State)
+		}
+
+		if res.NextToken == nil {
+			return states, nil
+		}
+		input.NextToken = res.NextToken
+	}
+}
+
+// Archive returns a zip archive with the document and a calendar of alarms firing within a year
+func Archive(document *Document) ([]byte, error) {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	file, err := archive.Create(DocumentFile)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+
+	if file, err = archive.Create(CalendarFile); err != nil {
+		return nil, err
+	}
+	if err := ical.Write(file, "-//Reminder//Export//EN", calendar(document), document.ExportedAt); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// calendar returns occurrences of alarms of a document, ordered by time
+func calendar(document *Document) []ical.Event {
+	var occurrences []ical.Event
+	for _, alarm := range document.alarms {
+		summary := alarm.Title
+		if summary == "" {
+			summary = alarm.Message
+		}
+		for _, fire := range alarmschedule.Fires(alarm, document.ExportedAt, document.ExportedAt.Add(calendarHorizon), calendarLimit) {
+			occurrences = append(occurrences, ical.Event{
+				UID:         fmt.Sprintf("%s-%d@reminder", alarm.Name, fire.Unix()),
+				Start:       fire,
+				Summary:     summary,
+				Description: alarm.Message,
+			})
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].Start.Before(occurrences[j].Start) })
+	return occurrences
+}
+
+// getItem returns the only item of a user in a table, it's nil when user doesn't have one
+func (h *Handler) getItem(ctx context.Context, tableName, userID string) (map[string]interface{}, error) {
+	res, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+		},
+	})
+	if err != nil || res.Item == nil {
+		return nil, err
+	}
+	return exported(res.Item), nil
+}
+
+// query returns all items of a user in a table
+func (h *Handler) query(ctx context.Context, tableName, userID string) ([]map[string]dynamotypes.AttributeValue, error) {
+	input := userQuery(tableName, userID)
+
+	var items []map[string]dynamotypes.AttributeValue
+	for {
+		res, err := h.DynamoClient.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, res.Items...)
+
+		if len(res.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
+}
+
+// userQuery returns input of a query of all items of a user in a table
+func userQuery(tableName, userID string) *dynamodb.QueryInput {
+	return &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("UserID = :userID"),
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":userID": &dynamotypes.AttributeValueMemberS{Value: userID},
+		},
+	}
+}
+
+// exported returns item the way it's exported, without ID of the user who's in the document already
+func exported(item map[string]dynamotypes.AttributeValue) map[string]interface{} {
+	simplified := dynamomapper.SimplifyDynamoDBItem(item)
+	delete(simplified, "UserID")
+	return simplified
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
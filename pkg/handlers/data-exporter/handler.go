@@ -0,0 +1,413 @@
+package dataexporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/google/uuid"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	pkgerrors "github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+)
+
+// Statuses of asynchronous exports
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+// Formats of exports
+const (
+	FormatJSON = "json"
+	// FormatZip is a zip archive with the JSON document and upcoming alarms in iCalendar format
+	FormatZip = "zip"
+)
+
+// DefaultSyncLimit is the largest number of events and deliveries exported in a response when
+// limit isn't configured
+const DefaultSyncLimit = 500
+
+// Retention is how long asynchronous exports are kept for
+const Retention = 7 * 24 * time.Hour
+
+// URLExpiry is how long links to asynchronous exports are valid for
+const URLExpiry = 15 * time.Minute
+
+type DynamoApiClient interface {
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+type CognitoApiClient interface {
+	AdminGetUser(context.Context, *cognito.AdminGetUserInput, ...func(*cognito.Options)) (*cognito.AdminGetUserOutput, error)
+}
+
+type SchedulerApiClient interface {
+	ListSchedules(context.Context, *scheduler.ListSchedulesInput, ...func(*scheduler.Options)) (*scheduler.ListSchedulesOutput, error)
+}
+
+type S3ApiClient interface {
+	PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+type PresignApiClient interface {
+	PresignGetObject(context.Context, *s3.GetObjectInput, ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+type LambdaApiClient interface {
+	Invoke(context.Context, *lambda.InvokeInput, ...func(*lambda.Options)) (*lambda.InvokeOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	AlarmsTableName     string
+	PhonesTableName     string
+	CodesTableName      string
+	SettingsTableName   string
+	DeliveriesTableName string
+	UsageTableName      string
+	// ExportsTableName is a table asynchronous exports are tracked in
+	ExportsTableName string
+	// BucketName is a bucket asynchronous exports are stored in
+	BucketName string
+	UserPoolID string
+	// ScheduleGroup is a group alarms are created in, the default group is used when it's empty
+	ScheduleGroup string
+	// FunctionName is a name of the function, it invokes itself to produce asynchronous exports
+	FunctionName string
+	// SyncLimit is the largest number of events and deliveries exported in a response, larger
+	// exports are produced asynchronously
+	SyncLimit int
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		AlarmsTableName:     env.Required("DYNAMO_TABLE_NAME"),
+		PhonesTableName:     env.Required("PHONES_TABLE_NAME"),
+		CodesTableName:      env.Required("CODES_TABLE_NAME"),
+		SettingsTableName:   env.Required("SETTINGS_TABLE_NAME"),
+		DeliveriesTableName: env.Required("DELIVERIES_TABLE_NAME"),
+		UsageTableName:      env.Required("USAGE_TABLE_NAME"),
+		ExportsTableName:    env.Required("EXPORTS_TABLE_NAME"),
+		BucketName:          env.Required("EXPORTS_BUCKET_NAME"),
+		UserPoolID:          env.Required("USER_POOL_ID"),
+		ScheduleGroup:       env.Optional("SCHEDULE_GROUP_NAME"),
+		// Lambda runtime sets name of the function
+		FunctionName:   env.Required("AWS_LAMBDA_FUNCTION_NAME"),
+		SyncLimit:      env.Int("EXPORT_SYNC_LIMIT", DefaultSyncLimit, 0),
+		AllowedOrigins: httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+	}
+	return config, env.Err()
+}
+
+type Handler struct {
+	DynamoClient    DynamoApiClient
+	CognitoClient   CognitoApiClient
+	SchedulerClient SchedulerApiClient
+	S3Client        S3ApiClient
+	PresignClient   PresignApiClient
+	LambdaClient    LambdaApiClient
+	Config          Config
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
+}
+
+// Account is an account whose data is exported
+type Account struct {
+	UserID   string
+	Username string
+}
+
+// Job is an input of asynchronous export, the function invokes itself with it
+type Job struct {
+	UserID   string `json:"userID"`
+	Username string `json:"username"`
+	ExportID string `json:"exportID"`
+	Format   string `json:"format"`
+}
+
+// Status is a response describing asynchronous export, token is used to poll for it
+type Status struct {
+	Status string `json:"status"`
+	Token  string `json:"token"`
+	// URL is a link to download a ready export from, it expires at ExpiresAt
+	URL       string     `json:"url,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Invoke handles both GET /me/export requests of API Gateway and asynchronous exports the
+// function starts. Requests coming through API Gateway always have HTTP method, so users can't
+// start jobs directly
+func (h *Handler) Invoke(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var request events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, err
+	}
+	if request.HTTPMethod != "" {
+		return h.Handle(ctx, request)
+	}
+
+	var job Job
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	return nil, h.HandleJob(ctx, job)
+}
+
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
+
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if principal.Username == "" {
+		return pkgerrors.Unauthorized("authorization data not found")
+	}
+	if token, ok := request.QueryStringParameters["token"]; ok {
+		return h.poll(ctx, principal.UserID, token)
+	}
+
+	format := request.QueryStringParameters["format"]
+	if format == "" {
+		format = FormatJSON
+	}
+	if format != FormatJSON && format != FormatZip {
+		return pkgerrors.Validation("invalid query parameters", pkgerrors.FieldError{Field: "format", Message: "must be json or zip"})
+	}
+
+	account := Account{UserID: principal.UserID, Username: principal.Username}
+	size, err := h.size(ctx, account.UserID)
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	if size > h.Config.SyncLimit {
+		return h.start(ctx, Job{UserID: account.UserID, Username: account.Username, ExportID: uuid.NewString(), Format: format})
+	}
+
+	document, err := h.Assemble(ctx, account)
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	if format == FormatJSON {
+		return httpx.JSON(ctx, http.StatusOK, document)
+	}
+
+	archive, err := Archive(document)
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			"Content-Type":        ZipMediaType,
+			"Content-Disposition": `attachment; filename="export.zip"`,
+		},
+		Body:            base64.StdEncoding.EncodeToString(archive),
+		IsBase64Encoded: true,
+	}, nil
+}
+
+// start records asynchronous export and invokes the function with it
+func (h *Handler) start(ctx context.Context, job Job) (events.APIGatewayProxyResponse, error) {
+	now := h.now()
+	if _, err := h.DynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(h.Config.ExportsTableName),
+		Item: map[string]dynamotypes.AttributeValue{
+			"UserID":    &dynamotypes.AttributeValueMemberS{Value: job.UserID},
+			"ExportID":  &dynamotypes.AttributeValueMemberS{Value: job.ExportID},
+			"Status":    &dynamotypes.AttributeValueMemberS{Value: StatusPending},
+			"Format":    &dynamotypes.AttributeValueMemberS{Value: job.Format},
+			"CreatedAt": &dynamotypes.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+			"ExpireOn":  &dynamotypes.AttributeValueMemberN{Value: fmt.Sprint(now.Add(Retention).Unix())},
+		},
+	}); err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	if _, err := h.LambdaClient.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(h.Config.FunctionName),
+		InvocationType: lambdatypes.InvocationTypeEvent,
+		Payload:        payload,
+	}); err != nil {
+		if err := h.setStatus(ctx, job, StatusFailed, ""); err != nil {
+			slog.ErrorContext(ctx, "export couldn't be marked as failed", "error", err)
+		}
+		return pkgerrors.Internal(ctx, err)
+	}
+
+	slog.InfoContext(ctx, "export started", "exportId", job.ExportID)
+	return httpx.JSON(ctx, http.StatusAccepted, Status{Status: StatusPending, Token: job.ExportID})
+}
+
+// poll returns status of asynchronous export of a user, ready ones come with a link to download them
+func (h *Handler) poll(ctx context.Context, userID, token string) (events.APIGatewayProxyResponse, error) {
+	// Tokens are UUIDs, other values can't identify any export
+	if _, err := uuid.Parse(token); err != nil {
+		return pkgerrors.NotFound("export not found")
+	}
+
+	res, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(h.Config.ExportsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID":   &dynamotypes.AttributeValueMemberS{Value: userID},
+			"ExportID": &dynamotypes.AttributeValueMemberS{Value: token},
+		},
+	})
+	if err != nil {
+		return pkgerrors.Internal(ctx, err)
+	}
+	if res.Item == nil {
+		return pkgerrors.NotFound("export not found")
+	}
+
+	status, _ := res.Item["Status"].(*dynamotypes.AttributeValueMemberS)
+	key, _ := res.Item["ObjectKey"].(*dynamotypes.AttributeValueMemberS)
+	switch {
+	case status != nil && status.Value == StatusReady && key != nil:
+		request, err := h.PresignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(h.Config.BucketName),
+			Key:    aws.String(key.Value),
+		}, s3.WithPresignExpires(URLExpiry))
+		if err != nil {
+			return pkgerrors.Internal(ctx, err)
+		}
+		expiresAt := h.now().Add(URLExpiry).UTC()
+		return httpx.JSON(ctx, http.StatusOK, Status{Status: StatusReady, Token: token, URL: request.URL, ExpiresAt: &expiresAt})
+	case status != nil && status.Value == StatusFailed:
+		return pkgerrors.New(http.StatusInternalServerError, pkgerrors.CodeInternal, "export failed, request a new one").Response()
+	default:
+		return httpx.JSON(ctx, http.StatusAccepted, Status{Status: StatusPending, Token: token})
+	}
+}
+
+// HandleJob produces asynchronous export, stores it in the bucket and marks it as ready. Failed
+// exports are marked as such, so that polling for them stops
+func (h *Handler) HandleJob(ctx context.Context, job Job) error {
+	if job.UserID == "" || job.ExportID == "" {
+		return fmt.Errorf("invalid export job: %+v", job)
+	}
+	ctx = logging.With(ctx, logging.KeyUserID, job.UserID)
+
+	key := path.Join("exports", job.UserID, job.ExportID+"."+job.Format)
+	if err := h.export(ctx, job, key); err != nil {
+		if err := h.setStatus(ctx, job, StatusFailed, ""); err != nil {
+			slog.ErrorContext(ctx, "export couldn't be marked as failed", "error", err)
+		}
+		return fmt.Errorf("export %s failed: %w", job.ExportID, err)
+	}
+	if err := h.setStatus(ctx, job, StatusReady, key); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "export ready", "exportId", job.ExportID)
+	return nil
+}
+
+// export assembles document of a user and stores it in the bucket under given key
+func (h *Handler) export(ctx context.Context, job Job, key string) error {
+	document, err := h.Assemble(ctx, Account{UserID: job.UserID, Username: job.Username})
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	contentType := "application/json"
+	if job.Format == FormatZip {
+		body, err = Archive(document)
+		contentType = ZipMediaType
+	} else {
+		body, err = json.MarshalIndent(document, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = h.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(h.Config.BucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+	return err
+}
+
+// setStatus updates status of asynchronous export, key of its object is saved when it's given
+func (h *Handler) setStatus(ctx context.Context, job Job, status, key string) error {
+	expression := "SET #status = :status"
+	values := map[string]dynamotypes.AttributeValue{
+		":status": &dynamotypes.AttributeValueMemberS{Value: status},
+	}
+	if key != "" {
+		expression += ", ObjectKey = :key"
+		values[":key"] = &dynamotypes.AttributeValueMemberS{Value: key}
+	}
+
+	_, err := h.DynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(h.Config.ExportsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID":   &dynamotypes.AttributeValueMemberS{Value: job.UserID},
+			"ExportID": &dynamotypes.AttributeValueMemberS{Value: job.ExportID},
+		},
+		UpdateExpression:          aws.String(expression),
+		ExpressionAttributeNames:  map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
+
+// size returns the number of events and deliveries of a user, they make up most of an export
+func (h *Handler) size(ctx context.Context, userID string) (int, error) {
+	size := 0
+	for _, tableName := range []string{h.Config.AlarmsTableName, h.Config.DeliveriesTableName} {
+		input := userQuery(tableName, userID)
+		input.Select = dynamotypes.SelectCount
+		for {
+			res, err := h.DynamoClient.Query(ctx, input)
+			if err != nil {
+				return 0, err
+			}
+			size += int(res.Count)
+
+			if len(res.LastEvaluatedKey) == 0 {
+				break
+			}
+			input.ExclusiveStartKey = res.LastEvaluatedKey
+		}
+	}
+	return size, nil
+}
+
+func (h *Handler) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,388 @@
+package dataexporter_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	dataexporter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/data-exporter"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+)
+
+var config = dataexporter.Config{
+	AlarmsTableName:     "GO_AlarmTable",
+	PhonesTableName:     "GO_PhonesTable",
+	CodesTableName:      "GO_CodesTable",
+	SettingsTableName:   "GO_SettingsTable",
+	DeliveriesTableName: "GO_DeliveriesTable",
+	UsageTableName:      "GO_UsageTable",
+	ExportsTableName:    "GO_ExportsTable",
+	BucketName:          "exports",
+	UserPoolID:          "eu-central-1_GOReminder",
+	ScheduleGroup:       "GO_Alarms",
+	FunctionName:        "GO_DataExporter",
+	SyncLimit:           dataexporter.DefaultSyncLimit,
+}
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+type mockS3 struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (m *mockS3) PutObject(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[*input.Bucket+"/"+*input.Key] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+type mockPresigner struct{}
+
+func (mockPresigner) PresignGetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	return &v4.PresignedHTTPRequest{URL: "https://" + *input.Bucket + ".s3.amazonaws.com/" + *input.Key, Method: "GET"}, nil
+}
+
+type mockLambda struct {
+	payloads [][]byte
+}
+
+func (m *mockLambda) Invoke(ctx context.Context, input *lambda.InvokeInput, optFns ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	if *input.FunctionName != config.FunctionName || input.InvocationType != "Event" {
+		return nil, errors.New("unexpected invocation")
+	}
+	m.payloads = append(m.payloads, input.Payload)
+	return &lambda.InvokeOutput{StatusCode: 202}, nil
+}
+
+// newHandler returns handler with accounts of two users, "john" whose data is exported and "jane"
+// whose data mustn't leak into his export
+func newHandler(t *testing.T) *dataexporter.Handler {
+	dynamo := fakes.NewDynamoDB()
+	for _, table := range [][3]string{
+		{config.AlarmsTableName, "UserID", "EventID"},
+		{config.PhonesTableName, "UserID", "Label"},
+		{config.CodesTableName, "UserID", ""},
+		{config.SettingsTableName, "UserID", ""},
+		{config.DeliveriesTableName, "UserID", "DeliveryID"},
+		{config.UsageTableName, "UserID", "Month"},
+		{config.ExportsTableName, "UserID", "ExportID"},
+	} {
+		dynamo.CreateTable(table[0], table[1], table[2])
+	}
+	cognito := fakes.NewCognito()
+	schedules := fakes.NewScheduler(fakes.NewVirtualClock(now))
+	schedules.CreateGroup(config.ScheduleGroup)
+
+	put := func(tableName string, item map[string]dynamotypes.AttributeValue) {
+		if _, err := dynamo.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String(tableName), Item: item}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, user := range []struct{ name, sub string }{{"john", "1"}, {"jane", "2"}} {
+		cognito.AddUser(user.name, map[string]string{"sub": user.sub, "phone_number": "+4812345678" + user.sub})
+
+		put(config.AlarmsTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":   &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"EventID":  &dynamotypes.AttributeValueMemberS{Value: "1"},
+			"Title":    &dynamotypes.AttributeValueMemberS{Value: "Stand-up"},
+			"Message":  &dynamotypes.AttributeValueMemberS{Value: "{{title}} in 10 minutes"},
+			"Timezone": &dynamotypes.AttributeValueMemberS{Value: "Europe/Warsaw"},
+			"Crons": &dynamotypes.AttributeValueMemberM{Value: map[string]dynamotypes.AttributeValue{
+				schedule.Name(user.sub, "1", "0"): &dynamotypes.AttributeValueMemberS{Value: "0 10 ? * MON-FRI *"},
+			}},
+			"Dates": &dynamotypes.AttributeValueMemberM{Value: map[string]dynamotypes.AttributeValue{
+				schedule.Name(user.sub, "1", "1"): &dynamotypes.AttributeValueMemberS{Value: "2030-01-01T00:00:00"},
+			}},
+		})
+		for _, name := range []string{schedule.Name(user.sub, "1", "0"), schedule.Name(user.sub, "1", "q1")} {
+			if _, err := schedules.CreateSchedule(context.Background(), &scheduler.CreateScheduleInput{
+				Name:               aws.String(name),
+				GroupName:          aws.String(config.ScheduleGroup),
+				ScheduleExpression: aws.String("at(2030-01-01T00:00:00)"),
+				Target:             &schedulertypes.Target{Arn: aws.String("arn:aws:lambda:eu-central-1:000000000000:function:GO_AlarmExecutor")},
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		put(config.PhonesTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":      &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"Label":       &dynamotypes.AttributeValueMemberS{Value: "work"},
+			"PhoneNumber": &dynamotypes.AttributeValueMemberS{Value: "+4898765432" + user.sub},
+		})
+		put(config.CodesTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":           &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"Label":            &dynamotypes.AttributeValueMemberS{Value: "home"},
+			"PhoneNumber":      &dynamotypes.AttributeValueMemberS{Value: "+4811111111" + user.sub},
+			"VerificationCode": &dynamotypes.AttributeValueMemberS{Value: "123456"},
+		})
+		put(config.DeliveriesTableName, map[string]dynamotypes.AttributeValue{
+			"UserID":     &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"DeliveryID": &dynamotypes.AttributeValueMemberS{Value: "1"},
+		})
+		put(config.UsageTableName, map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: user.sub},
+			"Month":  &dynamotypes.AttributeValueMemberS{Value: "2024-05"},
+		})
+	}
+
+	return &dataexporter.Handler{
+		DynamoClient:    dynamo,
+		CognitoClient:   cognito,
+		SchedulerClient: schedules,
+		S3Client:        &mockS3{objects: make(map[string][]byte)},
+		PresignClient:   mockPresigner{},
+		LambdaClient:    &mockLambda{},
+		Config:          config,
+		Now:             func() time.Time { return now },
+	}
+}
+
+func exportRequest(query map[string]string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod:            "GET",
+		QueryStringParameters: query,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{
+				"claims": map[string]interface{}{
+					"sub":              "1",
+					"cognito:username": "john",
+				},
+			},
+		},
+	}
+}
+
+// checkDocument checks that document holds all data of john and nothing of jane
+func checkDocument(t *testing.T, data []byte) {
+	var document dataexporter.Document
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Error when decoding document: %v", err)
+	}
+
+	if document.UserID != "1" || document.Username != "john" || document.Attributes["phone_number"] != "+48123456781" {
+		t.Errorf("Received user: %v (%v) with attributes %v is different than expected", document.Username, document.UserID, document.Attributes)
+	}
+	if len(document.Phones) != 1 || document.Phones[0]["PhoneNumber"] != "+48987654321" || document.Phones[0]["UserID"] != nil {
+		t.Errorf("Received phones: %v are different than expected", document.Phones)
+	}
+	expectedChange := map[string]interface{}{"Label": "home", "PhoneNumber": "+48111111111"}
+	if !reflect.DeepEqual(document.PendingPhoneChange, expectedChange) {
+		t.Errorf("Received result: %v is different than expected one: %v", document.PendingPhoneChange, expectedChange)
+	}
+	if document.QuietHours != nil {
+		t.Errorf("Received quiet hours of user that didn't set them: %v", document.QuietHours)
+	}
+	if len(document.Deliveries) != 1 || len(document.Usage) != 1 {
+		t.Errorf("Received deliveries: %v and usage: %v are different than expected", document.Deliveries, document.Usage)
+	}
+
+	nextCron, nextDate := time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC), time.Date(2029, 12, 31, 23, 0, 0, 0, time.UTC)
+	expectedSchedules := []dataexporter.Schedule{
+		{Name: schedule.Name("1", "1", "0"), Expression: "cron(0 10 ? * MON-FRI *)", Timezone: "Europe/Warsaw", State: "ENABLED", NextFireTime: &nextCron},
+		// schedule of saved alarm is missing in Scheduler
+		{Name: schedule.Name("1", "1", "1"), Expression: "at(2030-01-01T00:00:00)", Timezone: "Europe/Warsaw", NextFireTime: &nextDate},
+		{Name: schedule.Name("1", "1", "q1"), State: "ENABLED", Deferred: true},
+	}
+	if len(document.Events) != 1 || document.Events[0].Details["Title"] != "Stand-up" {
+		t.Fatalf("Received events: %v are different than expected", document.Events)
+	}
+	if !reflect.DeepEqual(document.Events[0].Schedules, expectedSchedules) {
+		t.Errorf("Received result: %+v is different than expected one: %+v", document.Events[0].Schedules, expectedSchedules)
+	}
+}
+
+// checkArchive checks that archive has the document and a calendar of the cron alarm, capped
+// at 100 occurrences, the one-time alarm fires more than a year later
+func checkArchive(t *testing.T, data []byte) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Error when reading archive: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	if len(files) != 2 {
+		t.Fatalf("Received %d files in archive instead of 2", len(files))
+	}
+	checkDocument(t, files[dataexporter.DocumentFile])
+
+	calendar := string(files[dataexporter.CalendarFile])
+	if count := strings.Count(calendar, "BEGIN:VEVENT"); count != 100 {
+		t.Errorf("Received result: %v is different than expected one: %v", count, 100)
+	}
+	if !strings.Contains(calendar, "DTSTART:20240502T080000Z\r\nSUMMARY:Stand-up\r\n") {
+		t.Errorf("Calendar doesn't contain the next alarm: %q", calendar)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		query              map[string]string
+		expectedStatusCode int
+		check              func(t *testing.T, response events.APIGatewayProxyResponse)
+	}{
+		{
+			desc:               "json",
+			expectedStatusCode: 200,
+			check: func(t *testing.T, response events.APIGatewayProxyResponse) {
+				checkDocument(t, []byte(response.Body))
+			},
+		},
+		{
+			desc:               "zip",
+			query:              map[string]string{"format": "zip"},
+			expectedStatusCode: 200,
+			check: func(t *testing.T, response events.APIGatewayProxyResponse) {
+				if !response.IsBase64Encoded || response.Headers["Content-Type"] != dataexporter.ZipMediaType {
+					t.Fatalf("Archive isn't returned as binary: %v", response.Headers)
+				}
+				data, err := base64.StdEncoding.DecodeString(response.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkArchive(t, data)
+			},
+		},
+		{
+			desc:               "invalid format",
+			query:              map[string]string{"format": "xml"},
+			expectedStatusCode: 400,
+		},
+		{
+			desc:               "unknown token",
+			query:              map[string]string{"token": "a8098c1a-f86e-11da-bd1a-00112444be1e"},
+			expectedStatusCode: 404,
+		},
+		{
+			desc:               "invalid token",
+			query:              map[string]string{"token": "export"},
+			expectedStatusCode: 404,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			response, err := newHandler(t).Handle(context.Background(), exportRequest(tC.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response.StatusCode != tC.expectedStatusCode {
+				t.Fatalf("Received status code: %v is different than expected one: %v, body: %s", response.StatusCode, tC.expectedStatusCode, response.Body)
+			}
+			if tC.check != nil {
+				tC.check(t, response)
+			}
+		})
+	}
+}
+
+func TestAsyncExport(t *testing.T) {
+	for _, format := range []string{dataexporter.FormatJSON, dataexporter.FormatZip} {
+		t.Run(format, func(t *testing.T) {
+			h := newHandler(t)
+			// john has an event and a delivery
+			h.Config.SyncLimit = 1
+			invoker := h.LambdaClient.(*mockLambda)
+
+			status := handle(t, h, map[string]string{"format": format}, 202)
+			if status.Status != dataexporter.StatusPending || status.Token == "" || len(invoker.payloads) != 1 {
+				t.Fatalf("Received result: %+v is different than expected one: %v", status, dataexporter.StatusPending)
+			}
+			if polled := handle(t, h, map[string]string{"token": status.Token}, 202); polled.Status != dataexporter.StatusPending {
+				t.Errorf("Received result: %v is different than expected one: %v", polled.Status, dataexporter.StatusPending)
+			}
+
+			if _, err := h.Invoke(context.Background(), invoker.payloads[0]); err != nil {
+				t.Fatal(err)
+			}
+			key := "exports/1/" + status.Token + "." + format
+			polled := handle(t, h, map[string]string{"token": status.Token}, 200)
+			expectedURL := "https://exports.s3.amazonaws.com/" + key
+			if polled.Status != dataexporter.StatusReady || polled.URL != expectedURL || !polled.ExpiresAt.Equal(now.Add(dataexporter.URLExpiry)) {
+				t.Errorf("Received result: %+v is different than expected one: %v", polled, expectedURL)
+			}
+
+			object := h.S3Client.(*mockS3).objects["exports/"+key]
+			if format == dataexporter.FormatZip {
+				checkArchive(t, object)
+			} else {
+				checkDocument(t, object)
+			}
+
+			// exports can only be polled for by users that requested them
+			request := exportRequest(map[string]string{"token": status.Token})
+			request.RequestContext.Authorizer["claims"] = map[string]interface{}{"sub": "2", "cognito:username": "jane"}
+			if response, _ := h.Handle(context.Background(), request); response.StatusCode != 404 {
+				t.Errorf("Received status code: %v is different than expected one: %v", response.StatusCode, 404)
+			}
+		})
+	}
+}
+
+func TestAsyncExportFails(t *testing.T) {
+	h := newHandler(t)
+	h.Config.SyncLimit = 1
+	h.S3Client.(*mockS3).err = errors.New("access denied")
+
+	status := handle(t, h, nil, 202)
+	if _, err := h.Invoke(context.Background(), h.LambdaClient.(*mockLambda).payloads[0]); err == nil {
+		t.Errorf("Failed export succeeded")
+	}
+	handle(t, h, map[string]string{"token": status.Token}, 500)
+}
+
+// handle sends request to the handler and decodes status of export it responds with
+func handle(t *testing.T, h *dataexporter.Handler, query map[string]string, expectedStatusCode int) dataexporter.Status {
+	t.Helper()
+	payload, _ := json.Marshal(exportRequest(query))
+	result, err := h.Invoke(context.Background(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := result.(events.APIGatewayProxyResponse)
+	if response.StatusCode != expectedStatusCode {
+		t.Fatalf("Received status code: %v is different than expected one: %v, body: %s", response.StatusCode, expectedStatusCode, response.Body)
+	}
+
+	var status dataexporter.Status
+	if expectedStatusCode < 300 {
+		if err := json.Unmarshal([]byte(response.Body), &status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return status
+}
@@ -0,0 +1,78 @@
+package phonegetter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+)
+
+type DynamoApiClient interface {
+	Query(context.Context, *dynamodb.QueryInput, ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	PhonesTableName string
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		PhonesTableName: env.Required("PHONES_TABLE_NAME"),
+		AllowedOrigins:  httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+	}
+	return config, env.Err()
+}
+
+type Handler struct {
+	DynamoClient DynamoApiClient
+	Config       Config
+}
+
+type Phone struct {
+	Label       string `json:"label"`
+	PhoneNumber string `json:"phone_number"`
+}
+
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
+
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	response, err := h.DynamoClient.Query(ctx, &dynamodb.QueryInput{
+		ExpressionAttributeNames: map[string]string{
+			"#userID": "UserID",
+		},
+		ExpressionAttributeValues: map[string]dynamotypes.AttributeValue{
+			":userID": &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
+		},
+		KeyConditionExpression: aws.String("#userID = :userID"),
+		TableName:              aws.String(h.Config.PhonesTableName),
+	})
+	if err != nil {
+		return errors.Internal(ctx, err)
+	}
+
+	result := []Phone{}
+	for _, item := range response.Items {
+		label, _ := item["Label"].(*dynamotypes.AttributeValueMemberS)
+		phoneNumber, _ := item["PhoneNumber"].(*dynamotypes.AttributeValueMemberS)
+		if label == nil || phoneNumber == nil {
+			continue
+		}
+		result = append(result, Phone{Label: label.Value, PhoneNumber: phoneNumber.Value})
+	}
+
+	return httpx.JSON(ctx, http.StatusOK, result)
+}
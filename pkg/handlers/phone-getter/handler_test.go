@@ -0,0 +1,120 @@
+package phonegetter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	phonegetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-getter"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type mockDynamo struct {
+	QueryError error
+}
+
+func (m *mockDynamo) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if m.QueryError != nil {
+		return nil, m.QueryError
+	}
+	userID := input.ExpressionAttributeValues[":userID"].(*dynamotypes.AttributeValueMemberS).Value
+
+	return &dynamodb.QueryOutput{
+		Items: []map[string]dynamotypes.AttributeValue{
+			{
+				"UserID":          &dynamotypes.AttributeValueMemberS{Value: userID},
+				"Label":           &dynamotypes.AttributeValueMemberS{Value: "default"},
+				"PhoneNumber":     &dynamotypes.AttributeValueMemberS{Value: "+11123456789"},
+				"SubscriptionArn": &dynamotypes.AttributeValueMemberS{Value: "some_arn"},
+			},
+			{
+				"UserID":          &dynamotypes.AttributeValueMemberS{Value: userID},
+				"Label":           &dynamotypes.AttributeValueMemberS{Value: "work"},
+				"PhoneNumber":     &dynamotypes.AttributeValueMemberS{Value: "+11987654321"},
+				"SubscriptionArn": &dynamotypes.AttributeValueMemberS{Value: "other_arn"},
+			},
+		},
+	}, nil
+}
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		name               string
+		request            events.APIGatewayProxyRequest
+		queryError         error
+		expectedBody       string
+		expectedStatusCode int
+	}{
+		{
+			name: "no authorizer",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
+			expectedStatusCode: 401,
+		},
+		{
+			name: "no sub",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{},
+					},
+				},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
+			expectedStatusCode: 401,
+		},
+		{
+			name: "query error",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			queryError:         errors.New("some error"),
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
+			expectedStatusCode: 500,
+		},
+		{
+			name: "success",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub": "1",
+						},
+					},
+				},
+			},
+			expectedBody:       `[{"label":"default","phone_number":"+11123456789"},{"label":"work","phone_number":"+11987654321"}]`,
+			expectedStatusCode: 200,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			handler := phonegetter.Handler{
+				DynamoClient: &mockDynamo{QueryError: tC.queryError},
+			}
+
+			res, err := handler.Handle(context.Background(), tC.request)
+			if err != nil {
+				t.Errorf("Error occured when handling request: %v", err)
+			}
+
+			if res.Body != tC.expectedBody {
+				t.Errorf("Received result: %v is different than expected one: %v", res.Body, tC.expectedBody)
+			}
+			if res.StatusCode != tC.expectedStatusCode {
+				t.Errorf("Received status code: %v is different than expected one: %v", res.StatusCode, tC.expectedStatusCode)
+			}
+		})
+	}
+}
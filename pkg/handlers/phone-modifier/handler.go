@@ -2,13 +2,15 @@ package phonemodifier
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
 	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -24,76 +26,84 @@ type DynamoApiClient interface {
 	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
 }
 
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	// CodesTableName is a table verification codes are saved in
+	CodesTableName string
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+	// Stage is a dimension of metrics handler emits
+	Stage string
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		CodesTableName: env.Required("DYNAMO_TABLE_NAME"),
+		AllowedOrigins: httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+		Stage:          env.Optional("STAGE"),
+	}
+	return config, env.Err()
+}
+
 type Handler struct {
 	SnsClient    SnsApiClient
 	DynamoClient DynamoApiClient
+	Config       Config
+	// Metrics are written to stdout unless writer of the emitter is set
+	Metrics metrics.Emitter
 }
 
-func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
 
-	claims, ok := request.RequestContext.Authorizer["claims"].(map[string]interface{})
-	if !ok {
-		return errors.Unauthorized("authorization data not found")
-	}
-	userID, ok := claims["sub"].(string)
-	if !ok {
-		return errors.Unauthorized("authorization data not found")
-	}
-	phone_number, ok := claims["phone_number"].(string)
-	if !ok {
-		return errors.Unauthorized("authorization data not found")
-	}
-	subscriptionArn, ok := claims["custom:subscription_arn"].(string)
-	if !ok {
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if principal.PhoneNumber == "" || principal.SubscriptionArn == "" {
 		return errors.Unauthorized("authorization data not found")
 	}
 
 	var reqBody struct {
 		PhoneNumber string `json:"phone_number"`
+		Label       string `json:"label"`
+	}
+	if err := httpx.DecodeJSON(request, &reqBody); err != nil {
+		return httpx.InvalidBody(err)
 	}
-	if err := json.Unmarshal([]byte(request.Body), &reqBody); err != nil {
-		return errors.BadRequest("invalid request body")
+	if reqBody.Label == "" {
+		reqBody.Label = phonebook.DefaultLabel
+	}
+	if err := phonebook.ValidateLabel(reqBody.Label); err != nil {
+		return errors.Invalid(errors.Field("label", err))
 	}
 
 	verificationCode := randstr.Dec(6)
 	expirationTimestamp := time.Now().Add(24 * time.Hour).Unix()
 
-	if _, err := h.DynamoClient.PutItem(context.Background(), &dynamodb.PutItemInput{
-		TableName: aws.String(os.Getenv("DYNAMO_TABLE_NAME")),
+	if _, err := h.DynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(h.Config.CodesTableName),
 		Item: map[string]dynamotypes.AttributeValue{
-			"UserID":           &dynamotypes.AttributeValueMemberS{Value: userID},
+			"UserID":           &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
 			"PhoneNumber":      &dynamotypes.AttributeValueMemberS{Value: reqBody.PhoneNumber},
+			"Label":            &dynamotypes.AttributeValueMemberS{Value: reqBody.Label},
 			"VerificationCode": &dynamotypes.AttributeValueMemberS{Value: verificationCode},
-			"SubscriptionArn":  &dynamotypes.AttributeValueMemberS{Value: subscriptionArn},
+			"SubscriptionArn":  &dynamotypes.AttributeValueMemberS{Value: principal.SubscriptionArn},
 			"ExpireOn":         &dynamotypes.AttributeValueMemberN{Value: fmt.Sprint(expirationTimestamp)},
 		},
 	}); err != nil {
-		return errors.Internal(err)
+		return errors.Internal(ctx, err)
 	}
 
-	if _, err := h.SnsClient.Publish(context.Background(), &sns.PublishInput{
-		PhoneNumber: &phone_number,
+	if _, err := h.SnsClient.Publish(ctx, &sns.PublishInput{
+		PhoneNumber: aws.String(principal.PhoneNumber),
 		Message:     aws.String(fmt.Sprintf("Your verification code: %s", verificationCode)),
 	}); err != nil {
-		return errors.Internal(err)
+		return errors.Internal(ctx, err)
 	}
+	h.Metrics.Record(ctx, metrics.ChannelSMS, metrics.Metric{Name: "VerificationCodesSent", Value: 1, Unit: metrics.Count})
 
-	responseJSON, err := json.Marshal(map[string]string{
+	return httpx.JSON(ctx, http.StatusOK, map[string]string{
 		"message": "verification code sent",
 	})
-	if err != nil {
-		return errors.Internal(err)
-	}
-
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Headers: map[string]string{
-			"Content-Type":                     "application/json",
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Headers":     "Content-Type",
-			"Access-Control-Allow-Methods":     "OPTIONS, GET, POST, DELETE",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		Body: string(responseJSON),
-	}, nil
 }
@@ -39,7 +39,7 @@ func TestHandler(t *testing.T) {
 			request: events.APIGatewayProxyRequest{
 				RequestContext: events.APIGatewayProxyRequestContext{},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -51,7 +51,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -65,7 +65,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -80,7 +80,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -96,9 +96,43 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"invalid request body"}`,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid request body","code":"bad_request"}`,
 			expectedStatusCode: 400,
 		},
+		{
+			name: "invalid label",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub":                     "1",
+							"phone_number":            "+11123456789",
+							"custom:subscription_arn": "some_arn",
+						},
+					},
+				},
+				Body: `{"phone_number":"+11987654321","label":"Work Phone"}`,
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"phone label must be 1-32 characters long and contain only lowercase letters, digits, \"_\" or \"-\"","code":"validation_failed","errors":[{"field":"label","message":"phone label must be 1-32 characters long and contain only lowercase letters, digits, \"_\" or \"-\""}]}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "success with label",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub":                     "1",
+							"phone_number":            "+11123456789",
+							"custom:subscription_arn": "some_arn",
+						},
+					},
+				},
+				Body: `{"phone_number":"+11987654321","label":"work"}`,
+			},
+			expectedBody:       `{"message":"verification code sent"}`,
+			expectedStatusCode: 200,
+		},
 		{
 			name: "success",
 			request: events.APIGatewayProxyRequest{
@@ -120,7 +154,7 @@ func TestHandler(t *testing.T) {
 
 	for _, tC := range testCases {
 		t.Run(tC.name, func(t *testing.T) {
-			res, err := handler.Handle(tC.request)
+			res, err := handler.Handle(context.Background(), tC.request)
 			if err != nil {
 				t.Errorf("Error occured when handling request: %v", err)
 			}
@@ -2,12 +2,14 @@ package phoneverifier
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
-	"os"
 	"sync"
 
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
 	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/metrics"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
@@ -23,60 +25,113 @@ type SnsApiClient interface {
 }
 type DynamoApiClient interface {
 	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
 	DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 }
 type CognitoApiClient interface {
 	AdminUpdateUserAttributes(context.Context, *cognito.AdminUpdateUserAttributesInput, ...func(*cognito.Options)) (*cognito.AdminUpdateUserAttributesOutput, error)
 }
 
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	// CodesTableName is a table verification codes are saved in
+	CodesTableName  string
+	PhonesTableName string
+	// TopicArn is a topic alarms are sent through, verified phones are subscribed to it
+	TopicArn   string
+	UserPoolID string
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+	// Stage is a dimension of metrics handler emits
+	Stage string
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		CodesTableName:  env.Required("DYNAMO_TABLE_NAME"),
+		PhonesTableName: env.Required("PHONES_TABLE_NAME"),
+		TopicArn:        env.Required("SNS_TOPIC_ARN"),
+		UserPoolID:      env.Required("USER_POOL_ID"),
+		AllowedOrigins:  httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+		Stage:           env.Optional("STAGE"),
+	}
+	return config, env.Err()
+}
+
 type Handler struct {
 	SnsClient     SnsApiClient
 	DynamoClient  DynamoApiClient
 	CognitoClient CognitoApiClient
+	Config        Config
+	// Metrics are written to stdout unless writer of the emitter is set
+	Metrics metrics.Emitter
 }
 
-func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
 
-	claims, ok := request.RequestContext.Authorizer["claims"].(map[string]interface{})
-	if !ok {
-		return errors.Unauthorized("authorization data not found")
-	}
-	userID, ok := claims["sub"].(string)
-	if !ok {
-		return errors.Unauthorized("authorization data not found")
-	}
-	userName, ok := claims["cognito:username"].(string)
-	if !ok {
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if principal.Username == "" {
 		return errors.Unauthorized("authorization data not found")
 	}
 
 	var reqBody struct {
 		VerificationCode string `json:"verification_code"`
 	}
-	if err := json.Unmarshal([]byte(request.Body), &reqBody); err != nil {
-		return errors.BadRequest("invalid request body")
+	if err := httpx.DecodeJSON(request, &reqBody); err != nil {
+		return httpx.InvalidBody(err)
 	}
 
-	item, err := h.DynamoClient.GetItem(context.Background(), &dynamodb.GetItemInput{
-		TableName: aws.String(os.Getenv("DYNAMO_TABLE_NAME")),
+	item, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(h.Config.CodesTableName),
 		Key: map[string]dynamotypes.AttributeValue{
-			"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
 		},
 	})
 	if err != nil {
-		return errors.Internal(err)
+		return errors.Internal(ctx, err)
 	}
 
 	newPhoneNumber := item.Item["PhoneNumber"].(*dynamotypes.AttributeValueMemberS).Value
 	verificationCode := item.Item["VerificationCode"].(*dynamotypes.AttributeValueMemberS).Value
-	subscriptionArn := item.Item["SubscriptionArn"].(*dynamotypes.AttributeValueMemberS).Value
+
+	// Codes created before phone labels were introduced don't have a label
+	// and always refer to the default phone number
+	label := phonebook.DefaultLabel
+	if labelAttr, ok := item.Item["Label"].(*dynamotypes.AttributeValueMemberS); ok {
+		label = labelAttr.Value
+	}
 
 	if verificationCode != reqBody.VerificationCode {
+		h.Metrics.Record(ctx, metrics.ChannelSMS, metrics.Metric{Name: "VerificationFailures", Value: 1, Unit: metrics.Count})
 		return errors.Unauthorized("verification code is incorrect")
 	}
 
+	phone, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(h.Config.PhonesTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
+			"Label":  &dynamotypes.AttributeValueMemberS{Value: label},
+		},
+	})
+	if err != nil {
+		return errors.Internal(ctx, err)
+	}
+
+	// Subscription of phone number that is being replaced. Default phone numbers of users that
+	// signed up before phone labels were introduced are only stored in Cognito
+	var subscriptionArn string
+	if subscriptionAttr, ok := phone.Item["SubscriptionArn"].(*dynamotypes.AttributeValueMemberS); ok {
+		subscriptionArn = subscriptionAttr.Value
+	} else if subscriptionAttr, ok := item.Item["SubscriptionArn"].(*dynamotypes.AttributeValueMemberS); ok && label == phonebook.DefaultLabel {
+		subscriptionArn = subscriptionAttr.Value
+	}
+
 	errChan := make(chan error, 1)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 
 	defer close(errChan)
 	defer cancel()
@@ -87,9 +142,9 @@ func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatew
 		defer wg.Done()
 
 		if _, err := h.DynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-			TableName: aws.String(os.Getenv("DYNAMO_TABLE_NAME")),
+			TableName: aws.String(h.Config.CodesTableName),
 			Key: map[string]dynamotypes.AttributeValue{
-				"UserID": &dynamotypes.AttributeValueMemberS{Value: userID},
+				"UserID": &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
 			},
 		}); err != nil {
 			select {
@@ -101,44 +156,44 @@ func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatew
 		}
 	}()
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	if subscriptionArn != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-		if _, err := h.SnsClient.Unsubscribe(ctx, &sns.UnsubscribeInput{
-			SubscriptionArn: &subscriptionArn,
-		}); err != nil {
-			select {
-			case errChan <- err:
-				cancel()
-			default:
+			if _, err := h.SnsClient.Unsubscribe(ctx, &sns.UnsubscribeInput{
+				SubscriptionArn: &subscriptionArn,
+			}); err != nil {
+				select {
+				case errChan <- err:
+					cancel()
+				default:
+				}
+				return
 			}
-			return
-		}
-	}()
+		}()
+	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
-		filterPolicy, err := json.Marshal(map[string]interface{}{
-			"userID": []string{userID},
-		})
+		filterPolicy, err := phonebook.FilterPolicy(principal.UserID, label)
 		if err != nil {
 			select {
 			case errChan <- err:
 				cancel()
 			default:
-				return
 			}
+			return
 		}
 
 		subResponse, err := h.SnsClient.Subscribe(ctx, &sns.SubscribeInput{
-			TopicArn: aws.String(os.Getenv("SNS_TOPIC_ARN")),
+			TopicArn: aws.String(h.Config.TopicArn),
 			Protocol: aws.String("sms"),
 			Endpoint: aws.String(newPhoneNumber),
 			Attributes: map[string]string{
-				"FilterPolicy": string(filterPolicy),
+				"FilterPolicy": filterPolicy,
 			},
 			ReturnSubscriptionArn: true,
 		})
@@ -151,9 +206,31 @@ func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatew
 			return
 		}
 
+		if _, err := h.DynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(h.Config.PhonesTableName),
+			Item: map[string]dynamotypes.AttributeValue{
+				"UserID":          &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
+				"Label":           &dynamotypes.AttributeValueMemberS{Value: label},
+				"PhoneNumber":     &dynamotypes.AttributeValueMemberS{Value: newPhoneNumber},
+				"SubscriptionArn": &dynamotypes.AttributeValueMemberS{Value: *subResponse.SubscriptionArn},
+			},
+		}); err != nil {
+			select {
+			case errChan <- err:
+				cancel()
+			default:
+			}
+			return
+		}
+
+		// Only default phone number is the one user signs in with
+		if label != phonebook.DefaultLabel {
+			return
+		}
+
 		if _, err := h.CognitoClient.AdminUpdateUserAttributes(ctx, &cognito.AdminUpdateUserAttributesInput{
-			UserPoolId: aws.String(os.Getenv("USER_POOL_ID")),
-			Username:   &userName,
+			UserPoolId: aws.String(h.Config.UserPoolID),
+			Username:   aws.String(principal.Username),
 			UserAttributes: []cognitotypes.AttributeType{
 				{
 					Name:  aws.String("phone_number"),
@@ -180,25 +257,12 @@ func (h *Handler) Handle(request events.APIGatewayProxyRequest) (events.APIGatew
 
 	wg.Wait()
 	if ctx.Err() != nil {
-		return errors.Internal(<-errChan)
+		return errors.Internal(ctx, <-errChan)
 	}
+	h.Metrics.Record(ctx, metrics.ChannelSMS, metrics.Metric{Name: "VerificationSuccesses", Value: 1, Unit: metrics.Count})
 
-	responseJSON, err := json.Marshal(map[string]string{
+	return httpx.JSON(ctx, http.StatusOK, map[string]string{
 		"phone_number": newPhoneNumber,
+		"label":        label,
 	})
-	if err != nil {
-		return errors.Internal(err)
-	}
-
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Headers: map[string]string{
-			"Content-Type":                     "application/json",
-			"Access-Control-Allow-Origin":      "*",
-			"Access-Control-Allow-Headers":     "Content-Type",
-			"Access-Control-Allow-Methods":     "OPTIONS, GET, POST, DELETE",
-			"Access-Control-Allow-Credentials": "true",
-		},
-		Body: string(responseJSON),
-	}, nil
 }
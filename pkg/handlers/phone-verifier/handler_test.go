@@ -31,17 +31,30 @@ func (m *mockSns) Unsubscribe(context.Context, *sns.UnsubscribeInput, ...func(*s
 
 type mockDynamo struct {
 	DeleteItemError error
+	PutItemError    error
+	Label           string
 }
 
-func (m *mockDynamo) GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
-	return &dynamodb.GetItemOutput{
-		Item: map[string]dynamotypes.AttributeValue{
-			"UserID":           &dynamotypes.AttributeValueMemberS{Value: "1"},
-			"VerificationCode": &dynamotypes.AttributeValueMemberS{Value: "123456"},
-			"SubscriptionArn":  &dynamotypes.AttributeValueMemberS{Value: "some arn"},
-			"PhoneNumber":      &dynamotypes.AttributeValueMemberS{Value: "+11123456789"},
-		},
-	}, nil
+func (m *mockDynamo) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	// phone numbers table is the one keyed with a label
+	if _, ok := input.Key["Label"]; ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	item := map[string]dynamotypes.AttributeValue{
+		"UserID":           &dynamotypes.AttributeValueMemberS{Value: "1"},
+		"VerificationCode": &dynamotypes.AttributeValueMemberS{Value: "123456"},
+		"SubscriptionArn":  &dynamotypes.AttributeValueMemberS{Value: "some arn"},
+		"PhoneNumber":      &dynamotypes.AttributeValueMemberS{Value: "+11123456789"},
+	}
+	if m.Label != "" {
+		item["Label"] = &dynamotypes.AttributeValueMemberS{Value: m.Label}
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+func (m *mockDynamo) PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return nil, m.PutItemError
 }
 func (m *mockDynamo) DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
 	return nil, m.DeleteItemError
@@ -49,9 +62,11 @@ func (m *mockDynamo) DeleteItem(context.Context, *dynamodb.DeleteItemInput, ...f
 
 type mockCognito struct {
 	AdminUpdateError error
+	called           bool
 }
 
 func (m *mockCognito) AdminUpdateUserAttributes(context.Context, *cognito.AdminUpdateUserAttributesInput, ...func(*cognito.Options)) (*cognito.AdminUpdateUserAttributesOutput, error) {
+	m.called = true
 	return nil, m.AdminUpdateError
 }
 
@@ -65,13 +80,16 @@ func TestHandler(t *testing.T) {
 		subscribeError     error
 		unsubscribeError   error
 		adminUpdateError   error
+		putItemError       error
+		label              string
+		cognitoUpdated     bool
 	}{
 		{
 			name: "no authorizer",
 			request: events.APIGatewayProxyRequest{
 				RequestContext: events.APIGatewayProxyRequestContext{},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -83,7 +101,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -97,7 +115,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"authorization data not found"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -112,7 +130,7 @@ func TestHandler(t *testing.T) {
 					},
 				},
 			},
-			expectedBody:       `{"message":"invalid request body"}`,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid request body","code":"bad_request"}`,
 			expectedStatusCode: 400,
 		},
 		{
@@ -128,7 +146,7 @@ func TestHandler(t *testing.T) {
 				},
 				Body: `{"verification_code":"123"}`,
 			},
-			expectedBody:       `{"message":"verification code is incorrect"}`,
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"verification code is incorrect","code":"unauthorized"}`,
 			expectedStatusCode: 401,
 		},
 		{
@@ -145,7 +163,7 @@ func TestHandler(t *testing.T) {
 				Body: `{"verification_code":"123456"}`,
 			},
 			deleteItemError:    errors.New("some error"),
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			expectedStatusCode: 500,
 		},
 		{
@@ -162,7 +180,7 @@ func TestHandler(t *testing.T) {
 				Body: `{"verification_code":"123456"}`,
 			},
 			subscribeError:     errors.New("some error"),
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			expectedStatusCode: 500,
 		},
 		{
@@ -179,7 +197,7 @@ func TestHandler(t *testing.T) {
 				Body: `{"verification_code":"123456"}`,
 			},
 			unsubscribeError:   errors.New("some error"),
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			expectedStatusCode: 500,
 		},
 		{
@@ -196,9 +214,43 @@ func TestHandler(t *testing.T) {
 				Body: `{"verification_code":"123456"}`,
 			},
 			adminUpdateError:   errors.New("some error"),
-			expectedBody:       `{"message":"internal server error"}`,
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
+			expectedStatusCode: 500,
+		},
+		{
+			name: "put item error",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub":              "1",
+							"cognito:username": "user",
+						},
+					},
+				},
+				Body: `{"verification_code":"123456"}`,
+			},
+			putItemError:       errors.New("some error"),
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
 			expectedStatusCode: 500,
 		},
+		{
+			name: "success with label",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					Authorizer: map[string]interface{}{
+						"claims": map[string]interface{}{
+							"sub":              "1",
+							"cognito:username": "user",
+						},
+					},
+				},
+				Body: `{"verification_code":"123456"}`,
+			},
+			label:              "work",
+			expectedBody:       `{"label":"work","phone_number":"+11123456789"}`,
+			expectedStatusCode: 200,
+		},
 		{
 			name: "success",
 			request: events.APIGatewayProxyRequest{
@@ -212,20 +264,22 @@ func TestHandler(t *testing.T) {
 				},
 				Body: `{"verification_code":"123456"}`,
 			},
-			expectedBody:       `{"phone_number":"+11123456789"}`,
+			expectedBody:       `{"label":"default","phone_number":"+11123456789"}`,
 			expectedStatusCode: 200,
+			cognitoUpdated:     true,
 		},
 	}
 
 	for _, tC := range testCases {
 		t.Run(tC.name, func(t *testing.T) {
+			cognitoClient := &mockCognito{AdminUpdateError: tC.adminUpdateError}
 			handler := phoneverifier.Handler{
-				DynamoClient:  &mockDynamo{DeleteItemError: tC.deleteItemError},
+				DynamoClient:  &mockDynamo{DeleteItemError: tC.deleteItemError, PutItemError: tC.putItemError, Label: tC.label},
 				SnsClient:     &mockSns{SubscribeError: tC.subscribeError, UnsubscribeError: tC.unsubscribeError},
-				CognitoClient: &mockCognito{AdminUpdateError: tC.adminUpdateError},
+				CognitoClient: cognitoClient,
 			}
 
-			res, err := handler.Handle(tC.request)
+			res, err := handler.Handle(context.Background(), tC.request)
 			if err != nil {
 				t.Errorf("Error occured when handling request: %v", err)
 			}
@@ -236,6 +290,9 @@ func TestHandler(t *testing.T) {
 			if res.StatusCode != tC.expectedStatusCode {
 				t.Errorf("Received status code: %v is different than expected one: %v", res.StatusCode, tC.expectedStatusCode)
 			}
+			if res.StatusCode == 200 && cognitoClient.called != tC.cognitoUpdated {
+				t.Errorf("Expected Cognito attributes update: %v, but got: %v", tC.cognitoUpdated, cognitoClient.called)
+			}
 		})
 	}
 }
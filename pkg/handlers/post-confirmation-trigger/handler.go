@@ -2,16 +2,20 @@ package postconfirmationtrigger
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"log"
-	"os"
+	"log/slog"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
 	cognitotypes "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/logging"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/phonebook"
 )
 
 type SnsApiClient interface {
@@ -21,42 +25,77 @@ type CognitoApiClient interface {
 	AdminUpdateUserAttributes(context.Context, *cognito.AdminUpdateUserAttributesInput, ...func(*cognito.Options)) (*cognito.AdminUpdateUserAttributesOutput, error)
 }
 
+type DynamoApiClient interface {
+	PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	// TopicArn is a topic alarms are sent through, phones of users are subscribed to it
+	TopicArn        string
+	PhonesTableName string
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		TopicArn:        env.Required("SNS_TOPIC_ARN"),
+		PhonesTableName: env.Required("PHONES_TABLE_NAME"),
+	}
+	return config, env.Err()
+}
+
 type Handler struct {
 	CognitoClient CognitoApiClient
 	SnsClient     SnsApiClient
+	DynamoClient  DynamoApiClient
+	Config        Config
 }
 
-func (h *Handler) Handle(event events.CognitoEventUserPoolsPostConfirmation) (events.CognitoEventUserPoolsPostConfirmation, error) {
+func (h *Handler) Handle(ctx context.Context, event events.CognitoEventUserPoolsPostConfirmation) (events.CognitoEventUserPoolsPostConfirmation, error) {
 
 	phoneNumber := event.Request.UserAttributes["phone_number"]
 	sub := event.Request.UserAttributes["sub"]
 	if phoneNumber == "" || sub == "" {
 		return event, errors.New("invalid user attributes")
 	}
+	ctx = logging.With(ctx, logging.KeyUserID, sub)
 
-	filterPolicy, err := json.Marshal(map[string]interface{}{
-		"userID": []string{sub},
-	})
+	filterPolicy, err := phonebook.FilterPolicy(sub, phonebook.DefaultLabel)
 	if err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "filter policy couldn't be created", "error", err)
 		return event, err
 	}
 
-	subResponse, err := h.SnsClient.Subscribe(context.Background(), &sns.SubscribeInput{
-		TopicArn: aws.String(os.Getenv("SNS_TOPIC_ARN")),
+	subResponse, err := h.SnsClient.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn: aws.String(h.Config.TopicArn),
 		Protocol: aws.String("sms"),
 		Endpoint: aws.String(phoneNumber),
 		Attributes: map[string]string{
-			"FilterPolicy": string(filterPolicy),
+			"FilterPolicy": filterPolicy,
 		},
 		ReturnSubscriptionArn: true,
 	})
 	if err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "phone number couldn't be subscribed", "error", err)
+		return event, err
+	}
+
+	if _, err := h.DynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(h.Config.PhonesTableName),
+		Item: map[string]dynamotypes.AttributeValue{
+			"UserID":          &dynamotypes.AttributeValueMemberS{Value: sub},
+			"Label":           &dynamotypes.AttributeValueMemberS{Value: phonebook.DefaultLabel},
+			"PhoneNumber":     &dynamotypes.AttributeValueMemberS{Value: phoneNumber},
+			"SubscriptionArn": &dynamotypes.AttributeValueMemberS{Value: *subResponse.SubscriptionArn},
+		},
+	}); err != nil {
+		slog.ErrorContext(ctx, "phone number couldn't be saved", "error", err)
 		return event, err
 	}
 
-	if _, err := h.CognitoClient.AdminUpdateUserAttributes(context.Background(), &cognito.AdminUpdateUserAttributesInput{
+	if _, err := h.CognitoClient.AdminUpdateUserAttributes(ctx, &cognito.AdminUpdateUserAttributesInput{
 		UserPoolId: aws.String(event.UserPoolID),
 		Username:   &event.UserName,
 		UserAttributes: []cognitotypes.AttributeType{{
@@ -64,7 +103,7 @@ func (h *Handler) Handle(event events.CognitoEventUserPoolsPostConfirmation) (ev
 			Value: subResponse.SubscriptionArn,
 		}},
 	}); err != nil {
-		log.Println(err.Error())
+		slog.ErrorContext(ctx, "subscription couldn't be saved in user attributes", "error", err)
 		return event, err
 	}
 
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 )
 
@@ -27,10 +28,17 @@ func (m *mockCognito) AdminUpdateUserAttributes(context.Context, *cognito.AdminU
 	return nil, nil
 }
 
+type mockDynamo struct{}
+
+func (m *mockDynamo) PutItem(context.Context, *dynamodb.PutItemInput, ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return nil, nil
+}
+
 func TestHandler(t *testing.T) {
 	handler := postconfirmationtrigger.Handler{
 		SnsClient:     &mockSns{},
 		CognitoClient: &mockCognito{},
+		DynamoClient:  &mockDynamo{},
 	}
 
 	testCases := []struct {
@@ -103,7 +111,7 @@ func TestHandler(t *testing.T) {
 
 	for _, tC := range testCases {
 		t.Run(tC.name, func(t *testing.T) {
-			res, err := handler.Handle(tC.event)
+			res, err := handler.Handle(context.Background(), tC.event)
 
 			if !reflect.DeepEqual(res, tC.expectedResult) {
 				t.Errorf("Response received: %v is different than expected: %v", res, tC.expectedResult)
@@ -0,0 +1,71 @@
+package quiethoursgetter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours"
+)
+
+type DynamoApiClient interface {
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	SettingsTableName string
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		SettingsTableName: env.Required("SETTINGS_TABLE_NAME"),
+		AllowedOrigins:    httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+	}
+	return config, env.Err()
+}
+
+type Handler struct {
+	DynamoClient DynamoApiClient
+	Config       Config
+}
+
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
+
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	res, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(h.Config.SettingsTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
+		},
+	})
+	if err != nil {
+		return errors.Internal(ctx, err)
+	}
+
+	// Users that never set quiet hours have no windows defined
+	settings := &quiethours.Settings{Windows: []quiethours.Window{}}
+	if value, ok := res.Item["QuietHours"]; ok {
+		if settings, err = quiethours.FromAttributeValue(value); err != nil {
+			return errors.Internal(ctx, err)
+		}
+		if settings.Windows == nil {
+			settings.Windows = []quiethours.Window{}
+		}
+	}
+
+	return httpx.JSON(ctx, http.StatusOK, settings)
+}
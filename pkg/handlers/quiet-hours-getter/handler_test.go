@@ -0,0 +1,109 @@
+package quiethoursgetter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/quiethours"
+	quiethoursgetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-getter"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type mockDynamo struct {
+	GetItemError error
+	settings     *quiethours.Settings
+}
+
+func (m *mockDynamo) GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if m.GetItemError != nil {
+		return nil, m.GetItemError
+	}
+	if m.settings == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{
+		Item: map[string]dynamotypes.AttributeValue{
+			"UserID":     &dynamotypes.AttributeValueMemberS{Value: "1"},
+			"QuietHours": m.settings.ToAttributeValue(),
+		},
+	}, nil
+}
+
+func TestHandler(t *testing.T) {
+	authorizer := map[string]interface{}{
+		"claims": map[string]interface{}{
+			"sub": "1",
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		request            events.APIGatewayProxyRequest
+		getItemError       error
+		settings           *quiethours.Settings
+		expectedBody       string
+		expectedStatusCode int
+	}{
+		{
+			name: "no authorizer",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
+			expectedStatusCode: 401,
+		},
+		{
+			name: "get item error",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			getItemError:       errors.New("some error"),
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
+			expectedStatusCode: 500,
+		},
+		{
+			name: "no settings",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			expectedBody:       `{"timezone":"","policy":"","windows":[]}`,
+			expectedStatusCode: 200,
+		},
+		{
+			name: "success",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			settings: &quiethours.Settings{
+				Timezone: "Europe/Warsaw",
+				Policy:   quiethours.PolicyDrop,
+				Windows:  []quiethours.Window{{Start: "22:00", End: "07:00"}},
+			},
+			expectedBody:       `{"timezone":"Europe/Warsaw","policy":"drop","windows":[{"start":"22:00","end":"07:00"}]}`,
+			expectedStatusCode: 200,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			handler := quiethoursgetter.Handler{
+				DynamoClient: &mockDynamo{GetItemError: tC.getItemError, settings: tC.settings},
+			}
+
+			res, err := handler.Handle(context.Background(), tC.request)
+			if err != nil {
+				t.Errorf("Error occured when handling request: %v", err)
+			}
+
+			if res.Body != tC.expectedBody {
+				t.Errorf("Received result: %v is different than expected one: %v", res.Body, tC.expectedBody)
+			}
+			if res.StatusCode != tC.expectedStatusCode {
+				t.Errorf("Received status code: %v is different than expected one: %v", res.StatusCode, tC.expectedStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package quiethourssetter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	quiethourssetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/quiet-hours-setter"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+type mockDynamo struct {
+	UpdateItemError error
+}
+
+func (m *mockDynamo) UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	return nil, m.UpdateItemError
+}
+
+func TestHandler(t *testing.T) {
+	authorizer := map[string]interface{}{
+		"claims": map[string]interface{}{
+			"sub": "1",
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		request            events.APIGatewayProxyRequest
+		updateItemError    error
+		expectedBody       string
+		expectedStatusCode int
+	}{
+		{
+			name: "no authorizer",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
+			expectedStatusCode: 401,
+		},
+		{
+			name: "no request body",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid request body","code":"bad_request"}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "invalid policy",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+				Body:           `{"timezone":"Europe/Warsaw","policy":"snooze","windows":[]}`,
+			},
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"\"policy\" must be either \"defer\" or \"drop\"","code":"validation_failed"}`,
+			expectedStatusCode: 400,
+		},
+		{
+			name: "quiet whole day",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+				Body:           `{"timezone":"Europe/Warsaw","policy":"defer","windows":[{"start":"00:00","end":"12:00"},{"start":"12:00","end":"00:00"}]}`,
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"quiet hours cannot cover whole day","code":"unprocessable_entity"}`,
+			expectedStatusCode: 422,
+		},
+		{
+			name: "update item error",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+				Body:           `{"timezone":"Europe/Warsaw","policy":"defer","windows":[{"start":"22:00","end":"07:00"}]}`,
+			},
+			updateItemError:    errors.New("some error"),
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
+			expectedStatusCode: 500,
+		},
+		{
+			name: "success",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+				Body:           `{"timezone":"Europe/Warsaw","policy":"defer","windows":[{"start":"22:00","end":"07:00"}]}`,
+			},
+			expectedBody:       `{"timezone":"Europe/Warsaw","policy":"defer","windows":[{"start":"22:00","end":"07:00"}]}`,
+			expectedStatusCode: 200,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			handler := quiethourssetter.Handler{
+				DynamoClient: &mockDynamo{UpdateItemError: tC.updateItemError},
+			}
+
+			res, err := handler.Handle(context.Background(), tC.request)
+			if err != nil {
+				t.Errorf("Error occured when handling request: %v", err)
+			}
+
+			if res.Body != tC.expectedBody {
+				t.Errorf("Received result: %v is different than expected one: %v", res.Body, tC.expectedBody)
+			}
+			if res.StatusCode != tC.expectedStatusCode {
+				t.Errorf("Received status code: %v is different than expected one: %v", res.StatusCode, tC.expectedStatusCode)
+			}
+		})
+	}
+}
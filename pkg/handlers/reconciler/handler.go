@@ -0,0 +1,311 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+	"github.com/google/uuid"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/dynamomapper"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
+)
+
+// GracePeriod is how old a schedule has to be to be considered orphaned. Creator creates schedules
+// before it saves their event, so recent ones may belong to an event that is being created
+const GracePeriod = 15 * time.Minute
+
+type DynamoApiClient interface {
+	Scan(context.Context, *dynamodb.ScanInput, ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+type SchedulerApiClient interface {
+	ListSchedules(context.Context, *scheduler.ListSchedulesInput, ...func(*scheduler.Options)) (*scheduler.ListSchedulesOutput, error)
+	CreateSchedule(context.Context, *scheduler.CreateScheduleInput, ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error)
+	DeleteSchedule(context.Context, *scheduler.DeleteScheduleInput, ...func(*scheduler.Options)) (*scheduler.DeleteScheduleOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	TableName string
+	// Target is where recreated schedules deliver alarms to, its group is the one checked
+	Target alarmschedule.Target
+	// SchedulerConcurrency is a number of schedules repaired at once
+	SchedulerConcurrency int
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		TableName: env.Required("DYNAMO_TABLE_NAME"),
+		Target: alarmschedule.Target{
+			FunctionArn:   env.Required("LAMBDA_FUNCTION_ARN"),
+			RoleArn:       env.Required("ROLE_ARN"),
+			Group:         env.Optional("SCHEDULE_GROUP_NAME"),
+			DeadLetterArn: env.Optional("DEAD_LETTER_QUEUE_ARN"),
+		},
+		SchedulerConcurrency: env.Int("SCHEDULER_CONCURRENCY", workerpool.DefaultSize, 1),
+	}
+	return config, env.Err()
+}
+
+type Handler struct {
+	DynamoClient    DynamoApiClient
+	SchedulerClient SchedulerApiClient
+	Config          Config
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
+}
+
+// Request is an input of reconciliation. In dry-run mode drift is only reported
+type Request struct {
+	DryRun bool `json:"dryRun"`
+}
+
+// Finding is a single schedule that drifted from the alarms table
+type Finding struct {
+	Schedule   string `json:"schedule"`
+	UserID     string `json:"userID,omitempty"`
+	EventID    string `json:"eventID,omitempty"`
+	Expression string `json:"expression,omitempty"`
+	Repaired   bool   `json:"repaired"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is a result of reconciliation
+type Report struct {
+	DryRun    bool `json:"dryRun"`
+	Events    int  `json:"events"`
+	Schedules int  `json:"schedules"`
+	// Orphans are schedules in the group that don't belong to any event
+	Orphans []Finding `json:"orphans"`
+	// Gaps are alarms saved with events whose schedules are missing although they would still fire
+	Gaps []Finding `json:"gaps"`
+	// Expired is the number of alarms without schedules that won't fire anymore, e.g. one-time
+	// ones deleted by scheduler after they fired
+	Expired int `json:"expired"`
+	// Legacy is the number of alarms created in the default schedule group, they aren't checked
+	Legacy int `json:"legacy"`
+}
+
+type event struct {
+	userID, eventID string
+	item            map[string]interface{}
+}
+
+// Handle compares schedules in the schedule group with alarms saved in the alarms table. Unless
+// it's a dry run, orphaned schedules are deleted and missing ones are created again
+func (h *Handler) Handle(ctx context.Context, request Request) (*Report, error) {
+	now := time.Now()
+	if h.Now != nil {
+		now = h.Now()
+	}
+
+	schedules, err := h.listSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events, err := h.scanEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: request.DryRun, Events: len(events), Schedules: len(schedules), Orphans: []Finding{}, Gaps: []Finding{}}
+	var gaps []alarmschedule.Alarm
+
+	referenced := make(map[string]bool)
+	for _, e := range events {
+		for _, alarm := range alarmschedule.FromEvent(e.item) {
+			referenced[alarm.Name] = true
+			if _, ok := schedules[alarm.Name]; ok {
+				continue
+			}
+			if _, _, ok := schedule.ParseName(alarm.Name); !ok {
+				report.Legacy++
+				continue
+			}
+			if !alarmschedule.WillFire(alarm, now) {
+				report.Expired++
+				continue
+			}
+			gaps = append(gaps, alarm)
+		}
+	}
+
+	var orphans []string
+	for name, created := range schedules {
+		if referenced[name] || now.Sub(created) < GracePeriod {
+			continue
+		}
+		// Deferred alarms aren't saved with events, they're orphaned only when their event is gone
+		userID, eventID, ok := schedule.ParseName(name)
+		if ok && schedule.IsDeferred(name) {
+			if _, exists := events[userID+"/"+eventID]; exists {
+				continue
+			}
+		}
+		orphans = append(orphans, name)
+	}
+	sort.Strings(orphans)
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].Name < gaps[j].Name })
+
+	for _, name := range orphans {
+		finding := Finding{Schedule: name}
+		finding.UserID, finding.EventID, _ = schedule.ParseName(name)
+		report.Orphans = append(report.Orphans, finding)
+	}
+	for _, alarm := range gaps {
+		report.Gaps = append(report.Gaps, Finding{Schedule: alarm.Name, UserID: alarm.UserID, EventID: alarm.EventID, Expression: alarm.Expression})
+	}
+
+	if !request.DryRun {
+		h.repair(ctx, report, gaps)
+	}
+
+	slog.InfoContext(ctx, "reconciliation finished", "report", report)
+
+	return report, nil
+}
+
+// repair deletes orphaned schedules and creates missing ones
+func (h *Handler) repair(ctx context.Context, report *Report, gaps []alarmschedule.Alarm) {
+	type task struct {
+		finding *Finding
+		repair  func(context.Context) error
+	}
+
+	var tasks []task
+	for i := range report.Orphans {
+		finding := &report.Orphans[i]
+		tasks = append(tasks, task{finding: finding, repair: func(ctx context.Context) error {
+			return h.deleteSchedule(ctx, finding.Schedule)
+		}})
+	}
+	for i, alarm := range gaps {
+		tasks = append(tasks, task{finding: &report.Gaps[i], repair: func(ctx context.Context) error {
+			return h.createSchedule(ctx, alarm)
+		}})
+	}
+
+	// Failures are recorded in findings, so that a single one doesn't stop repairing the rest
+	_ = workerpool.Run(ctx, h.Config.SchedulerConcurrency, tasks, func(ctx context.Context, t task) error {
+		if err := t.repair(ctx); err != nil {
+			t.finding.Error = err.Error()
+			return nil
+		}
+		t.finding.Repaired = true
+		return nil
+	})
+}
+
+func (h *Handler) deleteSchedule(ctx context.Context, name string) error {
+	var errNotFound *schedulertypes.ResourceNotFoundException
+	if _, err := h.SchedulerClient.DeleteSchedule(ctx, &scheduler.DeleteScheduleInput{
+		Name:        aws.String(name),
+		GroupName:   h.Config.scheduleGroup(),
+		ClientToken: aws.String(uuid.NewString()),
+	}); err != nil && !errors.As(err, &errNotFound) {
+		return err
+	}
+	return nil
+}
+
+// createSchedule creates missing schedule again, as long as its event still exists. Deleter deletes
+// schedules before their event, so it may be deleted since the table was scanned
+func (h *Handler) createSchedule(ctx context.Context, alarm alarmschedule.Alarm) error {
+	res, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(h.Config.TableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID":  &dynamotypes.AttributeValueMemberS{Value: alarm.UserID},
+			"EventID": &dynamotypes.AttributeValueMemberS{Value: alarm.EventID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+	if res.Item == nil {
+		return errors.New("event was deleted")
+	}
+
+	input, err := alarmschedule.CreateScheduleInput(alarm, h.Config.Target)
+	if err != nil {
+		return err
+	}
+	var errConflict *schedulertypes.ConflictException
+	if _, err := h.SchedulerClient.CreateSchedule(ctx, input); err != nil && !errors.As(err, &errConflict) {
+		return err
+	}
+	return nil
+}
+
+// listSchedules returns creation dates of all schedules in the schedule group by their names
+func (h *Handler) listSchedules(ctx context.Context) (map[string]time.Time, error) {
+	schedules := make(map[string]time.Time)
+	var nextToken *string
+	for {
+		res, err := h.SchedulerClient.ListSchedules(ctx, &scheduler.ListSchedulesInput{
+			GroupName: h.Config.scheduleGroup(),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range res.Schedules {
+			schedules[aws.ToString(s.Name)] = aws.ToTime(s.CreationDate)
+		}
+
+		if res.NextToken == nil {
+			return schedules, nil
+		}
+		nextToken = res.NextToken
+	}
+}
+
+// scanEvents returns all events by "<userID>/<eventID>" key
+func (h *Handler) scanEvents(ctx context.Context) (map[string]event, error) {
+	events := make(map[string]event)
+	var startKey map[string]dynamotypes.AttributeValue
+	for {
+		res, err := h.DynamoClient.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(h.Config.TableName),
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range res.Items {
+			e := event{item: dynamomapper.SimplifyDynamoDBItem(item)}
+			e.userID, _ = e.item["UserID"].(string)
+			e.eventID, _ = e.item["EventID"].(string)
+			events[e.userID+"/"+e.eventID] = e
+		}
+
+		if len(res.LastEvaluatedKey) == 0 {
+			return events, nil
+		}
+		startKey = res.LastEvaluatedKey
+	}
+}
+
+// scheduleGroup returns a name of schedule group alarms are created in, nil stands for the default group
+func (c Config) scheduleGroup() *string {
+	if c.Target.Group == "" {
+		return nil
+	}
+	return aws.String(c.Target.Group)
+}
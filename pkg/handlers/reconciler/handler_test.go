@@ -0,0 +1,258 @@
+package reconciler_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/reconciler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulertypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+)
+
+// fakeDynamoDB keeps items of alarms table in memory
+type fakeDynamoDB struct {
+	items map[string]map[string]dynamotypes.AttributeValue
+}
+
+func key(userID, eventID string) string {
+	return userID + "/" + eventID
+}
+
+// Scan returns one item per page to check pagination
+func (f *fakeDynamoDB) Scan(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	keys := make([]string, 0, len(f.items))
+	for k := range f.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	page := 0
+	if input.ExclusiveStartKey != nil {
+		page, _ = strconv.Atoi(input.ExclusiveStartKey["page"].(*dynamotypes.AttributeValueMemberN).Value)
+	}
+	if page >= len(keys) {
+		return &dynamodb.ScanOutput{}, nil
+	}
+	output := &dynamodb.ScanOutput{Items: []map[string]dynamotypes.AttributeValue{f.items[keys[page]]}}
+	if page+1 < len(keys) {
+		output.LastEvaluatedKey = map[string]dynamotypes.AttributeValue{"page": &dynamotypes.AttributeValueMemberN{Value: strconv.Itoa(page + 1)}}
+	}
+	return output, nil
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	userID := input.Key["UserID"].(*dynamotypes.AttributeValueMemberS).Value
+	eventID := input.Key["EventID"].(*dynamotypes.AttributeValueMemberS).Value
+	return &dynamodb.GetItemOutput{Item: f.items[key(userID, eventID)]}, nil
+}
+
+type fakeSchedule struct {
+	input   *scheduler.CreateScheduleInput
+	created time.Time
+}
+
+// fakeScheduler keeps schedules of a single group in memory
+type fakeScheduler struct {
+	sync.Mutex
+	schedules map[string]fakeSchedule
+	now       time.Time
+}
+
+// ListSchedules returns two schedules per page to check pagination
+func (f *fakeScheduler) ListSchedules(ctx context.Context, input *scheduler.ListSchedulesInput, opts ...func(*scheduler.Options)) (*scheduler.ListSchedulesOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	names := make([]string, 0, len(f.schedules))
+	for name := range f.schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	start := 0
+	if input.NextToken != nil {
+		start, _ = strconv.Atoi(*input.NextToken)
+	}
+	output := &scheduler.ListSchedulesOutput{}
+	for i := start; i < len(names) && i < start+2; i++ {
+		output.Schedules = append(output.Schedules, schedulertypes.ScheduleSummary{
+			Name:         aws.String(names[i]),
+			GroupName:    input.GroupName,
+			CreationDate: aws.Time(f.schedules[names[i]].created),
+		})
+	}
+	if start+2 < len(names) {
+		output.NextToken = aws.String(strconv.Itoa(start + 2))
+	}
+	return output, nil
+}
+
+func (f *fakeScheduler) CreateSchedule(ctx context.Context, input *scheduler.CreateScheduleInput, opts ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.schedules[*input.Name]; ok {
+		return nil, &schedulertypes.ConflictException{}
+	}
+	f.schedules[*input.Name] = fakeSchedule{input: input, created: f.now}
+	return &scheduler.CreateScheduleOutput{}, nil
+}
+
+func (f *fakeScheduler) DeleteSchedule(ctx context.Context, input *scheduler.DeleteScheduleInput, opts ...func(*scheduler.Options)) (*scheduler.DeleteScheduleOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if _, ok := f.schedules[*input.Name]; !ok {
+		return nil, &schedulertypes.ResourceNotFoundException{}
+	}
+	delete(f.schedules, *input.Name)
+	return &scheduler.DeleteScheduleOutput{}, nil
+}
+
+func eventItem(userID, eventID string, dates, crons map[string]string) map[string]dynamotypes.AttributeValue {
+	toMap := func(values map[string]string) *dynamotypes.AttributeValueMemberM {
+		m := &dynamotypes.AttributeValueMemberM{Value: map[string]dynamotypes.AttributeValue{}}
+		for name, value := range values {
+			m.Value[name] = &dynamotypes.AttributeValueMemberS{Value: value}
+		}
+		return m
+	}
+	return map[string]dynamotypes.AttributeValue{
+		"UserID":   &dynamotypes.AttributeValueMemberS{Value: userID},
+		"EventID":  &dynamotypes.AttributeValueMemberS{Value: eventID},
+		"Title":    &dynamotypes.AttributeValueMemberS{Value: "Stand-up"},
+		"Message":  &dynamotypes.AttributeValueMemberS{Value: "{{title}} in 10 minutes"},
+		"Timezone": &dynamotypes.AttributeValueMemberS{Value: "Europe/Warsaw"},
+		"Phones":   &dynamotypes.AttributeValueMemberL{Value: []dynamotypes.AttributeValue{&dynamotypes.AttributeValueMemberS{Value: "work"}}},
+		"Dates":    toMap(dates),
+		"Crons":    toMap(crons),
+	}
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-time.Hour)
+
+	working, expired, missing := schedule.Name("u1", "e1", "0"), schedule.Name("u1", "e1", "1"), schedule.Name("u1", "e1", "2")
+	deferred := schedule.DeferredName("u1", "e1", old, time.Time{})
+	orphan, orphanDeferred := schedule.Name("u1", "e2", "0"), schedule.DeferredName("u1", "e4", old, time.Time{})
+	recent := schedule.Name("u1", "e3", "0")
+
+	dynamoClient := &fakeDynamoDB{items: map[string]map[string]dynamotypes.AttributeValue{
+		key("u1", "e1"): eventItem("u1", "e1",
+			map[string]string{expired: "2024-02-01T10:00"},
+			map[string]string{working: "0 10 ? * MON-FRI *", missing: "0 12 ? * * *"},
+		),
+		// created before schedule groups were introduced
+		key("u2", "e5"): eventItem("u2", "e5", map[string]string{"8f0c5d9e-legacy": "2030-01-01T10:00"}, nil),
+	}}
+	schedulerClient := &fakeScheduler{now: now, schedules: map[string]fakeSchedule{
+		working:        {created: old},
+		deferred:       {created: old},
+		orphan:         {created: old},
+		orphanDeferred: {created: old},
+		recent:         {created: now.Add(-time.Minute)},
+	}}
+	handler := reconciler.Handler{
+		DynamoClient:    dynamoClient,
+		SchedulerClient: schedulerClient,
+		Config:          reconciler.Config{Target: alarmschedule.Target{Group: "alarms"}},
+		Now:             func() time.Time { return now },
+	}
+
+	report, err := handler.Handle(context.Background(), reconciler.Request{DryRun: true})
+	if err != nil {
+		t.Fatalf("Error when reconciling: %v", err)
+	}
+	expected := &reconciler.Report{
+		DryRun:    true,
+		Events:    2,
+		Schedules: 5,
+		Orphans: []reconciler.Finding{
+			{Schedule: orphan, UserID: "u1", EventID: "e2"},
+			{Schedule: orphanDeferred, UserID: "u1", EventID: "e4"},
+		},
+		Gaps: []reconciler.Finding{
+			{Schedule: missing, UserID: "u1", EventID: "e1", Expression: "cron(0 12 ? * * *)"},
+		},
+		Expired: 1,
+		Legacy:  1,
+	}
+	sort.Slice(expected.Orphans, func(i, j int) bool { return expected.Orphans[i].Schedule < expected.Orphans[j].Schedule })
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Received result: %+v is different than expected one: %+v", report, expected)
+	}
+	if len(schedulerClient.schedules) != 5 {
+		t.Errorf("Schedules were changed in dry-run mode")
+	}
+
+	report, err = handler.Handle(context.Background(), reconciler.Request{})
+	if err != nil {
+		t.Fatalf("Error when reconciling: %v", err)
+	}
+	for _, finding := range append(report.Orphans, report.Gaps...) {
+		if !finding.Repaired {
+			t.Errorf("Schedule %v wasn't repaired: %v", finding.Schedule, finding.Error)
+		}
+	}
+	if _, ok := schedulerClient.schedules[orphan]; ok {
+		t.Errorf("Orphaned schedule wasn't deleted")
+	}
+	created, ok := schedulerClient.schedules[missing]
+	if !ok {
+		t.Fatalf("Missing schedule wasn't created")
+	}
+	if *created.input.ScheduleExpression != "cron(0 12 ? * * *)" || *created.input.GroupName != "alarms" || *created.input.ScheduleExpressionTimezone != "Europe/Warsaw" {
+		t.Errorf("Created schedule: %v in %v (%v) is different than expected", *created.input.ScheduleExpression, *created.input.GroupName, *created.input.ScheduleExpressionTimezone)
+	}
+
+	report, err = handler.Handle(context.Background(), reconciler.Request{})
+	if err != nil {
+		t.Fatalf("Error when reconciling: %v", err)
+	}
+	if len(report.Orphans) != 0 || len(report.Gaps) != 0 {
+		t.Errorf("Drift remained after repair: %+v", report)
+	}
+}
+
+func TestHandlerEventDeleted(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	missing := schedule.Name("u1", "e1", "0")
+
+	dynamoClient := &fakeDynamoDB{items: map[string]map[string]dynamotypes.AttributeValue{
+		key("u1", "e1"): eventItem("u1", "e1", nil, map[string]string{missing: "0 12 ? * * *"}),
+	}}
+	// event is deleted after the table is scanned
+	deletingClient := &deletingDynamoDB{fakeDynamoDB: dynamoClient}
+	handler := reconciler.Handler{
+		DynamoClient:    deletingClient,
+		SchedulerClient: &fakeScheduler{now: now, schedules: map[string]fakeSchedule{}},
+		Now:             func() time.Time { return now },
+	}
+
+	report, err := handler.Handle(context.Background(), reconciler.Request{})
+	if err != nil {
+		t.Fatalf("Error when reconciling: %v", err)
+	}
+	if len(report.Gaps) != 1 || report.Gaps[0].Repaired || report.Gaps[0].Error == "" {
+		t.Errorf("Schedule of deleted event was repaired: %+v", report.Gaps)
+	}
+}
+
+type deletingDynamoDB struct {
+	*fakeDynamoDB
+}
+
+func (d *deletingDynamoDB) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{}, nil
+}
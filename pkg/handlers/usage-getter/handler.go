@@ -0,0 +1,103 @@
+package usagegetter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/envconfig"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/errors"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+)
+
+type DynamoApiClient interface {
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+// Config of the handler, read from environment variables of the function
+type Config struct {
+	UsageTableName string
+	// MonthlyQuota is a number of SMS segments user can use in a month
+	MonthlyQuota int
+	// AllowedOrigins are origins responses are allowed for by CORS
+	AllowedOrigins httpx.AllowedOrigins
+}
+
+// ConfigFromEnv reads configuration from environment variables, reporting all missing ones
+func ConfigFromEnv() (Config, error) {
+	env := envconfig.New()
+	config := Config{
+		UsageTableName: env.Required("USAGE_TABLE_NAME"),
+		MonthlyQuota:   env.Int("MONTHLY_SMS_QUOTA", usage.DefaultMonthlyQuota, 0),
+		AllowedOrigins: httpx.ParseOrigins(env.Optional("CORS_ALLOWED_ORIGINS")),
+	}
+	return config, env.Err()
+}
+
+type Handler struct {
+	DynamoClient DynamoApiClient
+	Config       Config
+	// Now returns current time, time.Now is used when it's not set
+	Now func() time.Time
+}
+
+type Usage struct {
+	Month     string `json:"month"`
+	Messages  int    `json:"messages"`
+	Segments  int    `json:"segments"`
+	Quota     int    `json:"quota"`
+	Remaining int    `json:"remaining"`
+}
+
+func (h *Handler) Handle(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return httpx.API(h.Config.AllowedOrigins, h.handle)(ctx, request)
+}
+
+func (h *Handler) handle(ctx context.Context, principal httpx.Principal, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	now := time.Now()
+	if h.Now != nil {
+		now = h.Now()
+	}
+
+	result := Usage{
+		Month: usage.Month(now),
+		Quota: h.Config.MonthlyQuota,
+	}
+
+	res, err := h.DynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(h.Config.UsageTableName),
+		Key: map[string]dynamotypes.AttributeValue{
+			"UserID": &dynamotypes.AttributeValueMemberS{Value: principal.UserID},
+			"Month":  &dynamotypes.AttributeValueMemberS{Value: result.Month},
+		},
+	})
+	if err != nil {
+		return errors.Internal(ctx, err)
+	}
+
+	// Usage item is created with the first message sent in a month
+	if result.Messages, err = numberAttribute(res.Item, "Messages"); err != nil {
+		return errors.Internal(ctx, err)
+	}
+	if result.Segments, err = numberAttribute(res.Item, "Segments"); err != nil {
+		return errors.Internal(ctx, err)
+	}
+	result.Remaining = max(result.Quota-result.Segments, 0)
+
+	return httpx.JSON(ctx, http.StatusOK, result)
+}
+
+func numberAttribute(item map[string]dynamotypes.AttributeValue, name string) (int, error) {
+	value, ok := item[name].(*dynamotypes.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.Atoi(value.Value)
+}
@@ -0,0 +1,122 @@
+package usagegetter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	usagegetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/usage-getter"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type mockDynamo struct {
+	GetItemError error
+	item         map[string]dynamotypes.AttributeValue
+	month        string
+}
+
+func (m *mockDynamo) GetItem(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if m.GetItemError != nil {
+		return nil, m.GetItemError
+	}
+	m.month = input.Key["Month"].(*dynamotypes.AttributeValueMemberS).Value
+	return &dynamodb.GetItemOutput{Item: m.item}, nil
+}
+
+func TestHandler(t *testing.T) {
+	authorizer := map[string]interface{}{
+		"claims": map[string]interface{}{
+			"sub": "1",
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		request            events.APIGatewayProxyRequest
+		getItemError       error
+		item               map[string]dynamotypes.AttributeValue
+		expectedBody       string
+		expectedStatusCode int
+	}{
+		{
+			name: "no authorizer",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{},
+			},
+			expectedBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"authorization data not found","code":"unauthorized"}`,
+			expectedStatusCode: 401,
+		},
+		{
+			name: "get item error",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			getItemError:       errors.New("some error"),
+			expectedBody:       `{"type":"about:blank","title":"Internal Server Error","status":500,"detail":"internal server error","code":"internal_error"}`,
+			expectedStatusCode: 500,
+		},
+		{
+			name: "nothing sent",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			expectedBody:       `{"month":"2024-03","messages":0,"segments":0,"quota":300,"remaining":300}`,
+			expectedStatusCode: 200,
+		},
+		{
+			name: "success",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			item: map[string]dynamotypes.AttributeValue{
+				"UserID":   &dynamotypes.AttributeValueMemberS{Value: "1"},
+				"Month":    &dynamotypes.AttributeValueMemberS{Value: "2024-03"},
+				"Messages": &dynamotypes.AttributeValueMemberN{Value: "40"},
+				"Segments": &dynamotypes.AttributeValueMemberN{Value: "52"},
+			},
+			expectedBody:       `{"month":"2024-03","messages":40,"segments":52,"quota":300,"remaining":248}`,
+			expectedStatusCode: 200,
+		},
+		{
+			name: "over quota",
+			request: events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{Authorizer: authorizer},
+			},
+			item: map[string]dynamotypes.AttributeValue{
+				"Messages": &dynamotypes.AttributeValueMemberN{Value: "300"},
+				"Segments": &dynamotypes.AttributeValueMemberN{Value: "302"},
+			},
+			expectedBody:       `{"month":"2024-03","messages":300,"segments":302,"quota":300,"remaining":0}`,
+			expectedStatusCode: 200,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			dynamoClient := &mockDynamo{GetItemError: tC.getItemError, item: tC.item}
+			handler := usagegetter.Handler{
+				DynamoClient: dynamoClient,
+				Config:       usagegetter.Config{UsageTableName: "GO_UsageTable", MonthlyQuota: 300},
+				Now:          func() time.Time { return time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC) },
+			}
+
+			res, err := handler.Handle(context.Background(), tC.request)
+			if err != nil {
+				t.Errorf("Error occured when handling request: %v", err)
+			}
+
+			if res.Body != tC.expectedBody {
+				t.Errorf("Received result: %v is different than expected one: %v", res.Body, tC.expectedBody)
+			}
+			if res.StatusCode != tC.expectedStatusCode {
+				t.Errorf("Received status code: %v is different than expected one: %v", res.StatusCode, tC.expectedStatusCode)
+			}
+			if tC.expectedStatusCode == 200 && dynamoClient.month != "2024-03" {
+				t.Errorf("Usage read for month: %v instead of current one", dynamoClient.month)
+			}
+		})
+	}
+}
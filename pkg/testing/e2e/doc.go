@@ -0,0 +1,4 @@
+// Package e2e holds scenario tests of user journeys spanning several handlers. Handlers are
+// wired together over shared in-memory backends from package fakes, and time is driven by
+// a virtual clock, so that alarms fire during tests without waiting or deploying the stack.
+package e2e
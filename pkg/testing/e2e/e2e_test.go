@@ -0,0 +1,506 @@
+package e2e_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/alarmschedule"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/httpx"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/sms"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/usage"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/workerpool"
+	accountdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/account-deleter"
+	alarmcreator "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-creator"
+	alarmdeleter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-deleter"
+	alarmexecutor "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-executor"
+	alarmgetter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/alarm-getter"
+	dataexporter "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/data-exporter"
+	phonemodifier "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-modifier"
+	phoneverifier "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/phone-verifier"
+	postconfirmationtrigger "github.com/Slimo300/Reminder-Serverless-Go/pkg/handlers/post-confirmation-trigger"
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+	"github.com/aws/aws-lambda-go/events"
+	dynamotypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	phonesTable      = "GO_PhonesTable"
+	alarmsTable      = "GO_AlarmTable"
+	codesTable       = "GO_CodesTable"
+	settingsTable    = "GO_SettingsTable"
+	deliveriesTable  = "GO_DeliveriesTable"
+	usageTable       = "GO_UsageTable"
+	idempotencyTable = "GO_IdempotencyTable"
+	auditTable       = "GO_AuditTable"
+	exportsTable     = "GO_ExportsTable"
+	scheduleGroup    = "GO_Alarms"
+
+	topicArn    = "arn:aws:sns:eu-central-1:000000000000:GO_ReminderSnsTopic"
+	executorArn = "arn:aws:lambda:eu-central-1:000000000000:function:GO_AlarmExecutor"
+	roleArn     = "arn:aws:iam::000000000000:role/GO_LambdaExecutorInvokeRole"
+	userPoolID  = "eu-central-1_GOReminder"
+)
+
+// stack wires handlers together over shared in-memory backends, the same way the stack
+// wires lambdas over AWS services
+type stack struct {
+	t         *testing.T
+	clock     *fakes.VirtualClock
+	dynamo    *fakes.DynamoDB
+	sns       *fakes.SNS
+	cognito   *fakes.Cognito
+	scheduler *fakes.Scheduler
+}
+
+func newStack(t *testing.T, now time.Time) *stack {
+	clock := fakes.NewVirtualClock(now)
+	s := &stack{
+		t:         t,
+		clock:     clock,
+		dynamo:    fakes.NewDynamoDB(),
+		sns:       fakes.NewSNS(clock),
+		cognito:   fakes.NewCognito(),
+		scheduler: fakes.NewScheduler(clock),
+	}
+
+	s.dynamo.CreateTable(phonesTable, "UserID", "Label")
+	s.dynamo.CreateTable(alarmsTable, "UserID", "EventID")
+	s.dynamo.CreateTable(codesTable, "UserID", "")
+	s.dynamo.CreateTable(settingsTable, "UserID", "")
+	s.dynamo.CreateTable(deliveriesTable, "UserID", "DeliveryID")
+	s.dynamo.CreateTable(usageTable, "UserID", "Month")
+	s.dynamo.CreateTable(idempotencyTable, "UserID", "Key")
+	s.dynamo.CreateTable(auditTable, "UserID", "Action")
+	s.dynamo.CreateTable(exportsTable, "UserID", "ExportID")
+	s.scheduler.CreateGroup(scheduleGroup)
+
+	executor := &alarmexecutor.Handler{SNSClient: s.sns, DynamoClient: s.dynamo, SchedulerClient: s.scheduler, Now: s.clock.Now, Config: alarmexecutor.Config{
+		TopicArn:            topicArn,
+		TableName:           alarmsTable,
+		SettingsTableName:   settingsTable,
+		UsageTableName:      usageTable,
+		DeliveriesTableName: deliveriesTable,
+		RoleArn:             roleArn,
+		ScheduleGroup:       scheduleGroup,
+		MaxSegments:         sms.DefaultMaxSegments,
+		MonthlyQuota:        usage.DefaultMonthlyQuota,
+	}}
+	s.scheduler.Register(executorArn, fakes.LambdaTarget(executorArn, executor.Handle))
+	return s
+}
+
+// signUp creates a confirmed user and runs post confirmation trigger, as Cognito does when user
+// confirms their phone number
+func (s *stack) signUp(userName, sub, phoneNumber string) {
+	s.t.Helper()
+
+	s.cognito.AddUser(userName, map[string]string{
+		"sub":                   sub,
+		"phone_number":          phoneNumber,
+		"phone_number_verified": "true",
+	})
+
+	trigger := &postconfirmationtrigger.Handler{CognitoClient: s.cognito, SnsClient: s.sns, DynamoClient: s.dynamo, Config: postconfirmationtrigger.Config{
+		TopicArn:        topicArn,
+		PhonesTableName: phonesTable,
+	}}
+	if _, err := trigger.Handle(context.Background(), events.CognitoEventUserPoolsPostConfirmation{
+		CognitoEventUserPoolsHeader: events.CognitoEventUserPoolsHeader{
+			TriggerSource: "PostConfirmation_ConfirmSignUp",
+			UserPoolID:    userPoolID,
+			UserName:      userName,
+		},
+		Request: events.CognitoEventUserPoolsPostConfirmationRequest{
+			UserAttributes: map[string]string{"sub": sub, "phone_number": phoneNumber},
+		},
+	}); err != nil {
+		s.t.Fatalf("Post confirmation trigger failed: %v", err)
+	}
+}
+
+// request builds API Gateway request of a user with claims Cognito authorizer would pass,
+// taken from current attributes of the user
+func (s *stack) request(userName, body string, pathParameters map[string]string) events.APIGatewayProxyRequest {
+	s.t.Helper()
+
+	_, attributes, ok := s.cognito.User(userName)
+	if !ok {
+		s.t.Fatalf("User %s doesn't exist", userName)
+	}
+	claims := map[string]interface{}{"cognito:username": userName}
+	for name, value := range attributes {
+		claims[name] = value
+	}
+	return events.APIGatewayProxyRequest{
+		Body:           body,
+		PathParameters: pathParameters,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{"claims": claims},
+		},
+	}
+}
+
+// call invokes API handler and fails the test unless it responds with expected status code
+func (s *stack) call(handler httpx.Handler, request events.APIGatewayProxyRequest, expectedStatus int) events.APIGatewayProxyResponse {
+	s.t.Helper()
+
+	res, err := handler(context.Background(), request)
+	if err != nil {
+		s.t.Fatalf("Handler failed: %v", err)
+	}
+	if res.StatusCode != expectedStatus {
+		s.t.Fatalf("Received status code: %v is different than expected one: %v, body: %s", res.StatusCode, expectedStatus, res.Body)
+	}
+	return res
+}
+
+// createEvent creates an event with alarm creator and returns its ID
+func (s *stack) createEvent(userName string, body alarmcreator.RequestBody) string {
+	s.t.Helper()
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	creator := &alarmcreator.Handler{DynamoClient: s.dynamo, SchedulerClient: s.scheduler, Now: s.clock.Now, Config: alarmcreator.Config{
+		TableName:            alarmsTable,
+		IdempotencyTableName: idempotencyTable,
+		PhonesTableName:      phonesTable,
+		Target:               alarmschedule.Target{FunctionArn: executorArn, RoleArn: roleArn, Group: scheduleGroup},
+		MaxSegments:          sms.DefaultMaxSegments,
+		MonthlyQuota:         usage.DefaultMonthlyQuota,
+		SchedulerConcurrency: workerpool.DefaultSize,
+		Limits: alarmcreator.Limits{
+			SchedulesPerRequest: alarmcreator.DefaultSchedulesPerRequest,
+			CronsPerEvent:       alarmcreator.DefaultCronsPerEvent,
+			EventsPerUser:       alarmcreator.DefaultEventsPerUser,
+		},
+	}}
+	res := s.call(creator.Handle, s.request(userName, string(reqBody), nil), http.StatusCreated)
+
+	var event struct {
+		EventID string `json:"EventID"`
+	}
+	if err := json.Unmarshal([]byte(res.Body), &event); err != nil || event.EventID == "" {
+		s.t.Fatalf("Invalid response of alarm creator: %s", res.Body)
+	}
+	return event.EventID
+}
+
+// events returns IDs of events of a user listed by alarm getter
+func (s *stack) events(userName string) []string {
+	s.t.Helper()
+
+	getter := &alarmgetter.AlarmGetterHandler{DynamoClient: s.dynamo, Config: alarmgetter.Config{TableName: alarmsTable}}
+	res := s.call(getter.Handle, s.request(userName, "", nil), http.StatusOK)
+
+	var items []struct {
+		EventID string `json:"EventID"`
+	}
+	if err := json.Unmarshal([]byte(res.Body), &items); err != nil {
+		s.t.Fatalf("Invalid response of alarm getter: %s", res.Body)
+	}
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.EventID)
+	}
+	return ids
+}
+
+// deleteEvent deletes an event with alarm deleter
+func (s *stack) deleteEvent(userName, eventID string) {
+	s.t.Helper()
+
+	deleter := &alarmdeleter.Handler{DynamoClient: s.dynamo, SchedulerClient: s.scheduler, Config: alarmdeleter.Config{
+		TableName:            alarmsTable,
+		ScheduleGroup:        scheduleGroup,
+		SchedulerConcurrency: workerpool.DefaultSize,
+	}}
+	s.call(deleter.Handle, s.request(userName, "", map[string]string{"id": eventID}), http.StatusOK)
+}
+
+var verificationCode = regexp.MustCompile(`Your verification code: (\d+)`)
+
+// changePhoneNumber replaces default phone number of a user with phone modifier and verifier,
+// using the code sent to the current number
+func (s *stack) changePhoneNumber(userName, phoneNumber string) {
+	s.t.Helper()
+
+	_, attributes, _ := s.cognito.User(userName)
+	modifier := &phonemodifier.Handler{SnsClient: s.sns, DynamoClient: s.dynamo, Config: phonemodifier.Config{CodesTableName: codesTable}}
+	s.call(modifier.Handle, s.request(userName, `{"phone_number": "`+phoneNumber+`"}`, nil), http.StatusOK)
+
+	messages := s.sns.MessagesTo(attributes["phone_number"])
+	if len(messages) == 0 {
+		s.t.Fatalf("Verification code was not sent to %s", attributes["phone_number"])
+	}
+	match := verificationCode.FindStringSubmatch(messages[len(messages)-1].Message)
+	if match == nil {
+		s.t.Fatalf("Last message to %s doesn't contain verification code: %q", attributes["phone_number"], messages[len(messages)-1].Message)
+	}
+
+	verifier := &phoneverifier.Handler{SnsClient: s.sns, DynamoClient: s.dynamo, CognitoClient: s.cognito, Config: phoneverifier.Config{
+		CodesTableName:  codesTable,
+		PhonesTableName: phonesTable,
+		TopicArn:        topicArn,
+		UserPoolID:      userPoolID,
+	}}
+	s.call(verifier.Handle, s.request(userName, `{"verification_code": "`+match[1]+`"}`, nil), http.StatusOK)
+}
+
+// deleteAccount deletes account of a user with account deleter
+func (s *stack) deleteAccount(userName string) {
+	s.t.Helper()
+
+	deleter := &accountdeleter.Handler{DynamoClient: s.dynamo, SchedulerClient: s.scheduler, SnsClient: s.sns, CognitoClient: s.cognito, Now: s.clock.Now, Config: accountdeleter.Config{
+		AlarmsTableName:      alarmsTable,
+		PhonesTableName:      phonesTable,
+		CodesTableName:       codesTable,
+		SettingsTableName:    settingsTable,
+		DeliveriesTableName:  deliveriesTable,
+		UsageTableName:       usageTable,
+		IdempotencyTableName: idempotencyTable,
+		ExportsTableName:     exportsTable,
+		AuditTableName:       auditTable,
+		UserPoolID:           userPoolID,
+		ScheduleGroup:        scheduleGroup,
+		SchedulerConcurrency: workerpool.DefaultSize,
+	}}
+	s.call(deleter.Handle, s.request(userName, "", nil), http.StatusOK)
+}
+
+// exportData returns export of personal data of a user produced by data exporter
+func (s *stack) exportData(userName string) dataexporter.Document {
+	s.t.Helper()
+
+	exporter := &dataexporter.Handler{DynamoClient: s.dynamo, CognitoClient: s.cognito, SchedulerClient: s.scheduler, Now: s.clock.Now, Config: dataexporter.Config{
+		AlarmsTableName:     alarmsTable,
+		PhonesTableName:     phonesTable,
+		CodesTableName:      codesTable,
+		SettingsTableName:   settingsTable,
+		DeliveriesTableName: deliveriesTable,
+		UsageTableName:      usageTable,
+		ExportsTableName:    exportsTable,
+		UserPoolID:          userPoolID,
+		ScheduleGroup:       scheduleGroup,
+		SyncLimit:           dataexporter.DefaultSyncLimit,
+	}}
+	res := s.call(exporter.Handle, s.request(userName, "", nil), http.StatusOK)
+
+	var document dataexporter.Document
+	if err := json.Unmarshal([]byte(res.Body), &document); err != nil {
+		s.t.Fatalf("Export couldn't be decoded: %v", err)
+	}
+	return document
+}
+
+// advance moves the clock forward, firing schedules that become due
+func (s *stack) advance(d time.Duration) {
+	s.t.Helper()
+
+	if err := s.scheduler.Advance(context.Background(), d); err != nil {
+		s.t.Fatalf("Firing schedules failed: %v", err)
+	}
+}
+
+// reminders returns messages delivered to a phone number other than verification codes
+func (s *stack) reminders(phoneNumber string) []fakes.SMS {
+	var reminders []fakes.SMS
+	for _, sms := range s.sns.MessagesTo(phoneNumber) {
+		if !verificationCode.MatchString(sms.Message) {
+			reminders = append(reminders, sms)
+		}
+	}
+	return reminders
+}
+
+func TestReminderJourney(t *testing.T) {
+	// Monday, 7:00 in Warsaw
+	s := newStack(t, time.Date(2024, 3, 4, 6, 0, 0, 0, time.UTC))
+	s.signUp("john", "1", "+48111111111")
+	s.signUp("jane", "2", "+48333333333")
+
+	_, john, _ := s.cognito.User("john")
+	if john["custom:subscription_arn"] == "" {
+		t.Fatalf("Subscription of signed up user was not saved in Cognito")
+	}
+
+	eventID := s.createEvent("john", alarmcreator.RequestBody{
+		Message:  "Stand-up",
+		Timezone: "Europe/Warsaw",
+		Crons:    []string{"0 9 ? * MON-FRI *"},
+		Dates:    []string{"2024-03-04T12:00"},
+	})
+	if ids := s.events("john"); len(ids) != 1 || ids[0] != eventID {
+		t.Errorf("Received events: %v are different than expected ones: %v", ids, []string{eventID})
+	}
+
+	// Both the cron and the date fire on Monday
+	s.advance(12 * time.Hour)
+
+	reminders := s.reminders("+48111111111")
+	if len(reminders) != 2 {
+		t.Fatalf("Received reminders: %v are different than expected ones", reminders)
+	}
+	expectedTimes := []time.Time{
+		time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 4, 11, 0, 0, 0, time.UTC),
+	}
+	for i, sms := range reminders {
+		if sms.SubscriptionArn != john["custom:subscription_arn"] {
+			t.Errorf("Reminder was delivered through subscription: %v instead of: %v", sms.SubscriptionArn, john["custom:subscription_arn"])
+		}
+		if !sms.SentAt.Equal(expectedTimes[i]) {
+			t.Errorf("Received time of reminder: %v is different than expected one: %v", sms.SentAt, expectedTimes[i])
+		}
+		if sms.Message != "Stand-up" {
+			t.Errorf("Received message: %q is different than expected one: %q", sms.Message, "Stand-up")
+		}
+	}
+	if reminders := s.reminders("+48333333333"); len(reminders) != 0 {
+		t.Errorf("Reminders of other user were delivered to %s: %v", "+48333333333", reminders)
+	}
+
+	s.changePhoneNumber("john", "+48222222222")
+
+	_, john, _ = s.cognito.User("john")
+	if john["phone_number"] != "+48222222222" {
+		t.Errorf("Received phone number: %v is different than expected one: %v", john["phone_number"], "+48222222222")
+	}
+
+	// Only the cron fires on Tuesday, and it goes to the new number
+	s.advance(24 * time.Hour)
+
+	if reminders := s.reminders("+48111111111"); len(reminders) != 2 {
+		t.Errorf("Reminders were still delivered to replaced number: %v", reminders)
+	}
+	reminders = s.reminders("+48222222222")
+	if len(reminders) != 1 {
+		t.Fatalf("Received reminders: %v are different than expected ones", reminders)
+	}
+	if reminders[0].SubscriptionArn != john["custom:subscription_arn"] {
+		t.Errorf("Reminder was delivered through subscription: %v instead of: %v", reminders[0].SubscriptionArn, john["custom:subscription_arn"])
+	}
+	if !reminders[0].SentAt.Equal(time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Received time of reminder: %v is different than expected one: %v", reminders[0].SentAt, time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC))
+	}
+}
+
+func TestDeletedEventStopsFiring(t *testing.T) {
+	s := newStack(t, time.Date(2024, 3, 4, 6, 0, 0, 0, time.UTC))
+	s.signUp("john", "1", "+48111111111")
+
+	eventID := s.createEvent("john", alarmcreator.RequestBody{
+		Message:  "Water the plants",
+		Timezone: "Europe/Warsaw",
+		Crons:    []string{"0 18 * * ? *"},
+	})
+	s.advance(24 * time.Hour)
+	if reminders := s.reminders("+48111111111"); len(reminders) != 1 {
+		t.Fatalf("Received reminders: %v are different than expected ones", reminders)
+	}
+
+	s.deleteEvent("john", eventID)
+	if ids := s.events("john"); len(ids) != 0 {
+		t.Errorf("Deleted event is still listed: %v", ids)
+	}
+
+	s.advance(7 * 24 * time.Hour)
+	if reminders := s.reminders("+48111111111"); len(reminders) != 1 {
+		t.Errorf("Deleted event kept firing: %v", reminders)
+	}
+}
+
+func TestDeletedAccountLeavesNoTrace(t *testing.T) {
+	s := newStack(t, time.Date(2024, 3, 4, 6, 0, 0, 0, time.UTC))
+	s.signUp("john", "1", "+48111111111")
+	s.signUp("jane", "2", "+48333333333")
+
+	for _, userName := range []string{"john", "jane"} {
+		s.createEvent(userName, alarmcreator.RequestBody{
+			Message:  "Water the plants",
+			Timezone: "Europe/Warsaw",
+			Crons:    []string{"0 18 * * ? *"},
+		})
+	}
+	s.changePhoneNumber("john", "+48222222222")
+	s.advance(24 * time.Hour)
+	if reminders := s.reminders("+48222222222"); len(reminders) != 1 {
+		t.Fatalf("Received reminders: %v are different than expected ones", reminders)
+	}
+
+	s.deleteAccount("john")
+
+	if _, _, ok := s.cognito.User("john"); ok {
+		t.Errorf("Deleted user still exists in Cognito")
+	}
+	for _, table := range []string{phonesTable, alarmsTable, codesTable, settingsTable, deliveriesTable, usageTable, idempotencyTable} {
+		items, err := s.dynamo.Items(table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			if item["UserID"].(*dynamotypes.AttributeValueMemberS).Value == "1" {
+				t.Errorf("Item of deleted user remained in %s: %v", table, item)
+			}
+		}
+	}
+	for _, subscription := range s.sns.Subscriptions() {
+		if subscription.Endpoint != "+48333333333" {
+			t.Errorf("Subscription of deleted user remained: %v", subscription)
+		}
+	}
+
+	s.advance(7 * 24 * time.Hour)
+	if reminders := s.reminders("+48222222222"); len(reminders) != 1 {
+		t.Errorf("Alarms of deleted account kept firing: %v", reminders)
+	}
+	if reminders := s.reminders("+48333333333"); len(reminders) != 8 {
+		t.Errorf("Received reminders of other user: %v are different than expected ones", reminders)
+	}
+}
+
+func TestExportHoldsEverything(t *testing.T) {
+	s := newStack(t, time.Date(2024, 3, 4, 6, 0, 0, 0, time.UTC))
+	s.signUp("john", "1", "+48111111111")
+	s.signUp("jane", "2", "+48333333333")
+
+	for _, userName := range []string{"john", "jane"} {
+		s.createEvent(userName, alarmcreator.RequestBody{
+			Message:  "Water the plants",
+			Timezone: "Europe/Warsaw",
+			Crons:    []string{"0 18 * * ? *"},
+		})
+	}
+	s.advance(24 * time.Hour)
+	// phone number change waiting for verification
+	modifier := &phonemodifier.Handler{SnsClient: s.sns, DynamoClient: s.dynamo, Config: phonemodifier.Config{CodesTableName: codesTable}}
+	s.call(modifier.Handle, s.request("john", `{"phone_number": "+48222222222"}`, nil), http.StatusOK)
+
+	document := s.exportData("john")
+
+	if document.UserID != "1" || document.Attributes["phone_number"] != "+48111111111" {
+		t.Errorf("Received user: %v with attributes %v is different than expected", document.UserID, document.Attributes)
+	}
+	if len(document.Phones) != 1 || document.Phones[0]["PhoneNumber"] != "+48111111111" {
+		t.Errorf("Received phones: %v are different than expected", document.Phones)
+	}
+	if document.PendingPhoneChange["PhoneNumber"] != "+48222222222" || document.PendingPhoneChange["VerificationCode"] != nil {
+		t.Errorf("Received pending phone change: %v is different than expected", document.PendingPhoneChange)
+	}
+	if len(document.Deliveries) != 1 || len(document.Usage) != 1 {
+		t.Errorf("Received deliveries: %v and usage: %v are different than expected", document.Deliveries, document.Usage)
+	}
+
+	if len(document.Events) != 1 || len(document.Events[0].Schedules) != 1 {
+		t.Fatalf("Received events: %v are different than expected", document.Events)
+	}
+	next := time.Date(2024, 3, 5, 17, 0, 0, 0, time.UTC)
+	if schedule := document.Events[0].Schedules[0]; schedule.State != "ENABLED" || schedule.NextFireTime == nil || !schedule.NextFireTime.Equal(next) {
+		t.Errorf("Received schedule: %+v is different than expected one firing at %v", schedule, next)
+	}
+}
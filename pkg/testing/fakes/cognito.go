@@ -0,0 +1,102 @@
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cognito "github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider/types"
+)
+
+// Cognito is in-memory Cognito user pool keeping attributes of users
+type Cognito struct {
+	Faults
+
+	mu    sync.Mutex
+	users map[string]map[string]string
+}
+
+func NewCognito() *Cognito {
+	return &Cognito{users: make(map[string]map[string]string)}
+}
+
+// AddUser creates a user with given attributes, replacing the existing one
+func (c *Cognito) AddUser(userName string, attributes map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.users[userName] = copyAttributes(attributes)
+}
+
+// User returns name and attributes of a user identified by name or sub
+func (c *Cognito) User(id string) (string, map[string]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for userName, attributes := range c.users {
+		if userName == id || attributes["sub"] == id {
+			return userName, copyAttributes(attributes), true
+		}
+	}
+	return "", nil, false
+}
+
+func (c *Cognito) AdminUpdateUserAttributes(ctx context.Context, input *cognito.AdminUpdateUserAttributesInput, optFns ...func(*cognito.Options)) (*cognito.AdminUpdateUserAttributesOutput, error) {
+	if err := c.check("AdminUpdateUserAttributes", input); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	user, ok := c.users[aws.ToString(input.Username)]
+	if !ok {
+		return nil, &types.UserNotFoundException{Message: aws.String("User does not exist.")}
+	}
+	for _, attribute := range input.UserAttributes {
+		user[aws.ToString(attribute.Name)] = aws.ToString(attribute.Value)
+	}
+	return &cognito.AdminUpdateUserAttributesOutput{}, nil
+}
+
+func (c *Cognito) AdminGetUser(ctx context.Context, input *cognito.AdminGetUserInput, optFns ...func(*cognito.Options)) (*cognito.AdminGetUserOutput, error) {
+	if err := c.check("AdminGetUser", input); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	user, ok := c.users[aws.ToString(input.Username)]
+	if !ok {
+		return nil, &types.UserNotFoundException{Message: aws.String("User does not exist.")}
+	}
+	output := &cognito.AdminGetUserOutput{Username: input.Username, Enabled: true}
+	for name, value := range user {
+		output.UserAttributes = append(output.UserAttributes, types.AttributeType{Name: aws.String(name), Value: aws.String(value)})
+	}
+	return output, nil
+}
+
+func (c *Cognito) AdminDeleteUser(ctx context.Context, input *cognito.AdminDeleteUserInput, optFns ...func(*cognito.Options)) (*cognito.AdminDeleteUserOutput, error) {
+	if err := c.check("AdminDeleteUser", input); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.users[aws.ToString(input.Username)]; !ok {
+		return nil, &types.UserNotFoundException{Message: aws.String("User does not exist.")}
+	}
+	delete(c.users, aws.ToString(input.Username))
+	return &cognito.AdminDeleteUserOutput{}, nil
+}
+
+func copyAttributes(attributes map[string]string) map[string]string {
+	copied := make(map[string]string, len(attributes))
+	for name, value := range attributes {
+		copied[name] = value
+	}
+	return copied
+}
@@ -0,0 +1,438 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// DynamoDB is in-memory DynamoDB implementing item operations used by handlers. Tables are
+// looked up by name or by ARN
+type DynamoDB struct {
+	Faults
+
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+type table struct {
+	partitionKey, sortKey string
+	items                 map[string]item
+}
+
+func NewDynamoDB() *DynamoDB {
+	return &DynamoDB{tables: make(map[string]*table)}
+}
+
+// CreateTable adds a table with given key schema, sortKey is empty for tables with simple primary key
+func (d *DynamoDB) CreateTable(name, partitionKey, sortKey string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tables[name] = &table{partitionKey: partitionKey, sortKey: sortKey, items: make(map[string]item)}
+}
+
+func (d *DynamoDB) table(name *string) (*table, error) {
+	tableName := aws.ToString(name)
+	if _, resource, ok := strings.Cut(tableName, ":table/"); ok {
+		tableName = resource
+	}
+	t, ok := d.tables[tableName]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Requested resource not found: Table: %s not found", tableName))}
+	}
+	return t, nil
+}
+
+// Items returns all items of a table ordered by their primary key
+func (d *DynamoDB) Items(tableName string) ([]map[string]types.AttributeValue, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, err := d.table(&tableName)
+	if err != nil {
+		return nil, err
+	}
+	var items []map[string]types.AttributeValue
+	for _, it := range t.sorted() {
+		items = append(items, copyItem(it))
+	}
+	return items, nil
+}
+
+func validationError(format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: fmt.Sprintf(format, args...)}
+}
+
+func conditionFailed(old item, returnValues types.ReturnValuesOnConditionCheckFailure) error {
+	err := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	if returnValues == types.ReturnValuesOnConditionCheckFailureAllOld {
+		err.Item = copyItem(old)
+	}
+	return err
+}
+
+// keyOf encodes primary key of an item, requiring key to consist of key attributes only when exact is set
+func (t *table) keyOf(it item, exact bool) (string, error) {
+	attributes := []string{t.partitionKey}
+	if t.sortKey != "" {
+		attributes = append(attributes, t.sortKey)
+	}
+	if exact && len(it) != len(attributes) {
+		return "", validationError("The provided key element does not match the schema")
+	}
+
+	var key strings.Builder
+	for _, attribute := range attributes {
+		switch value := it[attribute].(type) {
+		case *types.AttributeValueMemberS:
+			fmt.Fprintf(&key, "S%q", value.Value)
+		case *types.AttributeValueMemberN:
+			n, ok := number(value)
+			if !ok {
+				return "", validationError("Invalid number %q of key attribute %s", value.Value, attribute)
+			}
+			fmt.Fprintf(&key, "N%q", n.RatString())
+		case *types.AttributeValueMemberB:
+			fmt.Fprintf(&key, "B%q", value.Value)
+		default:
+			return "", validationError("The provided key element does not match the schema")
+		}
+	}
+	return key.String(), nil
+}
+
+func (t *table) keyAttributes(it item) item {
+	key := item{t.partitionKey: it[t.partitionKey]}
+	if t.sortKey != "" {
+		key[t.sortKey] = it[t.sortKey]
+	}
+	return key
+}
+
+// sorted returns items ordered by partition and sort key
+func (t *table) sorted() []item {
+	items := make([]item, 0, len(t.items))
+	for _, it := range t.items {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return t.order(items[i], items[j]) < 0
+	})
+	return items
+}
+
+// order compares primary keys of two items
+func (t *table) order(a, b item) int {
+	if c, _ := compare(a[t.partitionKey], b[t.partitionKey]); c != 0 || t.sortKey == "" {
+		return c
+	}
+	c, _ := compare(a[t.sortKey], b[t.sortKey])
+	return c
+}
+
+func (d *DynamoDB) GetItem(ctx context.Context, input *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if err := d.check("GetItem", input); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, err := d.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.keyOf(input.Key, true)
+	if err != nil {
+		return nil, err
+	}
+	it, err := project(copyItem(t.items[key]), aws.ToString(input.ProjectionExpression), input.ExpressionAttributeNames)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	return &dynamodb.GetItemOutput{Item: it}, nil
+}
+
+func (d *DynamoDB) PutItem(ctx context.Context, input *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if err := d.check("PutItem", input); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, err := d.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.keyOf(input.Item, false)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+	ok, err := evalCondition(aws.ToString(input.ConditionExpression), input.ExpressionAttributeNames, input.ExpressionAttributeValues, old)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	if !ok {
+		return nil, conditionFailed(old, input.ReturnValuesOnConditionCheckFailure)
+	}
+
+	t.items[key] = copyItem(input.Item)
+
+	output := &dynamodb.PutItemOutput{}
+	if input.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+	return output, nil
+}
+
+func (d *DynamoDB) UpdateItem(ctx context.Context, input *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if err := d.check("UpdateItem", input); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, err := d.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.keyOf(input.Key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	old, exists := t.items[key]
+	ok, err := evalCondition(aws.ToString(input.ConditionExpression), input.ExpressionAttributeNames, input.ExpressionAttributeValues, old)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	if !ok {
+		return nil, conditionFailed(old, input.ReturnValuesOnConditionCheckFailure)
+	}
+
+	base := old
+	if !exists {
+		base = copyItem(input.Key)
+	}
+	updated, changed, err := applyUpdate(aws.ToString(input.UpdateExpression), input.ExpressionAttributeNames, input.ExpressionAttributeValues, base)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	for _, name := range changed {
+		if name == t.partitionKey || name == t.sortKey {
+			return nil, validationError("Cannot update attribute %s. This attribute is part of the key", name)
+		}
+	}
+	t.items[key] = updated
+
+	output := &dynamodb.UpdateItemOutput{}
+	switch input.ReturnValues {
+	case types.ReturnValueAllOld:
+		output.Attributes = copyItem(old)
+	case types.ReturnValueAllNew:
+		output.Attributes = copyItem(updated)
+	case types.ReturnValueUpdatedOld, types.ReturnValueUpdatedNew:
+		source := updated
+		if input.ReturnValues == types.ReturnValueUpdatedOld {
+			source = old
+		}
+		output.Attributes = make(item)
+		for _, name := range changed {
+			if value, ok := source[name]; ok {
+				output.Attributes[name] = value
+			}
+		}
+	}
+	return output, nil
+}
+
+func (d *DynamoDB) DeleteItem(ctx context.Context, input *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if err := d.check("DeleteItem", input); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, err := d.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.keyOf(input.Key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	old := t.items[key]
+	ok, err := evalCondition(aws.ToString(input.ConditionExpression), input.ExpressionAttributeNames, input.ExpressionAttributeValues, old)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	if !ok {
+		return nil, conditionFailed(old, input.ReturnValuesOnConditionCheckFailure)
+	}
+
+	delete(t.items, key)
+
+	output := &dynamodb.DeleteItemOutput{}
+	if input.ReturnValues == types.ReturnValueAllOld {
+		output.Attributes = copyItem(old)
+	}
+	return output, nil
+}
+
+func (d *DynamoDB) Query(ctx context.Context, input *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if err := d.check("Query", input); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, err := d.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if input.IndexName != nil {
+		return nil, validationError("The table does not have the specified index: %s", aws.ToString(input.IndexName))
+	}
+	if input.KeyConditionExpression == nil {
+		return nil, validationError("KeyConditionExpression must be specified")
+	}
+
+	var candidates []item
+	for _, it := range t.sorted() {
+		ok, err := evalCondition(*input.KeyConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, it)
+		if err != nil {
+			return nil, validationError("%v", err)
+		}
+		if ok {
+			candidates = append(candidates, it)
+		}
+	}
+	backward := input.ScanIndexForward != nil && !*input.ScanIndexForward
+	if backward {
+		for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		}
+	}
+
+	page, err := t.page(candidates, pageInput{
+		startKey:   input.ExclusiveStartKey,
+		limit:      input.Limit,
+		filter:     aws.ToString(input.FilterExpression),
+		projection: aws.ToString(input.ProjectionExpression),
+		names:      input.ExpressionAttributeNames,
+		values:     input.ExpressionAttributeValues,
+		count:      input.Select == types.SelectCount,
+		backward:   backward,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.QueryOutput{Items: page.items, Count: page.count, ScannedCount: page.scanned, LastEvaluatedKey: page.lastKey}, nil
+}
+
+func (d *DynamoDB) Scan(ctx context.Context, input *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if err := d.check("Scan", input); err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, err := d.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if input.IndexName != nil {
+		return nil, validationError("The table does not have the specified index: %s", aws.ToString(input.IndexName))
+	}
+
+	page, err := t.page(t.sorted(), pageInput{
+		startKey:   input.ExclusiveStartKey,
+		limit:      input.Limit,
+		filter:     aws.ToString(input.FilterExpression),
+		projection: aws.ToString(input.ProjectionExpression),
+		names:      input.ExpressionAttributeNames,
+		values:     input.ExpressionAttributeValues,
+		count:      input.Select == types.SelectCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.ScanOutput{Items: page.items, Count: page.count, ScannedCount: page.scanned, LastEvaluatedKey: page.lastKey}, nil
+}
+
+type pageInput struct {
+	startKey   item
+	limit      *int32
+	filter     string
+	projection string
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	count      bool
+	backward   bool
+}
+
+type pageOutput struct {
+	items          []item
+	count, scanned int32
+	lastKey        item
+}
+
+// page evaluates ordered items following the exclusive start key until the limit is reached, filter
+// is applied after the limit as it is in DynamoDB
+func (t *table) page(items []item, input pageInput) (*pageOutput, error) {
+	if len(input.startKey) > 0 {
+		if _, err := t.keyOf(input.startKey, true); err != nil {
+			return nil, err
+		}
+		// start key doesn't need to exist anymore, evaluation continues with the following item
+		for len(items) > 0 {
+			c := t.order(items[0], input.startKey)
+			if (c > 0 && !input.backward) || (c < 0 && input.backward) {
+				break
+			}
+			items = items[1:]
+		}
+	}
+
+	output := &pageOutput{}
+	for i, it := range items {
+		if input.limit != nil && output.scanned == *input.limit {
+			output.lastKey = t.keyAttributes(items[i-1])
+			break
+		}
+		output.scanned++
+
+		ok, err := evalCondition(input.filter, input.names, input.values, it)
+		if err != nil {
+			return nil, validationError("%v", err)
+		}
+		if !ok {
+			continue
+		}
+		output.count++
+		if input.count {
+			continue
+		}
+		projected, err := project(copyItem(it), input.projection, input.names)
+		if err != nil {
+			return nil, validationError("%v", err)
+		}
+		output.items = append(output.items, projected)
+	}
+	return output, nil
+}
@@ -0,0 +1,197 @@
+package fakes_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestConditionExpression(t *testing.T) {
+	d := fakes.NewDynamoDB()
+	d.CreateTable("usage", "UserID", "")
+	it := map[string]types.AttributeValue{
+		"UserID":   &types.AttributeValueMemberS{Value: "1"},
+		"Segments": &types.AttributeValueMemberN{Value: "10"},
+		"Label":    &types.AttributeValueMemberS{Value: "work-phone"},
+	}
+
+	testCases := []struct {
+		name       string
+		expression string
+		values     map[string]types.AttributeValue
+		expected   bool
+		returnErr  bool
+	}{
+		{name: "empty", expression: "", expected: true},
+		{name: "attribute exists", expression: "attribute_exists(UserID)", expected: true},
+		{name: "attribute not exists", expression: "attribute_not_exists(Notified)", expected: true},
+		{
+			name:       "missing attribute or lower number",
+			expression: "attribute_not_exists(Segments) OR Segments <= :remaining",
+			values:     map[string]types.AttributeValue{":remaining": &types.AttributeValueMemberN{Value: "9"}},
+			expected:   false,
+		},
+		{
+			name:       "numbers are compared by value",
+			expression: "Segments > :value",
+			values:     map[string]types.AttributeValue{":value": &types.AttributeValueMemberN{Value: "9.5"}},
+			expected:   true,
+		},
+		{
+			name:       "key condition with begins_with",
+			expression: "#userID = :userID AND begins_with(Label, :prefix)",
+			values: map[string]types.AttributeValue{
+				":userID": &types.AttributeValueMemberS{Value: "1"},
+				":prefix": &types.AttributeValueMemberS{Value: "work"},
+			},
+			expected: true,
+		},
+		{
+			name:       "between and not",
+			expression: "NOT (Segments BETWEEN :low AND :high)",
+			values: map[string]types.AttributeValue{
+				":low":  &types.AttributeValueMemberN{Value: "1"},
+				":high": &types.AttributeValueMemberN{Value: "10"},
+			},
+			expected: false,
+		},
+		{name: "undefined value", expression: "UserID = :userID", returnErr: true},
+		{name: "undefined name", expression: "#label = Label", returnErr: true},
+		{name: "trailing token", expression: "attribute_exists(UserID) UserID", returnErr: true},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			if _, err := d.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("usage"), Item: it}); err != nil {
+				t.Fatalf("Error when putting item: %v", err)
+			}
+
+			input := &dynamodb.PutItemInput{
+				TableName:                 aws.String("usage"),
+				Item:                      it,
+				ConditionExpression:       aws.String(tC.expression),
+				ExpressionAttributeValues: tC.values,
+			}
+			if strings.Contains(tC.expression, "#userID") {
+				input.ExpressionAttributeNames = map[string]string{"#userID": "UserID"}
+			}
+			_, err := d.PutItem(context.Background(), input)
+
+			var conditionErr *types.ConditionalCheckFailedException
+			if failed := errors.As(err, &conditionErr); err != nil && !failed != tC.returnErr {
+				t.Fatalf("Unexpected evaluation error: %v", err)
+			}
+			if res := err == nil; !tC.returnErr && res != tC.expected {
+				t.Errorf("Received result: %v is different than expected one: %v", res, tC.expected)
+			}
+		})
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	d := fakes.NewDynamoDB()
+	d.CreateTable("usage", "UserID", "Month")
+	key := map[string]types.AttributeValue{
+		"UserID": &types.AttributeValueMemberS{Value: "1"},
+		"Month":  &types.AttributeValueMemberS{Value: "2024-03"},
+	}
+	charge := func(segments string) (*dynamodb.UpdateItemOutput, error) {
+		return d.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			TableName:           aws.String("arn:aws:dynamodb:local:000000000000:table/usage"),
+			Key:                 key,
+			UpdateExpression:    aws.String("ADD Segments :segments, Messages :one SET #updated = :updated"),
+			ConditionExpression: aws.String("attribute_not_exists(Segments) OR Segments <= :remaining"),
+			ExpressionAttributeNames: map[string]string{
+				"#updated": "Updated",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":segments":  &types.AttributeValueMemberN{Value: segments},
+				":one":       &types.AttributeValueMemberN{Value: "1"},
+				":remaining": &types.AttributeValueMemberN{Value: "5"},
+				":updated":   &types.AttributeValueMemberBOOL{Value: true},
+			},
+			ReturnValues:                        types.ReturnValueUpdatedNew,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		})
+	}
+
+	if _, err := charge("4"); err != nil {
+		t.Fatalf("Error when creating item: %v", err)
+	}
+	res, err := charge("3")
+	if err != nil {
+		t.Fatalf("Error when updating item: %v", err)
+	}
+	if segments := res.Attributes["Segments"].(*types.AttributeValueMemberN).Value; segments != "7" {
+		t.Errorf("Received result: %v is different than expected one: %v", segments, "7")
+	}
+	if _, ok := res.Attributes["UserID"]; ok {
+		t.Errorf("Key attributes returned as updated")
+	}
+
+	var conditionErr *types.ConditionalCheckFailedException
+	if _, err := charge("1"); !errors.As(err, &conditionErr) {
+		t.Fatalf("Received error: %v is not conditional check failure", err)
+	}
+	if messages := conditionErr.Item["Messages"].(*types.AttributeValueMemberN).Value; messages != "2" {
+		t.Errorf("Received result: %v is different than expected one: %v", messages, "2")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	d := fakes.NewDynamoDB()
+	d.CreateTable("alarms", "UserID", "EventID")
+	for _, userID := range []string{"1", "2"} {
+		for _, eventID := range []string{"c", "a", "b"} {
+			if _, err := d.PutItem(context.Background(), &dynamodb.PutItemInput{
+				TableName: aws.String("alarms"),
+				Item: map[string]types.AttributeValue{
+					"UserID":  &types.AttributeValueMemberS{Value: userID},
+					"EventID": &types.AttributeValueMemberS{Value: eventID},
+					"Message": &types.AttributeValueMemberS{Value: "message"},
+				},
+			}); err != nil {
+				t.Fatalf("Error when putting item: %v", err)
+			}
+		}
+	}
+
+	var events []string
+	var startKey map[string]types.AttributeValue
+	for {
+		res, err := d.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:                aws.String("alarms"),
+			KeyConditionExpression:   aws.String("#userID = :userID"),
+			ProjectionExpression:     aws.String("EventID"),
+			ExpressionAttributeNames: map[string]string{"#userID": "UserID"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":userID": &types.AttributeValueMemberS{Value: "2"},
+			},
+			Limit:             aws.Int32(2),
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			t.Fatalf("Error when querying table: %v", err)
+		}
+		for _, it := range res.Items {
+			if _, ok := it["Message"]; ok {
+				t.Errorf("Attribute not projected was returned")
+			}
+			events = append(events, it["EventID"].(*types.AttributeValueMemberS).Value)
+		}
+		if len(res.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = res.LastEvaluatedKey
+	}
+
+	if len(events) != 3 || events[0] != "a" || events[1] != "b" || events[2] != "c" {
+		t.Errorf("Received result: %v is different than expected one: %v", events, []string{"a", "b", "c"})
+	}
+}
@@ -0,0 +1,706 @@
+package fakes
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// item is a DynamoDB item, keyed by top-level attribute name
+type item = map[string]types.AttributeValue
+
+// expression evaluates DynamoDB condition, key condition and update expressions against an item.
+// It supports the subset of the expression language used by handlers: comparisons, BETWEEN, IN,
+// AND/OR/NOT, attribute_exists, attribute_not_exists, begins_with, contains and size functions,
+// SET (with +, -, if_not_exists and list_append), REMOVE, ADD and DELETE update actions
+type expression struct {
+	tokens []string
+	pos    int
+	names  map[string]string
+	values map[string]types.AttributeValue
+}
+
+func newExpression(text string, names map[string]string, values map[string]types.AttributeValue) (*expression, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	return &expression{tokens: tokens, names: names, values: values}, nil
+}
+
+func tokenize(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("(),=+-[]", c):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '<' || c == '>':
+			if i+1 < len(text) && (text[i+1] == '=' || (c == '<' && text[i+1] == '>')) {
+				tokens = append(tokens, text[i:i+2])
+				i += 2
+			} else {
+				tokens = append(tokens, string(c))
+				i++
+			}
+		default:
+			start := i
+			for i < len(text) && !unicode.IsSpace(rune(text[i])) && !strings.ContainsRune("(),=+-[]<>", rune(text[i])) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("invalid character %q in expression", c)
+			}
+			tokens = append(tokens, text[start:i])
+		}
+	}
+	return tokens, nil
+}
+
+func (e *expression) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+func (e *expression) peekAt(offset int) string {
+	if e.pos+offset < len(e.tokens) {
+		return e.tokens[e.pos+offset]
+	}
+	return ""
+}
+
+func (e *expression) next() string {
+	token := e.peek()
+	e.pos++
+	return token
+}
+
+func (e *expression) expect(token string) error {
+	if next := e.next(); !strings.EqualFold(next, token) {
+		return fmt.Errorf("expected %q in expression, got %q", token, next)
+	}
+	return nil
+}
+
+func (e *expression) end() error {
+	if e.pos < len(e.tokens) {
+		return fmt.Errorf("unexpected token %q in expression", e.peek())
+	}
+	return nil
+}
+
+// evalCondition reports whether an item satisfies condition expression. Empty expression is always satisfied
+func evalCondition(text string, names map[string]string, values map[string]types.AttributeValue, it item) (bool, error) {
+	if strings.TrimSpace(text) == "" {
+		return true, nil
+	}
+	e, err := newExpression(text, names, values)
+	if err != nil {
+		return false, err
+	}
+	result, err := e.or(it)
+	if err == nil {
+		err = e.end()
+	}
+	if err != nil {
+		return false, err
+	}
+	return result, nil
+}
+
+func (e *expression) or(it item) (bool, error) {
+	result, err := e.and(it)
+	for err == nil && strings.EqualFold(e.peek(), "OR") {
+		e.next()
+		var right bool
+		right, err = e.and(it)
+		result = result || right
+	}
+	return result, err
+}
+
+func (e *expression) and(it item) (bool, error) {
+	result, err := e.not(it)
+	for err == nil && strings.EqualFold(e.peek(), "AND") {
+		e.next()
+		var right bool
+		right, err = e.not(it)
+		result = result && right
+	}
+	return result, err
+}
+
+func (e *expression) not(it item) (bool, error) {
+	if strings.EqualFold(e.peek(), "NOT") {
+		e.next()
+		result, err := e.not(it)
+		return !result, err
+	}
+	return e.primary(it)
+}
+
+func (e *expression) primary(it item) (bool, error) {
+	if e.peek() == "(" {
+		e.next()
+		result, err := e.or(it)
+		if err != nil {
+			return false, err
+		}
+		return result, e.expect(")")
+	}
+
+	if e.peekAt(1) == "(" {
+		switch name := strings.ToLower(e.peek()); name {
+		case "attribute_exists", "attribute_not_exists":
+			e.next()
+			e.next()
+			value, err := e.path(it)
+			if err != nil {
+				return false, err
+			}
+			return (value != nil) == (name == "attribute_exists"), e.expect(")")
+		case "begins_with", "contains":
+			e.next()
+			e.next()
+			left, err := e.operand(it)
+			if err != nil {
+				return false, err
+			}
+			if err := e.expect(","); err != nil {
+				return false, err
+			}
+			right, err := e.operand(it)
+			if err != nil {
+				return false, err
+			}
+			if name == "begins_with" {
+				return beginsWith(left, right), e.expect(")")
+			}
+			return contains(left, right), e.expect(")")
+		}
+	}
+
+	left, err := e.operand(it)
+	if err != nil {
+		return false, err
+	}
+	switch op := strings.ToUpper(e.next()); op {
+	case "=", "<>", "<", "<=", ">", ">=":
+		right, err := e.operand(it)
+		if err != nil {
+			return false, err
+		}
+		return compareWith(op, left, right), nil
+	case "BETWEEN":
+		low, err := e.operand(it)
+		if err != nil {
+			return false, err
+		}
+		if err := e.expect("AND"); err != nil {
+			return false, err
+		}
+		high, err := e.operand(it)
+		if err != nil {
+			return false, err
+		}
+		return compareWith(">=", left, low) && compareWith("<=", left, high), nil
+	case "IN":
+		if err := e.expect("("); err != nil {
+			return false, err
+		}
+		found := false
+		for {
+			value, err := e.operand(it)
+			if err != nil {
+				return false, err
+			}
+			found = found || compareWith("=", left, value)
+			if e.peek() != "," {
+				break
+			}
+			e.next()
+		}
+		return found, e.expect(")")
+	default:
+		return false, fmt.Errorf("unexpected operator %q in expression", op)
+	}
+}
+
+// operand returns a value placeholder, a size of attribute or a value of attribute at path,
+// nil is returned for missing attributes
+func (e *expression) operand(it item) (types.AttributeValue, error) {
+	token := e.peek()
+	if strings.HasPrefix(token, ":") {
+		e.next()
+		value, ok := e.values[token]
+		if !ok {
+			return nil, fmt.Errorf("value %s is not defined in ExpressionAttributeValues", token)
+		}
+		return value, nil
+	}
+	if strings.EqualFold(token, "size") && e.peekAt(1) == "(" {
+		e.next()
+		e.next()
+		value, err := e.path(it)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.expect(")"); err != nil {
+			return nil, err
+		}
+		size, ok := sizeOf(value)
+		if !ok {
+			return nil, nil
+		}
+		return &types.AttributeValueMemberN{Value: fmt.Sprint(size)}, nil
+	}
+	return e.path(it)
+}
+
+// path returns a value of attribute at document path
+func (e *expression) path(it item) (types.AttributeValue, error) {
+	parts, err := e.pathParts()
+	if err != nil {
+		return nil, err
+	}
+	return lookup(it, parts), nil
+}
+
+func (e *expression) pathParts() ([]string, error) {
+	token := e.next()
+	if token == "" || strings.HasPrefix(token, ":") || strings.ContainsAny(token, "()") {
+		return nil, fmt.Errorf("expected attribute name in expression, got %q", token)
+	}
+	if e.peek() == "[" {
+		return nil, fmt.Errorf("list indexes are not supported in expression")
+	}
+
+	parts := strings.Split(token, ".")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "#") {
+			name, ok := e.names[part]
+			if !ok {
+				return nil, fmt.Errorf("name %s is not defined in ExpressionAttributeNames", part)
+			}
+			parts[i] = name
+		}
+	}
+	return parts, nil
+}
+
+func lookup(it item, parts []string) types.AttributeValue {
+	value, ok := it[parts[0]]
+	if !ok {
+		return nil
+	}
+	for _, part := range parts[1:] {
+		m, ok := value.(*types.AttributeValueMemberM)
+		if !ok {
+			return nil
+		}
+		if value, ok = m.Value[part]; !ok {
+			return nil
+		}
+	}
+	return value
+}
+
+// applyUpdate applies update expression to an item, values are computed from the item as it was
+// before the update. It returns top-level names of updated attributes
+func applyUpdate(text string, names map[string]string, values map[string]types.AttributeValue, old item) (item, []string, error) {
+	e, err := newExpression(text, names, values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updated := copyItem(old)
+	var changed []string
+	for e.peek() != "" {
+		clause := strings.ToUpper(e.next())
+		for {
+			parts, err := e.pathParts()
+			if err != nil {
+				return nil, nil, err
+			}
+			changed = append(changed, parts[0])
+
+			switch clause {
+			case "SET":
+				if err := e.expect("="); err != nil {
+					return nil, nil, err
+				}
+				value, err := e.setValue(old)
+				if err != nil {
+					return nil, nil, err
+				}
+				if err := setPath(updated, parts, value); err != nil {
+					return nil, nil, err
+				}
+			case "REMOVE":
+				removePath(updated, parts)
+			case "ADD", "DELETE":
+				value, err := e.operand(old)
+				if err != nil {
+					return nil, nil, err
+				}
+				result, err := addOrDelete(clause, lookup(old, parts), value)
+				if err != nil {
+					return nil, nil, err
+				}
+				if result == nil {
+					removePath(updated, parts)
+				} else if err := setPath(updated, parts, result); err != nil {
+					return nil, nil, err
+				}
+			default:
+				return nil, nil, fmt.Errorf("unknown update clause %q", clause)
+			}
+
+			if e.peek() != "," {
+				break
+			}
+			e.next()
+		}
+	}
+	return updated, changed, nil
+}
+
+// setValue parses right-hand side of SET action
+func (e *expression) setValue(it item) (types.AttributeValue, error) {
+	left, err := e.setOperand(it)
+	if err != nil {
+		return nil, err
+	}
+	if op := e.peek(); op == "+" || op == "-" {
+		e.next()
+		right, err := e.setOperand(it)
+		if err != nil {
+			return nil, err
+		}
+		a, okA := number(left)
+		b, okB := number(right)
+		if !okA || !okB {
+			return nil, fmt.Errorf("operator %s requires number operands", op)
+		}
+		if op == "-" {
+			b.Neg(b)
+		}
+		return formatNumber(a.Add(a, b)), nil
+	}
+	return left, nil
+}
+
+func (e *expression) setOperand(it item) (types.AttributeValue, error) {
+	if e.peekAt(1) == "(" {
+		switch name := strings.ToLower(e.peek()); name {
+		case "if_not_exists", "list_append":
+			e.next()
+			e.next()
+			first, err := e.operand(it)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.expect(","); err != nil {
+				return nil, err
+			}
+			second, err := e.operand(it)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.expect(")"); err != nil {
+				return nil, err
+			}
+			if name == "if_not_exists" {
+				if first != nil {
+					return first, nil
+				}
+				return second, nil
+			}
+			a, okA := first.(*types.AttributeValueMemberL)
+			b, okB := second.(*types.AttributeValueMemberL)
+			if !okA || !okB {
+				return nil, fmt.Errorf("list_append requires list operands")
+			}
+			return &types.AttributeValueMemberL{Value: append(append([]types.AttributeValue{}, a.Value...), b.Value...)}, nil
+		}
+	}
+
+	value, err := e.operand(it)
+	if err == nil && value == nil {
+		err = fmt.Errorf("attribute used in SET action doesn't exist")
+	}
+	return value, err
+}
+
+func setPath(it item, parts []string, value types.AttributeValue) error {
+	if len(parts) == 1 {
+		it[parts[0]] = value
+		return nil
+	}
+	parent, ok := lookup(it, parts[:len(parts)-1]).(*types.AttributeValueMemberM)
+	if !ok {
+		return fmt.Errorf("document path %s is invalid for update", strings.Join(parts, "."))
+	}
+	m := make(map[string]types.AttributeValue, len(parent.Value)+1)
+	for k, v := range parent.Value {
+		m[k] = v
+	}
+	m[parts[len(parts)-1]] = value
+	return setPath(it, parts[:len(parts)-1], &types.AttributeValueMemberM{Value: m})
+}
+
+func removePath(it item, parts []string) {
+	if len(parts) == 1 {
+		delete(it, parts[0])
+		return
+	}
+	parent, ok := lookup(it, parts[:len(parts)-1]).(*types.AttributeValueMemberM)
+	if !ok {
+		return
+	}
+	m := make(map[string]types.AttributeValue, len(parent.Value))
+	for k, v := range parent.Value {
+		if k != parts[len(parts)-1] {
+			m[k] = v
+		}
+	}
+	_ = setPath(it, parts[:len(parts)-1], &types.AttributeValueMemberM{Value: m})
+}
+
+// addOrDelete computes result of ADD or DELETE action, nil result removes the attribute
+func addOrDelete(clause string, current, value types.AttributeValue) (types.AttributeValue, error) {
+	if clause == "ADD" {
+		if n, ok := number(value); ok {
+			if current == nil {
+				return value, nil
+			}
+			c, ok := number(current)
+			if !ok {
+				return nil, fmt.Errorf("ADD requires number attribute")
+			}
+			return formatNumber(c.Add(c, n)), nil
+		}
+	}
+
+	switch v := value.(type) {
+	case *types.AttributeValueMemberSS:
+		var set []string
+		if current != nil {
+			c, ok := current.(*types.AttributeValueMemberSS)
+			if !ok {
+				return nil, fmt.Errorf("%s requires string set attribute", clause)
+			}
+			set = c.Value
+		}
+		result := updateSet(clause, set, v.Value)
+		if len(result) == 0 {
+			return nil, nil
+		}
+		return &types.AttributeValueMemberSS{Value: result}, nil
+	case *types.AttributeValueMemberNS:
+		var set []string
+		if current != nil {
+			c, ok := current.(*types.AttributeValueMemberNS)
+			if !ok {
+				return nil, fmt.Errorf("%s requires number set attribute", clause)
+			}
+			set = c.Value
+		}
+		result := updateSet(clause, set, v.Value)
+		if len(result) == 0 {
+			return nil, nil
+		}
+		return &types.AttributeValueMemberNS{Value: result}, nil
+	default:
+		return nil, fmt.Errorf("%s requires number or set value", clause)
+	}
+}
+
+func updateSet(clause string, set, values []string) []string {
+	var result []string
+	if clause == "ADD" {
+		result = append(result, set...)
+		for _, value := range values {
+			if !containsString(result, value) {
+				result = append(result, value)
+			}
+		}
+		return result
+	}
+	for _, value := range set {
+		if !containsString(values, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// project returns an item limited to top-level attributes listed in projection expression
+func project(it item, text string, names map[string]string) (item, error) {
+	if strings.TrimSpace(text) == "" || it == nil {
+		return it, nil
+	}
+	projected := make(item)
+	for _, attribute := range strings.Split(text, ",") {
+		name := strings.TrimSpace(attribute)
+		name, _, _ = strings.Cut(name, ".")
+		if strings.HasPrefix(name, "#") {
+			resolved, ok := names[name]
+			if !ok {
+				return nil, fmt.Errorf("name %s is not defined in ExpressionAttributeNames", name)
+			}
+			name = resolved
+		}
+		if value, ok := it[name]; ok {
+			projected[name] = value
+		}
+	}
+	return projected, nil
+}
+
+func number(value types.AttributeValue) (*big.Rat, bool) {
+	n, ok := value.(*types.AttributeValueMemberN)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(n.Value)
+}
+
+func formatNumber(n *big.Rat) types.AttributeValue {
+	if n.IsInt() {
+		return &types.AttributeValueMemberN{Value: n.Num().String()}
+	}
+	return &types.AttributeValueMemberN{Value: strings.TrimRight(n.FloatString(38), "0")}
+}
+
+// compare orders two scalar values of the same type, reporting false when they can't be ordered
+func compare(a, b types.AttributeValue) (int, bool) {
+	switch a := a.(type) {
+	case *types.AttributeValueMemberS:
+		if b, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(a.Value, b.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		x, okA := number(a)
+		y, okB := number(b)
+		if okA && okB {
+			return x.Cmp(y), true
+		}
+	case *types.AttributeValueMemberB:
+		if b, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(a.Value, b.Value), true
+		}
+	}
+	return 0, false
+}
+
+func compareWith(op string, a, b types.AttributeValue) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if op == "=" || op == "<>" {
+		equal := false
+		if c, ok := compare(a, b); ok {
+			equal = c == 0
+		} else {
+			equal = reflect.DeepEqual(a, b)
+		}
+		return equal == (op == "=")
+	}
+
+	c, ok := compare(a, b)
+	if !ok {
+		return false
+	}
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func beginsWith(value, prefix types.AttributeValue) bool {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		p, ok := prefix.(*types.AttributeValueMemberS)
+		return ok && strings.HasPrefix(v.Value, p.Value)
+	case *types.AttributeValueMemberB:
+		p, ok := prefix.(*types.AttributeValueMemberB)
+		return ok && bytes.HasPrefix(v.Value, p.Value)
+	}
+	return false
+}
+
+func contains(value, operand types.AttributeValue) bool {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		o, ok := operand.(*types.AttributeValueMemberS)
+		return ok && strings.Contains(v.Value, o.Value)
+	case *types.AttributeValueMemberSS:
+		o, ok := operand.(*types.AttributeValueMemberS)
+		return ok && containsString(v.Value, o.Value)
+	case *types.AttributeValueMemberNS:
+		o, ok := operand.(*types.AttributeValueMemberN)
+		return ok && containsString(v.Value, o.Value)
+	case *types.AttributeValueMemberL:
+		for _, element := range v.Value {
+			if compareWith("=", element, operand) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sizeOf(value types.AttributeValue) (int, bool) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberB:
+		return len(v.Value), true
+	case *types.AttributeValueMemberSS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberNS:
+		return len(v.Value), true
+	case *types.AttributeValueMemberL:
+		return len(v.Value), true
+	case *types.AttributeValueMemberM:
+		return len(v.Value), true
+	}
+	return 0, false
+}
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	copied := make(item, len(it))
+	for name, value := range it {
+		copied[name] = value
+	}
+	return copied
+}
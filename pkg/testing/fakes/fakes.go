@@ -0,0 +1,134 @@
+// Package fakes provides stateful in-memory implementations of DynamoDB, EventBridge Scheduler,
+// SNS and Cognito clients used by handlers, so that flows spanning several handlers can be
+// tested without AWS. Every fake can be made to fail with injected errors, and schedules are
+// fired by a clock that can be advanced by tests.
+package fakes
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides current time to fakes
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a clock returning current system time
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// VirtualClock is a clock that only moves when it's set or advanced
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewVirtualClock(now time.Time) *VirtualClock {
+	return &VirtualClock{now: now}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves clock to given time
+func (c *VirtualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves clock forward by given duration
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Faults injects errors into operations of a fake and counts calls of them. Operations are
+// named after client methods, e.g. "PutItem"
+type Faults struct {
+	mu     sync.Mutex
+	faults map[string][]*fault
+	calls  map[string]int
+}
+
+type fault struct {
+	fn func(input interface{}) error
+	// times is the number of calls fault fails, negative for every call
+	times int
+}
+
+// Inject makes every following call of operation fail with err
+func (f *Faults) Inject(operation string, err error) {
+	f.add(operation, &fault{fn: func(interface{}) error { return err }, times: -1})
+}
+
+// InjectN makes n following calls of operation fail with err
+func (f *Faults) InjectN(operation string, n int, err error) {
+	f.add(operation, &fault{fn: func(interface{}) error { return err }, times: n})
+}
+
+// InjectFunc calls fn with input of every following call of operation, and fails the call
+// with the error fn returns unless it's nil
+func (f *Faults) InjectFunc(operation string, fn func(input interface{}) error) {
+	f.add(operation, &fault{fn: fn, times: -1})
+}
+
+// Clear removes all injected errors
+func (f *Faults) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+// Calls returns the number of calls of operation, including failed ones
+func (f *Faults) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+func (f *Faults) add(operation string, flt *fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.faults == nil {
+		f.faults = make(map[string][]*fault)
+	}
+	f.faults[operation] = append(f.faults[operation], flt)
+}
+
+// check records a call of operation and returns an error injected into it
+func (f *Faults) check(operation string, input interface{}) error {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[operation]++
+
+	var fns []func(interface{}) error
+	for _, flt := range f.faults[operation] {
+		if flt.times == 0 {
+			continue
+		}
+		if flt.times > 0 {
+			flt.times--
+		}
+		fns = append(fns, flt.fn)
+	}
+	f.mu.Unlock()
+
+	// functions are called without the lock so that they can inspect the fake
+	for _, fn := range fns {
+		if err := fn(input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
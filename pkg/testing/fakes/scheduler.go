@@ -0,0 +1,461 @@
+package fakes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/features/schedule"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+	"github.com/google/uuid"
+)
+
+const defaultGroup = "default"
+
+// Target is invoked by a schedule with its input
+type Target func(ctx context.Context, input string) error
+
+// LambdaTarget returns a target decoding input into an event of Lambda function and calling
+// handler with Lambda context of function with given ARN, as Lambda runtime does
+func LambdaTarget[T any](arn string, handler func(context.Context, T) error) Target {
+	return func(ctx context.Context, input string) error {
+		var event T
+		if err := json.Unmarshal([]byte(input), &event); err != nil {
+			return err
+		}
+		ctx = lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{
+			AwsRequestID:       uuid.NewString(),
+			InvokedFunctionArn: arn,
+		})
+		return handler(ctx, event)
+	}
+}
+
+// Firing is an invocation of schedule's target
+type Firing struct {
+	Group, Name string
+	Time        time.Time
+	TargetArn   string
+	Input       string
+	// Err is an error target returned
+	Err error
+}
+
+// Scheduler is in-memory EventBridge Scheduler invoking targets of schedules when they're due
+// according to its clock. It evaluates at, cron and rate expressions in timezones of schedules,
+// with wall clock times skipped by DST transition not firing and repeated ones firing once,
+// fills context attributes into target input and deletes completed schedules whose
+// ActionAfterCompletion is DELETE. Flexible time windows are ignored and failed invocations
+// aren't retried, so targets are invoked exactly at fire times, once
+type Scheduler struct {
+	Faults
+
+	mu        sync.Mutex
+	groups    map[string]bool
+	schedules map[scheduleKey]*storedSchedule
+	targets   map[string]Target
+	firings   []Firing
+	clock     Clock
+}
+
+type scheduleKey struct {
+	group, name string
+}
+
+type storedSchedule struct {
+	input    scheduler.CreateScheduleInput
+	schedule schedule.Schedule
+	created  time.Time
+	next     time.Time
+	// done is set when schedule won't fire anymore
+	done bool
+}
+
+func NewScheduler(clock Clock) *Scheduler {
+	return &Scheduler{
+		groups:    map[string]bool{defaultGroup: true},
+		schedules: make(map[scheduleKey]*storedSchedule),
+		targets:   make(map[string]Target),
+		clock:     clock,
+	}
+}
+
+// CreateGroup adds a schedule group
+func (s *Scheduler) CreateGroup(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[name] = true
+}
+
+// Register makes target invokable by schedules under given ARN
+func (s *Scheduler) Register(arn string, target Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets[arn] = target
+}
+
+// Schedule returns input a schedule was created with and its next fire time, which is zero
+// when schedule won't fire anymore. Empty group stands for the default one
+func (s *Scheduler) Schedule(group, name string) (*scheduler.CreateScheduleInput, time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.schedules[scheduleKey{group: groupName(&group), name: name}]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	input := stored.input
+	if stored.done {
+		return &input, time.Time{}, true
+	}
+	return &input, stored.next, true
+}
+
+// Firings returns all invocations of targets in order they happened
+func (s *Scheduler) Firings() []Firing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Firing{}, s.firings...)
+}
+
+func groupName(name *string) string {
+	if aws.ToString(name) == "" {
+		return defaultGroup
+	}
+	return *name
+}
+
+func (s *Scheduler) arn(key scheduleKey) string {
+	return fmt.Sprintf("arn:aws:scheduler:local:000000000000:schedule/%s/%s", key.group, key.name)
+}
+
+func (s *Scheduler) CreateSchedule(ctx context.Context, input *scheduler.CreateScheduleInput, optFns ...func(*scheduler.Options)) (*scheduler.CreateScheduleOutput, error) {
+	if err := s.check("CreateSchedule", input); err != nil {
+		return nil, err
+	}
+	if name := aws.ToString(input.Name); name == "" || len(name) > 64 {
+		return nil, &types.ValidationException{Message: aws.String(fmt.Sprintf("invalid schedule name %q", name))}
+	}
+	if input.Target == nil || aws.ToString(input.Target.Arn) == "" {
+		return nil, &types.ValidationException{Message: aws.String("schedule target is required")}
+	}
+
+	now := s.clock.Now()
+	sched, err := parseExpression(input, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scheduleKey{group: groupName(input.GroupName), name: aws.ToString(input.Name)}
+	if !s.groups[key.group] {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Schedule group %s does not exist.", key.group))}
+	}
+	if _, ok := s.schedules[key]; ok {
+		return nil, &types.ConflictException{Message: aws.String(fmt.Sprintf("Schedule %s already exists.", key.name))}
+	}
+
+	stored := &storedSchedule{input: *input, schedule: sched, created: now}
+	from := now
+	if input.StartDate != nil && input.StartDate.After(from) {
+		from = input.StartDate.Add(-time.Nanosecond)
+	}
+	stored.advance(from)
+	s.schedules[key] = stored
+
+	return &scheduler.CreateScheduleOutput{ScheduleArn: aws.String(s.arn(key))}, nil
+}
+
+// parseExpression parses at, cron and rate expressions of a schedule. Rate schedules fire every
+// interval from their start date, or from the time they were created when they don't have one
+func parseExpression(input *scheduler.CreateScheduleInput, now time.Time) (schedule.Schedule, error) {
+	expression := aws.ToString(input.ScheduleExpression)
+	if strings.HasPrefix(expression, "rate(") && strings.HasSuffix(expression, ")") {
+		start := now
+		if input.StartDate != nil {
+			start = *input.StartDate
+		}
+		sched, err := schedule.ParseRate(expression[5:len(expression)-1], start)
+		if err != nil {
+			return nil, &types.ValidationException{Message: aws.String(err.Error())}
+		}
+		return sched, nil
+	}
+
+	loc := time.UTC
+	if timezone := aws.ToString(input.ScheduleExpressionTimezone); timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, &types.ValidationException{Message: aws.String(fmt.Sprintf("invalid timezone %q", timezone))}
+		}
+	}
+	sched, err := schedule.Parse(expression, loc)
+	if err != nil {
+		return nil, &types.ValidationException{Message: aws.String(err.Error())}
+	}
+	return sched, nil
+}
+
+// advance moves schedule to its first fire time after t
+func (stored *storedSchedule) advance(t time.Time) {
+	next, ok := stored.schedule.Next(t)
+	if !ok || (stored.input.EndDate != nil && next.After(*stored.input.EndDate)) {
+		stored.done = true
+		return
+	}
+	stored.next = next
+}
+
+func (s *Scheduler) GetSchedule(ctx context.Context, input *scheduler.GetScheduleInput, optFns ...func(*scheduler.Options)) (*scheduler.GetScheduleOutput, error) {
+	if err := s.check("GetSchedule", input); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scheduleKey{group: groupName(input.GroupName), name: aws.ToString(input.Name)}
+	stored, ok := s.schedules[key]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Schedule %s does not exist.", key.name))}
+	}
+	state := types.ScheduleStateEnabled
+	if stored.input.State != "" {
+		state = stored.input.State
+	}
+	return &scheduler.GetScheduleOutput{
+		ActionAfterCompletion:      stored.input.ActionAfterCompletion,
+		Arn:                        aws.String(s.arn(key)),
+		CreationDate:               aws.Time(stored.created),
+		Description:                stored.input.Description,
+		EndDate:                    stored.input.EndDate,
+		FlexibleTimeWindow:         stored.input.FlexibleTimeWindow,
+		GroupName:                  aws.String(key.group),
+		Name:                       aws.String(key.name),
+		ScheduleExpression:         stored.input.ScheduleExpression,
+		ScheduleExpressionTimezone: stored.input.ScheduleExpressionTimezone,
+		StartDate:                  stored.input.StartDate,
+		State:                      state,
+		Target:                     stored.input.Target,
+	}, nil
+}
+
+func (s *Scheduler) DeleteSchedule(ctx context.Context, input *scheduler.DeleteScheduleInput, optFns ...func(*scheduler.Options)) (*scheduler.DeleteScheduleOutput, error) {
+	if err := s.check("DeleteSchedule", input); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scheduleKey{group: groupName(input.GroupName), name: aws.ToString(input.Name)}
+	if _, ok := s.schedules[key]; !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String(fmt.Sprintf("Schedule %s does not exist.", key.name))}
+	}
+	delete(s.schedules, key)
+	return &scheduler.DeleteScheduleOutput{}, nil
+}
+
+func (s *Scheduler) ListSchedules(ctx context.Context, input *scheduler.ListSchedulesInput, optFns ...func(*scheduler.Options)) (*scheduler.ListSchedulesOutput, error) {
+	if err := s.check("ListSchedules", input); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []scheduleKey
+	for key := range s.schedules {
+		if input.GroupName != nil && key.group != *input.GroupName {
+			continue
+		}
+		if !strings.HasPrefix(key.name, aws.ToString(input.NamePrefix)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	start := 0
+	if input.NextToken != nil {
+		var err error
+		if start, err = strconv.Atoi(*input.NextToken); err != nil || start < 0 || start > len(keys) {
+			return nil, &types.ValidationException{Message: aws.String("invalid next token")}
+		}
+	}
+	end := len(keys)
+	if limit := int(aws.ToInt32(input.MaxResults)); limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	output := &scheduler.ListSchedulesOutput{Schedules: []types.ScheduleSummary{}}
+	for _, key := range keys[start:end] {
+		stored := s.schedules[key]
+		state := types.ScheduleStateEnabled
+		if stored.input.State != "" {
+			state = stored.input.State
+		}
+		output.Schedules = append(output.Schedules, types.ScheduleSummary{
+			Arn:          aws.String(s.arn(key)),
+			Name:         aws.String(key.name),
+			GroupName:    aws.String(key.group),
+			CreationDate: aws.Time(stored.created),
+			State:        state,
+			Target:       &types.TargetSummary{Arn: stored.input.Target.Arn},
+		})
+	}
+	if end < len(keys) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+// due returns the earliest due firing, moving its schedule to the following fire time. Schedules
+// that won't fire anymore are deleted when their ActionAfterCompletion is DELETE
+func (s *Scheduler) due(now time.Time) (Firing, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var earliest *scheduleKey
+	for key, stored := range s.schedules {
+		if stored.done || stored.input.State == types.ScheduleStateDisabled || stored.next.After(now) {
+			continue
+		}
+		if earliest == nil || stored.next.Before(s.schedules[*earliest].next) {
+			k := key
+			earliest = &k
+		}
+	}
+	if earliest == nil {
+		return Firing{}, false
+	}
+
+	stored := s.schedules[*earliest]
+	f := Firing{
+		Group:     earliest.group,
+		Name:      earliest.name,
+		Time:      stored.next,
+		TargetArn: aws.ToString(stored.input.Target.Arn),
+		Input:     fillPlaceholders(aws.ToString(stored.input.Target.Input), s.arn(*earliest), stored.next),
+	}
+	stored.advance(stored.next)
+	if stored.done && stored.input.ActionAfterCompletion == types.ActionAfterCompletionDelete {
+		delete(s.schedules, *earliest)
+	}
+	return f, true
+}
+
+// fillPlaceholders replaces context attributes of EventBridge Scheduler in target input
+func fillPlaceholders(input, arn string, fire time.Time) string {
+	return strings.NewReplacer(
+		"<aws.scheduler.scheduled-time>", fire.UTC().Format(time.RFC3339),
+		"<aws.scheduler.schedule-arn>", arn,
+		"<aws.scheduler.execution-id>", uuid.NewString(),
+		"<aws.scheduler.attempt-number>", "1",
+	).Replace(input)
+}
+
+// invoke calls target of a firing and records it
+func (s *Scheduler) invoke(ctx context.Context, f Firing) error {
+	s.mu.Lock()
+	target, ok := s.targets[f.TargetArn]
+	s.mu.Unlock()
+
+	if !ok {
+		f.Err = fmt.Errorf("unknown target %s", f.TargetArn)
+	} else {
+		f.Err = target(ctx, f.Input)
+	}
+
+	s.mu.Lock()
+	s.firings = append(s.firings, f)
+	s.mu.Unlock()
+
+	if f.Err != nil {
+		return fmt.Errorf("schedule %s/%s fired at %s: %w", f.Group, f.Name, f.Time.Format(time.RFC3339), f.Err)
+	}
+	return nil
+}
+
+// FireDue invokes targets of all schedules due at current time in order of their fire times.
+// Errors of targets don't stop other schedules from firing and are returned together
+func (s *Scheduler) FireDue(ctx context.Context) error {
+	now := s.clock.Now()
+	var errs []error
+	for {
+		f, ok := s.due(now)
+		if !ok {
+			return errors.Join(errs...)
+		}
+		if err := s.invoke(ctx, f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+// AdvanceTo moves virtual clock of scheduler to given time, firing schedules due on the way. Clock
+// is set to fire time of every schedule before its target is invoked, so targets observe the time
+// they were scheduled at and schedules they create fire within the same call when they're due
+func (s *Scheduler) AdvanceTo(ctx context.Context, t time.Time) error {
+	clock, ok := s.clock.(*VirtualClock)
+	if !ok {
+		return errors.New("scheduler clock is not virtual")
+	}
+
+	var errs []error
+	for {
+		f, ok := s.due(t)
+		if !ok {
+			break
+		}
+		if f.Time.After(clock.Now()) {
+			clock.Set(f.Time)
+		}
+		if err := s.invoke(ctx, f); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	clock.Set(t)
+	return errors.Join(errs...)
+}
+
+// Advance moves virtual clock of scheduler forward by given duration, firing schedules due on the way
+func (s *Scheduler) Advance(ctx context.Context, d time.Duration) error {
+	clock, ok := s.clock.(*VirtualClock)
+	if !ok {
+		return errors.New("scheduler clock is not virtual")
+	}
+	return s.AdvanceTo(ctx, clock.Now().Add(d))
+}
+
+// Run fires due schedules every interval until context is cancelled, passing errors of targets to onError
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.FireDue(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
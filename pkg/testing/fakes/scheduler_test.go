@@ -0,0 +1,324 @@
+package fakes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+)
+
+type event struct {
+	EventID       string `json:"eventID"`
+	ScheduledTime string `json:"scheduledTime"`
+}
+
+func TestSchedulerAdvance(t *testing.T) {
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	clock := fakes.NewVirtualClock(time.Date(2024, 3, 29, 12, 0, 0, 0, time.UTC))
+	s := fakes.NewScheduler(clock)
+	s.CreateGroup("alarms")
+
+	var fired []string
+	var firedAt []time.Time
+	s.Register("executor", fakes.LambdaTarget("executor", func(ctx context.Context, e event) error {
+		if lc, ok := lambdacontext.FromContext(ctx); !ok || lc.InvokedFunctionArn != "executor" {
+			t.Errorf("Target invoked without lambda context")
+		}
+		fired = append(fired, e.ScheduledTime)
+		firedAt = append(firedAt, clock.Now())
+		return nil
+	}))
+
+	create := func(name, expression string) error {
+		_, err := s.CreateSchedule(context.Background(), &scheduler.CreateScheduleInput{
+			Name:                       aws.String(name),
+			GroupName:                  aws.String("alarms"),
+			ScheduleExpression:         aws.String(expression),
+			ScheduleExpressionTimezone: aws.String("Europe/Warsaw"),
+			ActionAfterCompletion:      types.ActionAfterCompletionDelete,
+			Target: &types.Target{
+				Arn:   aws.String("executor"),
+				Input: aws.String(`{"eventID":"1","scheduledTime":"<aws.scheduler.scheduled-time>"}`),
+			},
+		})
+		return err
+	}
+
+	s.InjectN("CreateSchedule", 1, errors.New("throttled"))
+	if err := create("daily", "cron(0 9 * * ? *)"); err == nil {
+		t.Fatalf("Injected error was not returned")
+	}
+	if err := create("daily", "cron(0 9 * * ? *)"); err != nil {
+		t.Fatalf("Error when creating schedule: %v", err)
+	}
+	if err := create("once", "at(2024-03-30T10:00:00)"); err != nil {
+		t.Fatalf("Error when creating schedule: %v", err)
+	}
+	var conflictErr *types.ConflictException
+	if err := create("once", "at(2024-03-30T10:00:00)"); !errors.As(err, &conflictErr) {
+		t.Errorf("Received error: %v is not a conflict", err)
+	}
+
+	if err := s.Advance(context.Background(), 3*24*time.Hour); err != nil {
+		t.Fatalf("Error when advancing clock: %v", err)
+	}
+
+	// DST starts on March 31st, so 9:00 in Warsaw is 8:00 UTC before and 7:00 UTC after it
+	expected := []time.Time{
+		time.Date(2024, 3, 30, 9, 0, 0, 0, warsaw),
+		time.Date(2024, 3, 30, 10, 0, 0, 0, warsaw),
+		time.Date(2024, 3, 31, 9, 0, 0, 0, warsaw),
+		time.Date(2024, 4, 1, 9, 0, 0, 0, warsaw),
+	}
+	if len(fired) != len(expected) {
+		t.Fatalf("Received firings: %v are different than expected ones: %v", fired, expected)
+	}
+	for i := range expected {
+		if fired[i] != expected[i].UTC().Format(time.RFC3339) || !firedAt[i].Equal(expected[i]) {
+			t.Errorf("Received firing: %v at %v is different than expected one: %v", fired[i], firedAt[i], expected[i])
+		}
+	}
+
+	if _, _, ok := s.Schedule("alarms", "once"); ok {
+		t.Errorf("Completed schedule was not deleted")
+	}
+	if _, next, ok := s.Schedule("alarms", "daily"); !ok || !next.Equal(time.Date(2024, 4, 2, 9, 0, 0, 0, warsaw)) {
+		t.Errorf("Received next fire time: %v (%v) is different than expected one", next, ok)
+	}
+	if calls := s.Calls("CreateSchedule"); calls != 4 {
+		t.Errorf("Received calls: %v are different than expected ones: %v", calls, 4)
+	}
+}
+
+func TestSchedulerDST(t *testing.T) {
+	testCases := []struct {
+		name       string
+		expression string
+		timezone   string
+		from, to   time.Time
+		expected   []time.Time
+	}{
+		{
+			name:       "workdays across spring transition",
+			expression: "cron(0 9 ? * MON-FRI *)",
+			timezone:   "Europe/Warsaw",
+			from:       time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC),
+			to:         time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 29, 8, 0, 0, 0, time.UTC),
+				time.Date(2024, 4, 1, 7, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "workdays across autumn transition",
+			expression: "cron(0 9 ? * MON-FRI *)",
+			timezone:   "Europe/Warsaw",
+			from:       time.Date(2024, 10, 25, 0, 0, 0, 0, time.UTC),
+			to:         time.Date(2024, 10, 29, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 10, 25, 7, 0, 0, 0, time.UTC),
+				time.Date(2024, 10, 28, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "time skipped by transition",
+			expression: "cron(30 2 * * ? *)",
+			timezone:   "Europe/Warsaw",
+			from:       time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC),
+			to:         time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 30, 1, 30, 0, 0, time.UTC),
+				time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "time repeated by transition",
+			expression: "cron(30 2 * * ? *)",
+			timezone:   "Europe/Warsaw",
+			from:       time.Date(2024, 10, 26, 0, 0, 0, 0, time.UTC),
+			to:         time.Date(2024, 10, 29, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 10, 26, 0, 30, 0, 0, time.UTC),
+				time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC),
+				time.Date(2024, 10, 28, 1, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "transition at midnight of other timezone",
+			expression: "cron(0 2 * * ? *)",
+			timezone:   "America/New_York",
+			from:       time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+			to:         time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 9, 7, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 11, 6, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "rate ignores transition",
+			expression: "rate(1 hour)",
+			timezone:   "Europe/Warsaw",
+			from:       time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+			to:         time.Date(2024, 3, 31, 3, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 2, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 3, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			clock := fakes.NewVirtualClock(tC.from)
+			s := fakes.NewScheduler(clock)
+
+			var fired []time.Time
+			s.Register("executor", fakes.LambdaTarget("executor", func(ctx context.Context, e event) error {
+				scheduledTime, err := time.Parse(time.RFC3339, e.ScheduledTime)
+				if err != nil || !scheduledTime.Equal(clock.Now()) {
+					t.Errorf("Received scheduled time: %v is different than time of invocation: %v", e.ScheduledTime, clock.Now())
+				}
+				fired = append(fired, clock.Now())
+				return nil
+			}))
+
+			if _, err := s.CreateSchedule(context.Background(), &scheduler.CreateScheduleInput{
+				Name:                       aws.String("alarm"),
+				ScheduleExpression:         aws.String(tC.expression),
+				ScheduleExpressionTimezone: aws.String(tC.timezone),
+				Target: &types.Target{
+					Arn:   aws.String("executor"),
+					Input: aws.String(`{"scheduledTime":"<aws.scheduler.scheduled-time>"}`),
+				},
+			}); err != nil {
+				t.Fatalf("Error when creating schedule: %v", err)
+			}
+			if err := s.AdvanceTo(context.Background(), tC.to); err != nil {
+				t.Fatalf("Error when advancing clock: %v", err)
+			}
+
+			if len(fired) != len(tC.expected) {
+				t.Fatalf("Received firings: %v are different than expected ones: %v", fired, tC.expected)
+			}
+			for i := range tC.expected {
+				if !fired[i].Equal(tC.expected[i]) {
+					t.Errorf("Received firing: %v is different than expected one: %v", fired[i], tC.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSchedulerRate(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := fakes.NewVirtualClock(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	s := fakes.NewScheduler(clock)
+	s.Register("executor", func(ctx context.Context, input string) error { return nil })
+
+	if _, err := s.CreateSchedule(context.Background(), &scheduler.CreateScheduleInput{
+		Name:                  aws.String("rate"),
+		ScheduleExpression:    aws.String("rate(6 hours)"),
+		StartDate:             aws.Time(start),
+		EndDate:               aws.Time(start.Add(13 * time.Hour)),
+		ActionAfterCompletion: types.ActionAfterCompletionDelete,
+		Target:                &types.Target{Arn: aws.String("executor")},
+	}); err != nil {
+		t.Fatalf("Error when creating schedule: %v", err)
+	}
+
+	res, err := s.GetSchedule(context.Background(), &scheduler.GetScheduleInput{Name: aws.String("rate")})
+	if err != nil || aws.ToString(res.ScheduleExpression) != "rate(6 hours)" || res.State != types.ScheduleStateEnabled {
+		t.Fatalf("Received schedule: %v (%v) is different than created one", res, err)
+	}
+
+	if err := s.Advance(context.Background(), 48*time.Hour); err != nil {
+		t.Fatalf("Error when advancing clock: %v", err)
+	}
+	expected := []time.Time{start, start.Add(6 * time.Hour), start.Add(12 * time.Hour)}
+	firings := s.Firings()
+	if len(firings) != len(expected) {
+		t.Fatalf("Received firings: %v are different than expected ones: %v", firings, expected)
+	}
+	for i := range expected {
+		if !firings[i].Time.Equal(expected[i]) {
+			t.Errorf("Received firing: %v is different than expected one: %v", firings[i].Time, expected[i])
+		}
+	}
+
+	var notFoundErr *types.ResourceNotFoundException
+	if _, err := s.GetSchedule(context.Background(), &scheduler.GetScheduleInput{Name: aws.String("rate")}); !errors.As(err, &notFoundErr) {
+		t.Errorf("Schedule was not deleted after its end date: %v", err)
+	}
+}
+
+func TestSchedulerTargetErrors(t *testing.T) {
+	clock := fakes.NewVirtualClock(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	s := fakes.NewScheduler(clock)
+	s.Register("failing", func(ctx context.Context, input string) error {
+		return errors.New("target failed")
+	})
+
+	for _, arn := range []string{"failing", "unknown"} {
+		if _, err := s.CreateSchedule(context.Background(), &scheduler.CreateScheduleInput{
+			Name:               aws.String(arn),
+			ScheduleExpression: aws.String("cron(0 12 * * ? *)"),
+			Target:             &types.Target{Arn: aws.String(arn)},
+		}); err != nil {
+			t.Fatalf("Error when creating schedule: %v", err)
+		}
+	}
+
+	if err := s.Advance(context.Background(), 24*time.Hour); err == nil {
+		t.Errorf("Errors of targets were not returned")
+	}
+	firings := s.Firings()
+	if len(firings) != 2 || firings[0].Err == nil || firings[1].Err == nil {
+		t.Errorf("Received firings: %v are different than expected ones", firings)
+	}
+}
+
+func TestListSchedules(t *testing.T) {
+	s := fakes.NewScheduler(fakes.SystemClock{})
+	s.CreateGroup("alarms")
+	for _, name := range []string{"a.1", "a.2", "a.3", "b.1"} {
+		if _, err := s.CreateSchedule(context.Background(), &scheduler.CreateScheduleInput{
+			Name:               aws.String(name),
+			GroupName:          aws.String("alarms"),
+			ScheduleExpression: aws.String("cron(0 12 * * ? *)"),
+			Target:             &types.Target{Arn: aws.String("executor")},
+		}); err != nil {
+			t.Fatalf("Error when creating schedule: %v", err)
+		}
+	}
+
+	var names []string
+	var nextToken *string
+	for {
+		res, err := s.ListSchedules(context.Background(), &scheduler.ListSchedulesInput{
+			GroupName:  aws.String("alarms"),
+			NamePrefix: aws.String("a."),
+			MaxResults: aws.Int32(2),
+			NextToken:  nextToken,
+		})
+		if err != nil {
+			t.Fatalf("Error when listing schedules: %v", err)
+		}
+		for _, summary := range res.Schedules {
+			names = append(names, *summary.Name)
+		}
+		if nextToken = res.NextToken; nextToken == nil {
+			break
+		}
+	}
+
+	if len(names) != 3 || names[0] != "a.1" || names[2] != "a.3" {
+		t.Errorf("Received result: %v is different than expected one: %v", names, []string{"a.1", "a.2", "a.3"})
+	}
+}
@@ -0,0 +1,186 @@
+package fakes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/google/uuid"
+)
+
+// SMS is a text message delivered to a phone number
+type SMS struct {
+	PhoneNumber string `json:"phoneNumber"`
+	Message     string `json:"message"`
+	// SubscriptionArn is a subscription message was delivered through, empty for messages
+	// published directly to a phone number
+	SubscriptionArn string    `json:"subscriptionArn,omitempty"`
+	SentAt          time.Time `json:"sentAt"`
+}
+
+// Subscription is an SMS subscription of a topic
+type Subscription struct {
+	Arn          string
+	TopicArn     string
+	Endpoint     string
+	FilterPolicy map[string][]string
+}
+
+// SNS is in-memory SNS delivering messages published to a topic to SMS subscriptions whose filter
+// policy matches message attributes, and messages published to a phone number directly to it
+type SNS struct {
+	Faults
+
+	mu            sync.Mutex
+	subscriptions map[string]Subscription
+	messages      []SMS
+	clock         Clock
+	// OnSMS is called with every delivered message when it's set
+	OnSMS func(SMS)
+}
+
+func NewSNS(clock Clock) *SNS {
+	return &SNS{subscriptions: make(map[string]Subscription), clock: clock}
+}
+
+func (s *SNS) Subscribe(ctx context.Context, input *sns.SubscribeInput, optFns ...func(*sns.Options)) (*sns.SubscribeOutput, error) {
+	if err := s.check("Subscribe", input); err != nil {
+		return nil, err
+	}
+	if aws.ToString(input.Protocol) != "sms" {
+		return nil, &types.InvalidParameterException{Message: aws.String("only sms protocol is supported")}
+	}
+
+	var filterPolicy map[string][]string
+	if policy, ok := input.Attributes["FilterPolicy"]; ok {
+		if err := json.Unmarshal([]byte(policy), &filterPolicy); err != nil {
+			return nil, &types.InvalidParameterException{Message: aws.String(fmt.Sprintf("invalid filter policy: %v", err))}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	arn := fmt.Sprintf("%s:%s", aws.ToString(input.TopicArn), uuid.NewString())
+	s.subscriptions[arn] = Subscription{
+		Arn:          arn,
+		TopicArn:     aws.ToString(input.TopicArn),
+		Endpoint:     aws.ToString(input.Endpoint),
+		FilterPolicy: filterPolicy,
+	}
+	return &sns.SubscribeOutput{SubscriptionArn: &arn}, nil
+}
+
+func (s *SNS) Unsubscribe(ctx context.Context, input *sns.UnsubscribeInput, optFns ...func(*sns.Options)) (*sns.UnsubscribeOutput, error) {
+	if err := s.check("Unsubscribe", input); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[aws.ToString(input.SubscriptionArn)]; !ok {
+		return nil, &types.NotFoundException{Message: aws.String("subscription not found")}
+	}
+	delete(s.subscriptions, aws.ToString(input.SubscriptionArn))
+	return &sns.UnsubscribeOutput{}, nil
+}
+
+func (s *SNS) Publish(ctx context.Context, input *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	if err := s.check("Publish", input); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	var delivered []SMS
+	message := aws.ToString(input.Message)
+	if input.PhoneNumber != nil {
+		delivered = append(delivered, SMS{PhoneNumber: *input.PhoneNumber, Message: message, SentAt: s.clock.Now()})
+	} else {
+		for _, sub := range s.sortedSubscriptions() {
+			if sub.TopicArn == aws.ToString(input.TopicArn) && sub.matches(input.MessageAttributes) {
+				delivered = append(delivered, SMS{PhoneNumber: sub.Endpoint, Message: message, SubscriptionArn: sub.Arn, SentAt: s.clock.Now()})
+			}
+		}
+	}
+	s.messages = append(s.messages, delivered...)
+	onSMS := s.OnSMS
+	s.mu.Unlock()
+
+	if onSMS != nil {
+		for _, sms := range delivered {
+			onSMS(sms)
+		}
+	}
+	return &sns.PublishOutput{MessageId: aws.String(uuid.NewString())}, nil
+}
+
+// sortedSubscriptions returns subscriptions in a stable order, so that messages are delivered deterministically
+func (s *SNS) sortedSubscriptions() []Subscription {
+	subscriptions := make([]Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].Arn < subscriptions[j].Arn
+	})
+	return subscriptions
+}
+
+// Messages returns all delivered messages in order they were sent
+func (s *SNS) Messages() []SMS {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SMS{}, s.messages...)
+}
+
+// MessagesTo returns messages delivered to a phone number in order they were sent
+func (s *SNS) MessagesTo(phoneNumber string) []SMS {
+	var messages []SMS
+	for _, sms := range s.Messages() {
+		if sms.PhoneNumber == phoneNumber {
+			messages = append(messages, sms)
+		}
+	}
+	return messages
+}
+
+// Subscriptions returns current subscriptions ordered by their ARNs
+func (s *SNS) Subscriptions() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedSubscriptions()
+}
+
+// matches reports whether message attributes satisfy filter policy. Policies consist of exact string
+// values, String.Array attributes match when any of their elements does
+func (sub Subscription) matches(attributes map[string]types.MessageAttributeValue) bool {
+	for name, allowed := range sub.FilterPolicy {
+		attribute, ok := attributes[name]
+		if !ok {
+			return false
+		}
+
+		values := []string{aws.ToString(attribute.StringValue)}
+		if aws.ToString(attribute.DataType) == "String.Array" {
+			if err := json.Unmarshal([]byte(aws.ToString(attribute.StringValue)), &values); err != nil {
+				return false
+			}
+		}
+
+		matched := false
+		for _, value := range values {
+			matched = matched || containsString(allowed, value)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
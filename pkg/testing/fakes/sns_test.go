@@ -0,0 +1,74 @@
+package fakes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Slimo300/Reminder-Serverless-Go/pkg/testing/fakes"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+func TestSNSPublish(t *testing.T) {
+	s := fakes.NewSNS(fakes.NewVirtualClock(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	subscribe := func(phoneNumber, filterPolicy string) string {
+		res, err := s.Subscribe(context.Background(), &sns.SubscribeInput{
+			TopicArn:   aws.String("topic"),
+			Protocol:   aws.String("sms"),
+			Endpoint:   aws.String(phoneNumber),
+			Attributes: map[string]string{"FilterPolicy": filterPolicy},
+		})
+		if err != nil {
+			t.Fatalf("Error when subscribing: %v", err)
+		}
+		return *res.SubscriptionArn
+	}
+	home := subscribe("+48111111111", `{"userID":["1"],"phoneLabel":["home"]}`)
+	work := subscribe("+48222222222", `{"userID":["1"],"phoneLabel":["work"]}`)
+	subscribe("+48333333333", `{"userID":["2"],"phoneLabel":["home"]}`)
+
+	publish := func(labels string) error {
+		_, err := s.Publish(context.Background(), &sns.PublishInput{
+			TopicArn: aws.String("topic"),
+			Message:  aws.String("Stand-up"),
+			MessageAttributes: map[string]types.MessageAttributeValue{
+				"userID":     {DataType: aws.String("String"), StringValue: aws.String("1")},
+				"phoneLabel": {DataType: aws.String("String.Array"), StringValue: aws.String(labels)},
+			},
+		})
+		return err
+	}
+
+	if err := publish(`["home","work"]`); err != nil {
+		t.Fatalf("Error when publishing: %v", err)
+	}
+	if _, err := s.Unsubscribe(context.Background(), &sns.UnsubscribeInput{SubscriptionArn: aws.String(work)}); err != nil {
+		t.Fatalf("Error when unsubscribing: %v", err)
+	}
+	if err := publish(`["work"]`); err != nil {
+		t.Fatalf("Error when publishing: %v", err)
+	}
+
+	if messages := s.MessagesTo("+48111111111"); len(messages) != 1 || messages[0].SubscriptionArn != home {
+		t.Errorf("Received messages: %v are different than expected ones", messages)
+	}
+	if messages := s.MessagesTo("+48222222222"); len(messages) != 1 {
+		t.Errorf("Received messages: %v are different than expected ones", messages)
+	}
+	if messages := s.MessagesTo("+48333333333"); len(messages) != 0 {
+		t.Errorf("Message delivered to other user's subscription: %v", messages)
+	}
+
+	s.Inject("Publish", errors.New("throttled"))
+	if err := publish(`["home"]`); err == nil {
+		t.Errorf("Injected error was not returned")
+	}
+	s.Clear()
+	if err := publish(`["home"]`); err != nil {
+		t.Errorf("Error when publishing after clearing errors: %v", err)
+	}
+}
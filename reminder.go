@@ -1,29 +1,35 @@
 package main
 
 import (
+	"log"
+	"strings"
+
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigateway"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awscognito"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsevents"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awseventstargets"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsscheduler"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssqs"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
 
 	golambda "github.com/aws/aws-cdk-go/awscdklambdagoalpha/v2"
 )
 
-type AlerterStackProps struct {
-	awscdk.StackProps
-}
-
 func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackProps) awscdk.Stack {
-	var sprops awscdk.StackProps
-	if props != nil {
-		sprops = props.StackProps
+	if props == nil {
+		props = DefaultAlerterStackProps()
+	}
+	stack := awscdk.NewStack(scope, &id, &props.StackProps)
+	if props.Stage != "" {
+		awscdk.Tags_Of(stack).Add(jsii.String("Stage"), jsii.String(props.Stage), nil)
 	}
-	stack := awscdk.NewStack(scope, &id, &sprops)
 
 	// Lambda bundling options
 	bundlingOptions := &golambda.BundlingOptions{
@@ -33,22 +39,74 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 		},
 	}
 
+	// Maximum number of SMS segments a single alarm message can take
+	maxSmsSegments := jsii.String("3")
+	// Number of SMS segments a single user can be sent in a month
+	monthlySmsQuota := jsii.String("300")
+	// Limits of schedules a single request can create, crons a single event can have
+	// and events a single user can have
+	maxSchedulesPerRequest := jsii.String("25")
+	maxCronsPerEvent := jsii.String("10")
+	maxEventsPerUser := jsii.String("50")
+	// Number of schedules created or deleted concurrently by a single request
+	schedulerConcurrency := jsii.String("10")
+
+	// functionProps returns props of a function with settings shared by all functions of the stage
+	functionProps := func(name, entry string, environment map[string]*string) *golambda.GoFunctionProps {
+		environment["LOG_LEVEL"] = jsii.String(props.LogLevel)
+		// Stage is a dimension of business metrics functions emit
+		if props.Stage != "" {
+			environment["STAGE"] = jsii.String(props.Stage)
+		}
+		functionProps := &golambda.GoFunctionProps{
+			FunctionName: props.name(name),
+			Entry:        jsii.String(entry),
+			Runtime:      awslambda.Runtime_PROVIDED_AL2(),
+			Architecture: awslambda.Architecture_ARM_64(),
+			Environment:  &environment,
+			Bundling:     bundlingOptions,
+			// Functions record their calls of AWS services in X-Ray, see tracing package
+			Tracing: awslambda.Tracing_ACTIVE,
+		}
+		if props.LogRetention != "" {
+			functionProps.LogRetention = props.LogRetention
+		}
+		return functionProps
+	}
+
+	// apiFunctionProps returns props of a function handling API requests
+	apiFunctionProps := func(name, entry string, environment map[string]*string) *golambda.GoFunctionProps {
+		environment["CORS_ALLOWED_ORIGINS"] = jsii.String(strings.Join(props.AllowedOrigins, ","))
+		return functionProps(name, entry, environment)
+	}
+
+	// tableProps completes props of a table with its name and settings of the stage
+	tableProps := func(name string, tableProps *awsdynamodb.TableProps) *awsdynamodb.TableProps {
+		tableProps.TableName = props.name(name)
+		tableProps.RemovalPolicy = props.RemovalPolicy
+		tableProps.PointInTimeRecovery = jsii.Bool(props.PointInTimeRecovery)
+		if props.BillingMode != "" {
+			tableProps.BillingMode = props.BillingMode
+		}
+		return tableProps
+	}
+
 	// Creating an SNS Topic
 
 	snsTopic := awssns.NewTopic(stack, jsii.String("GO_ReminderSnsTopic"), &awssns.TopicProps{
 		EnforceSSL: jsii.Bool(true),
-		TopicName:  jsii.String("GO_ReminderSnsTopic"),
+		TopicName:  props.name("ReminderSnsTopic"),
 	})
 
 	// Creating Cognito User Pool
 
 	userPool := awscognito.NewUserPool(stack, jsii.String("GO_ReminderUserPool"), &awscognito.UserPoolProps{
-		UserPoolName: jsii.String("GO_ReminderUserPool"),
+		UserPoolName: props.name("ReminderUserPool"),
 		SignInAliases: &awscognito.SignInAliases{
 			Username: jsii.Bool(true),
 			Phone:    jsii.Bool(true),
 		},
-		SelfSignUpEnabled: jsii.Bool(true),
+		SelfSignUpEnabled: jsii.Bool(props.Features.SelfSignUp),
 		CustomAttributes: &map[string]awscognito.ICustomAttribute{
 			"subscription_arn": awscognito.NewStringAttribute(&awscognito.StringAttributeProps{Mutable: jsii.Bool(true)}),
 		},
@@ -60,21 +118,28 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 
 	_ = awscognito.NewUserPoolClient(stack, jsii.String("GO_ReminderUserPoolClient"), &awscognito.UserPoolClientProps{
 		UserPool:           userPool,
-		UserPoolClientName: jsii.String("GO_ReminderUserPoolClient"),
+		UserPoolClientName: props.name("ReminderUserPoolClient"),
 	})
 
-	// Creating Post Confirmation Trigger for Cognito User Pool
+	// Creating DynamoDB Phone Numbers Table
 
-	postConfirmationLambda := golambda.NewGoFunction(stack, jsii.String("GO_PostConfirmationTrigger"), &golambda.GoFunctionProps{
-		FunctionName: jsii.String("GO_PostConfirmationTrigger"),
-		Entry:        jsii.String("lambdas/post-confirmation-trigger"),
-		Runtime:      awslambda.Runtime_PROVIDED_AL2(),
-		Architecture: awslambda.Architecture_ARM_64(),
-		Environment: &map[string]*string{
-			"SNS_TOPIC_ARN": snsTopic.TopicArn(),
+	phonesTable := awsdynamodb.NewTable(stack, jsii.String("GO_PhonesTable"), tableProps("PhonesTable", &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("UserID"),
+			Type: awsdynamodb.AttributeType_STRING,
 		},
-		Bundling: bundlingOptions,
-	})
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("Label"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	}))
+
+	// Creating Post Confirmation Trigger for Cognito User Pool
+
+	postConfirmationLambda := golambda.NewGoFunction(stack, jsii.String("GO_PostConfirmationTrigger"), functionProps("PostConfirmationTrigger", "lambdas/post-confirmation-trigger", map[string]*string{
+		"SNS_TOPIC_ARN":     snsTopic.TopicArn(),
+		"PHONES_TABLE_NAME": phonesTable.TableName(),
+	}))
 	postConfirmationLambda.Role().AttachInlinePolicy(awsiam.NewPolicy(stack, jsii.String("GO_PostConfirmationTriggerRole"), &awsiam.PolicyProps{
 		Statements: &[]awsiam.PolicyStatement{
 			awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
@@ -85,6 +150,10 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 				Actions:   jsii.Strings("sns:Subscribe"),
 				Resources: jsii.Strings(*snsTopic.TopicArn()),
 			}),
+			awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+				Actions:   jsii.Strings("dynamodb:PutItem"),
+				Resources: jsii.Strings(*phonesTable.TableArn()),
+			}),
 		},
 	}))
 
@@ -92,8 +161,7 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 
 	// Creating DynamoDB Alarms Table
 
-	alarmsTable := awsdynamodb.NewTable(stack, jsii.String("GO_AlarmTable"), &awsdynamodb.TableProps{
-		TableName: jsii.String("GO_AlarmTable"),
+	alarmsTable := awsdynamodb.NewTable(stack, jsii.String("GO_AlarmTable"), tableProps("AlarmTable", &awsdynamodb.TableProps{
 		PartitionKey: &awsdynamodb.Attribute{
 			Name: jsii.String("UserID"),
 			Type: awsdynamodb.AttributeType_STRING,
@@ -102,68 +170,222 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 			Name: jsii.String("EventID"),
 			Type: awsdynamodb.AttributeType_STRING,
 		},
-		RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
-	})
+	}))
 
 	// Creating DynamoDB Verification Codes Table
 
-	codesTable := awsdynamodb.NewTable(stack, jsii.String("GO_CodesTable"), &awsdynamodb.TableProps{
-		TableName: jsii.String("GO_CodesTable"),
+	codesTable := awsdynamodb.NewTable(stack, jsii.String("GO_CodesTable"), tableProps("CodesTable", &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("UserID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		TimeToLiveAttribute: jsii.String("ExpireOn"),
+	}))
+
+	// Creating DynamoDB User Settings Table
+
+	settingsTable := awsdynamodb.NewTable(stack, jsii.String("GO_SettingsTable"), tableProps("SettingsTable", &awsdynamodb.TableProps{
 		PartitionKey: &awsdynamodb.Attribute{
 			Name: jsii.String("UserID"),
 			Type: awsdynamodb.AttributeType_STRING,
 		},
+	}))
+
+	// Creating DynamoDB Delivery Log Table
+
+	deliveriesTable := awsdynamodb.NewTable(stack, jsii.String("GO_DeliveriesTable"), tableProps("DeliveriesTable", &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("UserID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("DeliveryID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
 		TimeToLiveAttribute: jsii.String("ExpireOn"),
-		RemovalPolicy:       awscdk.RemovalPolicy_DESTROY,
+	}))
+
+	// Creating DynamoDB SMS Usage Table
+
+	usageTable := awsdynamodb.NewTable(stack, jsii.String("GO_UsageTable"), tableProps("UsageTable", &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("UserID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("Month"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	}))
+
+	// Creating DynamoDB Idempotency Keys Table
+
+	idempotencyTable := awsdynamodb.NewTable(stack, jsii.String("GO_IdempotencyTable"), tableProps("IdempotencyTable", &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("UserID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("Key"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		TimeToLiveAttribute: jsii.String("ExpireOn"),
+	}))
+
+	// Creating DynamoDB Audit Table, entries stay there after accounts they concern are deleted
+
+	auditTable := awsdynamodb.NewTable(stack, jsii.String("GO_AuditTable"), tableProps("AuditTable", &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("UserID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("Action"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+	}))
+
+	// Creating DynamoDB Exports Table, it tracks asynchronous exports of personal data
+
+	exportsTable := awsdynamodb.NewTable(stack, jsii.String("GO_ExportsTable"), tableProps("ExportsTable", &awsdynamodb.TableProps{
+		PartitionKey: &awsdynamodb.Attribute{
+			Name: jsii.String("UserID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		SortKey: &awsdynamodb.Attribute{
+			Name: jsii.String("ExportID"),
+			Type: awsdynamodb.AttributeType_STRING,
+		},
+		TimeToLiveAttribute: jsii.String("ExpireOn"),
+	}))
+
+	// Creating S3 Bucket for exports of personal data, they're only downloaded with presigned
+	// links and expire after a week, like their entries in exports table
+
+	exportsBucket := awss3.NewBucket(stack, jsii.String("GO_ExportsBucket"), &awss3.BucketProps{
+		BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+		Encryption:        awss3.BucketEncryption_S3_MANAGED,
+		EnforceSSL:        jsii.Bool(true),
+		LifecycleRules: &[]*awss3.LifecycleRule{{
+			Expiration: awscdk.Duration_Days(jsii.Number(7)),
+		}},
+	})
+
+	// Creating EventBridge Schedule Group for alarms
+
+	scheduleGroup := awsscheduler.NewCfnScheduleGroup(stack, jsii.String("GO_ScheduleGroup"), &awsscheduler.CfnScheduleGroupProps{
+		Name: props.name("Alarms"),
+	})
+	// Scheduler supports resource-level permissions only for schedules within a group
+	groupSchedulesArn := stack.FormatArn(&awscdk.ArnComponents{
+		Service:      jsii.String("scheduler"),
+		Resource:     jsii.String("schedule"),
+		ResourceName: jsii.String(*scheduleGroup.Ref() + "/*"),
+	})
+	// Events scheduler fails to deliver to alarm executor are kept for two weeks, so they can be
+	// inspected and redriven
+	deadLetterQueue := awssqs.NewQueue(stack, jsii.String("GO_AlarmsDeadLetterQueue"), &awssqs.QueueProps{
+		QueueName:       props.name("AlarmsDeadLetterQueue"),
+		RetentionPeriod: awscdk.Duration_Days(jsii.Number(14)),
+		Encryption:      awssqs.QueueEncryption_SQS_MANAGED,
+	})
+	// Schedules created before the group was introduced are in the default one
+	defaultGroupSchedulesArn := stack.FormatArn(&awscdk.ArnComponents{
+		Service:      jsii.String("scheduler"),
+		Resource:     jsii.String("schedule"),
+		ResourceName: jsii.String("default/*"),
 	})
 
 	// Creating Lambda functions and adding permissions to them
 
 	// Creating Alarm Executor Function
-	alarmExecutorLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmExecutor"), &golambda.GoFunctionProps{
-		FunctionName: jsii.String("GO_AlarmExecutor"),
-		Entry:        jsii.String("lambdas/alarm-executor"),
-		Runtime:      awslambda.Runtime_PROVIDED_AL2(),
-		Architecture: awslambda.Architecture_ARM_64(),
-		Environment: &map[string]*string{
-			"SNS_TOPIC_ARN": snsTopic.TopicArn(),
-		},
-		Bundling: bundlingOptions,
+	lambdaExecutorInvokeRole := awsiam.NewRole(stack, jsii.String("GO_AlarmExecutorInvokeRole"), &awsiam.RoleProps{
+		RoleName:  props.name("AlarmExecutorInvokeRole"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("scheduler.amazonaws.com"), nil),
 	})
+
+	alarmExecutorLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmExecutor"), functionProps("AlarmExecutor", "lambdas/alarm-executor", map[string]*string{
+		"SNS_TOPIC_ARN":         snsTopic.TopicArn(),
+		"DYNAMO_TABLE_NAME":     alarmsTable.TableName(),
+		"SETTINGS_TABLE_NAME":   settingsTable.TableName(),
+		"DELIVERIES_TABLE_NAME": deliveriesTable.TableName(),
+		"ROLE_ARN":              lambdaExecutorInvokeRole.RoleArn(),
+		"MAX_SMS_SEGMENTS":      maxSmsSegments,
+		"USAGE_TABLE_NAME":      usageTable.TableName(),
+		"MONTHLY_SMS_QUOTA":     monthlySmsQuota,
+		"SCHEDULE_GROUP_NAME":   scheduleGroup.Ref(),
+		"DEAD_LETTER_QUEUE_ARN": deadLetterQueue.QueueArn(),
+	}))
 	alarmExecutorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("sns:Publish"),
 		Resources: jsii.Strings(*snsTopic.TopicArn()),
 	}))
+	alarmExecutorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:GetItem"),
+		Resources: jsii.Strings(*settingsTable.TableArn()),
+	}))
+	alarmExecutorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:UpdateItem"),
+		Resources: jsii.Strings(*usageTable.TableArn()),
+	}))
+	// Executor counts occurrences of events for message templates
+	alarmExecutorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:UpdateItem"),
+		Resources: jsii.Strings(*alarmsTable.TableArn()),
+	}))
+	alarmExecutorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:PutItem"),
+		Resources: jsii.Strings(*deliveriesTable.TableArn()),
+	}))
+	// Executor defers alarms falling into quiet hours by scheduling itself
+	alarmExecutorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("scheduler:CreateSchedule"),
+		Resources: jsii.Strings(*groupSchedulesArn),
+	}))
+	alarmExecutorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("iam:PassRole"),
+		Resources: jsii.Strings(*lambdaExecutorInvokeRole.RoleArn()),
+	}))
 
-	lambdaExecutorInvokeRole := awsiam.NewRole(stack, jsii.String("GO_AlarmExecutorInvokeRole"), &awsiam.RoleProps{
-		RoleName:  jsii.String("GO_AlarmExecutorInvokeRole"),
-		AssumedBy: awsiam.NewServicePrincipal(jsii.String("scheduler.amazonaws.com"), nil),
-	})
 	lambdaExecutorInvokeRole.AddToPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("lambda:InvokeFunction"),
 		Resources: jsii.Strings(*alarmExecutorLambda.FunctionArn()),
 	}))
+	// Scheduler sends undelivered events to dead letter queue with role of schedules
+	deadLetterQueue.GrantSendMessages(lambdaExecutorInvokeRole)
 
 	// Alarm Creator Function
-	alarmCreatorLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmCreator"), &golambda.GoFunctionProps{
-		FunctionName: jsii.String("GO_AlarmCreator"),
-		Entry:        jsii.String("lambdas/alarm-creator"),
-		Runtime:      awslambda.Runtime_PROVIDED_AL2(),
-		Architecture: awslambda.Architecture_ARM_64(),
-		Environment: &map[string]*string{
-			"DYNAMO_TABLE_NAME":   alarmsTable.TableArn(),
-			"LAMBDA_FUNCTION_ARN": alarmExecutorLambda.FunctionArn(),
-			"ROLE_ARN":            lambdaExecutorInvokeRole.RoleArn(),
-		},
-		Bundling: bundlingOptions,
-	})
+	alarmCreatorLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmCreator"), apiFunctionProps("AlarmCreator", "lambdas/alarm-creator", map[string]*string{
+		"DYNAMO_TABLE_NAME":   alarmsTable.TableName(),
+		"LAMBDA_FUNCTION_ARN": alarmExecutorLambda.FunctionArn(),
+		"ROLE_ARN":            lambdaExecutorInvokeRole.RoleArn(),
+		"MAX_SMS_SEGMENTS":    maxSmsSegments,
+		"MONTHLY_SMS_QUOTA":   monthlySmsQuota,
+
+		"MAX_SCHEDULES_PER_REQUEST": maxSchedulesPerRequest,
+		"MAX_CRONS_PER_EVENT":       maxCronsPerEvent,
+		"MAX_EVENTS_PER_USER":       maxEventsPerUser,
+		"SCHEDULER_CONCURRENCY":     schedulerConcurrency,
+		"IDEMPOTENCY_TABLE_NAME":    idempotencyTable.TableName(),
+		"PHONES_TABLE_NAME":         phonesTable.TableName(),
+		"SCHEDULE_GROUP_NAME":       scheduleGroup.Ref(),
+		"DEAD_LETTER_QUEUE_ARN":     deadLetterQueue.QueueArn(),
+	}))
 	alarmCreatorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
-		Actions:   jsii.Strings("dynamodb:PutItem"),
+		Actions:   jsii.Strings("dynamodb:PutItem", "dynamodb:Query"),
 		Resources: jsii.Strings(*alarmsTable.TableArn()),
 	}))
+	alarmCreatorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:Query"),
+		Resources: jsii.Strings(*phonesTable.TableArn()),
+	}))
+	alarmCreatorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:PutItem", "dynamodb:UpdateItem", "dynamodb:DeleteItem"),
+		Resources: jsii.Strings(*idempotencyTable.TableArn()),
+	}))
 	alarmCreatorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("scheduler:CreateSchedule"),
-		Resources: jsii.Strings("*"),
+		Resources: jsii.Strings(*groupSchedulesArn),
 	}))
 	alarmCreatorLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("iam:PassRole"),
@@ -171,52 +393,38 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 	}))
 
 	// Alarm Getter Function
-	alarmGetterLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmGetter"), &golambda.GoFunctionProps{
-		FunctionName: jsii.String("GO_AlarmGetter"),
-		Entry:        jsii.String("lambdas/alarm-getter"),
-		Runtime:      awslambda.Runtime_PROVIDED_AL2(),
-		Architecture: awslambda.Architecture_ARM_64(),
-		Environment: &map[string]*string{
-			"DYNAMO_TABLE_NAME": alarmsTable.TableName(),
-		},
-		Bundling: bundlingOptions,
-	})
+	alarmGetterLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmGetter"), apiFunctionProps("AlarmGetter", "lambdas/alarm-getter", map[string]*string{
+		"DYNAMO_TABLE_NAME": alarmsTable.TableName(),
+	}))
 	alarmGetterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("dynamodb:Query"),
 		Resources: jsii.Strings(*alarmsTable.TableArn()),
 	}))
 
 	// Alarm Deleter Function
-	alarmDeleterLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmDeleter"), &golambda.GoFunctionProps{
-		FunctionName: jsii.String("GO_AlarmDeleter"),
-		Entry:        jsii.String("lambdas/alarm-deleter"),
-		Runtime:      awslambda.Runtime_PROVIDED_AL2(),
-		Architecture: awslambda.Architecture_ARM_64(),
-		Environment: &map[string]*string{
-			"DYNAMO_TABLE_NAME": alarmsTable.TableName(),
-		},
-		Bundling: bundlingOptions,
-	})
+	alarmDeleterLambda := golambda.NewGoFunction(stack, jsii.String("GO_AlarmDeleter"), apiFunctionProps("AlarmDeleter", "lambdas/alarm-deleter", map[string]*string{
+		"DYNAMO_TABLE_NAME":     alarmsTable.TableName(),
+		"SCHEDULER_CONCURRENCY": schedulerConcurrency,
+		"SCHEDULE_GROUP_NAME":   scheduleGroup.Ref(),
+	}))
 	alarmDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("dynamodb:GetItem", "dynamodb:DeleteItem"),
 		Resources: jsii.Strings(*alarmsTable.TableArn()),
 	}))
 	alarmDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("scheduler:DeleteSchedule"),
+		Resources: jsii.Strings(*groupSchedulesArn, *defaultGroupSchedulesArn),
+	}))
+	// Listing schedules doesn't support resource-level permissions
+	alarmDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("scheduler:ListSchedules"),
 		Resources: jsii.Strings("*"),
 	}))
 
 	// Phone Number Modifier Function
-	phoneModifierLambda := golambda.NewGoFunction(stack, jsii.String("GO_PhoneModifier"), &golambda.GoFunctionProps{
-		FunctionName: jsii.String("GO_PhoneModifier"),
-		Entry:        jsii.String("lambdas/phone-modifier"),
-		Runtime:      awslambda.Runtime_PROVIDED_AL2(),
-		Architecture: awslambda.Architecture_ARM_64(),
-		Environment: &map[string]*string{
-			"DYNAMO_TABLE_NAME": codesTable.TableArn(),
-		},
-		Bundling: bundlingOptions,
-	})
+	phoneModifierLambda := golambda.NewGoFunction(stack, jsii.String("GO_PhoneModifier"), apiFunctionProps("PhoneModifier", "lambdas/phone-modifier", map[string]*string{
+		"DYNAMO_TABLE_NAME": codesTable.TableName(),
+	}))
 	phoneModifierLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("dynamodb:PutItem"),
 		Resources: jsii.Strings(*codesTable.TableArn()),
@@ -227,22 +435,20 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 	}))
 
 	// Phone Number Verifier Function
-	phoneVerifierLambda := golambda.NewGoFunction(stack, jsii.String("GO_PhoneVerifier"), &golambda.GoFunctionProps{
-		FunctionName: jsii.String("GO_PhoneVerifier"),
-		Entry:        jsii.String("lambdas/phone-verifier"),
-		Runtime:      awslambda.Runtime_PROVIDED_AL2(),
-		Architecture: awslambda.Architecture_ARM_64(),
-		Environment: &map[string]*string{
-			"DYNAMO_TABLE_NAME": codesTable.TableArn(),
-			"SNS_TOPIC_ARN":     snsTopic.TopicArn(),
-			"USER_POOL_ID":      userPool.UserPoolId(),
-		},
-		Bundling: bundlingOptions,
-	})
+	phoneVerifierLambda := golambda.NewGoFunction(stack, jsii.String("GO_PhoneVerifier"), apiFunctionProps("PhoneVerifier", "lambdas/phone-verifier", map[string]*string{
+		"DYNAMO_TABLE_NAME": codesTable.TableName(),
+		"PHONES_TABLE_NAME": phonesTable.TableName(),
+		"SNS_TOPIC_ARN":     snsTopic.TopicArn(),
+		"USER_POOL_ID":      userPool.UserPoolId(),
+	}))
 	phoneVerifierLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("dynamodb:GetItem", "dynamodb:DeleteItem"),
 		Resources: jsii.Strings(*codesTable.TableArn()),
 	}))
+	phoneVerifierLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:GetItem", "dynamodb:PutItem"),
+		Resources: jsii.Strings(*phonesTable.TableArn()),
+	}))
 	phoneVerifierLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
 		Actions:   jsii.Strings("sns:Subscribe", "sns:Unsubscribe"),
 		Resources: jsii.Strings(*snsTopic.TopicArn()),
@@ -252,13 +458,233 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 		Resources: jsii.Strings(*userPool.UserPoolArn()),
 	}))
 
+	// Phone Numbers Getter Function
+	phoneGetterLambda := golambda.NewGoFunction(stack, jsii.String("GO_PhoneGetter"), apiFunctionProps("PhoneGetter", "lambdas/phone-getter", map[string]*string{
+		"PHONES_TABLE_NAME": phonesTable.TableName(),
+	}))
+	phoneGetterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:Query"),
+		Resources: jsii.Strings(*phonesTable.TableArn()),
+	}))
+
+	// Quiet Hours Setter Function
+	quietHoursSetterLambda := golambda.NewGoFunction(stack, jsii.String("GO_QuietHoursSetter"), apiFunctionProps("QuietHoursSetter", "lambdas/quiet-hours-setter", map[string]*string{
+		"SETTINGS_TABLE_NAME": settingsTable.TableName(),
+	}))
+	quietHoursSetterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:UpdateItem"),
+		Resources: jsii.Strings(*settingsTable.TableArn()),
+	}))
+
+	// Quiet Hours Getter Function
+	quietHoursGetterLambda := golambda.NewGoFunction(stack, jsii.String("GO_QuietHoursGetter"), apiFunctionProps("QuietHoursGetter", "lambdas/quiet-hours-getter", map[string]*string{
+		"SETTINGS_TABLE_NAME": settingsTable.TableName(),
+	}))
+	quietHoursGetterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:GetItem"),
+		Resources: jsii.Strings(*settingsTable.TableArn()),
+	}))
+
+	// Usage Getter Function
+	usageGetterLambda := golambda.NewGoFunction(stack, jsii.String("GO_UsageGetter"), apiFunctionProps("UsageGetter", "lambdas/usage-getter", map[string]*string{
+		"USAGE_TABLE_NAME":  usageTable.TableName(),
+		"MONTHLY_SMS_QUOTA": monthlySmsQuota,
+	}))
+	usageGetterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:GetItem"),
+		Resources: jsii.Strings(*usageTable.TableArn()),
+	}))
+
+	// Account Deleter Function, it's invoked by API Gateway and directly by admins
+	accountDeleterLambda := golambda.NewGoFunction(stack, jsii.String("GO_AccountDeleter"), apiFunctionProps("AccountDeleter", "lambdas/account-deleter", map[string]*string{
+		"DYNAMO_TABLE_NAME":      alarmsTable.TableName(),
+		"PHONES_TABLE_NAME":      phonesTable.TableName(),
+		"CODES_TABLE_NAME":       codesTable.TableName(),
+		"SETTINGS_TABLE_NAME":    settingsTable.TableName(),
+		"DELIVERIES_TABLE_NAME":  deliveriesTable.TableName(),
+		"USAGE_TABLE_NAME":       usageTable.TableName(),
+		"IDEMPOTENCY_TABLE_NAME": idempotencyTable.TableName(),
+		"EXPORTS_TABLE_NAME":     exportsTable.TableName(),
+		"EXPORTS_BUCKET_NAME":    exportsBucket.BucketName(),
+		"AUDIT_TABLE_NAME":       auditTable.TableName(),
+		"USER_POOL_ID":           userPool.UserPoolId(),
+		"SCHEDULE_GROUP_NAME":    scheduleGroup.Ref(),
+		"SCHEDULER_CONCURRENCY":  schedulerConcurrency,
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:Query", "dynamodb:DeleteItem"),
+		Resources: jsii.Strings(*alarmsTable.TableArn(), *phonesTable.TableArn(), *deliveriesTable.TableArn(), *usageTable.TableArn(), *idempotencyTable.TableArn(), *exportsTable.TableArn()),
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:DeleteItem"),
+		Resources: jsii.Strings(*codesTable.TableArn(), *settingsTable.TableArn()),
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("s3:DeleteObject"),
+		Resources: jsii.Strings(*exportsBucket.ArnForObjects(jsii.String("exports/*"))),
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("s3:ListBucket"),
+		Resources: jsii.Strings(*exportsBucket.BucketArn()),
+		Conditions: &map[string]interface{}{
+			"StringLike": map[string]interface{}{"s3:prefix": "exports/*"},
+		},
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:UpdateItem"),
+		Resources: jsii.Strings(*auditTable.TableArn()),
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("scheduler:DeleteSchedule"),
+		Resources: jsii.Strings(*groupSchedulesArn, *defaultGroupSchedulesArn),
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("scheduler:ListSchedules"),
+		Resources: jsii.Strings("*"),
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("sns:Unsubscribe"),
+		Resources: jsii.Strings(*snsTopic.TopicArn()),
+	}))
+	accountDeleterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("cognito-idp:AdminGetUser", "cognito-idp:AdminDeleteUser"),
+		Resources: jsii.Strings(*userPool.UserPoolArn()),
+	}))
+
+	// Data Exporter Function, it invokes itself to produce exports too large for a response
+	dataExporterProps := apiFunctionProps("DataExporter", "lambdas/data-exporter", map[string]*string{
+		"DYNAMO_TABLE_NAME":     alarmsTable.TableName(),
+		"PHONES_TABLE_NAME":     phonesTable.TableName(),
+		"CODES_TABLE_NAME":      codesTable.TableName(),
+		"SETTINGS_TABLE_NAME":   settingsTable.TableName(),
+		"DELIVERIES_TABLE_NAME": deliveriesTable.TableName(),
+		"USAGE_TABLE_NAME":      usageTable.TableName(),
+		"EXPORTS_TABLE_NAME":    exportsTable.TableName(),
+		"EXPORTS_BUCKET_NAME":   exportsBucket.BucketName(),
+		"USER_POOL_ID":          userPool.UserPoolId(),
+		"SCHEDULE_GROUP_NAME":   scheduleGroup.Ref(),
+	})
+	dataExporterProps.Timeout = awscdk.Duration_Minutes(jsii.Number(5))
+	// Failed exports are marked as such, so users request them again rather than wait for retries
+	dataExporterProps.RetryAttempts = jsii.Number(0)
+	dataExporterLambda := golambda.NewGoFunction(stack, jsii.String("GO_DataExporter"), dataExporterProps)
+	dataExporterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:Query"),
+		Resources: jsii.Strings(*alarmsTable.TableArn(), *phonesTable.TableArn(), *deliveriesTable.TableArn(), *usageTable.TableArn()),
+	}))
+	dataExporterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:GetItem"),
+		Resources: jsii.Strings(*codesTable.TableArn(), *settingsTable.TableArn()),
+	}))
+	dataExporterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("dynamodb:GetItem", "dynamodb:PutItem", "dynamodb:UpdateItem"),
+		Resources: jsii.Strings(*exportsTable.TableArn()),
+	}))
+	dataExporterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("s3:PutObject", "s3:GetObject"),
+		Resources: jsii.Strings(*exportsBucket.ArnForObjects(jsii.String("exports/*"))),
+	}))
+	dataExporterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("scheduler:ListSchedules"),
+		Resources: jsii.Strings("*"),
+	}))
+	dataExporterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions:   jsii.Strings("cognito-idp:AdminGetUser"),
+		Resources: jsii.Strings(*userPool.UserPoolArn()),
+	}))
+	// ARN is built from the name, as referring to the function in its own policy is a circular dependency
+	dataExporterLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Actions: jsii.Strings("lambda:InvokeFunction"),
+		Resources: jsii.Strings(*stack.FormatArn(&awscdk.ArnComponents{
+			Service:      jsii.String("lambda"),
+			Resource:     jsii.String("function"),
+			ResourceName: dataExporterProps.FunctionName,
+			ArnFormat:    awscdk.ArnFormat_COLON_RESOURCE_NAME,
+		})),
+	}))
+
+	monitoredFunctions := []MonitoredFunction{
+		{Name: "PostConfirmationTrigger", Function: postConfirmationLambda},
+		{Name: "AlarmExecutor", Function: alarmExecutorLambda},
+		{Name: "AlarmCreator", Function: alarmCreatorLambda},
+		{Name: "AlarmGetter", Function: alarmGetterLambda},
+		{Name: "AlarmDeleter", Function: alarmDeleterLambda},
+		{Name: "PhoneModifier", Function: phoneModifierLambda},
+		{Name: "PhoneVerifier", Function: phoneVerifierLambda},
+		{Name: "PhoneGetter", Function: phoneGetterLambda},
+		{Name: "QuietHoursSetter", Function: quietHoursSetterLambda},
+		{Name: "QuietHoursGetter", Function: quietHoursGetterLambda},
+		{Name: "UsageGetter", Function: usageGetterLambda},
+		{Name: "AccountDeleter", Function: accountDeleterLambda},
+		{Name: "DataExporter", Function: dataExporterLambda},
+	}
+
+	if props.Features.Reconciler {
+		// Reconciler Function, it repairs drift between alarms table and schedules every night
+		reconcilerProps := functionProps("Reconciler", "lambdas/reconciler", map[string]*string{
+			"DYNAMO_TABLE_NAME":     alarmsTable.TableName(),
+			"SCHEDULE_GROUP_NAME":   scheduleGroup.Ref(),
+			"LAMBDA_FUNCTION_ARN":   alarmExecutorLambda.FunctionArn(),
+			"ROLE_ARN":              lambdaExecutorInvokeRole.RoleArn(),
+			"SCHEDULER_CONCURRENCY": schedulerConcurrency,
+			"DEAD_LETTER_QUEUE_ARN": deadLetterQueue.QueueArn(),
+		})
+		reconcilerProps.Timeout = awscdk.Duration_Minutes(jsii.Number(5))
+		reconcilerLambda := golambda.NewGoFunction(stack, jsii.String("GO_Reconciler"), reconcilerProps)
+		monitoredFunctions = append(monitoredFunctions, MonitoredFunction{Name: "Reconciler", Function: reconcilerLambda})
+		reconcilerLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("dynamodb:Scan", "dynamodb:GetItem"),
+			Resources: jsii.Strings(*alarmsTable.TableArn()),
+		}))
+		reconcilerLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("scheduler:CreateSchedule", "scheduler:DeleteSchedule"),
+			Resources: jsii.Strings(*groupSchedulesArn),
+		}))
+		reconcilerLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("scheduler:ListSchedules"),
+			Resources: jsii.Strings("*"),
+		}))
+		reconcilerLambda.AddToRolePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Actions:   jsii.Strings("iam:PassRole"),
+			Resources: jsii.Strings(*lambdaExecutorInvokeRole.RoleArn()),
+		}))
+		awsevents.NewRule(stack, jsii.String("GO_ReconcilerRule"), &awsevents.RuleProps{
+			Schedule: awsevents.Schedule_Cron(&awsevents.CronOptions{
+				Minute: jsii.String("0"),
+				Hour:   jsii.String("3"),
+			}),
+			Targets: &[]awsevents.IRuleTarget{
+				awseventstargets.NewLambdaFunction(reconcilerLambda, &awseventstargets.LambdaFunctionProps{
+					Event: awsevents.RuleTargetInput_FromObject(map[string]interface{}{
+						"dryRun": props.Features.ReconcilerDryRun,
+					}),
+				}),
+			},
+		})
+	}
+
 	// Defining Rest API in API Gateway
 	myGateway := awsapigateway.NewRestApi(stack, jsii.String("GO_RestApi"), &awsapigateway.RestApiProps{
 		DefaultCorsPreflightOptions: &awsapigateway.CorsOptions{
-			AllowOrigins: &[]*string{jsii.String("*")},
-			AllowMethods: &[]*string{jsii.String("OPTIONS"), jsii.String("GET"), jsii.String("POST"), jsii.String("DELETE")},
+			AllowOrigins: jsii.Strings(props.AllowedOrigins...),
+			AllowMethods: &[]*string{jsii.String("OPTIONS"), jsii.String("GET"), jsii.String("POST"), jsii.String("PUT"), jsii.String("DELETE")},
+			AllowHeaders: &[]*string{
+				jsii.String("Content-Type"),
+				jsii.String("X-Amz-Date"),
+				jsii.String("Authorization"),
+				jsii.String("X-Api-Key"),
+				jsii.String("X-Amz-Security-Token"),
+				jsii.String("X-Amz-User-Agent"),
+				jsii.String("Idempotency-Key"),
+			},
+		},
+		RestApiName: props.name("RestApi"),
+		// Exports of personal data can be downloaded as zip archives
+		BinaryMediaTypes: jsii.Strings("application/zip"),
+		// Traces of requests start in API Gateway and continue in functions handling them
+		DeployOptions: &awsapigateway.StageOptions{
+			TracingEnabled: jsii.Bool(true),
 		},
-		RestApiName: jsii.String("GO_RestApi"),
 	})
 
 	cognitoAuthorizer := awsapigateway.NewCognitoUserPoolsAuthorizer(stack, jsii.String("GO_Authorizer"), &awsapigateway.CognitoUserPoolsAuthorizerProps{
@@ -270,6 +696,12 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 	alarmDeleterIntegration := awsapigateway.NewLambdaIntegration(alarmDeleterLambda, nil)
 	phoneModifierIntegration := awsapigateway.NewLambdaIntegration(phoneModifierLambda, nil)
 	phoneVerifierIntegration := awsapigateway.NewLambdaIntegration(phoneVerifierLambda, nil)
+	phoneGetterIntegration := awsapigateway.NewLambdaIntegration(phoneGetterLambda, nil)
+	quietHoursSetterIntegration := awsapigateway.NewLambdaIntegration(quietHoursSetterLambda, nil)
+	quietHoursGetterIntegration := awsapigateway.NewLambdaIntegration(quietHoursGetterLambda, nil)
+	usageGetterIntegration := awsapigateway.NewLambdaIntegration(usageGetterLambda, nil)
+	accountDeleterIntegration := awsapigateway.NewLambdaIntegration(accountDeleterLambda, nil)
+	dataExporterIntegration := awsapigateway.NewLambdaIntegration(dataExporterLambda, nil)
 
 	alarmsResource := myGateway.Root().AddResource(jsii.String("alarms"), nil)
 	alarmsResource.AddMethod(jsii.String("POST"), alarmCreatorIntegration, &awsapigateway.MethodOptions{
@@ -297,6 +729,47 @@ func NewAlerterStack(scope constructs.Construct, id string, props *AlerterStackP
 		Authorizer:        cognitoAuthorizer,
 	})
 
+	phonesResource := myGateway.Root().AddResource(jsii.String("phones"), nil)
+	phonesResource.AddMethod(jsii.String("GET"), phoneGetterIntegration, &awsapigateway.MethodOptions{
+		AuthorizationType: awsapigateway.AuthorizationType_COGNITO,
+		Authorizer:        cognitoAuthorizer,
+	})
+
+	quietHoursResource := myGateway.Root().AddResource(jsii.String("quiet-hours"), nil)
+	quietHoursResource.AddMethod(jsii.String("PUT"), quietHoursSetterIntegration, &awsapigateway.MethodOptions{
+		AuthorizationType: awsapigateway.AuthorizationType_COGNITO,
+		Authorizer:        cognitoAuthorizer,
+	})
+	quietHoursResource.AddMethod(jsii.String("GET"), quietHoursGetterIntegration, &awsapigateway.MethodOptions{
+		AuthorizationType: awsapigateway.AuthorizationType_COGNITO,
+		Authorizer:        cognitoAuthorizer,
+	})
+
+	meResource := myGateway.Root().AddResource(jsii.String("me"), nil)
+	meResource.AddMethod(jsii.String("DELETE"), accountDeleterIntegration, &awsapigateway.MethodOptions{
+		AuthorizationType: awsapigateway.AuthorizationType_COGNITO,
+		Authorizer:        cognitoAuthorizer,
+	})
+	usageResource := meResource.AddResource(jsii.String("usage"), nil)
+	usageResource.AddMethod(jsii.String("GET"), usageGetterIntegration, &awsapigateway.MethodOptions{
+		AuthorizationType: awsapigateway.AuthorizationType_COGNITO,
+		Authorizer:        cognitoAuthorizer,
+	})
+	exportResource := meResource.AddResource(jsii.String("export"), nil)
+	exportResource.AddMethod(jsii.String("GET"), dataExporterIntegration, &awsapigateway.MethodOptions{
+		AuthorizationType: awsapigateway.AuthorizationType_COGNITO,
+		Authorizer:        cognitoAuthorizer,
+	})
+
+	NewMonitoring(stack, "GO_Monitoring", &MonitoringProps{
+		Stack:           props,
+		Functions:       monitoredFunctions,
+		Api:             myGateway,
+		ScheduleGroup:   scheduleGroup.Ref(),
+		DeadLetterQueue: deadLetterQueue,
+		SmsTopic:        snsTopic,
+	})
+
 	return stack
 }
 
@@ -305,15 +778,13 @@ func main() {
 
 	app := awscdk.NewApp(nil)
 
-	NewAlerterStack(app, "ReminderStack", &AlerterStackProps{
-		awscdk.StackProps{
-			Env: env(),
-		},
-	})
+	stacks, err := stages(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, id := range sortedStacks(stacks) {
+		NewAlerterStack(app, id, stacks[id])
+	}
 
 	app.Synth(nil)
 }
-
-func env() *awscdk.Environment {
-	return nil
-}
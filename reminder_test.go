@@ -1,26 +1,989 @@
 package main
 
-// import (
-// 	"testing"
-
-// 	"github.com/aws/aws-cdk-go/awscdk/v2"
-// 	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
-// 	"github.com/aws/jsii-runtime-go"
-// )
-
-// example tests. To run these tests, uncomment this file along with the
-// example resource in alerter_test.go
-// func TestAlerterStack(t *testing.T) {
-// 	// GIVEN
-// 	app := awscdk.NewApp(nil)
-
-// 	// WHEN
-// 	stack := NewAlerterStack(app, "MyStack", nil)
-
-// 	// THEN
-// 	template := assertions.Template_FromStack(stack, nil)
-
-// 	template.HasResourceProperties(jsii.String("AWS::SQS::Queue"), map[string]interface{}{
-// 		"VisibilityTimeout": 300,
-// 	})
-// }
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/jsii-runtime-go"
+)
+
+var (
+	synthOnce sync.Once
+	template  assertions.Template
+	resources map[string]resource
+	names     map[string]string
+)
+
+type resource struct {
+	Type       string                 `json:"Type"`
+	Properties map[string]interface{} `json:"Properties"`
+}
+
+// synth synthesizes the stack once for all tests. Lambdas aren't bundled, since tests only
+// look at the template
+func synth(t *testing.T) assertions.Template {
+	t.Helper()
+
+	synthOnce.Do(func() {
+		app := awscdk.NewApp(&awscdk.AppProps{
+			Context: &map[string]interface{}{
+				"aws:cdk:bundling-stacks": []string{},
+			},
+		})
+		stack := NewAlerterStack(app, "TestStack", nil)
+		template = assertions.Template_FromStack(stack, nil)
+
+		raw, _ := json.Marshal(template.ToJSON())
+		var parsed struct {
+			Resources map[string]resource `json:"Resources"`
+		}
+		if err := json.Unmarshal(raw, &parsed); err != nil {
+			panic(err)
+		}
+		resources = parsed.Resources
+
+		// Logical IDs are hashed, so resources are referred to by names they are given in the stack
+		names = make(map[string]string)
+		for id, res := range resources {
+			names[id] = id
+			for _, property := range []string{"FunctionName", "TableName", "RoleName", "TopicName", "UserPoolName", "QueueName", "Name"} {
+				if name, ok := res.Properties[property].(string); ok {
+					names[id] = name
+					break
+				}
+			}
+		}
+	})
+	if template == nil {
+		t.Fatal("Stack could not be synthesized")
+	}
+	return template
+}
+
+// render converts a template value into a string, with references to resources replaced by
+// ${Name} and their attributes by ${Name.Attribute}
+func render(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if ref, ok := v["Ref"].(string); ok {
+			if name, ok := names[ref]; ok {
+				return "${" + name + "}"
+			}
+			return "${" + ref + "}"
+		}
+		if att, ok := v["Fn::GetAtt"].([]interface{}); ok && len(att) == 2 {
+			id, _ := att[0].(string)
+			if name, ok := names[id]; ok {
+				id = name
+			}
+			return fmt.Sprintf("${%s.%v}", id, att[1])
+		}
+		if join, ok := v["Fn::Join"].([]interface{}); ok && len(join) == 2 {
+			sep, _ := join[0].(string)
+			parts, _ := join[1].([]interface{})
+			rendered := make([]string, len(parts))
+			for i, part := range parts {
+				rendered[i] = render(part)
+			}
+			return strings.Join(rendered, sep)
+		}
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// list returns a value that is either a single element or a list of them as a list
+func list(v interface{}) []interface{} {
+	if l, ok := v.([]interface{}); ok {
+		return l
+	}
+	return []interface{}{v}
+}
+
+// function returns properties of Lambda function with given name
+func function(t *testing.T, name string) map[string]interface{} {
+	t.Helper()
+	for _, res := range resources {
+		if res.Type == "AWS::Lambda::Function" && res.Properties["FunctionName"] == name {
+			return res.Properties
+		}
+	}
+	t.Fatalf("Function %s not found", name)
+	return nil
+}
+
+// logicalID returns ID of the only resource of given type, it's how resources without names are
+// referred to
+func logicalID(t *testing.T, resourceType string) string {
+	t.Helper()
+	var ids []string
+	for id, res := range resources {
+		if res.Type == resourceType {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) != 1 {
+		t.Fatalf("Received resources of type %s: %v instead of one", resourceType, ids)
+	}
+	return ids[0]
+}
+
+// permissions returns "action resource" pairs granted by inline policies of a role
+func permissions(role string) []string {
+	permissions := []string{}
+	for _, res := range resources {
+		if res.Type != "AWS::IAM::Policy" {
+			continue
+		}
+		attached := false
+		for _, r := range list(res.Properties["Roles"]) {
+			attached = attached || render(r) == role
+		}
+		if !attached {
+			continue
+		}
+
+		document, _ := res.Properties["PolicyDocument"].(map[string]interface{})
+		for _, s := range list(document["Statement"]) {
+			statement, _ := s.(map[string]interface{})
+			if statement["Effect"] != "Allow" {
+				continue
+			}
+			for _, action := range list(statement["Action"]) {
+				for _, resource := range list(statement["Resource"]) {
+					permissions = append(permissions, fmt.Sprintf("%v %s", action, render(resource)))
+				}
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// check turns failed assertion, which panics, into test error
+func check(t *testing.T, assertion func()) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Assertion failed: %v", r)
+		}
+	}()
+	assertion()
+}
+
+func TestLambdaEnvironment(t *testing.T) {
+	synth(t)
+
+	exportsBucket := logicalID(t, "AWS::S3::Bucket")
+
+	testCases := []struct {
+		function string
+		expected map[string]string
+	}{
+		{
+			function: "GO_PostConfirmationTrigger",
+			expected: map[string]string{
+				"SNS_TOPIC_ARN":     "${GO_ReminderSnsTopic}",
+				"PHONES_TABLE_NAME": "${GO_PhonesTable}",
+			},
+		},
+		{
+			function: "GO_AlarmExecutor",
+			expected: map[string]string{
+				"SNS_TOPIC_ARN":         "${GO_ReminderSnsTopic}",
+				"DYNAMO_TABLE_NAME":     "${GO_AlarmTable}",
+				"SETTINGS_TABLE_NAME":   "${GO_SettingsTable}",
+				"DELIVERIES_TABLE_NAME": "${GO_DeliveriesTable}",
+				"ROLE_ARN":              "${GO_AlarmExecutorInvokeRole.Arn}",
+				"MAX_SMS_SEGMENTS":      "3",
+				"USAGE_TABLE_NAME":      "${GO_UsageTable}",
+				"MONTHLY_SMS_QUOTA":     "300",
+				"SCHEDULE_GROUP_NAME":   "${GO_Alarms}",
+				"DEAD_LETTER_QUEUE_ARN": "${GO_AlarmsDeadLetterQueue.Arn}",
+			},
+		},
+		{
+			function: "GO_AlarmCreator",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS":      "*",
+				"DYNAMO_TABLE_NAME":         "${GO_AlarmTable}",
+				"LAMBDA_FUNCTION_ARN":       "${GO_AlarmExecutor.Arn}",
+				"ROLE_ARN":                  "${GO_AlarmExecutorInvokeRole.Arn}",
+				"MAX_SMS_SEGMENTS":          "3",
+				"MONTHLY_SMS_QUOTA":         "300",
+				"MAX_SCHEDULES_PER_REQUEST": "25",
+				"MAX_CRONS_PER_EVENT":       "10",
+				"MAX_EVENTS_PER_USER":       "50",
+				"SCHEDULER_CONCURRENCY":     "10",
+				"IDEMPOTENCY_TABLE_NAME":    "${GO_IdempotencyTable}",
+				"PHONES_TABLE_NAME":         "${GO_PhonesTable}",
+				"SCHEDULE_GROUP_NAME":       "${GO_Alarms}",
+				"DEAD_LETTER_QUEUE_ARN":     "${GO_AlarmsDeadLetterQueue.Arn}",
+			},
+		},
+		{
+			function: "GO_AlarmGetter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "*",
+				"DYNAMO_TABLE_NAME":    "${GO_AlarmTable}",
+			},
+		},
+		{
+			function: "GO_AlarmDeleter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS":  "*",
+				"DYNAMO_TABLE_NAME":     "${GO_AlarmTable}",
+				"SCHEDULER_CONCURRENCY": "10",
+				"SCHEDULE_GROUP_NAME":   "${GO_Alarms}",
+			},
+		},
+		{
+			function: "GO_PhoneModifier",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "*",
+				"DYNAMO_TABLE_NAME":    "${GO_CodesTable}",
+			},
+		},
+		{
+			function: "GO_PhoneVerifier",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "*",
+				"DYNAMO_TABLE_NAME":    "${GO_CodesTable}",
+				"PHONES_TABLE_NAME":    "${GO_PhonesTable}",
+				"SNS_TOPIC_ARN":        "${GO_ReminderSnsTopic}",
+				"USER_POOL_ID":         "${GO_ReminderUserPool}",
+			},
+		},
+		{
+			function: "GO_PhoneGetter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "*",
+				"PHONES_TABLE_NAME":    "${GO_PhonesTable}",
+			},
+		},
+		{
+			function: "GO_QuietHoursSetter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "*",
+				"SETTINGS_TABLE_NAME":  "${GO_SettingsTable}",
+			},
+		},
+		{
+			function: "GO_QuietHoursGetter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "*",
+				"SETTINGS_TABLE_NAME":  "${GO_SettingsTable}",
+			},
+		},
+		{
+			function: "GO_UsageGetter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS": "*",
+				"USAGE_TABLE_NAME":     "${GO_UsageTable}",
+				"MONTHLY_SMS_QUOTA":    "300",
+			},
+		},
+		{
+			function: "GO_AccountDeleter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS":   "*",
+				"DYNAMO_TABLE_NAME":      "${GO_AlarmTable}",
+				"PHONES_TABLE_NAME":      "${GO_PhonesTable}",
+				"CODES_TABLE_NAME":       "${GO_CodesTable}",
+				"SETTINGS_TABLE_NAME":    "${GO_SettingsTable}",
+				"DELIVERIES_TABLE_NAME":  "${GO_DeliveriesTable}",
+				"USAGE_TABLE_NAME":       "${GO_UsageTable}",
+				"IDEMPOTENCY_TABLE_NAME": "${GO_IdempotencyTable}",
+				"EXPORTS_TABLE_NAME":     "${GO_ExportsTable}",
+				"EXPORTS_BUCKET_NAME":    "${" + exportsBucket + "}",
+				"AUDIT_TABLE_NAME":       "${GO_AuditTable}",
+				"USER_POOL_ID":           "${GO_ReminderUserPool}",
+				"SCHEDULE_GROUP_NAME":    "${GO_Alarms}",
+				"SCHEDULER_CONCURRENCY":  "10",
+			},
+		},
+		{
+			function: "GO_DataExporter",
+			expected: map[string]string{
+				"CORS_ALLOWED_ORIGINS":  "*",
+				"DYNAMO_TABLE_NAME":     "${GO_AlarmTable}",
+				"PHONES_TABLE_NAME":     "${GO_PhonesTable}",
+				"CODES_TABLE_NAME":      "${GO_CodesTable}",
+				"SETTINGS_TABLE_NAME":   "${GO_SettingsTable}",
+				"DELIVERIES_TABLE_NAME": "${GO_DeliveriesTable}",
+				"USAGE_TABLE_NAME":      "${GO_UsageTable}",
+				"EXPORTS_TABLE_NAME":    "${GO_ExportsTable}",
+				"EXPORTS_BUCKET_NAME":   "${" + exportsBucket + "}",
+				"USER_POOL_ID":          "${GO_ReminderUserPool}",
+				"SCHEDULE_GROUP_NAME":   "${GO_Alarms}",
+			},
+		},
+		{
+			function: "GO_Reconciler",
+			expected: map[string]string{
+				"DYNAMO_TABLE_NAME":     "${GO_AlarmTable}",
+				"SCHEDULE_GROUP_NAME":   "${GO_Alarms}",
+				"LAMBDA_FUNCTION_ARN":   "${GO_AlarmExecutor.Arn}",
+				"ROLE_ARN":              "${GO_AlarmExecutorInvokeRole.Arn}",
+				"SCHEDULER_CONCURRENCY": "10",
+				"DEAD_LETTER_QUEUE_ARN": "${GO_AlarmsDeadLetterQueue.Arn}",
+			},
+		},
+	}
+
+	functions := 0
+	for _, res := range resources {
+		// functions of custom resources don't have names
+		if res.Type == "AWS::Lambda::Function" && res.Properties["FunctionName"] != nil {
+			functions++
+		}
+	}
+	if functions != len(testCases) {
+		t.Errorf("Received number of functions: %v is different than expected one: %v", functions, len(testCases))
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.function, func(t *testing.T) {
+			environment, _ := function(t, tC.function)["Environment"].(map[string]interface{})
+			variables, _ := environment["Variables"].(map[string]interface{})
+
+			tC.expected["LOG_LEVEL"] = "info"
+			received := make(map[string]string)
+			for name, value := range variables {
+				received[name] = render(value)
+			}
+			if !reflect.DeepEqual(received, tC.expected) {
+				t.Errorf("Received result: %v is different than expected one: %v", received, tC.expected)
+			}
+		})
+	}
+}
+
+func TestLeastPrivilege(t *testing.T) {
+	synth(t)
+
+	groupSchedules := "arn:${AWS::Partition}:scheduler:${AWS::Region}:${AWS::AccountId}:schedule/${GO_Alarms}/*"
+	defaultGroupSchedules := "arn:${AWS::Partition}:scheduler:${AWS::Region}:${AWS::AccountId}:schedule/default/*"
+	exports := "${" + logicalID(t, "AWS::S3::Bucket") + ".Arn}/exports/*"
+
+	testCases := []struct {
+		function string
+		expected []string
+	}{
+		{
+			function: "GO_PostConfirmationTrigger",
+			expected: []string{
+				"cognito-idp:AdminUpdateUserAttributes ${GO_ReminderUserPool.Arn}",
+				"dynamodb:PutItem ${GO_PhonesTable.Arn}",
+				"sns:Subscribe ${GO_ReminderSnsTopic}",
+			},
+		},
+		{
+			function: "GO_AlarmExecutor",
+			expected: []string{
+				"dynamodb:GetItem ${GO_SettingsTable.Arn}",
+				"dynamodb:PutItem ${GO_DeliveriesTable.Arn}",
+				"dynamodb:UpdateItem ${GO_AlarmTable.Arn}",
+				"dynamodb:UpdateItem ${GO_UsageTable.Arn}",
+				"iam:PassRole ${GO_AlarmExecutorInvokeRole.Arn}",
+				"scheduler:CreateSchedule " + groupSchedules,
+				"sns:Publish ${GO_ReminderSnsTopic}",
+			},
+		},
+		{
+			function: "GO_AlarmCreator",
+			expected: []string{
+				"dynamodb:DeleteItem ${GO_IdempotencyTable.Arn}",
+				"dynamodb:PutItem ${GO_AlarmTable.Arn}",
+				"dynamodb:PutItem ${GO_IdempotencyTable.Arn}",
+				"dynamodb:Query ${GO_AlarmTable.Arn}",
+				"dynamodb:Query ${GO_PhonesTable.Arn}",
+				"dynamodb:UpdateItem ${GO_IdempotencyTable.Arn}",
+				"iam:PassRole ${GO_AlarmExecutorInvokeRole.Arn}",
+				"scheduler:CreateSchedule " + groupSchedules,
+			},
+		},
+		{
+			function: "GO_AlarmGetter",
+			expected: []string{
+				"dynamodb:Query ${GO_AlarmTable.Arn}",
+			},
+		},
+		{
+			function: "GO_AlarmDeleter",
+			expected: []string{
+				"dynamodb:DeleteItem ${GO_AlarmTable.Arn}",
+				"dynamodb:GetItem ${GO_AlarmTable.Arn}",
+				"scheduler:DeleteSchedule " + defaultGroupSchedules,
+				"scheduler:DeleteSchedule " + groupSchedules,
+				// Listing schedules doesn't support resource-level permissions
+				"scheduler:ListSchedules *",
+			},
+		},
+		{
+			function: "GO_PhoneModifier",
+			expected: []string{
+				"dynamodb:PutItem ${GO_CodesTable.Arn}",
+				// SMS messages sent directly to phone numbers don't have a resource
+				"sns:Publish *",
+			},
+		},
+		{
+			function: "GO_PhoneVerifier",
+			expected: []string{
+				"cognito-idp:AdminUpdateUserAttributes ${GO_ReminderUserPool.Arn}",
+				"dynamodb:DeleteItem ${GO_CodesTable.Arn}",
+				"dynamodb:GetItem ${GO_CodesTable.Arn}",
+				"dynamodb:GetItem ${GO_PhonesTable.Arn}",
+				"dynamodb:PutItem ${GO_PhonesTable.Arn}",
+				"sns:Subscribe ${GO_ReminderSnsTopic}",
+				"sns:Unsubscribe ${GO_ReminderSnsTopic}",
+			},
+		},
+		{
+			function: "GO_DataExporter",
+			expected: []string{
+				"cognito-idp:AdminGetUser ${GO_ReminderUserPool.Arn}",
+				"dynamodb:GetItem ${GO_CodesTable.Arn}",
+				"dynamodb:GetItem ${GO_ExportsTable.Arn}",
+				"dynamodb:GetItem ${GO_SettingsTable.Arn}",
+				"dynamodb:PutItem ${GO_ExportsTable.Arn}",
+				"dynamodb:Query ${GO_AlarmTable.Arn}",
+				"dynamodb:Query ${GO_DeliveriesTable.Arn}",
+				"dynamodb:Query ${GO_PhonesTable.Arn}",
+				"dynamodb:Query ${GO_UsageTable.Arn}",
+				"dynamodb:UpdateItem ${GO_ExportsTable.Arn}",
+				"lambda:InvokeFunction arn:${AWS::Partition}:lambda:${AWS::Region}:${AWS::AccountId}:function:GO_DataExporter",
+				"s3:GetObject " + exports,
+				"s3:PutObject " + exports,
+				"scheduler:ListSchedules *",
+			},
+		},
+		{
+			function: "GO_PhoneGetter",
+			expected: []string{
+				"dynamodb:Query ${GO_PhonesTable.Arn}",
+			},
+		},
+		{
+			function: "GO_QuietHoursSetter",
+			expected: []string{
+				"dynamodb:UpdateItem ${GO_SettingsTable.Arn}",
+			},
+		},
+		{
+			function: "GO_QuietHoursGetter",
+			expected: []string{
+				"dynamodb:GetItem ${GO_SettingsTable.Arn}",
+			},
+		},
+		{
+			function: "GO_UsageGetter",
+			expected: []string{
+				"dynamodb:GetItem ${GO_UsageTable.Arn}",
+			},
+		},
+		{
+			function: "GO_AccountDeleter",
+			expected: []string{
+				"cognito-idp:AdminDeleteUser ${GO_ReminderUserPool.Arn}",
+				"cognito-idp:AdminGetUser ${GO_ReminderUserPool.Arn}",
+				"dynamodb:DeleteItem ${GO_AlarmTable.Arn}",
+				"dynamodb:DeleteItem ${GO_CodesTable.Arn}",
+				"dynamodb:DeleteItem ${GO_DeliveriesTable.Arn}",
+				"dynamodb:DeleteItem ${GO_ExportsTable.Arn}",
+				"dynamodb:DeleteItem ${GO_IdempotencyTable.Arn}",
+				"dynamodb:DeleteItem ${GO_PhonesTable.Arn}",
+				"dynamodb:DeleteItem ${GO_SettingsTable.Arn}",
+				"dynamodb:DeleteItem ${GO_UsageTable.Arn}",
+				"dynamodb:Query ${GO_AlarmTable.Arn}",
+				"dynamodb:Query ${GO_DeliveriesTable.Arn}",
+				"dynamodb:Query ${GO_ExportsTable.Arn}",
+				"dynamodb:Query ${GO_IdempotencyTable.Arn}",
+				"dynamodb:Query ${GO_PhonesTable.Arn}",
+				"dynamodb:Query ${GO_UsageTable.Arn}",
+				"dynamodb:UpdateItem ${GO_AuditTable.Arn}",
+				"s3:DeleteObject " + exports,
+				// listing is limited to the exports prefix with a condition
+				"s3:ListBucket ${" + logicalID(t, "AWS::S3::Bucket") + ".Arn}",
+				"scheduler:DeleteSchedule " + defaultGroupSchedules,
+				"scheduler:DeleteSchedule " + groupSchedules,
+				"scheduler:ListSchedules *",
+				"sns:Unsubscribe ${GO_ReminderSnsTopic}",
+			},
+		},
+		{
+			function: "GO_Reconciler",
+			expected: []string{
+				"dynamodb:GetItem ${GO_AlarmTable.Arn}",
+				"dynamodb:Scan ${GO_AlarmTable.Arn}",
+				"iam:PassRole ${GO_AlarmExecutorInvokeRole.Arn}",
+				"scheduler:CreateSchedule " + groupSchedules,
+				"scheduler:DeleteSchedule " + groupSchedules,
+				"scheduler:ListSchedules *",
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.function, func(t *testing.T) {
+			role := strings.TrimSuffix(render(function(t, tC.function)["Role"]), ".Arn}") + "}"
+			// Every function sends traces to X-Ray, which doesn't support resource-level permissions
+			expected := append(tC.expected, "xray:PutTelemetryRecords *", "xray:PutTraceSegments *")
+			sort.Strings(expected)
+			if received := permissions(role); !reflect.DeepEqual(received, expected) {
+				t.Errorf("Received result: %v is different than expected one: %v", received, expected)
+			}
+		})
+	}
+
+	t.Run("GO_AlarmExecutorInvokeRole", func(t *testing.T) {
+		expected := []string{
+			"lambda:InvokeFunction ${GO_AlarmExecutor.Arn}",
+			"sqs:GetQueueAttributes ${GO_AlarmsDeadLetterQueue.Arn}",
+			"sqs:GetQueueUrl ${GO_AlarmsDeadLetterQueue.Arn}",
+			"sqs:SendMessage ${GO_AlarmsDeadLetterQueue.Arn}",
+		}
+		if received := permissions("${GO_AlarmExecutorInvokeRole}"); !reflect.DeepEqual(received, expected) {
+			t.Errorf("Received result: %v is different than expected one: %v", received, expected)
+		}
+	})
+}
+
+// routes returns methods of the API by "METHOD /path", along with their properties
+func routes() map[string]map[string]interface{} {
+	paths := make(map[string]string)
+	var resolve func(id string) string
+	resolve = func(id string) string {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+		res, ok := resources[id]
+		if !ok || res.Type != "AWS::ApiGateway::Resource" {
+			return ""
+		}
+		parent := ""
+		if ref, ok := res.Properties["ParentId"].(map[string]interface{})["Ref"].(string); ok {
+			parent = resolve(ref)
+		}
+		paths[id] = fmt.Sprintf("%s/%v", parent, res.Properties["PathPart"])
+		return paths[id]
+	}
+
+	routes := make(map[string]map[string]interface{})
+	for _, res := range resources {
+		if res.Type != "AWS::ApiGateway::Method" {
+			continue
+		}
+		path := "/"
+		if ref, ok := res.Properties["ResourceId"].(map[string]interface{})["Ref"].(string); ok {
+			path = resolve(ref)
+		}
+		routes[fmt.Sprintf("%v %s", res.Properties["HttpMethod"], path)] = res.Properties
+	}
+	return routes
+}
+
+var integratedFunction = regexp.MustCompile(`\$\{(GO_\w+)\.Arn\}`)
+
+func TestApiRoutes(t *testing.T) {
+	synth(t)
+
+	expected := map[string]string{
+		"POST /alarms":              "GO_AlarmCreator",
+		"GET /alarms":               "GO_AlarmGetter",
+		"DELETE /alarms/{id}":       "GO_AlarmDeleter",
+		"POST /update-phone-number": "GO_PhoneModifier",
+		"POST /verify-phone-number": "GO_PhoneVerifier",
+		"GET /phones":               "GO_PhoneGetter",
+		"PUT /quiet-hours":          "GO_QuietHoursSetter",
+		"GET /quiet-hours":          "GO_QuietHoursGetter",
+		"GET /me/usage":             "GO_UsageGetter",
+		"DELETE /me":                "GO_AccountDeleter",
+		"GET /me/export":            "GO_DataExporter",
+	}
+
+	received := make(map[string]string)
+	for route, properties := range routes() {
+		if strings.HasPrefix(route, "OPTIONS ") {
+			continue
+		}
+		integration, _ := properties["Integration"].(map[string]interface{})
+		if match := integratedFunction.FindStringSubmatch(render(integration["Uri"])); match != nil {
+			received[route] = match[1]
+		} else {
+			received[route] = ""
+		}
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("Received result: %v is different than expected one: %v", received, expected)
+	}
+}
+
+func TestCognitoAuthorizer(t *testing.T) {
+	synth(t)
+
+	check(t, func() {
+		template.ResourceCountIs(jsii.String("AWS::ApiGateway::Authorizer"), jsii.Number(1))
+	})
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::ApiGateway::Authorizer"), map[string]interface{}{
+			"Type": "COGNITO_USER_POOLS",
+		})
+	})
+
+	authorizer := ""
+	for id, res := range resources {
+		if res.Type == "AWS::ApiGateway::Authorizer" {
+			authorizer = "${" + names[id] + "}"
+		}
+	}
+
+	for route, properties := range routes() {
+		// Preflight requests are sent by browsers without credentials
+		if strings.HasPrefix(route, "OPTIONS ") {
+			if properties["AuthorizationType"] != "NONE" {
+				t.Errorf("Preflight route %s requires authorization %v", route, properties["AuthorizationType"])
+			}
+			continue
+		}
+		if properties["AuthorizationType"] != "COGNITO_USER_POOLS" || render(properties["AuthorizerId"]) != authorizer {
+			t.Errorf("Route %s is not authorized by Cognito: %v %v", route, properties["AuthorizationType"], render(properties["AuthorizerId"]))
+		}
+	}
+}
+
+func TestTablesTTL(t *testing.T) {
+	synth(t)
+
+	for _, table := range []string{"GO_CodesTable", "GO_DeliveriesTable", "GO_IdempotencyTable", "GO_ExportsTable"} {
+		check(t, func() {
+			template.HasResourceProperties(jsii.String("AWS::DynamoDB::Table"), map[string]interface{}{
+				"TableName": table,
+				"TimeToLiveSpecification": map[string]interface{}{
+					"AttributeName": "ExpireOn",
+					"Enabled":       true,
+				},
+			})
+		})
+	}
+}
+
+func TestTablesBackup(t *testing.T) {
+	synth(t)
+
+	// data of a stack without stages outlives it and can be restored to any point of last 35 days
+	check(t, func() {
+		template.AllResources(jsii.String("AWS::DynamoDB::Table"), map[string]interface{}{
+			"DeletionPolicy":      "Retain",
+			"UpdateReplacePolicy": "Retain",
+			"Properties": assertions.Match_ObjectLike(&map[string]interface{}{
+				"BillingMode":                      "PAY_PER_REQUEST",
+				"PointInTimeRecoverySpecification": map[string]interface{}{"PointInTimeRecoveryEnabled": true},
+			}),
+		})
+	})
+}
+
+func TestExports(t *testing.T) {
+	synth(t)
+
+	// exports of personal data are private and expire after a week
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::S3::Bucket"), map[string]interface{}{
+			"PublicAccessBlockConfiguration": map[string]interface{}{
+				"BlockPublicAcls":       true,
+				"BlockPublicPolicy":     true,
+				"IgnorePublicAcls":      true,
+				"RestrictPublicBuckets": true,
+			},
+			"LifecycleConfiguration": map[string]interface{}{
+				"Rules": []interface{}{map[string]interface{}{"ExpirationInDays": 7, "Status": "Enabled"}},
+			},
+		})
+	})
+	// exports of deleted accounts are found by listing their prefix only
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::IAM::Policy"), map[string]interface{}{
+			"PolicyDocument": map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{assertions.Match_ObjectLike(&map[string]interface{}{
+					"Action":    "s3:ListBucket",
+					"Condition": map[string]interface{}{"StringLike": map[string]interface{}{"s3:prefix": "exports/*"}},
+				})}),
+			},
+		})
+	})
+	// asynchronous exports aren't retried
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::Lambda::EventInvokeConfig"), map[string]interface{}{
+			"MaximumRetryAttempts": 0,
+		})
+	})
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::ApiGateway::RestApi"), map[string]interface{}{
+			"BinaryMediaTypes": []interface{}{"application/zip"},
+		})
+	})
+}
+
+func TestTracing(t *testing.T) {
+	synth(t)
+
+	for _, res := range resources {
+		// functions of custom resources aren't traced
+		if res.Type != "AWS::Lambda::Function" || res.Properties["FunctionName"] == nil {
+			continue
+		}
+		tracingConfig, _ := res.Properties["TracingConfig"].(map[string]interface{})
+		if tracingConfig["Mode"] != "Active" {
+			t.Errorf("Function %v isn't traced: %v", render(res.Properties["FunctionName"]), res.Properties["TracingConfig"])
+		}
+	}
+
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::ApiGateway::Stage"), map[string]interface{}{
+			"TracingEnabled": true,
+		})
+	})
+}
+
+func TestMonitoring(t *testing.T) {
+	synth(t)
+
+	// errors and throttles of 14 functions, scheduler failures, dead letters, SMS and API
+	check(t, func() {
+		template.ResourceCountIs(jsii.String("AWS::CloudWatch::Alarm"), jsii.Number(34))
+	})
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::SNS::Topic"), map[string]interface{}{
+			"TopicName": "GO_OpsTopic",
+		})
+	})
+
+	var opsTopic string
+	for id, res := range resources {
+		if res.Type == "AWS::SNS::Topic" && res.Properties["TopicName"] == "GO_OpsTopic" {
+			opsTopic = id
+		}
+	}
+	alarms := make(map[string]map[string]interface{})
+	for _, res := range resources {
+		if res.Type != "AWS::CloudWatch::Alarm" {
+			continue
+		}
+		name, _ := res.Properties["AlarmName"].(string)
+		alarms[name] = res.Properties
+		for _, actions := range []string{"AlarmActions", "OKActions"} {
+			if received := render(list(res.Properties[actions])[0]); received != "${GO_OpsTopic}" {
+				t.Errorf("Received %s of alarm %s: %v are different than expected ones", actions, name, received)
+			}
+		}
+	}
+	if opsTopic == "" {
+		t.Fatal("Ops topic not found")
+	}
+
+	testCases := []struct {
+		alarm      string
+		metric     string
+		dimension  string
+		value      string
+		threshold  float64
+		expression bool
+	}{
+		{alarm: "GO_AlarmExecutorErrors", metric: "Errors", dimension: "FunctionName", value: "${GO_AlarmExecutor}", threshold: 1},
+		{alarm: "GO_AlarmExecutorThrottles", metric: "Throttles", dimension: "FunctionName", value: "${GO_AlarmExecutor}", threshold: 1},
+		{alarm: "GO_ReconcilerErrors", metric: "Errors", dimension: "FunctionName", value: "${GO_Reconciler}", threshold: 1},
+		{alarm: "GO_SchedulerTargetErrors", metric: "TargetErrorCount", dimension: "ScheduleGroup", value: "${GO_Alarms}", threshold: 1},
+		{alarm: "GO_SchedulerDroppedInvocations", metric: "InvocationDroppedCount", dimension: "ScheduleGroup", value: "${GO_Alarms}", threshold: 1},
+		{alarm: "GO_DeadLetterQueueDepth", metric: "ApproximateNumberOfMessagesVisible", dimension: "QueueName", value: "${GO_AlarmsDeadLetterQueue.QueueName}", threshold: 1},
+		{alarm: "GO_SmsSpend", metric: "SMSMonthToDateSpentUSD", threshold: 10},
+		{alarm: "GO_SmsFailureRate", threshold: 10, expression: true},
+		{alarm: "GO_ApiServerErrorRate", threshold: 5, expression: true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.alarm, func(t *testing.T) {
+			alarm, ok := alarms[tC.alarm]
+			if !ok {
+				t.Fatalf("Alarm %s not found", tC.alarm)
+			}
+			if alarm["Threshold"] != tC.threshold {
+				t.Errorf("Received threshold: %v is different than expected one: %v", alarm["Threshold"], tC.threshold)
+			}
+			if tC.expression {
+				if alarm["Metrics"] == nil {
+					t.Errorf("Alarm isn't raised on metric math expression")
+				}
+				return
+			}
+			if alarm["MetricName"] != tC.metric {
+				t.Errorf("Received metric: %v is different than expected one: %v", alarm["MetricName"], tC.metric)
+			}
+			// metrics reported for the whole account have no dimensions, alarms with any would get no data
+			if tC.dimension == "" {
+				if alarm["Dimensions"] != nil {
+					t.Errorf("Received dimensions: %v are different than expected none", alarm["Dimensions"])
+				}
+				return
+			}
+			dimensions := make(map[string]string)
+			for _, dimension := range list(alarm["Dimensions"]) {
+				dimension, _ := dimension.(map[string]interface{})
+				name, _ := dimension["Name"].(string)
+				dimensions[name] = render(dimension["Value"])
+			}
+			if dimensions[tC.dimension] != tC.value {
+				t.Errorf("Received dimensions: %v are different than expected %s: %v", dimensions, tC.dimension, tC.value)
+			}
+		})
+	}
+
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::CloudWatch::Dashboard"), map[string]interface{}{
+			"DashboardName": "GO_Dashboard",
+		})
+	})
+	// schedules deliver events they fail to deliver to dead letter queue kept for two weeks
+	check(t, func() {
+		template.HasResourceProperties(jsii.String("AWS::SQS::Queue"), map[string]interface{}{
+			"QueueName":              "GO_AlarmsDeadLetterQueue",
+			"MessageRetentionPeriod": 14 * 24 * 60 * 60,
+		})
+	})
+}
+
+func TestStages(t *testing.T) {
+	app := awscdk.NewApp(&awscdk.AppProps{
+		Context: &map[string]interface{}{
+			"aws:cdk:bundling-stacks": []string{},
+			"stages": map[string]interface{}{
+				"dev": map[string]interface{}{
+					"removalPolicy":       "destroy",
+					"pointInTimeRecovery": false,
+					"billingMode":         "provisioned",
+					"logRetention":        "one_week",
+					"logLevel":            "debug",
+					"allowedOrigins":      []string{"https://dev.example.com", "http://localhost:3000"},
+					"opsEmails":           []string{"ops@example.com"},
+					"smsSpendAlarm":       5,
+					"features":            map[string]interface{}{"reconciler": false},
+				},
+				"prod": map[string]interface{}{
+					"stackName":  "ReminderStack",
+					"namePrefix": "GO_",
+					"account":    "123456789012",
+					"region":     "eu-central-1",
+				},
+			},
+		},
+	})
+
+	stacks, err := stages(app)
+	if err != nil {
+		t.Fatalf("Error when reading stages: %v", err)
+	}
+	if ids := sortedStacks(stacks); !reflect.DeepEqual(ids, []string{"ReminderStack", "ReminderStack-dev"}) {
+		t.Fatalf("Received stacks: %v are different than expected ones", ids)
+	}
+
+	prod := stacks["ReminderStack"]
+	if prod.Stage != "prod" || prod.NamePrefix != "GO_" || prod.RemovalPolicy != awscdk.RemovalPolicy_RETAIN || *prod.Env.Region != "eu-central-1" {
+		t.Errorf("Received props of prod stage: %+v are different than expected ones", prod)
+	}
+	// data of stages is protected unless they opt out of it
+	if !prod.PointInTimeRecovery || prod.BillingMode != awsdynamodb.BillingMode_PAY_PER_REQUEST {
+		t.Errorf("Received table settings of prod stage: %v, %v are different than expected ones", prod.PointInTimeRecovery, prod.BillingMode)
+	}
+	// features that aren't configured keep their defaults
+	if !prod.Features.Reconciler || !prod.Features.SelfSignUp || prod.Features.ReconcilerDryRun {
+		t.Errorf("Received features of prod stage: %+v are different than expected ones", prod.Features)
+	}
+
+	dev := stacks["ReminderStack-dev"]
+	if dev.NamePrefix != "GO_dev_" || dev.RemovalPolicy != awscdk.RemovalPolicy_DESTROY || dev.PointInTimeRecovery || dev.LogLevel != "debug" || dev.Features.Reconciler {
+		t.Errorf("Received props of dev stage: %+v are different than expected ones", dev)
+	}
+
+	stack := NewAlerterStack(app, "ReminderStack-dev", dev)
+	devTemplate := assertions.Template_FromStack(stack, nil)
+
+	check(t, func() {
+		devTemplate.HasResourceProperties(jsii.String("AWS::DynamoDB::Table"), map[string]interface{}{
+			"TableName": "GO_dev_AlarmTable",
+		})
+	})
+	check(t, func() {
+		devTemplate.AllResources(jsii.String("AWS::DynamoDB::Table"), map[string]interface{}{
+			"DeletionPolicy": "Delete",
+			"Properties": assertions.Match_ObjectLike(&map[string]interface{}{
+				"PointInTimeRecoverySpecification": map[string]interface{}{"PointInTimeRecoveryEnabled": false},
+				"ProvisionedThroughput":            assertions.Match_AnyValue(),
+			}),
+		})
+	})
+	check(t, func() {
+		devTemplate.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"FunctionName": "GO_dev_AlarmCreator",
+			"Environment": map[string]interface{}{
+				"Variables": assertions.Match_ObjectLike(&map[string]interface{}{
+					"LOG_LEVEL":            "debug",
+					"STAGE":                "dev",
+					"CORS_ALLOWED_ORIGINS": "https://dev.example.com,http://localhost:3000",
+				}),
+			},
+			"Tags": assertions.Match_ArrayWith(&[]interface{}{
+				map[string]interface{}{"Key": "Stage", "Value": "dev"},
+			}),
+		})
+	})
+	check(t, func() {
+		devTemplate.HasResourceProperties(jsii.String("Custom::LogRetention"), map[string]interface{}{
+			"RetentionInDays": 7,
+		})
+	})
+	check(t, func() {
+		devTemplate.ResourcePropertiesCountIs(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"FunctionName": "GO_dev_Reconciler",
+		}, jsii.Number(0))
+	})
+	check(t, func() {
+		devTemplate.ResourceCountIs(jsii.String("AWS::Events::Rule"), jsii.Number(0))
+	})
+	check(t, func() {
+		devTemplate.HasResourceProperties(jsii.String("AWS::SNS::Subscription"), map[string]interface{}{
+			"Protocol": "email",
+			"Endpoint": "ops@example.com",
+		})
+	})
+	check(t, func() {
+		devTemplate.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"AlarmName": "GO_dev_SmsSpend",
+			"Threshold": 5,
+		})
+	})
+
+	for _, invalid := range []map[string]interface{}{
+		{"dev": map[string]interface{}{"removalPolicy": "keep"}},
+		{"dev": map[string]interface{}{"billingMode": "free"}},
+		{"dev": map[string]interface{}{"logRetention": "one_fortnight"}},
+		{"dev": map[string]interface{}{"allowedOrigins": []string{}}},
+		{"dev": map[string]interface{}{"smsSpendAlarm": 0}},
+		{"dev": map[string]interface{}{"stackName": "ReminderStack"}, "prod": map[string]interface{}{"stackName": "ReminderStack"}},
+	} {
+		app := awscdk.NewApp(&awscdk.AppProps{Context: &map[string]interface{}{"stages": invalid}})
+		if _, err := stages(app); err == nil {
+			t.Errorf("Invalid stages: %v were accepted", invalid)
+		}
+	}
+}
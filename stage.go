@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsdynamodb"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// Features turn optional parts of the stack on and off
+type Features struct {
+	// Reconciler repairs drift between alarms table and schedules every night
+	Reconciler bool `json:"reconciler"`
+	// ReconcilerDryRun makes reconciler only report drift
+	ReconcilerDryRun bool `json:"reconcilerDryRun"`
+	// SelfSignUp lets users sign up on their own
+	SelfSignUp bool `json:"selfSignUp"`
+}
+
+type AlerterStackProps struct {
+	awscdk.StackProps
+	// Stage is a name of deployment stage, e.g. "dev" or "prod", it's added to resources as a tag
+	Stage string
+	// NamePrefix is prepended to names of all resources, so that stages can share an account
+	NamePrefix string
+	// RemovalPolicy is applied to tables when they're removed from the stack
+	RemovalPolicy awscdk.RemovalPolicy
+	// PointInTimeRecovery enables continuous backups of tables, so they can be restored to any
+	// second of last 35 days
+	PointInTimeRecovery bool
+	// BillingMode of tables, they're billed per request when it's empty
+	BillingMode awsdynamodb.BillingMode
+	// LogRetention is how long logs of functions are kept, they never expire when it's empty
+	LogRetention awslogs.RetentionDays
+	// LogLevel is passed to functions in LOG_LEVEL variable
+	LogLevel string
+	// AllowedOrigins are origins allowed to call API from a browser, e.g. "https://app.example.com",
+	// all of them are allowed with "*"
+	AllowedOrigins []string
+	// OpsEmails are subscribed to ops topic monitoring alarms are published to
+	OpsEmails []string
+	// SmsSpendAlarm is month to date SMS spend in USD that raises an alarm
+	SmsSpendAlarm float64
+	Features      Features
+}
+
+// DefaultAlerterStackProps returns props of a stack with resource names used before stages were
+// introduced. Props of stages should start from them, since features are opt-out and data is
+// protected unless a stage opts out of it
+func DefaultAlerterStackProps() *AlerterStackProps {
+	return &AlerterStackProps{
+		NamePrefix:          "GO_",
+		RemovalPolicy:       awscdk.RemovalPolicy_RETAIN,
+		PointInTimeRecovery: true,
+		BillingMode:         awsdynamodb.BillingMode_PAY_PER_REQUEST,
+		LogLevel:            "info",
+		AllowedOrigins:      []string{"*"},
+		SmsSpendAlarm:       10,
+		Features: Features{
+			Reconciler: true,
+			SelfSignUp: true,
+		},
+	}
+}
+
+// name returns name of a resource in the stage
+func (p *AlerterStackProps) name(resource string) *string {
+	return jsii.String(p.NamePrefix + resource)
+}
+
+// metricsStage returns value of stage dimension of business metrics functions of the stage emit
+func (p *AlerterStackProps) metricsStage() string {
+	if p.Stage == "" {
+		return "default"
+	}
+	return p.Stage
+}
+
+// stageConfig is configuration of a stage in "stages" context of the app
+type stageConfig struct {
+	// StackName defaults to "ReminderStack-<stage>"
+	StackName string `json:"stackName"`
+	// NamePrefix defaults to "GO_<stage>_"
+	NamePrefix string `json:"namePrefix"`
+	// RemovalPolicy is one of "destroy", "retain" and "snapshot", it defaults to "retain"
+	RemovalPolicy       string `json:"removalPolicy"`
+	PointInTimeRecovery bool   `json:"pointInTimeRecovery"`
+	// BillingMode is either "pay_per_request" or "provisioned"
+	BillingMode string `json:"billingMode"`
+	// LogRetention is a name of awslogs.RetentionDays value, e.g. "ONE_MONTH"
+	LogRetention   string   `json:"logRetention"`
+	LogLevel       string   `json:"logLevel"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+	OpsEmails      []string `json:"opsEmails"`
+	SmsSpendAlarm  float64  `json:"smsSpendAlarm"`
+	Account        string   `json:"account"`
+	Region         string   `json:"region"`
+	Features       Features `json:"features"`
+}
+
+var billingModes = map[string]awsdynamodb.BillingMode{
+	"pay_per_request": awsdynamodb.BillingMode_PAY_PER_REQUEST,
+	"provisioned":     awsdynamodb.BillingMode_PROVISIONED,
+}
+
+var removalPolicies = map[string]awscdk.RemovalPolicy{
+	"destroy":  awscdk.RemovalPolicy_DESTROY,
+	"retain":   awscdk.RemovalPolicy_RETAIN,
+	"snapshot": awscdk.RemovalPolicy_SNAPSHOT,
+}
+
+// logRetentions are awslogs.RetentionDays values by their lower case names, empty one keeps logs forever
+var logRetentions = func() map[string]awslogs.RetentionDays {
+	retentions := map[string]awslogs.RetentionDays{"": ""}
+	for _, retention := range []awslogs.RetentionDays{
+		awslogs.RetentionDays_ONE_DAY, awslogs.RetentionDays_THREE_DAYS, awslogs.RetentionDays_FIVE_DAYS,
+		awslogs.RetentionDays_ONE_WEEK, awslogs.RetentionDays_TWO_WEEKS, awslogs.RetentionDays_ONE_MONTH,
+		awslogs.RetentionDays_TWO_MONTHS, awslogs.RetentionDays_THREE_MONTHS, awslogs.RetentionDays_FOUR_MONTHS,
+		awslogs.RetentionDays_FIVE_MONTHS, awslogs.RetentionDays_SIX_MONTHS, awslogs.RetentionDays_ONE_YEAR,
+		awslogs.RetentionDays_THIRTEEN_MONTHS, awslogs.RetentionDays_EIGHTEEN_MONTHS, awslogs.RetentionDays_TWO_YEARS,
+		awslogs.RetentionDays_THREE_YEARS, awslogs.RetentionDays_FIVE_YEARS, awslogs.RetentionDays_SIX_YEARS,
+		awslogs.RetentionDays_SEVEN_YEARS, awslogs.RetentionDays_EIGHT_YEARS, awslogs.RetentionDays_NINE_YEARS,
+		awslogs.RetentionDays_TEN_YEARS, awslogs.RetentionDays_INFINITE,
+	} {
+		retentions[strings.ToLower(string(retention))] = retention
+	}
+	return retentions
+}()
+
+// stages reads props of stacks from "stages" context of the app, keyed by their IDs. Only the
+// stage given in "stage" context is returned when it's set. Without stages a single stack
+// with default props is returned, as it was deployed before stages were introduced
+func stages(app awscdk.App) (map[string]*AlerterStackProps, error) {
+	raw := app.Node().TryGetContext(jsii.String("stages"))
+	if raw == nil {
+		return map[string]*AlerterStackProps{"ReminderStack": DefaultAlerterStackProps()}, nil
+	}
+
+	// context passed on command line is a JSON string rather than an object
+	data, ok := raw.(string)
+	if !ok {
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		data = string(encoded)
+	}
+	var configs map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &configs); err != nil {
+		return nil, fmt.Errorf("invalid stages context: %w", err)
+	}
+
+	selected, _ := app.Node().TryGetContext(jsii.String("stage")).(string)
+	if _, ok := configs[selected]; selected != "" && !ok {
+		return nil, fmt.Errorf("stage %q is not configured", selected)
+	}
+
+	stacks := make(map[string]*AlerterStackProps)
+	for stage, rawConfig := range configs {
+		if selected != "" && stage != selected {
+			continue
+		}
+
+		defaults := DefaultAlerterStackProps()
+		config := stageConfig{
+			StackName:           "ReminderStack-" + stage,
+			NamePrefix:          "GO_" + stage + "_",
+			RemovalPolicy:       "retain",
+			PointInTimeRecovery: defaults.PointInTimeRecovery,
+			BillingMode:         "pay_per_request",
+			LogLevel:            defaults.LogLevel,
+			AllowedOrigins:      defaults.AllowedOrigins,
+			SmsSpendAlarm:       defaults.SmsSpendAlarm,
+			Features:            defaults.Features,
+		}
+		if err := json.Unmarshal(rawConfig, &config); err != nil {
+			return nil, fmt.Errorf("invalid configuration of stage %s: %w", stage, err)
+		}
+
+		removalPolicy, ok := removalPolicies[strings.ToLower(config.RemovalPolicy)]
+		if !ok {
+			return nil, fmt.Errorf("invalid removal policy of stage %s: %q", stage, config.RemovalPolicy)
+		}
+		billingMode, ok := billingModes[strings.ToLower(config.BillingMode)]
+		if !ok {
+			return nil, fmt.Errorf("invalid billing mode of stage %s: %q", stage, config.BillingMode)
+		}
+		logRetention, ok := logRetentions[strings.ToLower(config.LogRetention)]
+		if !ok {
+			return nil, fmt.Errorf("invalid log retention of stage %s: %q", stage, config.LogRetention)
+		}
+		if len(config.AllowedOrigins) == 0 {
+			return nil, fmt.Errorf("stage %s doesn't allow any origins", stage)
+		}
+		if config.SmsSpendAlarm <= 0 {
+			return nil, fmt.Errorf("SMS spend alarm of stage %s must be positive", stage)
+		}
+		if _, ok := stacks[config.StackName]; ok {
+			return nil, fmt.Errorf("stack name %s is used by more than one stage", config.StackName)
+		}
+
+		props := &AlerterStackProps{
+			Stage:               stage,
+			NamePrefix:          config.NamePrefix,
+			RemovalPolicy:       removalPolicy,
+			PointInTimeRecovery: config.PointInTimeRecovery,
+			BillingMode:         billingMode,
+			LogRetention:        logRetention,
+			LogLevel:            config.LogLevel,
+			AllowedOrigins:      config.AllowedOrigins,
+			OpsEmails:           config.OpsEmails,
+			SmsSpendAlarm:       config.SmsSpendAlarm,
+			Features:            config.Features,
+		}
+		props.StackName = jsii.String(config.StackName)
+		if config.Account != "" || config.Region != "" {
+			props.Env = &awscdk.Environment{}
+			if config.Account != "" {
+				props.Env.Account = jsii.String(config.Account)
+			}
+			if config.Region != "" {
+				props.Env.Region = jsii.String(config.Region)
+			}
+		}
+		stacks[config.StackName] = props
+	}
+	return stacks, nil
+}
+
+// sortedStacks returns IDs of stacks in alphabetical order, so that they're synthesized deterministically
+func sortedStacks(stacks map[string]*AlerterStackProps) []string {
+	ids := make([]string, 0, len(stacks))
+	for id := range stacks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}